@@ -0,0 +1,98 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package ast
+
+import (
+	"strconv"
+
+	"github.com/creachadair/jtree/internal/escape"
+	"go4.org/mem"
+)
+
+// AppendJSON appends the JSON encoding of v to buf and returns the extended
+// buffer. The result is the same as append(buf, v.JSON()...), but the values
+// nested in v are appended to buf directly, rather than rendered as separate
+// strings, so the cost is linear in the size of the output regardless of the
+// depth of v.
+//
+// A value of a type defined outside this package is appended using its own
+// AppendJSON method, if it has one with this signature:
+//
+//	AppendJSON(buf []byte) []byte
+//
+// and otherwise using its JSON method.
+func AppendJSON(buf []byte, v Value) []byte {
+	switch t := v.(type) {
+	case Object:
+		return t.AppendJSON(buf)
+	case Array:
+		return t.AppendJSON(buf)
+	case *Member:
+		return t.AppendJSON(buf)
+	case Member:
+		return t.AppendJSON(buf)
+	case quotedText:
+		return mem.Append(buf, t.data)
+	case *quotedText:
+		return mem.Append(buf, t.data)
+	case String:
+		return escape.AppendQuote(buf, mem.S(string(t)), 0)
+	case rawNumber:
+		return append(buf, t.text...)
+	case *rawNumber:
+		return append(buf, t.text...)
+	case Int:
+		return strconv.AppendInt(buf, int64(t), 10)
+	case Bool:
+		return strconv.AppendBool(buf, bool(t))
+	case nullValue:
+		return append(buf, "null"...)
+	case jsonAppender:
+		return t.AppendJSON(buf)
+	}
+	return append(buf, v.JSON()...)
+}
+
+// A jsonAppender is a Value that can append its JSON encoding to a buffer.
+type jsonAppender interface {
+	AppendJSON(buf []byte) []byte
+}
+
+// AppendJSON appends the JSON encoding of o to buf, as described by the
+// AppendJSON function.
+func (o Object) AppendJSON(buf []byte) []byte {
+	buf = append(buf, '{')
+	for i, m := range o {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = m.AppendJSON(buf)
+	}
+	return append(buf, '}')
+}
+
+// AppendJSON appends the JSON encoding of m to buf, as described by the
+// AppendJSON function.
+func (m Member) AppendJSON(buf []byte) []byte {
+	switch m.Key.(type) {
+	case quotedText, *quotedText, String:
+		buf = AppendJSON(buf, m.Key) // avoid allocating a quoted copy
+	default:
+		buf = AppendJSON(buf, m.Key.Quote())
+	}
+	buf = append(buf, ':')
+	return AppendJSON(buf, m.Value)
+}
+
+// AppendJSON appends the JSON encoding of a to buf, as described by the
+// AppendJSON function.
+func (a Array) AppendJSON(buf []byte) []byte {
+	buf = append(buf, '[')
+	for i, v := range a {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = AppendJSON(buf, v)
+	}
+	return append(buf, ']')
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package ast
+
+import (
+	"github.com/creachadair/jtree"
+	"go4.org/mem"
+)
+
+// An Arena allocates the nodes of syntax trees in large blocks, to reduce the
+// number of separate allocations, and hence the load on the garbage
+// collector, when parsing large inputs. To use an arena, pass it to the
+// SetArena method of a Parser or Builder. A zero Arena is ready for use.
+//
+// The values constructed using an arena remain valid until the arena is
+// released. After a call to Release, the arena reuses its blocks to construct
+// new values, so the caller must not use any value constructed before the
+// call. An Arena is not safe for concurrent use by multiple goroutines.
+type Arena struct {
+	members block[Member]
+	keys    block[*Member] // storage for Objects
+	elts    block[Value]   // storage for Arrays
+	texts   block[quotedText]
+	nums    block[rawNumber]
+}
+
+// Release discards all the values constructed using a, so that their storage
+// can be reused.
+func (a *Arena) Release() {
+	a.members.release()
+	a.keys.release()
+	a.elts.release()
+	a.texts.release()
+	a.nums.release()
+}
+
+// member returns a new member with the specified key.
+func (a *Arena) member(key Text) *Member {
+	if a == nil {
+		return &Member{Key: key}
+	}
+	m := &a.members.alloc(1)[0]
+	m.Key = key
+	return m
+}
+
+// object returns a new empty Object with capacity n.
+func (a *Arena) object(n int) Object {
+	if a == nil {
+		return make(Object, 0, n)
+	}
+	return a.keys.alloc(n)[:0]
+}
+
+// array returns a new Array of length n.
+func (a *Arena) array(n int) Array {
+	if a == nil {
+		return make(Array, n)
+	}
+	return a.elts.alloc(n)
+}
+
+// key constructs an object key from loc, as AnchorKey does.
+func (a *Arena) key(loc jtree.Anchor, ic jtree.StringInterner) Text {
+	if a == nil {
+		return AnchorKey(loc, ic)
+	}
+	q := &a.texts.alloc(1)[0]
+	q.data = mem.S(ic.Intern(stringText(loc)))
+	return q
+}
+
+// value constructs a Value from loc, as AnchorValue does.
+func (a *Arena) value(loc jtree.Anchor) (Value, error) {
+	if a != nil {
+		switch tok := loc.Token(); tok {
+		case jtree.String:
+			if isJSONString(loc.Text()) {
+				q := &a.texts.alloc(1)[0]
+				q.data = mem.B(jtree.AnchorBytes(loc))
+				return q, nil
+			}
+		case jtree.Integer, jtree.Number:
+			if isJSONNumber(loc.Text()) {
+				n := &a.nums.alloc(1)[0]
+				n.text, n.isInt = jtree.AnchorBytes(loc), tok == jtree.Integer
+				return n, nil
+			}
+		}
+	}
+	return AnchorValue(loc)
+}
+
+// blockSize is the number of elements in each block of an arena.
+const blockSize = 1024
+
+// A block is a sequence of blocks of values of type T, allocated in order.
+type block[T any] struct {
+	bufs [][]T
+	cur  int // index of the block currently being allocated
+	used int // number of elements used in the current block
+}
+
+// alloc returns a slice of n zero values of type T. A request larger than
+// the block size is allocated separately.
+func (b *block[T]) alloc(n int) []T {
+	if n > blockSize/4 {
+		return make([]T, n)
+	}
+	for {
+		if b.cur == len(b.bufs) {
+			b.bufs = append(b.bufs, make([]T, blockSize))
+		}
+		if buf := b.bufs[b.cur]; b.used+n <= len(buf) {
+			out := buf[b.used : b.used+n : b.used+n]
+			b.used += n
+			return out
+		}
+		b.cur++
+		b.used = 0
+	}
+}
+
+// release zeroes the elements of b that have been allocated, and rewinds b
+// to reuse them.
+func (b *block[T]) release() {
+	for i := 0; i < len(b.bufs) && i <= b.cur; i++ {
+		clear(b.bufs[i])
+	}
+	b.cur, b.used = 0, 0
+}
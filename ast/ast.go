@@ -6,6 +6,7 @@ package ast
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -72,6 +73,79 @@ func (o Object) IndexKey(f func(Text) bool) int {
 	return -1
 }
 
+// Get returns the value of the first member of o whose key is exactly equal
+// to key, and reports whether such a member exists. A member whose value is
+// null is reported as present, with value Null.
+//
+// The typed accessors GetString, GetInt, GetFloat, GetBool, GetArray, and
+// GetObject report false both for a missing member and for a member whose
+// value has a different type, including null. Use Get or IsNull to
+// distinguish these cases.
+func (o Object) Get(key string) (Value, bool) {
+	if m := o.FindKey(TextEqual(key)); m != nil {
+		return m.Value, true
+	}
+	return nil, false
+}
+
+// IsNull reports whether o has a member with the given key whose value is
+// null. It reports false if there is no such member.
+func (o Object) IsNull(key string) bool {
+	v, ok := o.Get(key)
+	return ok && v == Null
+}
+
+// GetString returns the string value of the member of o with the given key.
+func (o Object) GetString(key string) (string, bool) {
+	v, _ := o.Get(key)
+	if t, ok := v.(Text); ok {
+		return t.String(), true
+	}
+	return "", false
+}
+
+// GetInt returns the integer value of the member of o with the given key.
+// It reports false if the value is a number that is not an integer.
+func (o Object) GetInt(key string) (int64, bool) {
+	v, _ := o.Get(key)
+	if n, ok := v.(Number); ok && n.IsInt() {
+		return int64(n.Int()), true
+	}
+	return 0, false
+}
+
+// GetFloat returns the numeric value of the member of o with the given key.
+func (o Object) GetFloat(key string) (float64, bool) {
+	v, _ := o.Get(key)
+	if n, ok := v.(Number); ok {
+		return float64(n.Float()), true
+	}
+	return 0, false
+}
+
+// GetBool returns the Boolean value of the member of o with the given key.
+func (o Object) GetBool(key string) (bool, bool) {
+	v, _ := o.Get(key)
+	if b, ok := v.(Bool); ok {
+		return bool(b), true
+	}
+	return false, false
+}
+
+// GetArray returns the array value of the member of o with the given key.
+func (o Object) GetArray(key string) (Array, bool) {
+	v, _ := o.Get(key)
+	a, ok := v.(Array)
+	return a, ok
+}
+
+// GetObject returns the object value of the member of o with the given key.
+func (o Object) GetObject(key string) (Object, bool) {
+	v, _ := o.Get(key)
+	obj, ok := v.(Object)
+	return obj, ok
+}
+
 // Len returns the number of members in the object.
 func (o Object) Len() int { return len(o) }
 
@@ -80,15 +154,7 @@ func (o Object) JSON() string {
 	if len(o) == 0 {
 		return "{}"
 	}
-	var sb strings.Builder
-	sb.WriteByte('{')
-	sb.WriteString(o[0].JSON())
-	for _, elt := range o[1:] {
-		sb.WriteByte(',')
-		sb.WriteString(elt.JSON())
-	}
-	sb.WriteByte('}')
-	return sb.String()
+	return string(o.AppendJSON(nil))
 }
 
 func (o Object) String() string { return fmt.Sprintf("Object(len=%d)", len(o)) }
@@ -98,6 +164,37 @@ func (o Object) Sort() {
 	sort.Slice(o, func(i, j int) bool { return o[i].Key.String() < o[j].Key.String() })
 }
 
+// SortStable sorts the object in ascending order by key, keeping members with
+// equal keys in their original order.
+func (o Object) SortStable() {
+	sort.SliceStable(o, func(i, j int) bool { return o[i].Key.String() < o[j].Key.String() })
+}
+
+// SortBy sorts the object in the order defined by less, keeping members that
+// are not ordered by less in their original order.
+func (o Object) SortBy(less func(a, b *Member) bool) {
+	sort.SliceStable(o, func(i, j int) bool { return less(o[i], o[j]) })
+}
+
+// SortKeys sorts the object so that members whose keys are listed in order
+// come first, in the order listed. The remaining members follow in their
+// original order. Keys are compared case-sensitively.
+func (o Object) SortKeys(order []string) {
+	pos := make(map[string]int, len(order))
+	for i, key := range order {
+		if _, ok := pos[key]; !ok {
+			pos[key] = i
+		}
+	}
+	rank := func(m *Member) int {
+		if i, ok := pos[m.Key.String()]; ok {
+			return i
+		}
+		return len(order)
+	}
+	o.SortBy(func(a, b *Member) bool { return rank(a) < rank(b) })
+}
+
 // A Member is a single key-value pair belonging to an Object. A Key must
 // support being rendered as text, typically an ast.String.
 type Member struct {
@@ -111,6 +208,37 @@ func Field(key string, value any) *Member {
 	return &Member{Key: String(key), Value: ToValue(value)}
 }
 
+// ObjectOf constructs an object from alternating keys and values. Each key
+// must be a string, and each value must be a string, int, float, bool, nil,
+// or ast.Value. It panics if the arguments do not have this form.
+//
+//	ObjectOf("name", "Dennis", "age", 37) // {"name":"Dennis","age":37}
+func ObjectOf(pairs ...any) Object {
+	if len(pairs)%2 != 0 {
+		panic("odd number of arguments to ObjectOf")
+	}
+	out := make(Object, len(pairs)/2)
+	for i := range out {
+		key, ok := pairs[2*i].(string)
+		if !ok {
+			panic(fmt.Sprintf("invalid key %T", pairs[2*i]))
+		}
+		out[i] = Field(key, pairs[2*i+1])
+	}
+	return out
+}
+
+// ArrayOf constructs an array of the given values, each of which must be a
+// string, int, float, bool, nil, or ast.Value. It panics if any value does
+// not have one of those types.
+func ArrayOf(vs ...any) Array {
+	out := make(Array, len(vs))
+	for i, v := range vs {
+		out[i] = ToValue(v)
+	}
+	return out
+}
+
 // ToValue converts a string, int, float, bool, nil, or ast.Value into an
 // ast.Value. It panics if v does not have one of those types.
 func ToValue(v any) Value {
@@ -135,15 +263,7 @@ func ToValue(v any) Value {
 }
 
 // JSON renders the member as JSON text.
-func (m Member) JSON() string {
-	k := m.Key.Quote().JSON()
-	v := m.Value.JSON()
-	buf := make([]byte, len(k)+len(v)+1)
-	n := copy(buf, k)
-	buf[n] = ':'
-	copy(buf[n+1:], v)
-	return string(buf)
-}
+func (m Member) JSON() string { return string(m.AppendJSON(nil)) }
 
 func (m Member) String() string { return fmt.Sprintf("Member(key=%q)", m.Key) }
 
@@ -158,19 +278,95 @@ func (a Array) JSON() string {
 	if len(a) == 0 {
 		return "[]"
 	}
-	var sb strings.Builder
-	sb.WriteString("[")
-	sb.WriteString(a[0].JSON())
-	for _, elt := range a[1:] {
-		sb.WriteByte(',')
-		sb.WriteString(elt.JSON())
-	}
-	sb.WriteByte(']')
-	return sb.String()
+	return string(a.AppendJSON(nil))
 }
 
 func (a Array) String() string { return fmt.Sprintf("Array(len=%d)", len(a)) }
 
+// MapValues returns a new array containing the results of calling f for each
+// element of a, in order.
+func (a Array) MapValues(f func(Value) Value) Array {
+	out := make(Array, len(a))
+	for i, v := range a {
+		out[i] = f(v)
+	}
+	return out
+}
+
+// FilterValues returns a new array containing the elements of a for which
+// pred reports true, in order.
+func (a Array) FilterValues(pred func(Value) bool) Array {
+	var out Array
+	for _, v := range a {
+		if pred(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// IndexWhere returns the index of the first element of a for which pred
+// reports true, or -1.
+func (a Array) IndexWhere(pred func(Value) bool) int {
+	for i, v := range a {
+		if pred(v) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Contains reports whether a has an element equal to v. Values are equal if
+// they have the same structure: Objects must have equal members with the same
+// keys in the same order, arrays must have equal elements, and numbers must
+// have the same numeric value regardless of how they are written.
+func (a Array) Contains(v Value) bool {
+	return a.IndexWhere(func(elt Value) bool { return equal(elt, v) }) >= 0
+}
+
+// equal reports whether a and b are structurally equal, as described for
+// Array.Contains.
+func equal(a, b Value) bool {
+	switch t := a.(type) {
+	case Object:
+		u, ok := b.(Object)
+		if !ok || len(t) != len(u) {
+			return false
+		}
+		for i, m := range t {
+			if m.Key.String() != u[i].Key.String() || !equal(m.Value, u[i].Value) {
+				return false
+			}
+		}
+		return true
+	case Array:
+		u, ok := b.(Array)
+		if !ok || len(t) != len(u) {
+			return false
+		}
+		for i, elt := range t {
+			if !equal(elt, u[i]) {
+				return false
+			}
+		}
+		return true
+	case Number:
+		u, ok := b.(Number)
+		if !ok {
+			return false
+		} else if t.IsInt() && u.IsInt() {
+			return t.Int() == u.Int()
+		}
+		return t.Float() == u.Float()
+	case Text:
+		u, ok := b.(Text)
+		return ok && t.String() == u.String()
+	case nil:
+		return b == nil
+	}
+	return b != nil && a.JSON() == b.JSON()
+}
+
 // A rawNumber is a numeric literal parsed from source txt.
 type rawNumber struct {
 	text  []byte
@@ -225,8 +421,20 @@ func (Float) IsInt() bool { return false }
 // Int satisfies the numeric interface.
 func (f Float) Int() Int { return Int(f) }
 
-// JSON renders f as JSON text.
-func (f Float) JSON() string { return strconv.FormatFloat(float64(f), 'g', -1, 64) }
+// JSON renders f as JSON text. JSON has no representation for non-finite
+// numbers, so these are rendered as NaN, Infinity, or -Infinity, as in
+// JavaScript and JSON5. Use EncodeJSON to handle them differently.
+func (f Float) JSON() string {
+	switch {
+	case math.IsNaN(float64(f)):
+		return "NaN"
+	case math.IsInf(float64(f), 1):
+		return "Infinity"
+	case math.IsInf(float64(f), -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(float64(f), 'g', -1, 64)
+}
 
 func (f Float) String() string { return f.JSON() }
 
@@ -0,0 +1,242 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/creachadair/jtree/internal/escape"
+)
+
+// An Editor records a batch of edits to a value. Use Edit to construct an
+// Editor and apply its edits.
+//
+// Each edit is addressed by a JSON Pointer (RFC 6901) relative to the
+// original value. Because all the edits are applied together, the locations
+// they address are not affected by other edits in the same batch: In
+// particular, array indices always refer to the positions of elements in the
+// original array, regardless of insertions or deletions before them.
+//
+// At most one Set or Delete may address a given location, and no edit may
+// address a location within a value that is replaced or deleted by another.
+type Editor struct {
+	root editNode
+	errs []error
+}
+
+// An editNode records the edits at one location, and at the locations
+// nested within it.
+type editNode struct {
+	path    string
+	set     Value   // if non-nil, replace the value here
+	del     bool    // delete the value here
+	inserts []Value // array elements to insert before this one
+
+	kids map[string]*editNode
+	keys []string // keys of kids, in order of first use
+}
+
+// Edit calls f with an Editor to record a batch of edits to v, then applies
+// the edits and returns the resulting value. The result shares any part of v
+// that is not affected by an edit, and v itself is not modified. Each object
+// or array containing an edited location is copied only once, however many
+// edits it contains.
+//
+// If any edit is invalid, for example because it addresses a location that
+// does not exist, Edit reports an error and returns nil.
+func Edit(v Value, f func(e *Editor)) (Value, error) {
+	var e Editor
+	f(&e)
+	if err := errors.Join(e.errs...); err != nil {
+		return nil, err
+	}
+	return e.root.apply(v)
+}
+
+// Set replaces the value at pointer with v. If pointer addresses an object
+// member that does not exist, a member with that key is added to the end of
+// the object. If pointer addresses an object with more than one member with
+// the same key, the first such member is replaced. If v == nil, Null is used.
+func (e *Editor) Set(pointer string, v Value) {
+	if v == nil {
+		v = Null
+	}
+	if n := e.find(pointer); n != nil && e.checkReplace(n) {
+		n.set = v
+	}
+}
+
+// Delete removes the object member or array element at pointer. It is an
+// error if pointer does not address an existing member or element.
+func (e *Editor) Delete(pointer string) {
+	if pointer == "" {
+		e.errorf("cannot delete the root")
+		return
+	}
+	if n := e.find(pointer); n != nil && e.checkReplace(n) {
+		n.del = true
+	}
+}
+
+// Insert inserts v into an array before the element at pointer. The final
+// reference token of pointer must be an index no greater than the length of
+// the array, or "-" to insert after the last element. Values inserted at the
+// same location appear in the order they were inserted, and values inserted
+// with "-" follow any inserted at an index equal to the length of the array.
+func (e *Editor) Insert(pointer string, v Value) {
+	if pointer == "" {
+		e.errorf("cannot insert at the root")
+		return
+	}
+	if n := e.find(pointer); n != nil {
+		n.inserts = append(n.inserts, v)
+	}
+}
+
+func (e *Editor) errorf(msg string, args ...any) {
+	e.errs = append(e.errs, fmt.Errorf(msg, args...))
+}
+
+// find returns the node for pointer, creating it if necessary. It records an
+// error and returns nil if pointer is invalid, or lies within a value that
+// is replaced or deleted.
+func (e *Editor) find(pointer string) *editNode {
+	toks, err := escape.SplitPointer(pointer)
+	if err != nil {
+		e.errs = append(e.errs, err)
+		return nil
+	}
+	cur := &e.root
+	for _, tok := range toks {
+		if cur.set != nil || cur.del {
+			e.errorf("edit to %q is within an edited value at %q", pointer, cur.path)
+			return nil
+		}
+		next, ok := cur.kids[tok]
+		if !ok {
+			if cur.kids == nil {
+				cur.kids = make(map[string]*editNode)
+			}
+			next = &editNode{path: cur.path + "/" + escape.EscapeToken(tok)}
+			cur.kids[tok] = next
+			cur.keys = append(cur.keys, tok)
+		}
+		cur = next
+	}
+	return cur
+}
+
+// checkReplace reports whether the value at n may be replaced or deleted,
+// and records an error if not.
+func (e *Editor) checkReplace(n *editNode) bool {
+	if n.set != nil || n.del {
+		e.errorf("multiple edits to %q", n.path)
+		return false
+	} else if len(n.kids) != 0 {
+		e.errorf("edit to %q contains an edited value at %q", n.path, n.kids[n.keys[0]].path)
+		return false
+	}
+	return true
+}
+
+// apply returns the result of applying the edits nested within n to v.
+// The edits at n itself are applied by its parent.
+func (n *editNode) apply(v Value) (Value, error) {
+	if n.set != nil {
+		return n.set, nil
+	} else if len(n.kids) == 0 {
+		return v, nil // nothing to do
+	}
+	switch t := v.(type) {
+	case Object:
+		return n.applyObject(t)
+	case Array:
+		return n.applyArray(t)
+	default:
+		return nil, fmt.Errorf("cannot edit %q in %T", n.kids[n.keys[0]].path, v)
+	}
+}
+
+func (n *editNode) applyObject(o Object) (Value, error) {
+	for _, key := range n.keys {
+		if kid := n.kids[key]; len(kid.inserts) != 0 {
+			return nil, fmt.Errorf("cannot insert at %q in an object", kid.path)
+		}
+	}
+	out := make(Object, 0, len(o))
+	done := make(map[string]bool, len(n.kids))
+	for _, m := range o {
+		key := m.Key.String()
+		kid, ok := n.kids[key]
+		if !ok || done[key] {
+			out = append(out, m)
+			continue
+		}
+		done[key] = true
+		if kid.del {
+			continue
+		}
+		w, err := kid.apply(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &Member{Key: m.Key, Value: w})
+	}
+	for _, key := range n.keys {
+		if done[key] {
+			continue
+		}
+		kid := n.kids[key]
+		if kid.set == nil {
+			return nil, fmt.Errorf("key not found at %q", kid.path)
+		}
+		out = append(out, &Member{Key: String(key), Value: kid.set})
+	}
+	return out, nil
+}
+
+func (n *editNode) applyArray(a Array) (Value, error) {
+	// Resolve the indices of the edits, and make sure they are in range.
+	edits := make(map[int]*editNode, len(n.kids))
+	var tail []Value // inserted with "-"
+	for _, key := range n.keys {
+		kid := n.kids[key]
+		i, err := strconv.Atoi(key)
+		if key == "-" {
+			i = len(a)
+		} else if err != nil || i < 0 || (key != "0" && key[0] == '0') {
+			return nil, fmt.Errorf("invalid array index at %q", kid.path)
+		}
+		if i > len(a) || (i == len(a) && (kid.del || kid.set != nil || len(kid.kids) != 0)) {
+			return nil, fmt.Errorf("index out of range at %q", kid.path)
+		}
+		if key == "-" {
+			tail = kid.inserts
+		} else {
+			edits[i] = kid
+		}
+	}
+
+	out := make(Array, 0, len(a)+len(tail))
+	for i := 0; i <= len(a); i++ {
+		kid := edits[i]
+		if kid != nil {
+			out = append(out, kid.inserts...)
+		}
+		if i == len(a) {
+			out = append(out, tail...)
+			break
+		} else if kid == nil {
+			out = append(out, a[i])
+		} else if !kid.del {
+			w, err := kid.apply(a[i])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
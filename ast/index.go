@@ -0,0 +1,91 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package ast
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ToMap returns a map from the keys of o to their values. If o has more than
+// one member with the same key, the map contains the value of the first, as
+// for FindKey with TextEqual.
+func (o Object) ToMap() map[string]Value {
+	out := make(map[string]Value, len(o))
+	for _, m := range o {
+		key := m.Key.String()
+		if _, ok := out[key]; !ok {
+			out[key] = m.Value
+		}
+	}
+	return out
+}
+
+// An IndexedObject is an Object with an index of its keys, so that members
+// can be found in constant time rather than by a linear scan. This is worth
+// the cost of building the index only for large objects that are searched
+// repeatedly.
+//
+// The index is not updated if the Object is modified, so the members of the
+// Object must not be added, removed, or rekeyed after it is indexed.
+type IndexedObject struct {
+	Object
+
+	exact map[string]int // key → offset of first member
+	fold  map[string]int // folded key → offset of first member
+}
+
+// NewIndexedObject returns an IndexedObject for the members of o.
+func NewIndexedObject(o Object) *IndexedObject {
+	x := &IndexedObject{
+		Object: o,
+		exact:  make(map[string]int, len(o)),
+		fold:   make(map[string]int, len(o)),
+	}
+	for i, m := range o {
+		key := m.Key.String()
+		if _, ok := x.exact[key]; !ok {
+			x.exact[key] = i
+		}
+		fk := FoldKey(key)
+		if _, ok := x.fold[fk]; !ok {
+			x.fold[fk] = i
+		}
+	}
+	return x
+}
+
+// Find returns the first member of x whose key is case-insensitively equal
+// to key, or nil. It is equivalent to Object.Find.
+func (x *IndexedObject) Find(key string) *Member {
+	if i, ok := x.fold[FoldKey(key)]; ok {
+		return x.Object[i]
+	}
+	return nil
+}
+
+// FindExact returns the first member of x whose key is exactly equal to key,
+// or nil. It is equivalent to FindKey with TextEqual.
+func (x *IndexedObject) FindExact(key string) *Member {
+	if i, ok := x.exact[key]; ok {
+		return x.Object[i]
+	}
+	return nil
+}
+
+// FoldKey returns a canonical form of s such that two strings have the same
+// canonical form if and only if they are equal under strings.EqualFold.
+// Each rune is replaced by the least rune in its case-folding orbit. This is
+// useful as a map key for case-insensitive lookups, as done by Object.Find.
+func FoldKey(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range s {
+		least := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			least = min(least, f)
+		}
+		sb.WriteRune(least)
+	}
+	return sb.String()
+}
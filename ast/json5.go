@@ -0,0 +1,99 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/internal/escape"
+	"go4.org/mem"
+)
+
+// AnchorKey constructs an object key from the specified anchor, which must be
+// a String. If ic != nil, it is used to intern the text of the key.
+func AnchorKey(loc jtree.Anchor, ic jtree.StringInterner) Text {
+	text := stringText(loc)
+	if ic == nil {
+		return Quoted(string(text))
+	}
+	return Quoted(ic.Intern(text))
+}
+
+// stringText returns the text of a String anchor as a JSON string. A JSON5
+// string or identifier, which is not valid JSON, is decoded and re-encoded.
+func stringText(loc jtree.Anchor) []byte {
+	text := loc.Text()
+	if isJSONString(text) {
+		return text
+	}
+	if len(text) == 0 || (text[0] != '"' && text[0] != '\'') {
+		return escape.AppendQuote(nil, mem.B(text), 0) // an identifier
+	}
+	dec, err := escape.UnquoteJSON5(mem.B(text[1 : len(text)-1]))
+	if err != nil {
+		return text
+	}
+	return escape.AppendQuote(nil, mem.B(dec), 0)
+}
+
+// isJSONString reports whether text, the text of a String token, is a valid
+// JSON string. It may not be if the scanner allowed JSON5.
+func isJSONString(text []byte) bool {
+	if len(text) == 0 || text[0] != '"' {
+		return false
+	}
+	for {
+		i := bytes.IndexByte(text, '\\')
+		if i < 0 || i+1 == len(text) {
+			return true
+		} else if !strings.ContainsRune(`"\/bfnrtu`, rune(text[i+1])) {
+			return false
+		}
+		text = text[i+2:]
+	}
+}
+
+// isJSONNumber reports whether text, the text of an Integer or Number token,
+// is a valid JSON number. It may not be if the scanner allowed JSON5.
+func isJSONNumber(text []byte) bool {
+	t := bytes.TrimPrefix(text, []byte("-"))
+	if len(t) == 0 || t[0] < '0' || t[0] > '9' {
+		return false // a leading "+" or ".", or Infinity or NaN
+	} else if len(t) > 1 && (t[1] == 'x' || t[1] == 'X') {
+		return false // hexadecimal
+	}
+	i := bytes.IndexByte(t, '.')
+	return i < 0 || (i+1 < len(t) && t[i+1] >= '0' && t[i+1] <= '9')
+}
+
+// json5Number constructs a Value for the JSON5 number text, which is not a
+// valid JSON number.
+func json5Number(text []byte, isInt bool) (Value, error) {
+	t := string(text)
+	if strings.ContainsAny(t, "xX") {
+		v, err := strconv.ParseInt(t, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", t, err)
+		}
+		return Int(v), nil
+	}
+
+	neg := strings.HasPrefix(t, "-")
+	t = strings.TrimLeft(t, "+-")
+	if strings.HasPrefix(t, ".") {
+		t = "0" + t
+	}
+	if i := strings.IndexByte(t, '.'); i == len(t)-1 {
+		t += "0"
+	} else if i >= 0 && (t[i+1] == 'e' || t[i+1] == 'E') {
+		t = t[:i+1] + "0" + t[i+1:]
+	}
+	if neg {
+		t = "-" + t
+	}
+	return rawNumber{text: []byte(t), isInt: isInt}, nil
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package ast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree/internal/escape"
+)
+
+// A NonFinitePolicy specifies how EncodeJSON handles non-finite Float values
+// (NaN and ±Inf), which JSON cannot represent.
+type NonFinitePolicy int
+
+const (
+	// Encode non-finite values as NaN, Infinity, or -Infinity. The result is
+	// valid JavaScript and JSON5, but not JSON. This is what Float.JSON does.
+	NonFiniteLiteral NonFinitePolicy = iota
+
+	// Encode non-finite values as null.
+	NonFiniteNull
+
+	// Report an error if v contains a non-finite value.
+	NonFiniteError
+)
+
+// EncodeJSON returns the JSON encoding of v, as v.JSON does, but handles
+// non-finite Float values nested in v according to policy.
+func EncodeJSON(v Value, policy NonFinitePolicy) (string, error) {
+	switch policy {
+	case NonFiniteNull:
+		v = Rewrite(v, func(_ []any, v Value) Value {
+			if isNonFinite(v) {
+				return Null
+			}
+			return v
+		})
+	case NonFiniteError:
+		var err error
+		Walk(v, func(path []any, v Value) bool {
+			if err == nil && isNonFinite(v) {
+				err = fmt.Errorf("at %q: cannot encode %s as JSON", pointerString(path), v.JSON())
+			}
+			return err == nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return v.JSON(), nil
+}
+
+func isNonFinite(v Value) bool {
+	f, ok := v.(Float)
+	return ok && (math.IsNaN(float64(f)) || math.IsInf(float64(f), 0))
+}
+
+// pointerString renders path as a JSON Pointer (RFC 6901).
+func pointerString(path []any) string {
+	var sb strings.Builder
+	for _, elt := range path {
+		sb.WriteByte('/')
+		switch t := elt.(type) {
+		case string:
+			sb.WriteString(escape.EscapeToken(t))
+		case int:
+			sb.WriteString(strconv.Itoa(t))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package ast
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ParseParallel parses and returns the JSON values from r, as Parse does, but
+// divides the work among up to workers goroutines. If workers ≤ 0, it uses
+// runtime.GOMAXPROCS(0) workers. This is useful for input consisting of many
+// concatenated values, such as a log or an export, and does not help to parse
+// a single large value. The values are returned in input order.
+//
+// ParseParallel reads all of r into memory, then divides the input into
+// batches of consecutive values, which it parses concurrently. If parsing
+// fails, ParseParallel parses the input again sequentially, so that it
+// reports the same values and error as Parse would.
+func ParseParallel(r io.Reader, workers int) ([]Value, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	batches := splitValues(data, max(len(data)/(4*workers), minBatchSize))
+	if workers == 1 || len(batches) < 2 {
+		return ParseBytes(data)
+	}
+
+	results := make([][]Value, len(batches))
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for range min(workers, len(batches)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(batches) {
+					return
+				}
+				vs, err := ParseBytes(batches[i])
+				if err != nil {
+					failed.Store(true)
+					return
+				}
+				results[i] = vs
+			}
+		}()
+	}
+	wg.Wait()
+	if failed.Load() {
+		return ParseBytes(data) // to report the values and error as Parse would
+	}
+	return slices.Concat(results...), nil
+}
+
+// minBatchSize is the minimum size in bytes of a batch for ParseParallel.
+const minBatchSize = 16 << 10
+
+// splitValues divides data into batches of at least size bytes, each of which
+// ends at the boundary between top-level values. It does not fully check the
+// syntax of data, but only tracks strings and brackets, so a batch may not be
+// valid even if data is. If data is not valid, at least one batch is not.
+// It returns nil if data contains only whitespace.
+func splitValues(data []byte, size int) [][]byte {
+	var out [][]byte
+	start, depth, inString := 0, 0, false
+	cut := func(end int) {
+		if end-start >= size {
+			out = append(out, data[start:end])
+			start = end
+		}
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+				if depth == 0 {
+					cut(i + 1)
+				}
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth < 0 {
+				return [][]byte{data} // unbalanced; let the parser report it
+			} else if depth == 0 {
+				cut(i + 1)
+			}
+		case ' ', '\t', '\r', '\n':
+			if depth == 0 {
+				cut(i)
+			}
+		}
+	}
+	if len(bytes.Trim(data[start:], " \t\r\n")) != 0 {
+		out = append(out, data[start:])
+	}
+	return out
+}
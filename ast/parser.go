@@ -13,7 +13,7 @@ import (
 
 // A Parser parses and returns JSON values from a reader.
 type Parser struct {
-	h  *parseHandler
+	h  *Builder
 	st *jtree.Stream
 }
 
@@ -28,19 +28,81 @@ func (p *Parser) AllowJWCC(ok bool) {
 	p.st.AllowTrailingCommas(ok)
 }
 
+// AllowJSON5 configures p to accept (true) or reject (false) JSON5 syntax, as
+// described by jtree.Scanner.AllowJSON5. The values constructed from JSON5
+// input are converted to standard JSON.
+func (p *Parser) AllowJSON5(ok bool) { p.st.AllowJSON5(ok) }
+
+// AllowNonFiniteNumbers configures p to accept (true) or reject (false) the
+// non-standard numbers NaN, Infinity, and -Infinity, which are converted to
+// Float values. See EncodeJSON for how these values are encoded.
+func (p *Parser) AllowNonFiniteNumbers(ok bool) { p.st.AllowNonFiniteNumbers(ok) }
+
+// ReuseStorage configures p to share (true) or copy (false) the text of the
+// strings and numbers it constructs from storage owned by the underlying
+// stream, as described by jtree.Scanner.ReuseStorage. If enabled, the values
+// returned by Parse remain valid only until the next call of Release.
+// The values of a parser from NewParserBytes share its input in either case.
+func (p *Parser) ReuseStorage(ok bool) { p.st.ReuseStorage(ok) }
+
+// Release discards the storage for text shared by the values p has
+// constructed while ReuseStorage is enabled, so that it can be reused. After
+// Release, the caller must not use any value returned by Parse before the
+// call. Release does not affect an Arena set by SetArena, which must be
+// released separately.
+func (p *Parser) Release() { p.st.Release() }
+
+// SetArena configures p to allocate the values it constructs from a, as
+// described by Arena. If a == nil, values are allocated individually.
+func (p *Parser) SetArena(a *Arena) { p.h.SetArena(a) }
+
+// SetInterner configures p to use ic to intern the keys of objects. By
+// default, each parser has its own jtree.Interner, which grows without bound.
+// A *jtree.InternPool may be bounded, and may be shared by many parsers. If
+// ic == nil, p uses a new jtree.Interner.
+func (p *Parser) SetInterner(ic jtree.StringInterner) {
+	if ic == nil {
+		ic = make(jtree.Interner)
+	}
+	p.h.ic = ic
+}
+
+// RuneColumns configures p to count the column offsets of the locations in
+// its error messages in runes (true) or bytes (false).
+func (p *Parser) RuneColumns(ok bool) { p.st.RuneColumns(ok) }
+
 // NewParser constructs a parser that consumes input from r.
 func NewParser(r io.Reader) *Parser {
-	h := &parseHandler{ic: make(jtree.Interner)}
+	h := &Builder{ic: make(jtree.Interner)}
 	return &Parser{h: h, st: jtree.NewStream(r)}
 }
 
+// NewParserBytes constructs a parser that consumes input directly from data.
+// The values it returns may share storage with data, so the caller must not
+// modify the contents of data while they are in use.
+func NewParserBytes(data []byte) *Parser {
+	h := &Builder{ic: make(jtree.Interner)}
+	return &Parser{h: h, st: jtree.NewStreamBytes(data)}
+}
+
 // Parse parses and returns the next JSON value from its input.
 // It returns io.EOF if no further values are available.
+//
+// If parsing fails partway through a value, Parse returns the portion of the
+// value that was complete before the error, along with an error that wraps
+// both ErrIncomplete and the underlying error. In the partial value, objects
+// and arrays that were not closed contain only their members and elements
+// that were complete before the error.
 func (p *Parser) Parse() (Value, error) {
-	if err := p.st.ParseOne(p.h); err != nil {
+	if err := p.st.ParseOne(p.h); err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		if v := p.h.partial(); v != nil {
+			return v, fmt.Errorf("%w: %w", ErrIncomplete, err)
+		}
 		return nil, err
 	} else if len(p.h.stk) != 1 {
-		return nil, errors.New("incomplete value")
+		return nil, ErrIncomplete
 	}
 	out := p.h.stk[0]
 	p.h.stk = p.h.stk[:0]
@@ -50,8 +112,15 @@ func (p *Parser) Parse() (Value, error) {
 // Parse parses and returns the JSON values from r. In case of error, any
 // complete values already parsed are returned along with the error.
 // It reports ErrEmptyInput if the input is entirely empty.
-func Parse(r io.Reader) ([]Value, error) {
-	p := NewParser(r)
+func Parse(r io.Reader) ([]Value, error) { return parseAll(NewParser(r)) }
+
+// ParseBytes parses and returns the JSON values from data, as Parse does.
+// It is faster than Parse for input that is already in memory. The values it
+// returns may share storage with data, so the caller must not modify the
+// contents of data while they are in use.
+func ParseBytes(data []byte) ([]Value, error) { return parseAll(NewParserBytes(data)) }
+
+func parseAll(p *Parser) ([]Value, error) {
 	var vs []Value
 	for {
 		v, err := p.Parse()
@@ -69,14 +138,15 @@ func Parse(r io.Reader) ([]Value, error) {
 
 // ParseSingle parses and returns a single JSON value from r. If r contains
 // more data after the first value, ParseSingle returns the first value along
-// with an ErrExtraInput error.
+// with an ErrExtraInput error. If parsing fails partway through the value,
+// ParseSingle returns the partial value as described by Parser.Parse.
 func ParseSingle(r io.Reader) (Value, error) {
 	p := NewParser(r)
 	v, err := p.Parse()
 	if err == io.EOF {
 		return v, ErrEmptyInput
 	} else if err != nil {
-		return nil, err
+		return v, err
 	}
 
 	// Trigger the parser with a handler that fails on any non-empty input.
@@ -86,14 +156,67 @@ func ParseSingle(r io.Reader) (Value, error) {
 	return v, nil
 }
 
-// A parseHandler implements the jtree.Handler interface to construct abstract
-// syntax trees for JSON values.
-type parseHandler struct {
-	stk []Value
-	ic  jtree.Interner
+// Extract reads a single JSON value from r and returns the values within it
+// at the specified paths, which are JSON Pointers (RFC 6901), as described by
+// jtree.Extract. Only the values returned are constructed. An element of the
+// result is nil if the value does not contain that path.
+func Extract(r io.Reader, paths ...string) ([]Value, error) {
+	texts, err := jtree.Extract(r, paths...)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Value, len(texts))
+	for i, text := range texts {
+		if text != nil {
+			v, err := NewParserBytes(text).Parse()
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+	}
+	return out, nil
+}
+
+// A Builder implements the jtree.Handler interface to construct abstract
+// syntax trees for JSON values. A zero Builder is ready for use.
+//
+// A Builder may be driven by a jtree.Stream directly, or by another handler
+// that forwards only some of the events it receives, for example to construct
+// values for selected parts of a larger input. Each event must be forwarded
+// to the Builder, from the beginning to the end of a value, in the order
+// received.
+type Builder struct {
+	stk   []Value
+	ic    jtree.StringInterner
+	arena *Arena
 }
 
-func (h *parseHandler) reduceValue(v Value) error {
+// NewBuilder constructs a new empty Builder.
+func NewBuilder() *Builder { return new(Builder) }
+
+// SetArena configures h to allocate the values it constructs from a. If
+// a == nil, values are allocated individually. See Arena.
+func (h *Builder) SetArena(a *Arena) { h.arena = a }
+
+// Result returns the complete values constructed since the Builder was
+// created or last reset, in order, and resets the Builder. If an object or
+// array is not yet complete, Result reports ErrIncomplete and has no effect.
+func (h *Builder) Result() ([]Value, error) {
+	for _, v := range h.stk {
+		if isStub(v) {
+			return nil, ErrIncomplete
+		}
+	}
+	out := h.stk
+	h.stk = nil
+	return out, nil
+}
+
+// Reset discards all values constructed so far, including incomplete values.
+func (h *Builder) Reset() { h.stk = h.stk[:0] }
+
+func (h *Builder) reduceValue(v Value) error {
 	// If there is an incomplete object member waiting for a value, populate the
 	// value directly.
 	if n := len(h.stk); n > 0 {
@@ -108,17 +231,61 @@ func (h *parseHandler) reduceValue(v Value) error {
 	return nil
 }
 
-func (h *parseHandler) push(v Value) { h.stk = append(h.stk, v) }
+func (h *Builder) push(v Value) { h.stk = append(h.stk, v) }
 
-func (h *parseHandler) BeginObject(loc jtree.Anchor) error {
+// partial closes any objects and arrays left open on the stack by a failed
+// parse, discarding incomplete members, and returns the resulting value.  It
+// returns nil if the stack is empty. The stack is empty after partial returns.
+func (h *Builder) partial() Value {
+	defer func() { h.stk = h.stk[:0] }()
+	for {
+		i := len(h.stk) - 1
+		for i >= 0 && !isStub(h.stk[i]) {
+			i--
+		}
+		if i < 0 {
+			break
+		}
+		var v Value
+		if _, ok := h.stk[i].(objectStub); ok {
+			o := make(Object, 0, len(h.stk)-i-1)
+			for _, elt := range h.stk[i+1:] {
+				if m, ok := elt.(*Member); ok && m.Value != nil {
+					o = append(o, m)
+				}
+			}
+			v = o
+		} else {
+			a := make(Array, len(h.stk)-i-1)
+			copy(a, h.stk[i+1:])
+			v = a
+		}
+		h.stk = h.stk[:i]
+		h.reduceValue(v)
+	}
+	if len(h.stk) == 0 {
+		return nil
+	}
+	return h.stk[0]
+}
+
+func isStub(v Value) bool {
+	switch v.(type) {
+	case objectStub, arrayStub:
+		return true
+	}
+	return false
+}
+
+func (h *Builder) BeginObject(loc jtree.Anchor) error {
 	h.push(objectStub{})
 	return nil
 }
 
-func (h *parseHandler) EndObject(loc jtree.Anchor) error {
+func (h *Builder) EndObject(loc jtree.Anchor) error {
 	for i := len(h.stk) - 1; i >= 0; i-- {
 		if _, ok := h.stk[i].(objectStub); ok {
-			o := make(Object, 0, len(h.stk)-i-1)
+			o := h.arena.object(len(h.stk) - i - 1)
 			for j := i + 1; j < len(h.stk); j++ {
 				o = append(o, h.stk[j].(*Member))
 			}
@@ -129,15 +296,15 @@ func (h *parseHandler) EndObject(loc jtree.Anchor) error {
 	panic("unbalanced EndObject")
 }
 
-func (h *parseHandler) BeginArray(loc jtree.Anchor) error {
+func (h *Builder) BeginArray(loc jtree.Anchor) error {
 	h.push(arrayStub{})
 	return nil
 }
 
-func (h *parseHandler) EndArray(loc jtree.Anchor) error {
+func (h *Builder) EndArray(loc jtree.Anchor) error {
 	for i := len(h.stk) - 1; i >= 0; i-- {
 		if _, ok := h.stk[i].(arrayStub); ok {
-			a := make(Array, len(h.stk)-i-1)
+			a := h.arena.array(len(h.stk) - i - 1)
 			copy(a, h.stk[i+1:])
 			h.stk = h.stk[:i]
 			return h.reduceValue(a)
@@ -146,15 +313,18 @@ func (h *parseHandler) EndArray(loc jtree.Anchor) error {
 	panic("unbalanced EndArray")
 }
 
-func (h *parseHandler) BeginMember(loc jtree.Anchor) error {
-	h.push(&Member{Key: Quoted(h.ic.Intern(loc.Text()))})
+func (h *Builder) BeginMember(loc jtree.Anchor) error {
+	if h.ic == nil {
+		h.ic = make(jtree.Interner)
+	}
+	h.push(h.arena.member(h.arena.key(loc, h.ic)))
 	return nil
 }
 
-func (h *parseHandler) EndMember(loc jtree.Anchor) error { return nil }
+func (h *Builder) EndMember(loc jtree.Anchor) error { return nil }
 
-func (h *parseHandler) Value(loc jtree.Anchor) error {
-	v, err := AnchorValue(loc)
+func (h *Builder) Value(loc jtree.Anchor) error {
+	v, err := h.arena.value(loc)
 	if err != nil {
 		return err
 	}
@@ -163,15 +333,24 @@ func (h *parseHandler) Value(loc jtree.Anchor) error {
 }
 
 // AnchorValue constructs a Value from the specified anchor, or reports an
-// error if the anchor does not record a value.
+// error if the anchor does not record a value. JSON5 strings and numbers are
+// converted to their standard JSON equivalents, and the non-finite numbers
+// NaN, Infinity, and -Infinity are converted to Float values.
 func AnchorValue(loc jtree.Anchor) (Value, error) {
-	switch loc.Token() {
+	switch tok := loc.Token(); tok {
 	case jtree.String:
-		return quotedText{data: mem.B(loc.Copy())}, nil
-	case jtree.Integer:
-		return rawNumber{text: loc.Copy(), isInt: true}, nil
-	case jtree.Number:
-		return rawNumber{text: loc.Copy(), isInt: false}, nil
+		if !isJSONString(loc.Text()) {
+			return quotedText{data: mem.B(stringText(loc))}, nil
+		}
+		return quotedText{data: mem.B(jtree.AnchorBytes(loc))}, nil
+	case jtree.Integer, jtree.Number:
+		if !isJSONNumber(loc.Text()) {
+			return json5Number(loc.Text(), tok == jtree.Integer)
+		}
+		return rawNumber{text: jtree.AnchorBytes(loc), isInt: tok == jtree.Integer}, nil
+	case jtree.NaN, jtree.Infinity, jtree.NegInfinity:
+		f, err := jtree.AnchorFloat64(loc)
+		return Float(f), err
 	case jtree.True, jtree.False:
 		return Bool(loc.Token() == jtree.True), nil
 	case jtree.Null:
@@ -181,13 +360,29 @@ func AnchorValue(loc jtree.Anchor) (Value, error) {
 	}
 }
 
-func (h *parseHandler) SyntaxError(loc jtree.Anchor, err error) error { return err }
+func (h *Builder) SyntaxError(loc jtree.Anchor, err error) error { return err }
 
-func (h *parseHandler) EndOfInput(loc jtree.Anchor) {}
+func (h *Builder) EndOfInput(loc jtree.Anchor) {}
 
 // ErrEmptyInput is a sentinel error reported by Parse if the input is empty.
 var ErrEmptyInput = errors.New("empty input")
 
+// ErrIncomplete is a sentinel error reported along with a partial value if
+// parsing fails partway through a value.
+var ErrIncomplete = errors.New("incomplete value")
+
+// FormatError renders a diagnostic for err, an error reported by parsing src.
+// If err is or wraps a *jtree.SyntaxError, the result includes a snippet of
+// src showing where the error occurred, as described by jtree.Snippet;
+// otherwise it is the text of err.
+func FormatError(err error, src []byte) string {
+	var serr *jtree.SyntaxError
+	if errors.As(err, &serr) {
+		return serr.FormatError(src)
+	}
+	return err.Error()
+}
+
 // ErrExtraInput is a sentinel error reported by ParseOne if the input contains
 // additional values after the first one.
 var ErrExtraInput = errors.New("extra data after value")
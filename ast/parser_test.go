@@ -6,12 +6,15 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/creachadair/jtree"
 	"github.com/creachadair/jtree/ast"
 	"github.com/google/go-cmp/cmp"
 )
@@ -46,6 +49,134 @@ func TestParse_JWCC(t *testing.T) {
 	t.Log(v.JSON())
 }
 
+func TestParse_JSON5(t *testing.T) {
+	// The example from https://json5.org.
+	const input = `{
+  // comments
+  unquoted: 'and you can quote me on that',
+  singleQuotes: 'I can use "double quotes" here',
+  lineBreaks: "Look, Mom! \
+No \\n's!",
+  hexadecimal: 0xdecaf,
+  leadingDecimalPoint: .8675309, andTrailing: 8675309.,
+  positiveSign: +1,
+  trailingComma: 'in objects', andIn: ['arrays',],
+  "backwardsCompatible": "with JSON",
+  null: -.5e2,
+}`
+	p := ast.NewParser(strings.NewReader(input))
+	p.AllowJSON5(true)
+	v, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	const want = `{"unquoted":"and you can quote me on that",` +
+		`"singleQuotes":"I can use \"double quotes\" here",` +
+		`"lineBreaks":"Look, Mom! No \\n's!",` +
+		`"hexadecimal":912559,` +
+		`"leadingDecimalPoint":0.8675309,"andTrailing":8675309.0,` +
+		`"positiveSign":1,` +
+		`"trailingComma":"in objects","andIn":["arrays"],` +
+		`"backwardsCompatible":"with JSON",` +
+		`"null":-0.5e2}`
+	if got := v.JSON(); got != want {
+		t.Errorf("Parse JSON5:\ngot:  %s\nwant: %s", got, want)
+	}
+
+	// Non-finite numbers are converted to floats.
+	p = ast.NewParser(strings.NewReader(`[Infinity, -Infinity, NaN]`))
+	p.AllowJSON5(true)
+	v, err = p.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	arr := v.(ast.Array)
+	if f := arr[0].(ast.Float); !math.IsInf(float64(f), 1) {
+		t.Errorf("Infinity: got %v", f)
+	}
+	if f := arr[1].(ast.Float); !math.IsInf(float64(f), -1) {
+		t.Errorf("-Infinity: got %v", f)
+	}
+	if f := arr[2].(ast.Float); !math.IsNaN(float64(f)) {
+		t.Errorf("NaN: got %v", f)
+	}
+
+	// An unquoted string is accepted only as a key.
+	p = ast.NewParser(strings.NewReader(`{a: b}`))
+	p.AllowJSON5(true)
+	if v, err := p.Parse(); err == nil {
+		t.Errorf("Parse: got %v, want error", v)
+	}
+
+	// Without the option, JSON5 is rejected.
+	if v, err := ast.Parse(strings.NewReader(`{a: 1}`)); err == nil {
+		t.Errorf("Parse: got %v, want error", v)
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	p := ast.NewParser(strings.NewReader(`{"a": [1, NaN], "b/c": -Infinity, "d": Infinity}`))
+	p.AllowNonFiniteNumbers(true)
+	v, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if f := v.(ast.Object).Find("d").Value.(ast.Float); !math.IsInf(float64(f), 1) {
+		t.Errorf("Value of d: got %v, want +Inf", f)
+	}
+
+	tests := []struct {
+		policy ast.NonFinitePolicy
+		want   string
+		err    string
+	}{
+		{ast.NonFiniteLiteral, `{"a":[1,NaN],"b/c":-Infinity,"d":Infinity}`, ""},
+		{ast.NonFiniteNull, `{"a":[1,null],"b/c":null,"d":null}`, ""},
+		{ast.NonFiniteError, "", `at "/a/1": cannot encode NaN as JSON`},
+	}
+	for _, test := range tests {
+		got, err := ast.EncodeJSON(v, test.policy)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("EncodeJSON(%v): got %q, %v; want error %q", test.policy, got, err, test.err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("EncodeJSON(%v): unexpected error: %v", test.policy, err)
+		}
+		if got != test.want {
+			t.Errorf("EncodeJSON(%v): got %s, want %s", test.policy, got, test.want)
+		}
+	}
+
+	// Rewriting to null does not modify the original.
+	if got, want := v.JSON(), tests[0].want; got != want {
+		t.Errorf("JSON: got %s, want %s", got, want)
+	}
+	if _, err := ast.EncodeJSON(ast.ArrayOf(1, "x"), ast.NonFiniteError); err != nil {
+		t.Errorf("EncodeJSON: unexpected error for finite values: %v", err)
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"[1,\n  2 3]", "line 2, column 5: expected \"]\" or \",\", got integer\n  2 3]\n    ^"},
+		{`{"a": nope}`, "line 1, column 7: unknown constant \"nope\" (offset 10)\n{\"a\": nope}\n      ^^^^"},
+		{`1 2`, "extra data after value"},
+	}
+	for _, test := range tests {
+		_, err := ast.ParseSingle(strings.NewReader(test.input))
+		if err == nil {
+			t.Fatalf("ParseSingle %#q: unexpectedly succeeded", test.input)
+		}
+		if got := ast.FormatError(err, []byte(test.input)); got != test.want {
+			t.Errorf("FormatError %#q:\ngot:\n%s\nwant:\n%s", test.input, got, test.want)
+		}
+	}
+}
+
 func TestParse(t *testing.T) {
 	input, err := os.ReadFile("../testdata/input.json")
 	if err != nil {
@@ -114,6 +245,216 @@ func TestParse(t *testing.T) {
 	})
 }
 
+func TestParseBytes(t *testing.T) {
+	input, err := os.ReadFile("../testdata/input.json")
+	if err != nil {
+		t.Fatalf("Reading test input: %v", err)
+	}
+	want, err := ast.Parse(bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got, err := ast.ParseBytes(input)
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseBytes: got %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if g, w := got[i].JSON(), want[i].JSON(); g != w {
+			t.Errorf("Value %d: got %d bytes of JSON, want %d", i, len(g), len(w))
+		}
+	}
+
+	if _, err := ast.ParseBytes(nil); !errors.Is(err, ast.ErrEmptyInput) {
+		t.Errorf("ParseBytes(nil): got %v, want %v", err, ast.ErrEmptyInput)
+	}
+	if vs, err := ast.ParseBytes([]byte(`1 [2`)); err == nil || len(vs) != 1 {
+		t.Errorf("ParseBytes: got %v, %v; want 1 value and an error", vs, err)
+	}
+}
+
+func TestParseBytesShared(t *testing.T) {
+	input := []byte(`{"key": ["abc", 12345]}`)
+	vs, err := ast.ParseBytes(input)
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	// Values constructed from the input refer to it without copying.
+	copy(input[10:], "xyz")
+	copy(input[16:], "67890")
+	if got, want := vs[0].JSON(), `{"key":["xyz",67890]}`; got != want {
+		t.Errorf("Value: got %#q, want %#q", got, want)
+	}
+}
+
+func TestParserInterner(t *testing.T) {
+	pool := jtree.NewInternPool(0)
+	for _, input := range []string{`{"a": 1, "b": 2}`, `{"a": 3, "c": {"b": 4}}`} {
+		p := ast.NewParser(strings.NewReader(input))
+		p.SetInterner(pool)
+		if _, err := p.Parse(); err != nil {
+			t.Fatalf("Parse %#q: %v", input, err)
+		}
+	}
+	if got, want := pool.Stats(), (jtree.InternStats{Len: 3, Hits: 2, Misses: 3}); got != want {
+		t.Errorf("Stats: got %+v, want %+v", got, want)
+	}
+}
+
+func TestParserReuseStorage(t *testing.T) {
+	p := ast.NewParser(strings.NewReader(`{"a": "bcd", "e": 123} ["fgh", 4.5]`))
+	p.ReuseStorage(true)
+	for _, want := range []string{`{"a":"bcd","e":123}`, `["fgh",4.5]`} {
+		v, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if got := v.JSON(); got != want {
+			t.Errorf("Parse: got %#q, want %#q", got, want)
+		}
+		p.Release()
+	}
+	if v, err := p.Parse(); err != io.EOF {
+		t.Errorf("Parse: got (%v, %v), want EOF", v, err)
+	}
+}
+
+func TestArena(t *testing.T) {
+	input, err := os.ReadFile("../testdata/input.json")
+	if err != nil {
+		t.Fatalf("Reading test input: %v", err)
+	}
+	want, err := ast.Parse(bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var arena ast.Arena
+	for i := range 3 {
+		p := ast.NewParser(bytes.NewReader(input))
+		p.SetArena(&arena)
+		var got []ast.Value
+		for {
+			v, err := p.Parse()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Parse %d failed: %v", i, err)
+			}
+			got = append(got, v)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Parse %d: got %d values, want %d", i, len(got), len(want))
+		}
+		for j := range want {
+			if g, w := got[j].JSON(), want[j].JSON(); g != w {
+				t.Errorf("Parse %d: value %d differs", i, j)
+			}
+		}
+		arena.Release()
+	}
+
+	// Values from an arena behave like other values.
+	p := ast.NewParser(strings.NewReader(`{"a": "b", "c": [1, 2.5]}`))
+	p.SetArena(&arena)
+	v, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	o := v.(ast.Object)
+	if s, ok := o.Find("a").Value.(ast.Text); !ok || s.String() != "b" {
+		t.Errorf("Find a: got %v, want text b", o.Find("a").Value)
+	}
+	if n, ok := o.Find("c").Value.(ast.Array)[1].(ast.Number); !ok || n.Float() != 2.5 {
+		t.Errorf("Find c[1]: got %v, want 2.5", o.Find("c").Value)
+	}
+}
+
+func TestAppendJSON(t *testing.T) {
+	vs, err := ast.Parse(strings.NewReader(`{"a": [1, 2.5, "x\"y", true, null, {}], "b": {"c": []}} "s" -3`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	vs = append(vs, ast.String("a\tb"), ast.Float(1.5), ast.Array{testValue(7), ast.Object{
+		{Key: ast.String("k"), Value: ast.Int(9)},
+	}})
+	for _, v := range vs {
+		want := "<>" + v.JSON()
+		if got := string(ast.AppendJSON([]byte("<>"), v)); got != want {
+			t.Errorf("AppendJSON: got %#q, want %#q", got, want)
+		}
+	}
+
+	// Rendering deeply nested values should not allocate per level.
+	var v ast.Value = ast.Null
+	for range 1000 {
+		v = ast.Array{ast.Object{{Key: ast.Quoted(`"k"`), Value: v}}}
+	}
+	if n := testing.AllocsPerRun(10, func() { _ = v.JSON() }); n > 50 {
+		t.Errorf("JSON of nested value: got %v allocations, want ≤ 50", n)
+	}
+}
+
+func TestParseParallel(t *testing.T) {
+	var sb strings.Builder
+	for i := range 5000 {
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&sb, `{"id": %d, "tags": ["a", "b\"]"], "ok": true}`+"\n", i)
+		case 1:
+			fmt.Fprintf(&sb, "[%d, {\"x\": null}]", i)
+		case 2:
+			fmt.Fprintf(&sb, "\t%q ", fmt.Sprint("s", i))
+		default:
+			fmt.Fprintf(&sb, "%d\r\n", i)
+		}
+	}
+	good := sb.String()
+
+	check := func(t *testing.T, input string, workers int) {
+		t.Helper()
+		want, werr := ast.Parse(strings.NewReader(input))
+		got, gerr := ast.ParseParallel(strings.NewReader(input), workers)
+		if fmt.Sprint(gerr) != fmt.Sprint(werr) {
+			t.Errorf("ParseParallel(%d): got error %v, want %v", workers, gerr, werr)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("ParseParallel(%d): got %d values, want %d", workers, len(got), len(want))
+		}
+		for i := range want {
+			if g, w := got[i].JSON(), want[i].JSON(); g != w {
+				t.Errorf("Value %d: got %#q, want %#q", i, g, w)
+			}
+		}
+	}
+	for _, workers := range []int{0, 1, 3, 16} {
+		check(t, good, workers)
+	}
+	check(t, "  \n ", 4)
+	check(t, good+` [1, 2`, 4)
+	check(t, good[:len(good)/2]+"} "+good[len(good)/2:], 4)
+	check(t, good+" ", 4)
+}
+
+func TestExtract(t *testing.T) {
+	const input = `{"config": {"version": "1.2", "tags": ["a", "b"]}, "n": 5}`
+	vs, err := ast.Extract(strings.NewReader(input), "/config/tags", "/n", "/missing")
+	if err != nil {
+		t.Fatalf("Extract: unexpected error: %v", err)
+	}
+	if len(vs) != 3 || vs[2] != nil {
+		t.Fatalf("Extract: got %v, want 3 values, the last nil", vs)
+	}
+	if got, want := vs[0].JSON(), `["a","b"]`; got != want {
+		t.Errorf("Value 0: got %#q, want %#q", got, want)
+	}
+	if got, want := vs[1].JSON(), `5`; got != want {
+		t.Errorf("Value 1: got %#q, want %#q", got, want)
+	}
+}
+
 func TestRegression(t *testing.T) {
 	// Regression: Plain values were not correctly reduced at the top level.
 	t.Run("TopLevelValue", func(t *testing.T) {
@@ -166,6 +507,28 @@ func TestParseSingle(t *testing.T) {
 			t.Errorf("ParseSingle: error should not be %v", err)
 		}
 	})
+
+	t.Run("Partial", func(t *testing.T) {
+		tests := []struct {
+			input, want string
+		}{
+			{`[1, 2, tru`, `[1,2]`},
+			{`{"a": 1, "b": [true, {"c": null, "d"`, `{"a":1,"b":[true,{"c":null}]}`},
+			{`{"a": {"b": 1}, "c":`, `{"a":{"b":1}}`},
+			{`[[], [1, 2}`, `[[],[1,2]]`},
+		}
+		for _, test := range tests {
+			v, err := ast.ParseSingle(strings.NewReader(test.input))
+			if !errors.Is(err, ast.ErrIncomplete) {
+				t.Errorf("ParseSingle(%#q): got err=%v, want %v", test.input, err, ast.ErrIncomplete)
+			}
+			if v == nil {
+				t.Errorf("ParseSingle(%#q): got nil, want partial value", test.input)
+			} else if got := v.JSON(); got != test.want {
+				t.Errorf("ParseSingle(%#q): got %#q, want %#q", test.input, got, test.want)
+			}
+		}
+	})
 }
 
 func check[T any](t *testing.T, obj ast.Object, key string, f func(T)) {
@@ -247,3 +610,471 @@ func TestString(t *testing.T) {
 		}
 	}
 }
+
+// elements is a jtree.Handler that forwards the events for the elements of a
+// top-level array to a Builder.
+type elements struct {
+	b     *ast.Builder
+	depth int
+}
+
+func (e *elements) begin(f func(jtree.Anchor) error, loc jtree.Anchor) error {
+	e.depth++
+	if e.depth > 1 {
+		return f(loc)
+	}
+	return nil
+}
+
+func (e *elements) end(f func(jtree.Anchor) error, loc jtree.Anchor) error {
+	e.depth--
+	if e.depth > 0 {
+		return f(loc)
+	}
+	return nil
+}
+
+func (e *elements) inner(f func(jtree.Anchor) error, loc jtree.Anchor) error {
+	if e.depth > 1 {
+		return f(loc)
+	}
+	return nil
+}
+
+func (e *elements) BeginObject(loc jtree.Anchor) error { return e.begin(e.b.BeginObject, loc) }
+func (e *elements) EndObject(loc jtree.Anchor) error   { return e.end(e.b.EndObject, loc) }
+func (e *elements) BeginArray(loc jtree.Anchor) error  { return e.begin(e.b.BeginArray, loc) }
+func (e *elements) EndArray(loc jtree.Anchor) error    { return e.end(e.b.EndArray, loc) }
+func (e *elements) BeginMember(loc jtree.Anchor) error { return e.inner(e.b.BeginMember, loc) }
+func (e *elements) EndMember(loc jtree.Anchor) error   { return e.inner(e.b.EndMember, loc) }
+func (e *elements) EndOfInput(jtree.Anchor)            {}
+
+func (e *elements) Value(loc jtree.Anchor) error {
+	if e.depth > 0 {
+		return e.b.Value(loc)
+	}
+	return nil
+}
+
+func TestBuilder(t *testing.T) {
+	const input = `[{"a": [1, {"b": null}]}, [2, 3], "x", true]`
+
+	e := &elements{b: ast.NewBuilder()}
+	if err := jtree.NewStream(strings.NewReader(input)).Parse(e); err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	vs, err := e.b.Result()
+	if err != nil {
+		t.Fatalf("Result: unexpected error: %v", err)
+	}
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.JSON())
+	}
+	want := []string{`{"a":[1,{"b":null}]}`, `[2,3]`, `"x"`, `true`}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Result (-want, +got):\n%s", diff)
+	}
+
+	// The builder is reset after Result.
+	if vs, err := e.b.Result(); err != nil || len(vs) != 0 {
+		t.Errorf("Result: got (%v, %v), want empty", vs, err)
+	}
+
+	// An incomplete value is reported.
+	var b ast.Builder
+	err = jtree.NewStream(strings.NewReader(`{"a": [1, 2`)).Parse(&b)
+	if err == nil {
+		t.Fatal("Parse: got nil, want error")
+	}
+	if vs, err := b.Result(); !errors.Is(err, ast.ErrIncomplete) {
+		t.Errorf("Result: got (%v, %v), want %v", vs, err, ast.ErrIncomplete)
+	}
+	b.Reset()
+	if vs, err := b.Result(); err != nil || len(vs) != 0 {
+		t.Errorf("Result after Reset: got (%v, %v), want empty", vs, err)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	const input = `{"a": [1, 2, 3], "b": {"c": true, "d": null}, "e": "x"}`
+	mustParse := func(s string) ast.Value {
+		t.Helper()
+		v, err := ast.ParseSingle(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("ParseSingle %q: %v", s, err)
+		}
+		return v
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		tests := []struct {
+			name string
+			edit func(e *ast.Editor)
+			want string
+		}{
+			{"None", func(*ast.Editor) {}, input},
+			{"SetRoot", func(e *ast.Editor) { e.Set("", ast.Int(1)) }, `1`},
+			{"SetMember", func(e *ast.Editor) {
+				e.Set("/e", ast.String("y"))
+			}, `{"a":[1,2,3],"b":{"c":true,"d":null},"e":"y"}`},
+			{"AddMember", func(e *ast.Editor) {
+				e.Set("/b/f~1g", ast.Int(5))
+			}, `{"a":[1,2,3],"b":{"c":true,"d":null,"f/g":5},"e":"x"}`},
+			{"DeleteMember", func(e *ast.Editor) {
+				e.Delete("/b/c")
+				e.Delete("/e")
+			}, `{"a":[1,2,3],"b":{"d":null}}`},
+			{"Array", func(e *ast.Editor) {
+				// Indices refer to the original array.
+				e.Delete("/a/0")
+				e.Insert("/a/1", ast.String("p"))
+				e.Set("/a/1", ast.Int(20))
+				e.Insert("/a/-", ast.String("q"))
+				e.Insert("/a/3", ast.String("r"))
+			}, `{"a":["p",20,3,"r","q"],"b":{"c":true,"d":null},"e":"x"}`},
+			{"Mixed", func(e *ast.Editor) {
+				e.Set("/b/d", ast.Array{})
+				e.Delete("/a/2")
+			}, `{"a":[1,2],"b":{"c":true,"d":[]},"e":"x"}`},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				v := mustParse(input)
+				got, err := ast.Edit(v, tc.edit)
+				if err != nil {
+					t.Fatalf("Edit: unexpected error: %v", err)
+				}
+				if diff := cmp.Diff(mustParse(tc.want).JSON(), got.JSON()); diff != "" {
+					t.Errorf("Result (-want, +got):\n%s", diff)
+				}
+				if v.JSON() != mustParse(input).JSON() {
+					t.Errorf("Input was modified: %s", v.JSON())
+				}
+			})
+		}
+	})
+
+	t.Run("Sharing", func(t *testing.T) {
+		v := mustParse(input).(ast.Object)
+		got, err := ast.Edit(v, func(e *ast.Editor) {
+			e.Set("/b/c", ast.Bool(false))
+			e.Set("/b/d", ast.Int(0))
+		})
+		if err != nil {
+			t.Fatalf("Edit: unexpected error: %v", err)
+		}
+		obj := got.(ast.Object)
+		if obj[0] != v[0] || obj[2] != v[2] {
+			t.Error("Unedited members were not shared")
+		}
+		if obj[1] == v[1] {
+			t.Error("Edited member was not copied")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name string
+			edit func(e *ast.Editor)
+		}{
+			{"BadPointer", func(e *ast.Editor) { e.Set("a", nil) }},
+			{"DeleteRoot", func(e *ast.Editor) { e.Delete("") }},
+			{"SetTwice", func(e *ast.Editor) { e.Set("/e", nil); e.Set("/e", nil) }},
+			{"SetAndDelete", func(e *ast.Editor) { e.Set("/e", nil); e.Delete("/e") }},
+			{"Within", func(e *ast.Editor) { e.Delete("/b"); e.Set("/b/c", nil) }},
+			{"Contains", func(e *ast.Editor) { e.Set("/b/c", nil); e.Delete("/b") }},
+			{"NoSuchKey", func(e *ast.Editor) { e.Delete("/b/nonesuch") }},
+			{"InsertObject", func(e *ast.Editor) { e.Insert("/b/c", nil) }},
+			{"BadIndex", func(e *ast.Editor) { e.Set("/a/01", nil) }},
+			{"IndexRange", func(e *ast.Editor) { e.Delete("/a/3") }},
+			{"InsertRange", func(e *ast.Editor) { e.Insert("/a/4", nil) }},
+			{"SetAppend", func(e *ast.Editor) { e.Set("/a/-", nil) }},
+			{"Scalar", func(e *ast.Editor) { e.Set("/e/0", nil) }},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				got, err := ast.Edit(mustParse(input), tc.edit)
+				if err == nil {
+					t.Errorf("Edit: got %v, want error", got)
+				} else {
+					t.Logf("Edit: got expected error: %v", err)
+				}
+			})
+		}
+	})
+}
+
+func TestWalk(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(`{"a": [1, {"b": null}], "c": "d"}`))
+	if err != nil {
+		t.Fatalf("ParseSingle: %v", err)
+	}
+
+	t.Run("Walk", func(t *testing.T) {
+		var got []string
+		ast.Walk(v, func(path []any, v ast.Value) bool {
+			got = append(got, fmt.Sprint(path, " ", v.JSON()))
+			return len(path) < 2 // do not visit below depth 2
+		})
+		want := []string{
+			`[] {"a":[1,{"b":null}],"c":"d"}`,
+			`[a] [1,{"b":null}]`,
+			`[a 0] 1`,
+			`[a 1] {"b":null}`,
+			`[c] "d"`,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Walk (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Rewrite", func(t *testing.T) {
+		var order []string
+		got := ast.Rewrite(v, func(path []any, v ast.Value) ast.Value {
+			order = append(order, fmt.Sprint(path))
+			switch t := v.(type) {
+			case ast.Number:
+				return ast.Int(t.Int() * 10)
+			case ast.Text:
+				return nil
+			}
+			return v
+		})
+		if diff := cmp.Diff(`{"a":[10,{"b":null}]}`, got.JSON()); diff != "" {
+			t.Errorf("Rewrite (-want, +got):\n%s", diff)
+		}
+		wantOrder := []string{"[a 0]", "[a 1 b]", "[a 1]", "[a]", "[c]", "[]"}
+		if diff := cmp.Diff(wantOrder, order); diff != "" {
+			t.Errorf("Rewrite order (-want, +got):\n%s", diff)
+		}
+
+		// The input is not modified, and unchanged values are shared.
+		if v.JSON() != `{"a":[1,{"b":null}],"c":"d"}` {
+			t.Errorf("Input was modified: %s", v.JSON())
+		}
+		in := v.(ast.Object)[0].Value.(ast.Array)[1].(ast.Object)
+		out := got.(ast.Object)[0].Value.(ast.Array)[1].(ast.Object)
+		if in[0] != out[0] {
+			t.Error("Unchanged object was not shared")
+		}
+	})
+}
+
+func TestObjectOf(t *testing.T) {
+	got := ast.ObjectOf(
+		"name", "Dennis",
+		"age", 37,
+		"tags", ast.ArrayOf("a", 1, true, nil),
+		"nested", ast.ObjectOf(),
+	)
+	const want = `{"name":"Dennis","age":37,"tags":["a",1,true,null],"nested":{}}`
+	if diff := cmp.Diff(want, got.JSON()); diff != "" {
+		t.Errorf("ObjectOf (-want, +got):\n%s", diff)
+	}
+
+	for _, bad := range [][]any{{"odd"}, {1, "x"}} {
+		func() {
+			defer func() {
+				if x := recover(); x == nil {
+					t.Errorf("ObjectOf(%v): did not panic", bad)
+				}
+			}()
+			ast.ObjectOf(bad...)
+		}()
+	}
+}
+
+func TestSort(t *testing.T) {
+	keys := func(o ast.Object) string {
+		var out []string
+		for _, m := range o {
+			out = append(out, m.Key.String()+"="+m.Value.JSON())
+		}
+		return strings.Join(out, " ")
+	}
+	input := func() ast.Object {
+		return ast.ObjectOf("c", 1, "a", 2, "b", 3, "a", 4, "d", 5)
+	}
+	tests := []struct {
+		name string
+		sort func(ast.Object)
+		want string
+	}{
+		{"Stable", ast.Object.SortStable, "a=2 a=4 b=3 c=1 d=5"},
+		{"By", func(o ast.Object) {
+			o.SortBy(func(a, b *ast.Member) bool { return a.Key.String() > b.Key.String() })
+		}, "d=5 c=1 b=3 a=2 a=4"},
+		{"Keys", func(o ast.Object) { o.SortKeys([]string{"b", "a"}) }, "b=3 a=2 a=4 c=1 d=5"},
+		{"KeysMissing", func(o ast.Object) { o.SortKeys([]string{"x", "d", "d"}) }, "d=5 c=1 a=2 b=3 a=4"},
+		{"KeysNone", func(o ast.Object) { o.SortKeys(nil) }, "c=1 a=2 b=3 a=4 d=5"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := input()
+			tc.sort(o)
+			if got := keys(o); got != tc.want {
+				t.Errorf("Got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIndexedObject(t *testing.T) {
+	obj := ast.ObjectOf("Alpha", 1, "beta", 2, "ALPHA", 3, "", 4, "straße", 5, "ǅ", 6)
+
+	m := obj.ToMap()
+	if len(m) != 6 || m["Alpha"] != ast.Int(1) || m["ALPHA"] != ast.Int(3) || m[""] != ast.Int(4) {
+		t.Errorf("ToMap: got %v", m)
+	}
+
+	x := ast.NewIndexedObject(obj)
+	for _, key := range []string{"alpha", "ALPHA", "Beta", "", "STRASSE", "STRAßE", "ǆ", "Ǆ", "nonesuch"} {
+		want, got := obj.Find(key), x.Find(key)
+		if got != want {
+			t.Errorf("Find(%q): got %v, want %v", key, got, want)
+		}
+		want, got = obj.FindKey(ast.TextEqual(key)), x.FindExact(key)
+		if got != want {
+			t.Errorf("FindExact(%q): got %v, want %v", key, got, want)
+		}
+	}
+	if x.JSON() != obj.JSON() {
+		t.Errorf("JSON: got %s, want %s", x.JSON(), obj.JSON())
+	}
+}
+
+func TestGetters(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(`{
+  "s": "text", "i": 25, "f": 1.5, "b": true, "n": null,
+  "a": [1, 2], "o": {"x": 1}, "S": "other"
+}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	obj := v.(ast.Object)
+
+	check := func(name string, got, want any, gotOK, wantOK bool) {
+		t.Helper()
+		if gotOK != wantOK || (wantOK && fmt.Sprint(got) != fmt.Sprint(want)) {
+			t.Errorf("%s: got (%v, %v), want (%v, %v)", name, got, gotOK, want, wantOK)
+		}
+	}
+	s, ok := obj.GetString("s")
+	check("GetString(s)", s, "text", ok, true)
+	s, ok = obj.GetString("S") // keys are case-sensitive
+	check("GetString(S)", s, "other", ok, true)
+	s, ok = obj.GetString("n")
+	check("GetString(n)", s, "", ok, false)
+	z, ok := obj.GetInt("i")
+	check("GetInt(i)", z, 25, ok, true)
+	z, ok = obj.GetInt("f")
+	check("GetInt(f)", z, 0, ok, false)
+	f, ok := obj.GetFloat("i")
+	check("GetFloat(i)", f, 25, ok, true)
+	f, ok = obj.GetFloat("f")
+	check("GetFloat(f)", f, 1.5, ok, true)
+	b, ok := obj.GetBool("b")
+	check("GetBool(b)", b, true, ok, true)
+	b, ok = obj.GetBool("nonesuch")
+	check("GetBool(nonesuch)", b, false, ok, false)
+	a, ok := obj.GetArray("a")
+	check("GetArray(a)", a.JSON(), "[1,2]", ok, true)
+	o, ok := obj.GetObject("o")
+	check("GetObject(o)", o.JSON(), `{"x":1}`, ok, true)
+	_, ok = obj.GetObject("a")
+	check("GetObject(a)", nil, nil, ok, false)
+
+	// Null and missing members are distinguished by Get and IsNull.
+	if v, ok := obj.Get("n"); !ok || v != ast.Null || !obj.IsNull("n") {
+		t.Errorf("Get(n): got (%v, %v), want (null, true)", v, ok)
+	}
+	if v, ok := obj.Get("nonesuch"); ok || obj.IsNull("nonesuch") {
+		t.Errorf("Get(nonesuch): got (%v, %v), want (nil, false)", v, ok)
+	}
+}
+
+func TestArrayHelpers(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(`[1, "two", 3.0, null, {"a": [true]}, [4]]`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	arr := v.(ast.Array)
+	isNum := func(v ast.Value) bool { _, ok := v.(ast.Number); return ok }
+
+	if got := arr.FilterValues(isNum).JSON(); got != `[1,3.0]` {
+		t.Errorf("FilterValues: got %s, want [1,3.0]", got)
+	}
+	if got := arr.FilterValues(func(ast.Value) bool { return false }); len(got) != 0 {
+		t.Errorf("FilterValues: got %v, want empty", got)
+	}
+	got := arr.MapValues(func(v ast.Value) ast.Value {
+		if n, ok := v.(ast.Number); ok {
+			return n.Int() * 2
+		}
+		return v
+	})
+	if want := `[2,"two",6,null,{"a":[true]},[4]]`; got.JSON() != want {
+		t.Errorf("MapValues: got %s, want %s", got.JSON(), want)
+	}
+	if i := arr.IndexWhere(func(v ast.Value) bool { return v == ast.Null }); i != 3 {
+		t.Errorf("IndexWhere: got %d, want 3", i)
+	}
+	if i := arr.IndexWhere(func(ast.Value) bool { return false }); i != -1 {
+		t.Errorf("IndexWhere: got %d, want -1", i)
+	}
+
+	for _, tc := range []struct {
+		v    ast.Value
+		want bool
+	}{
+		{ast.Int(3), true},
+		{ast.Float(1), true},
+		{ast.String("two"), true},
+		{ast.Null, true},
+		{ast.ObjectOf("a", ast.ArrayOf(true)), true},
+		{ast.ArrayOf(4), true},
+		{ast.ArrayOf(4, 5), false},
+		{ast.ObjectOf("A", ast.ArrayOf(true)), false},
+		{ast.String("1"), false},
+		{ast.Bool(false), false},
+	} {
+		if got := arr.Contains(tc.v); got != tc.want {
+			t.Errorf("Contains(%s): got %v, want %v", tc.v.JSON(), got, tc.want)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(`{
+  "user": "alice", "Password": "hunter2",
+  "sessions": [{"token": "abc", "id": 1}, {"id": 2}],
+  "auth": {"token": {"value": "xyz"}},
+  "keep": {"a": 1}
+}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	orig := v.JSON()
+
+	got := ast.Redact(v, ast.MatchKeys("password", "token"), ast.String("*"))
+	const want = `{"user":"alice","Password":"*","sessions":[{"token":"*","id":1},{"id":2}],` +
+		`"auth":{"token":"*"},"keep":{"a":1}}`
+	if diff := cmp.Diff(want, got.JSON()); diff != "" {
+		t.Errorf("Redact (-want, +got):\n%s", diff)
+	}
+	if v.JSON() != orig {
+		t.Errorf("Input was modified: %s", v.JSON())
+	}
+	if got.(ast.Object)[4] != v.(ast.Object)[4] {
+		t.Error("Unredacted member was not shared")
+	}
+
+	// Match by path: redact the second element of any array.
+	got = ast.Redact(v, func(path []any, _ ast.Value) bool {
+		return len(path) != 0 && path[len(path)-1] == 1
+	}, nil)
+	if want := `[{"token":"abc","id":1},null]`; got.(ast.Object)[2].Value.JSON() != want {
+		t.Errorf("Redact by path: got %s, want %s", got.(ast.Object)[2].Value.JSON(), want)
+	}
+}
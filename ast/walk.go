@@ -0,0 +1,205 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package ast
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Walk visits v and the values nested within it in preorder, calling f for
+// each. The path gives the location of each value relative to v, as a
+// sequence of object keys (string) and array indices (int); it is empty for v
+// itself. If f returns false, Walk does not visit the values nested within
+// the current value.
+//
+// Only Object and Array values are traversed; Walk does not descend into
+// other implementations of Value. The path slice is reused between calls, so
+// f must copy it if it needs to retain it.
+func Walk(v Value, f func(path []any, v Value) bool) {
+	walk(nil, v, f)
+}
+
+func walk(path []any, v Value, f func([]any, Value) bool) {
+	if !f(path, v) {
+		return
+	}
+	switch t := v.(type) {
+	case Object:
+		for _, m := range t {
+			walk(append(path, m.Key.String()), m.Value, f)
+		}
+	case Array:
+		for i, elt := range t {
+			walk(append(path, i), elt, f)
+		}
+	}
+}
+
+// Rewrite visits v and the values nested within it in postorder, calling f
+// for each with its path as described for Walk, and returns the result of
+// replacing each value with the value f returns for it. When f is called for
+// an object or array, its members or elements have already been rewritten.
+//
+// If f returns nil for an object member or array element, it is removed.
+// If f returns nil for v itself, Rewrite returns nil.
+//
+// Rewrite does not modify v. An object or array is copied only if one of the
+// values nested within it was replaced, so the result shares any part of v
+// that f did not change.
+func Rewrite(v Value, f func(path []any, v Value) Value) Value {
+	return rewrite(nil, v, f)
+}
+
+func rewrite(path []any, v Value, f func([]any, Value) Value) Value {
+	switch t := v.(type) {
+	case Object:
+		var out Object // allocated at the first change
+		for i, m := range t {
+			w := rewrite(append(path, m.Key.String()), m.Value, f)
+			if out == nil {
+				if same(w, m.Value) {
+					continue
+				}
+				out = append(make(Object, 0, len(t)), t[:i]...)
+			}
+			if w == nil {
+				continue
+			} else if same(w, m.Value) {
+				out = append(out, m)
+			} else {
+				out = append(out, &Member{Key: m.Key, Value: w})
+			}
+		}
+		if out != nil {
+			v = out
+		}
+	case Array:
+		var out Array // allocated at the first change
+		for i, elt := range t {
+			w := rewrite(append(path, i), elt, f)
+			if out == nil {
+				if same(w, elt) {
+					continue
+				}
+				out = append(make(Array, 0, len(t)), t[:i]...)
+			}
+			if w != nil {
+				out = append(out, w)
+			}
+		}
+		if out != nil {
+			v = out
+		}
+	}
+	return f(path, v)
+}
+
+// same reports whether a and b are the same value, without panicking if they
+// are not comparable. Objects and arrays are the same if they share the same
+// elements. Other values that are not comparable are the same if they are
+// deeply equal.
+func same(a, b Value) bool {
+	switch t := a.(type) {
+	case Object:
+		u, ok := b.(Object)
+		return ok && len(t) == len(u) && (len(t) == 0 || &t[0] == &u[0])
+	case Array:
+		u, ok := b.(Array)
+		return ok && len(t) == len(u) && (len(t) == 0 || &t[0] == &u[0])
+	}
+	if a == nil || b == nil {
+		return a == b
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	} else if !va.Comparable() {
+		// This is not an Object or Array, so it is safe to treat equal values
+		// as the same.
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+// Redact returns a copy of v in which each value for which match reports true
+// is replaced by replacement. The path passed to match is as described for
+// Walk. The values nested within a replaced value are not reported to match.
+// If replacement is nil, Null is used.
+// Like Rewrite, Redact does not modify v, and the result shares any part of v
+// that was not redacted.
+//
+// MatchKeys constructs a match function for the common case of redacting
+// members by key:
+//
+//	safe := ast.Redact(payload, ast.MatchKeys("password", "token"), ast.String("*"))
+func Redact(v Value, match func(path []any, v Value) bool, replacement Value) Value {
+	if replacement == nil {
+		replacement = Null
+	}
+	return redact(nil, v, match, replacement)
+}
+
+func redact(path []any, v Value, match func([]any, Value) bool, repl Value) Value {
+	if match(path, v) {
+		return repl
+	}
+	switch t := v.(type) {
+	case Object:
+		var out Object // allocated at the first change
+		for i, m := range t {
+			w := redact(append(path, m.Key.String()), m.Value, match, repl)
+			if out == nil {
+				if same(w, m.Value) {
+					continue
+				}
+				out = append(make(Object, 0, len(t)), t[:i]...)
+			}
+			if same(w, m.Value) {
+				out = append(out, m)
+			} else {
+				out = append(out, &Member{Key: m.Key, Value: w})
+			}
+		}
+		if out != nil {
+			return out
+		}
+	case Array:
+		var out Array // allocated at the first change
+		for i, elt := range t {
+			w := redact(append(path, i), elt, match, repl)
+			if out == nil {
+				if same(w, elt) {
+					continue
+				}
+				out = append(make(Array, 0, len(t)), t[:i]...)
+			}
+			out = append(out, w)
+		}
+		if out != nil {
+			return out
+		}
+	}
+	return v
+}
+
+// MatchKeys returns a match function for Redact that reports true for the
+// value of any object member whose key is case-insensitively equal to one of
+// the given keys.
+func MatchKeys(keys ...string) func(path []any, v Value) bool {
+	return func(path []any, _ Value) bool {
+		if len(path) == 0 {
+			return false
+		}
+		key, ok := path[len(path)-1].(string)
+		if !ok {
+			return false
+		}
+		for _, k := range keys {
+			if strings.EqualFold(k, key) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -111,10 +112,24 @@ func BenchmarkScanner(b *testing.B) {
 			}
 		})
 
+		b.Run("ScannerBytes", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dec := jtree.NewScannerBytes(input)
+				for {
+					err := dec.Next()
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						b.Fatalf("Unexpected error: %v", err)
+					}
+				}
+			}
+		})
+
 		b.Run("Stream", func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
 				dec := jtree.NewStream(bytes.NewReader(input))
-				if err := dec.Parse(noopHandler{}); err != nil {
+				if err := dec.Parse(jtree.NopHandler{}); err != nil {
 					b.Fatalf("Unexpected error: %v", err)
 				}
 			}
@@ -128,6 +143,32 @@ func BenchmarkScanner(b *testing.B) {
 				}
 			}
 		})
+
+		b.Run("ParseASTBytes", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := ast.ParseBytes(input)
+				if err != nil {
+					b.Fatalf("Unexpected error: %v", err)
+				}
+			}
+		})
+
+		b.Run("ParseASTArena", func(b *testing.B) {
+			var arena ast.Arena
+			for i := 0; i < b.N; i++ {
+				p := ast.NewParser(bytes.NewReader(input))
+				p.SetArena(&arena)
+				for {
+					_, err := p.Parse()
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						b.Fatalf("Unexpected error: %v", err)
+					}
+				}
+				arena.Release()
+			}
+		})
 	})
 
 	b.Run("JWCC", func(b *testing.B) {
@@ -157,14 +198,138 @@ func BenchmarkScanner(b *testing.B) {
 	})
 }
 
-type noopHandler struct{}
-
-func (noopHandler) BeginObject(jtree.Anchor) error        { return nil }
-func (noopHandler) EndObject(jtree.Anchor) error          { return nil }
-func (noopHandler) BeginArray(jtree.Anchor) error         { return nil }
-func (noopHandler) EndArray(jtree.Anchor) error           { return nil }
-func (noopHandler) BeginMember(jtree.Anchor) error        { return nil }
-func (noopHandler) EndMember(jtree.Anchor) error          { return nil }
-func (noopHandler) Value(jtree.Anchor) error              { return nil }
-func (noopHandler) SyntaxError(jtree.Anchor, error) error { return nil }
-func (noopHandler) EndOfInput(jtree.Anchor)               {}
+// BenchmarkTokens compares tokenizing inputs dominated by long strings or by
+// numbers with the standard library.
+func BenchmarkTokens(b *testing.B) {
+	var strs, nums bytes.Buffer
+	strs.WriteString("[")
+	nums.WriteString("[")
+	for i := 0; i < 2000; i++ {
+		if i > 0 {
+			strs.WriteString(",")
+			nums.WriteString(",")
+		}
+		fmt.Fprintf(&strs, `"%s entry %d, café \"quoted\""`, strings.Repeat("lorem ipsum dolor sit amet ", 4), i)
+		fmt.Fprintf(&nums, "%d,%d.%d,-%de%d", i*7919, i, i*31, i, i%40)
+	}
+	strs.WriteString("]")
+	nums.WriteString("]")
+
+	for _, input := range []struct {
+		name string
+		data []byte
+	}{{"Strings", strs.Bytes()}, {"Numbers", nums.Bytes()}} {
+		b.Run(input.name, func(b *testing.B) {
+			b.Run("Std", func(b *testing.B) {
+				b.SetBytes(int64(len(input.data)))
+				for i := 0; i < b.N; i++ {
+					dec := json.NewDecoder(bytes.NewReader(input.data))
+					for {
+						if _, err := dec.Token(); err == io.EOF {
+							break
+						} else if err != nil {
+							b.Fatalf("Unexpected error: %v", err)
+						}
+					}
+				}
+			})
+			b.Run("Scanner", func(b *testing.B) {
+				b.SetBytes(int64(len(input.data)))
+				for i := 0; i < b.N; i++ {
+					s := jtree.NewScanner(bytes.NewReader(input.data))
+					for {
+						if err := s.Next(); err == io.EOF {
+							break
+						} else if err != nil {
+							b.Fatalf("Unexpected error: %v", err)
+						}
+					}
+				}
+			})
+			b.Run("ScannerBytes", func(b *testing.B) {
+				b.SetBytes(int64(len(input.data)))
+				for i := 0; i < b.N; i++ {
+					s := jtree.NewScannerBytes(input.data)
+					for {
+						if err := s.Next(); err == io.EOF {
+							break
+						} else if err != nil {
+							b.Fatalf("Unexpected error: %v", err)
+						}
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkJSON(b *testing.B) {
+	// Nested values: the cost of rendering should grow linearly with depth.
+	nest := func(depth int) ast.Value {
+		var v ast.Value = ast.String("leaf")
+		for i := range depth {
+			if i%2 == 0 {
+				v = ast.Array{ast.Int(i), v}
+			} else {
+				v = ast.Object{{Key: ast.Quoted(`"k"`), Value: v}}
+			}
+		}
+		return v
+	}
+	for _, depth := range []int{10, 100, 1000, 10000} {
+		v := nest(depth)
+		b.Run(fmt.Sprintf("Deep/%d", depth), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = v.JSON()
+			}
+		})
+	}
+
+	input, err := readInput()
+	if err != nil {
+		b.Fatalf("Reading test input: %v", err)
+	}
+	vs, err := ast.ParseBytes(input)
+	if err != nil {
+		b.Fatalf("Parse failed: %v", err)
+	}
+	b.Run("Wide", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(input)))
+		for i := 0; i < b.N; i++ {
+			for _, v := range vs {
+				_ = v.JSON()
+			}
+		}
+	})
+}
+
+func BenchmarkMinify(b *testing.B) {
+	input, err := readInput()
+	if err != nil {
+		b.Fatalf("Reading test input: %v", err)
+	}
+	b.Run("Std", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(input)))
+		var buf bytes.Buffer
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			if err := json.Compact(&buf, input); err != nil {
+				b.Fatalf("Compact failed: %v", err)
+			}
+		}
+	})
+	b.Run("JTree", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(input)))
+		var buf bytes.Buffer
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			if err := jtree.Minify(&buf, bytes.NewReader(input)); err != nil {
+				b.Fatalf("Minify failed: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,456 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package cbor converts JSON values to and from the Concise Binary Object
+// Representation (CBOR) defined by RFC 8949.
+//
+// Encode maps each ast.Value to the corresponding CBOR data item: An object
+// becomes a map whose keys are text strings, in the order of its members; an
+// array, string, Boolean, or null becomes the CBOR item of the same kind. An
+// integer becomes a CBOR integer (or a bignum, if it does not fit in 64 bits)
+// and any other number becomes a floating-point value, so the distinction
+// between integers and floats survives a round trip.
+//
+// Decode accepts any well-formed CBOR item and maps it onto the nearest JSON
+// value, as described by RFC 8949 Section 6.1. In particular, byte strings
+// become base64url-encoded strings, undefined becomes null, integer map keys
+// become their decimal text, and tags other than bignums are ignored in favor
+// of their content.
+package cbor
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/ast"
+)
+
+// CBOR major types (RFC 8949 Section 3.1).
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// Simple values and other special encodings of major type 7.
+const (
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	infoFloat16     = 25
+	infoFloat32     = 26
+	infoFloat64     = 27
+	infoIndefinite  = 31
+)
+
+// Tags for unsigned and negative bignums (RFC 8949 Section 3.4.3).
+const (
+	tagPosBignum = 2
+	tagNegBignum = 3
+)
+
+// maxDepth is the maximum nesting depth of arrays, maps, and tags accepted by
+// Decode, to bound the stack used by hostile input.
+const maxDepth = 10000
+
+// Encode returns the CBOR encoding of v. It reports an error if v contains a
+// value that is not an ast.Object, ast.Array, ast.Text, ast.Number, ast.Bool,
+// or ast.Null.
+func Encode(v ast.Value) ([]byte, error) { return Append(nil, v) }
+
+// Append appends the CBOR encoding of v to buf, and returns the extended
+// slice. It reports an error if v contains a value that cannot be encoded, as
+// Encode does.
+func Append(buf []byte, v ast.Value) ([]byte, error) {
+	switch t := v.(type) {
+	case ast.Object:
+		buf = appendHead(buf, majorMap, uint64(len(t)))
+		for _, m := range t {
+			buf = appendText(buf, m.Key.String())
+			var err error
+			buf, err = Append(buf, m.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case ast.Array:
+		buf = appendHead(buf, majorArray, uint64(len(t)))
+		for _, elt := range t {
+			var err error
+			buf, err = Append(buf, elt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case ast.Bool:
+		if t {
+			return append(buf, majorSimple<<5|simpleTrue), nil
+		}
+		return append(buf, majorSimple<<5|simpleFalse), nil
+	case ast.Number:
+		return appendNumber(buf, t)
+	case ast.Text:
+		return appendText(buf, t.String()), nil
+	}
+	if v == ast.Null {
+		return append(buf, majorSimple<<5|simpleNull), nil
+	}
+	return nil, fmt.Errorf("cannot encode %T as CBOR", v)
+}
+
+// appendHead appends the initial byte and argument of a data item.
+func appendHead(buf []byte, major byte, arg uint64) []byte {
+	m := major << 5
+	switch {
+	case arg < 24:
+		return append(buf, m|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(buf, m|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, m|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, m|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, m|27), arg)
+	}
+}
+
+func appendText(buf []byte, s string) []byte {
+	return append(appendHead(buf, majorText, uint64(len(s))), s...)
+}
+
+func appendNumber(buf []byte, n ast.Number) ([]byte, error) {
+	switch t := n.(type) {
+	case ast.Int:
+		return appendInt(buf, int64(t)), nil
+	case ast.Float:
+		return appendFloat(buf, float64(t)), nil
+	}
+	if !n.IsInt() {
+		// A number parsed from JSON text may be out of range for a float64.
+		text := n.JSON()
+		f, err := jtree.ParseFloat([]byte(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("number %s out of range for CBOR", text)
+		}
+		return appendFloat(buf, f), nil
+	}
+
+	// An integer parsed from JSON text may not fit in an int64.
+	text := n.JSON()
+	if z, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return appendInt(buf, z), nil
+	}
+	z, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", text)
+	}
+	major, tag := byte(majorUint), uint64(tagPosBignum)
+	if z.Sign() < 0 {
+		major, tag = majorNegInt, tagNegBignum
+		z.Not(z) // -1 - z
+	}
+	if z.IsUint64() {
+		return appendHead(buf, major, z.Uint64()), nil
+	}
+	mag := z.Bytes()
+	buf = appendHead(buf, majorTag, tag)
+	buf = appendHead(buf, majorBytes, uint64(len(mag)))
+	return append(buf, mag...), nil
+}
+
+func appendInt(buf []byte, z int64) []byte {
+	if z < 0 {
+		return appendHead(buf, majorNegInt, uint64(-1-z))
+	}
+	return appendHead(buf, majorUint, uint64(z))
+}
+
+// appendFloat appends f as a single-precision float if that is exact, and
+// otherwise as a double-precision float.
+func appendFloat(buf []byte, f float64) []byte {
+	if f32 := float32(f); float64(f32) == f || math.IsNaN(f) {
+		return binary.BigEndian.AppendUint32(append(buf, majorSimple<<5|infoFloat32), math.Float32bits(f32))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, majorSimple<<5|infoFloat64), math.Float64bits(f))
+}
+
+// Decode decodes a single CBOR data item from data and returns the
+// corresponding JSON value. It reports an error if data is not well-formed,
+// or contains anything other than a single data item.
+func Decode(data []byte) (ast.Value, error) {
+	d := &decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("offset %d: extra data after value", d.pos)
+	}
+	return v, nil
+}
+
+// A decoder decodes CBOR data items from a buffer.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// errBreak is reported by value when it reads the "break" stop code, which
+// ends an item of indefinite length.
+var errBreak = errors.New("unexpected break")
+
+func (d *decoder) fail(msg string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", d.pos, fmt.Sprintf(msg, args...))
+}
+
+// head decodes the initial byte and argument of a data item. For an item of
+// indefinite length, indef is true and arg is 0.
+func (d *decoder) head() (major byte, info byte, arg uint64, indef bool, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, false, d.fail("unexpected end of input")
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info = b>>5, b&0x1f
+	var n int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info <= 27:
+		n = 1 << (info - 24)
+	case info == infoIndefinite:
+		return major, info, 0, true, nil
+	default:
+		return 0, 0, 0, false, d.fail("invalid additional information %d", info)
+	}
+	if len(d.data)-d.pos < n {
+		return 0, 0, 0, false, d.fail("unexpected end of input")
+	}
+	for _, b := range d.data[d.pos : d.pos+n] {
+		arg = arg<<8 | uint64(b)
+	}
+	d.pos += n
+	return major, info, arg, false, nil
+}
+
+// count checks that arg, the number of items or bytes promised by a head,
+// could be satisfied by the remaining input, with each item taking at least
+// size bytes.
+func (d *decoder) count(arg uint64, size int) (int, error) {
+	if arg > uint64(len(d.data)-d.pos)/uint64(size) {
+		return 0, d.fail("length %d exceeds the input", arg)
+	}
+	return int(arg), nil
+}
+
+func (d *decoder) value(depth int) (ast.Value, error) {
+	if depth > maxDepth {
+		return nil, d.fail("value nested too deeply")
+	}
+	start := d.pos
+	major, info, arg, indef, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if indef && (major == majorUint || major == majorNegInt || major == majorTag) {
+		d.pos = start
+		return nil, d.fail("invalid indefinite length for major type %d", major)
+	}
+	switch major {
+	case majorUint:
+		if arg > math.MaxInt64 {
+			return bigNumber(new(big.Int).SetUint64(arg))
+		}
+		return ast.Int(arg), nil
+
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			z := new(big.Int).SetUint64(arg)
+			return bigNumber(z.Not(z))
+		}
+		return ast.Int(-1 - int64(arg)), nil
+
+	case majorBytes, majorText:
+		s, err := d.str(major, arg, indef)
+		if err != nil {
+			return nil, err
+		}
+		if major == majorBytes {
+			return ast.String(base64.RawURLEncoding.EncodeToString(s)), nil
+		}
+		return ast.String(s), nil
+
+	case majorArray:
+		var out ast.Array
+		if !indef {
+			n, err := d.count(arg, 1)
+			if err != nil {
+				return nil, err
+			}
+			out = make(ast.Array, 0, n)
+		}
+		for i := 0; indef || i < int(arg); i++ {
+			v, err := d.value(depth + 1)
+			if err == errBreak && indef {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+
+	case majorMap:
+		var out ast.Object
+		if !indef {
+			n, err := d.count(arg, 2)
+			if err != nil {
+				return nil, err
+			}
+			out = make(ast.Object, 0, n)
+		}
+		for i := 0; indef || i < int(arg); i++ {
+			kpos := d.pos
+			k, err := d.value(depth + 1)
+			if err == errBreak && indef {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			var key ast.String
+			switch t := k.(type) {
+			case ast.String:
+				key = t
+			case ast.Int:
+				key = ast.String(t.JSON())
+			default:
+				d.pos = kpos
+				return nil, d.fail("unsupported map key %s", k.JSON())
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, &ast.Member{Key: key, Value: v})
+		}
+		return out, nil
+
+	case majorTag:
+		if arg == tagPosBignum || arg == tagNegBignum {
+			cmajor, _, n, cindef, err := d.head()
+			if err != nil {
+				return nil, err
+			} else if cmajor != majorBytes {
+				return nil, d.fail("invalid bignum content")
+			}
+			mag, err := d.str(majorBytes, n, cindef)
+			if err != nil {
+				return nil, err
+			}
+			z := new(big.Int).SetBytes(mag)
+			if arg == tagNegBignum {
+				z.Not(z)
+			}
+			return bigNumber(z)
+		}
+		return d.value(depth + 1) // ignore other tags
+
+	default: // majorSimple
+		switch {
+		case info == simpleFalse:
+			return ast.Bool(false), nil
+		case info == simpleTrue:
+			return ast.Bool(true), nil
+		case info == simpleNull, info == simpleUndefined:
+			return ast.Null, nil
+		case info == infoFloat16:
+			return ast.Float(float16(uint16(arg))), nil
+		case info == infoFloat32:
+			return ast.Float(math.Float32frombits(uint32(arg))), nil
+		case info == infoFloat64:
+			return ast.Float(math.Float64frombits(arg)), nil
+		case indef:
+			return nil, errBreak
+		}
+		d.pos = start
+		return nil, d.fail("unsupported simple value %d", arg)
+	}
+}
+
+// str decodes the content of a byte or text string whose head has already
+// been read. The chunks of an indefinite-length string must be definite
+// strings of the same major type.
+func (d *decoder) str(major byte, arg uint64, indef bool) ([]byte, error) {
+	if !indef {
+		n, err := d.count(arg, 1)
+		if err != nil {
+			return nil, err
+		}
+		s := d.data[d.pos : d.pos+n]
+		d.pos += n
+		return s, nil
+	}
+	var out []byte
+	for {
+		start := d.pos
+		cmajor, info, carg, cindef, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if cmajor == majorSimple && info == infoIndefinite {
+			return out, nil // break
+		}
+		if cmajor != major || cindef {
+			d.pos = start
+			return nil, d.fail("invalid chunk in indefinite-length string")
+		}
+		chunk, err := d.str(major, carg, false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+}
+
+// bigNumber returns an ast.Number with the value of z.
+func bigNumber(z *big.Int) (ast.Value, error) {
+	return ast.ParseSingle(strings.NewReader(z.String()))
+}
+
+// float16 converts the bits of an IEEE 754 half-precision value to float64.
+func float16(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,157 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package cbor_test
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/cbor"
+	"github.com/creachadair/jtree/internal/testutil"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		input ast.Value
+		want  string // hex
+	}{
+		{ast.Int(0), "00"},
+		{ast.Int(23), "17"},
+		{ast.Int(24), "1818"},
+		{ast.Int(1000), "1903e8"},
+		{ast.Int(1000000), "1a000f4240"},
+		{ast.Int(-1), "20"},
+		{ast.Int(-1000), "3903e7"},
+		{ast.Int(math.MinInt64), "3b7fffffffffffffff"},
+		{ast.Float(1.5), "fa3fc00000"},
+		{ast.Float(1.1), "fb3ff199999999999a"},
+		{ast.Float(math.Inf(-1)), "faff800000"},
+		{ast.Float(2), "fa40000000"},
+		{ast.Bool(false), "f4"},
+		{ast.Bool(true), "f5"},
+		{ast.Null, "f6"},
+		{ast.String(""), "60"},
+		{ast.String("IETF"), "6449455446"},
+		{ast.String("ü"), "62c3bc"},
+		{ast.Array{}, "80"},
+		{ast.ArrayOf(1, ast.ArrayOf(2, 3)), "8201820203"},
+		{ast.ObjectOf("b", 1, "a", ast.ArrayOf(2)), "a2616201616181 02"},
+
+		// Numbers parsed from text.
+		{testutil.MustParse(t, `25`), "1819"},
+		{testutil.MustParse(t, `2.0`), "fa40000000"},
+		{testutil.MustParse(t, `18446744073709551615`), "1bffffffffffffffff"},
+		{testutil.MustParse(t, `18446744073709551616`), "c249010000000000000000"},
+		{testutil.MustParse(t, `-18446744073709551616`), "3bffffffffffffffff"},
+		{testutil.MustParse(t, `-18446744073709551617`), "c349010000000000000000"},
+	}
+	for _, tc := range tests {
+		got, err := cbor.Encode(tc.input)
+		if err != nil {
+			t.Errorf("Encode %s: unexpected error: %v", tc.input.JSON(), err)
+			continue
+		}
+		want := strings.ReplaceAll(tc.want, " ", "")
+		if h := hex.EncodeToString(got); h != want {
+			t.Errorf("Encode %s: got %s, want %s", tc.input.JSON(), h, want)
+		}
+
+		// Every encoding should decode to a value with the same encoding.
+		dec, err := cbor.Decode(got)
+		if err != nil {
+			t.Errorf("Decode %s: unexpected error: %v", want, err)
+			continue
+		}
+		if re, err := cbor.Encode(dec); err != nil {
+			t.Errorf("Encode %s: unexpected error: %v", dec.JSON(), err)
+		} else if h := hex.EncodeToString(re); h != want {
+			t.Errorf("Round trip %s: got %s", want, h)
+		}
+	}
+
+	for _, bad := range []ast.Value{
+		ast.ArrayOf(1, badValue{}),
+		testutil.MustParse(t, `1e400`),
+		testutil.MustParse(t, `-1e400`),
+	} {
+		if _, err := cbor.Encode(bad); err == nil {
+			t.Errorf("Encode %s: got nil, want error", bad.JSON())
+		}
+	}
+}
+
+type badValue struct{}
+
+func (badValue) JSON() string   { return "bad" }
+func (badValue) String() string { return "bad" }
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		input string // hex
+		want  string // JSON
+	}{
+		// Examples from RFC 8949 Appendix A.
+		{"f93c00", "1"},
+		{"f9c400", "-4"},
+		{"f90001", "5.960464477539063e-08"},
+		{"f97c00", "Infinity"},
+		{"f7", "null"},
+		{"c074323031332d30332d32315432303a30343a30305a", `"2013-03-21T20:04:00Z"`},
+		{"c11a514b67b0", "1363896240"},
+		{"4401020304", `"AQIDBA"`},
+		{"5f42010243030405ff", `"AQIDBAU"`},
+		{"7f657374726561646d696e67ff", `"streaming"`},
+		{"9f018202039f0405ffff", "[1,[2,3],[4,5]]"},
+		{"bf61610161629f0203ffff", `{"a":1,"b":[2,3]}`},
+		{"a201020304", `{"1":2,"3":4}`},
+		{"c249010000000000000000", "18446744073709551616"},
+		{"3bffffffffffffffff", "-18446744073709551616"},
+
+		// Integer and float distinction.
+		{"fb4000000000000000", "2"},
+		{"02", "2"},
+	}
+	for _, tc := range tests {
+		data, err := hex.DecodeString(tc.input)
+		if err != nil {
+			t.Fatalf("Invalid test input %q: %v", tc.input, err)
+		}
+		got, err := cbor.Decode(data)
+		if err != nil {
+			t.Errorf("Decode %s: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if s := got.JSON(); s != tc.want {
+			t.Errorf("Decode %s: got %s, want %s", tc.input, s, tc.want)
+		}
+	}
+
+	if v, _ := cbor.Decode([]byte{0xfb, 0x40, 0, 0, 0, 0, 0, 0, 0}); v.(ast.Number).IsInt() {
+		t.Error("Decode float 2.0: got an integer, want a float")
+	}
+	if v, _ := cbor.Decode([]byte{0x02}); !v.(ast.Number).IsInt() {
+		t.Error("Decode integer 2: got a float, want an integer")
+	}
+
+	for _, bad := range []string{
+		"",                   // empty
+		"18",                 // truncated argument
+		"62c3",               // truncated string
+		"9b00000000ffffffff", // length beyond input
+		"0000",               // extra data
+		"ff",                 // unexpected break
+		"1f",                 // indefinite integer
+		"5f6161ff",           // mismatched chunk
+		"a1f401",             // unsupported key
+		"f8ff",               // unsupported simple value
+		"1c",                 // reserved additional info
+	} {
+		data, _ := hex.DecodeString(bad)
+		if v, err := cbor.Decode(data); err == nil {
+			t.Errorf("Decode %s: got %v, want error", bad, v)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// An ErrorList is a collection of errors, typically reported by a process
+// that does not stop at the first problem it finds.
+type ErrorList []error
+
+// Error satisfies the error interface. It reports each error in the list on
+// a separate line.
+func (e ErrorList) Error() string {
+	ss := make([]string, len(e))
+	for i, err := range e {
+		ss[i] = err.Error()
+	}
+	return strings.Join(ss, "\n")
+}
+
+// Err returns nil if e is empty, otherwise it returns e.
+func (e ErrorList) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Unwrap supports error wrapping.
+func (e ErrorList) Unwrap() []error { return e }
+
+// Sort sorts the errors in e in order of their locations in the input.
+// Errors that have an ErrorLocation method are ordered by its result; all
+// other errors are ordered after them, retaining their relative order.
+func (e ErrorList) Sort() {
+	sort.SliceStable(e, func(i, j int) bool {
+		li, iok := errorLocation(e[i])
+		lj, jok := errorLocation(e[j])
+		if iok && jok {
+			return li.Line < lj.Line || (li.Line == lj.Line && li.Column < lj.Column)
+		}
+		return iok && !jok
+	})
+}
+
+func errorLocation(err error) (LineCol, bool) {
+	var le interface{ ErrorLocation() LineCol }
+	if errors.As(err, &le) {
+		return le.ErrorLocation(), true
+	}
+	return LineCol{}, false
+}
+
+// DuplicateKeyError is the concrete type of errors reported by Check for an
+// object member whose key duplicates that of an earlier member.
+type DuplicateKeyError struct {
+	Key      string  // the decoded key
+	Location LineCol // the location of the duplicate
+	First    LineCol // the location of the first occurrence
+}
+
+// Error satisfies the error interface.
+func (d *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("at %s: duplicate key %q (first at %s)", d.Location, d.Key, d.First)
+}
+
+// ErrorLocation reports the location of the duplicate key.
+func (d *DuplicateKeyError) ErrorLocation() LineCol { return d.Location }
+
+// Check reads a single JSON value from r and reports all the problems it can
+// detect, subject to the given options. A nil *ValidOptions accepts only
+// standard JSON. The result is sorted by location, and is empty if the input
+// is valid.
+//
+// Unlike a Stream, which stops at the first syntax error, Check attempts to
+// recover after each error by skipping to the next member or element of the
+// enclosing object or array. Errors reported after a recovery may be a
+// consequence of an earlier error.
+//
+// In addition to syntax errors, Check reports an error of concrete type
+// *DuplicateKeyError for each duplicated key in an object.
+func Check(r io.Reader, opts *ValidOptions) ErrorList {
+	c := &checker{
+		s:      NewScanner(r),
+		tcomma: opts.allowTrailingCommas(),
+		max:    opts.maxDepth(),
+	}
+	c.s.AllowComments(opts.allowComments())
+
+	c.advance()
+	if c.eof {
+		c.fail(io.EOF, "empty input")
+	} else {
+		c.element()
+		if !c.eof {
+			c.fail(nil, "extra input after value")
+		}
+	}
+	c.errs.Sort()
+	return c.errs
+}
+
+// checker implements a recursive-descent syntax checker with error recovery.
+type checker struct {
+	s      *Scanner
+	tcomma bool // allow trailing commas
+	max    int  // maximum nesting depth, if positive
+
+	tok   Token // current token
+	eof   bool  // no more tokens are available
+	depth int   // current nesting depth
+
+	errs   ErrorList
+	eofErr bool    // an error has been reported at the end of input
+	last   LineCol // the location of the last syntax error reported
+}
+
+func (c *checker) fail(err error, msg string, args ...any) {
+	c.report(nil, err, fmt.Sprintf(msg, args...))
+}
+
+// unexpected reports a syntax error for the current token, where one of want
+// was expected.
+func (c *checker) unexpected(want ...Token) { c.report(want, nil, tokLabel(want, c.got())) }
+
+func (c *checker) report(want []Token, err error, msg string) {
+	if c.eof {
+		// Report at most one error for the end of input.
+		if c.eofErr {
+			return
+		}
+		c.eofErr = true
+	}
+	serr := newSyntaxError(c.s, want, err, msg)
+	if serr.Location == c.last && len(c.errs) != 0 {
+		return // report at most one syntax error per location
+	}
+	c.last = serr.Location
+	c.errs = append(c.errs, serr)
+}
+
+// advance moves to the next non-comment token of the input, recording and
+// skipping any lexical errors.
+func (c *checker) advance() {
+	for !c.eof {
+		err := c.s.Next()
+		if err == io.EOF {
+			c.tok, c.eof = Invalid, true
+		} else if err != nil {
+			c.fail(err, err.Error())
+			var perr posError
+			if !errors.As(err, &perr) {
+				c.tok, c.eof = Invalid, true // not a lexical error; give up
+			}
+			continue
+		} else if tok := c.s.Token(); tok != LineComment && tok != BlockComment {
+			c.tok = tok
+			return
+		}
+	}
+}
+
+// got returns a description of the current token for error messages.
+func (c *checker) got() any {
+	if c.eof {
+		return io.EOF
+	}
+	return c.tok
+}
+
+// element checks a single value beginning at the current token, and leaves
+// the checker at the token following the value.
+func (c *checker) element() {
+	switch c.tok {
+	case LBrace:
+		c.container(c.members)
+	case LSquare:
+		c.container(c.elements)
+	case Integer, Number, String, True, False, Null, NaN, Infinity, NegInfinity:
+		c.advance()
+	default:
+		c.fail(nil, "unexpected %v", c.got())
+		c.skip()
+	}
+}
+
+// container checks an object or array beginning at the current token, using
+// body to check its contents.
+func (c *checker) container(body func()) {
+	c.depth++
+	if c.max > 0 && c.depth == c.max+1 {
+		c.fail(nil, "nesting depth exceeds %d", c.max)
+	}
+	c.advance()
+	body()
+	c.depth--
+}
+
+// members checks the members of an object, following its open brace.
+func (c *checker) members() {
+	keys := make(map[string]LineCol)
+	if c.tok == RBrace {
+		c.advance()
+		return
+	}
+	for {
+		// Key
+		if c.tok != String {
+			c.unexpected(String)
+			if c.recover(RBrace) {
+				return
+			}
+			continue
+		}
+		loc := c.s.Location().First
+		if key, err := Unquote(c.s.Text()); err == nil {
+			if first, ok := keys[string(key)]; ok {
+				c.errs = append(c.errs, &DuplicateKeyError{Key: string(key), Location: loc, First: first})
+			} else {
+				keys[string(key)] = loc
+			}
+		}
+		c.advance()
+
+		// Colon and value
+		if c.tok != Colon {
+			c.unexpected(Colon)
+			if c.recover(RBrace) {
+				return
+			}
+			continue
+		}
+		c.advance()
+		c.element()
+
+		// Separator
+		if c.separator(RBrace) {
+			return
+		}
+	}
+}
+
+// elements checks the elements of an array, following its open bracket.
+func (c *checker) elements() {
+	if c.tok == RSquare {
+		c.advance()
+		return
+	}
+	for {
+		c.element()
+		if c.separator(RSquare) {
+			return
+		}
+	}
+}
+
+// separator checks for a comma or the closing token of a container. It
+// reports true if the container is finished.
+func (c *checker) separator(end Token) bool {
+	switch c.tok {
+	case end:
+		c.advance()
+		return true
+	case Comma:
+		c.advance()
+		if c.tok == end {
+			if !c.tcomma {
+				c.fail(nil, "unexpected %v after %v", end, Comma)
+			}
+			c.advance()
+			return true
+		}
+		return false
+	default:
+		c.unexpected(end, Comma)
+		return c.recover(end)
+	}
+}
+
+// recover skips to the end of the current member or element of a container
+// whose closing token is end. It reports true if the container is finished.
+func (c *checker) recover(end Token) bool {
+	c.skip()
+	switch c.tok {
+	case Comma:
+		c.advance()
+		return c.eof
+	case RBrace, RSquare:
+		if c.tok != end {
+			c.unexpected(end)
+		}
+		c.advance()
+		return true
+	}
+	return true // end of input
+}
+
+// skip discards tokens until the end of input, or until a comma or closing
+// bracket is found outside any nested object or array.
+func (c *checker) skip() {
+	var depth int
+	for ; !c.eof; c.advance() {
+		switch c.tok {
+		case LBrace, LSquare:
+			depth++
+		case RBrace, RSquare:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case Comma:
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
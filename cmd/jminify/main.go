@@ -0,0 +1,66 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Program jminify removes insignificant whitespace from JSON and JWCC input.
+//
+// Usage:
+//
+//	jminify [-comments] [-commas] [file ...]
+//
+// The input is read from the named files, or from stdin, and the result is
+// written to stdout. By default, comments and trailing commas are removed so
+// that the output of valid JWCC input is strict JSON. See jtree.Minify.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/creachadair/jtree"
+)
+
+var (
+	keepComments = flag.Bool("comments", false, "Keep comments")
+	keepCommas   = flag.Bool("commas", false, "Keep trailing commas")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [file ...]\n\nOptions:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if err := minify(os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "jminify: <stdin>: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	failed := false
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err == nil {
+			err = minify(f)
+			f.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "jminify: %s: %v\n", path, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func minify(r io.Reader) error {
+	opts := &jtree.MinifyOptions{KeepComments: *keepComments, KeepTrailingCommas: *keepCommas}
+	if err := jtree.MinifyWith(os.Stdout, r, opts); err != nil {
+		return err
+	}
+	_, err := fmt.Println()
+	return err
+}
@@ -0,0 +1,163 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Program jtq evaluates a query against JSON or JWCC input.
+//
+// Usage:
+//
+//	jtq [-jwcc] [-c] [-r] query [file ...]
+//
+// The query is a path expression in the subset of JSONPath syntax accepted
+// by repl.ParseQuery, for example:
+//
+//	jtq '.store.book[*].author' input.json
+//
+// The query is applied to each value in the input, which is read from the
+// named files or from stdin, and each result is written to stdout. By default
+// results are pretty-printed; use -c for compact output, and -r to write a
+// string result as plain text rather than as a quoted JSON string.
+//
+// With -jwcc, each input must be a single JWCC document, and the comments of
+// the input are retained in the output.
+//
+// Use -define to give a name to a query, which other queries can then call
+// as a step, for example:
+//
+//	jtq -define 'authors=.book[*].author' '.store.authors()' input.json
+//
+// The flag may be repeated, and a definition may call names that are defined
+// by later flags.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/repl"
+	"github.com/creachadair/jtree/tq"
+)
+
+var (
+	doJWCC    = flag.Bool("jwcc", false, "Parse input as JWCC and retain comments")
+	doCompact = flag.Bool("c", false, "Write compact output, one result per line")
+	doRaw     = flag.Bool("r", false, "Write string results as plain text")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] query [file ...]\n\nOptions:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Func("define", "Define a named query (`name=query`), which may be repeated", defineQuery)
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	q, err := repl.ParseQuery(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jtq: %v\n", err)
+		os.Exit(2)
+	}
+
+	files := flag.Args()[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	failed := false
+	for _, path := range files {
+		if err := run(os.Stdout, q, path); err != nil {
+			fmt.Fprintf(os.Stderr, "jtq: %v\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// defineQuery defines a named query in tq.DefaultRegistry from a flag value of
+// the form name=query.
+func defineQuery(spec string) error {
+	name, expr, ok := strings.Cut(spec, "=")
+	if name = strings.TrimSpace(name); !ok || name == "" {
+		return fmt.Errorf("invalid definition %q, want name=query", spec)
+	}
+	q, err := repl.ParseQuery(expr)
+	if err != nil {
+		return err
+	}
+	tq.Define(name, q)
+	return nil
+}
+
+// run evaluates q against each value in the input file at path, or stdin if
+// path is "-", and writes the results to w.
+func run(w io.Writer, q tq.Query, path string) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+		path = "stdin"
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	var inputs []ast.Value
+	if *doJWCC {
+		doc, err := jwcc.Parse(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, jwcc.FormatError(err, data))
+		}
+		inputs = []ast.Value{doc}
+	} else if inputs, err = ast.ParseBytes(data); err != nil {
+		return fmt.Errorf("%s: %s", path, ast.FormatError(err, data))
+	}
+
+	for _, in := range inputs {
+		out, err := tq.Eval[ast.Value](in, q)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		} else if out == nil {
+			return fmt.Errorf("%s: query produced no value", path)
+		}
+		if err := writeValue(w, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeValue writes v to w in the format selected by the flags.
+func writeValue(w io.Writer, v ast.Value) error {
+	plain := v
+	if jv, ok := v.(jwcc.Value); ok {
+		plain = jv.Undecorate()
+	}
+	if s, ok := plain.(ast.Text); ok && *doRaw && plain != ast.Null {
+		_, err := fmt.Fprintln(w, s.String())
+		return err
+	}
+	if *doCompact {
+		_, err := fmt.Fprintln(w, v.JSON())
+		return err
+	}
+	jv, ok := v.(jwcc.Value)
+	if !ok {
+		jv = jwcc.Decorate(v)
+	}
+	if err := jwcc.Format(w, jv); err != nil {
+		return errors.Join(errors.New("formatting output"), err)
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
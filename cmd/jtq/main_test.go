@@ -0,0 +1,42 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/repl"
+)
+
+func TestDefine(t *testing.T) {
+	for _, spec := range []string{
+		"books=.store.book",
+		"authors=books()[*].author",
+	} {
+		if err := defineQuery(spec); err != nil {
+			t.Fatalf("Define %q: unexpected error: %v", spec, err)
+		}
+	}
+	for _, bad := range []string{"", "books", "=.store", "x=$.["} {
+		if err := defineQuery(bad); err == nil {
+			t.Errorf("Define %q: got nil, want error", bad)
+		}
+	}
+
+	q, err := repl.ParseQuery("authors()")
+	if err != nil {
+		t.Fatalf("ParseQuery: unexpected error: %v", err)
+	}
+	*doCompact = true
+	defer func() { *doCompact = false }()
+
+	var buf strings.Builder
+	if err := run(&buf, q, "../../testdata/jsonpath.json"); err != nil {
+		t.Fatalf("Run: unexpected error: %v", err)
+	}
+	const want = `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Run: got %q, want %q", got, want)
+	}
+}
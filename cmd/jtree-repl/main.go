@@ -0,0 +1,46 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Program jtree-repl is an interactive explorer for JSON and JWCC documents.
+//
+// Usage:
+//
+//	jtree-repl [-page n] [file]
+//
+// If a file is named, it is loaded at startup. Type "help" at the prompt for
+// a list of commands. See package github.com/creachadair/jtree/repl for
+// details.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/creachadair/jtree/repl"
+)
+
+var pageSize = flag.Int("page", 40, "Paginate output in pages of this many lines (0 to disable)")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [file]\n\nOptions:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	s := repl.NewSession(os.Stdout)
+	s.PageSize = *pageSize
+	if flag.NArg() == 1 {
+		if err := s.Exec("load " + flag.Arg(0)); err != nil {
+			log.Fatalf("Loading %q: %v", flag.Arg(0), err)
+		}
+	}
+	if err := s.Run(os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
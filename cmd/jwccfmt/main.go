@@ -0,0 +1,179 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Program jwccfmt formats JWCC and JSON documents.
+//
+// Usage:
+//
+//	jwccfmt [flags] [file ...]
+//
+// With no files, jwccfmt formats stdin to stdout. Otherwise, by default, it
+// writes the formatted text of each file to stdout. Like gofmt, it can
+// instead rewrite each file in place (-w), list the files whose formatting
+// differs (-l), or show the changes as a diff (-d).
+//
+// The layout is controlled by the settings of a jwcc.Formatter. The -preset
+// flag selects a named set of settings (see jwcc.Preset), which the other
+// layout flags override. With -strict, comments are discarded and the output
+// is plain JSON.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/creachadair/jtree/jwcc"
+)
+
+var (
+	doWrite  = flag.Bool("w", false, "Write the result to each file instead of stdout")
+	doList   = flag.Bool("l", false, "List files whose formatting differs")
+	doDiff   = flag.Bool("d", false, "Write diffs instead of formatted text")
+	doStrict = flag.Bool("strict", false, "Discard comments and write plain JSON")
+
+	preset   = flag.String("preset", "default", "Formatting preset (default, hujson, prettier, vscode)")
+	indent   = flag.String("indent", "", "Indentation text (overrides preset)")
+	useTabs  = flag.Bool("tabs", false, "Pad aligned values with tabs")
+	maxItems = flag.Int("max-items", 0, "Maximum items in a single-line array or object (overrides preset)")
+	maxWidth = flag.Int("width", 0, "Maximum width of a single-line array or object (0 = unlimited)")
+	noAlign  = flag.Bool("no-align", false, "Do not align the values of object members")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [file ...]\n\nOptions:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	f, ok := jwcc.Preset(*preset)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "jwccfmt: unknown preset %q\n", *preset)
+		os.Exit(2)
+	}
+	flag.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "indent":
+			f.Indent = *indent
+		case "tabs":
+			f.UseTabs = *useTabs
+		case "max-items":
+			f.MaxInlineItems = *maxItems
+		case "width":
+			f.MaxLineWidth = *maxWidth
+		case "no-align":
+			f.NoAlign = *noAlign
+		}
+	})
+	if *doStrict {
+		f.NoTrailingCommas = true
+	}
+
+	if flag.NArg() == 0 {
+		if *doWrite {
+			fmt.Fprintln(os.Stderr, "jwccfmt: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if err := process(f, "<stdin>", os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "jwccfmt: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	failed := false
+	for _, path := range flag.Args() {
+		if err := processFile(f, path); err != nil {
+			fmt.Fprintf(os.Stderr, "jwccfmt: %v\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func processFile(f jwcc.Formatter, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return process(f, path, in, os.Stdout)
+}
+
+// process formats the document read from r, whose name is path, and writes
+// the output selected by the flags to w.
+func process(f jwcc.Formatter, path string, r io.Reader, w io.Writer) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	doc, err := jwcc.Parse(bytes.NewReader(src))
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, jwcc.FormatError(err, src))
+	}
+	var v jwcc.Value = doc
+	if *doStrict {
+		v = jwcc.Decorate(doc.Undecorate())
+	}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	buf.WriteByte('\n')
+	out := buf.Bytes()
+
+	changed := !bytes.Equal(src, out)
+	if *doList && changed {
+		fmt.Fprintln(w, path)
+	}
+	if *doWrite && changed {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, out, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if *doDiff && changed {
+		d, err := diff(path, src, out)
+		if err != nil {
+			return fmt.Errorf("computing diff: %w", err)
+		}
+		w.Write(d)
+	}
+	if !*doList && !*doWrite && !*doDiff {
+		_, err := w.Write(out)
+		return err
+	}
+	return nil
+}
+
+// diff returns a unified diff of a and b, labelled with path, computed by the
+// system diff program.
+func diff(path string, a, b []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "jwccfmt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	fa, fb := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := os.WriteFile(fa, a, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(fb, b, 0600); err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("diff", "-u", "--label", path+".orig", "--label", path, fa, fb).Output()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() == 1 {
+		err = nil // diff reports 1 when the inputs differ
+	}
+	return out, err
+}
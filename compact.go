@@ -0,0 +1,149 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import (
+	"errors"
+	"io"
+)
+
+// TruncationMarker is the text Truncate adds to the innermost object or array
+// that was open when the size limit was reached. In an object it is the key of
+// a member whose value is true; in an array it is a string element.
+const TruncationMarker = "__truncated__"
+
+// Truncate copies the JSON values from r to w in compact form until the
+// output would exceed n bytes, then closes any objects and arrays that are
+// still open so that the output is syntactically valid. The innermost open
+// object or array is marked with TruncationMarker. Multiple values in the
+// input are separated by newlines in the output. If the limit is reached
+// between two top-level values, the marker is added as a separate value.
+// Truncate reports whether the output was truncated.
+//
+// The size limit applies to the data copied from the input: The closing
+// brackets and the marker added after truncation may cause the total output to
+// exceed n bytes. Truncate does not split tokens, so a string or number that
+// does not fit in the remaining space is omitted entirely. If no part of the
+// input fits, the output is the marker as a JSON string.
+func Truncate(w io.Writer, r io.Reader, n int) (bool, error) {
+	cw := &compactWriter{w: w, limit: max(n, 0)}
+	err := NewStream(r).Parse(cw)
+	if errors.Is(err, errTruncated) {
+		return true, cw.finish()
+	}
+	return false, err
+}
+
+// errTruncated is a sentinel error reported by a compactWriter handler when
+// its output has reached its size limit.
+var errTruncated = errors.New("output truncated")
+
+// A compactWriter is a Handler that writes its input to w as compact JSON,
+// without comments or insignificant whitespace. Top-level values are
+// separated by newlines.
+type compactWriter struct {
+	w     io.Writer
+	limit int // if non-negative, the maximum number of bytes to copy
+
+	stk    []cwFrame // open objects and arrays
+	key    []byte    // pending object key, including the colon
+	nw     int       // number of bytes written
+	values int       // number of top-level values started
+	err    error     // error from writing
+}
+
+type cwFrame struct {
+	close byte // the closing bracket
+	n     int  // the number of members or elements written
+}
+
+func (c *compactWriter) write(data ...[]byte) error {
+	for _, b := range data {
+		if c.err != nil {
+			break
+		}
+		nw, err := c.w.Write(b)
+		c.nw += nw
+		c.err = err
+	}
+	return c.err
+}
+
+// start writes the first token of a value, along with any separator and
+// pending object key that precede it. It reports errTruncated without writing
+// anything if the result would exceed the size limit.
+func (c *compactWriter) start(text []byte) error {
+	var sep []byte
+	if i := len(c.stk) - 1; i >= 0 {
+		if c.stk[i].n > 0 {
+			sep = []byte(",")
+		}
+	} else if c.values > 0 {
+		sep = []byte("\n")
+	}
+	if c.limit >= 0 && c.nw+len(sep)+len(c.key)+len(text) > c.limit {
+		return errTruncated
+	}
+	if err := c.write(sep, c.key, text); err != nil {
+		return err
+	}
+	if i := len(c.stk) - 1; i >= 0 {
+		c.stk[i].n++
+	} else {
+		c.values++
+	}
+	c.key = c.key[:0]
+	return nil
+}
+
+func (c *compactWriter) open(text string, close byte) error {
+	if err := c.start([]byte(text)); err != nil {
+		return err
+	}
+	c.stk = append(c.stk, cwFrame{close: close})
+	return nil
+}
+
+func (c *compactWriter) end() error {
+	n := len(c.stk) - 1
+	close := c.stk[n].close
+	c.stk = c.stk[:n]
+	return c.write([]byte{close})
+}
+
+// finish closes all open objects and arrays after truncation, marking the
+// innermost one. If none are open, it adds the marker as a top-level value.
+func (c *compactWriter) finish() error {
+	if len(c.stk) == 0 {
+		if c.values > 0 {
+			c.write([]byte("\n"))
+		}
+		return c.write([]byte(`"` + TruncationMarker + `"`))
+	}
+	top := c.stk[len(c.stk)-1]
+	if top.n > 0 {
+		c.write([]byte(","))
+	}
+	if top.close == '}' {
+		c.write([]byte(`"` + TruncationMarker + `":true`))
+	} else {
+		c.write([]byte(`"` + TruncationMarker + `"`))
+	}
+	for len(c.stk) != 0 {
+		c.end()
+	}
+	return c.err
+}
+
+func (c *compactWriter) BeginObject(Anchor) error { return c.open("{", '}') }
+func (c *compactWriter) EndObject(Anchor) error   { return c.end() }
+func (c *compactWriter) BeginArray(Anchor) error  { return c.open("[", ']') }
+func (c *compactWriter) EndArray(Anchor) error    { return c.end() }
+func (c *compactWriter) EndMember(Anchor) error   { return nil }
+func (c *compactWriter) Value(loc Anchor) error   { return c.start(loc.Text()) }
+func (c *compactWriter) EndOfInput(Anchor)        {}
+
+func (c *compactWriter) BeginMember(loc Anchor) error {
+	c.key = append(append(c.key[:0], loc.Text()...), ':')
+	return nil
+}
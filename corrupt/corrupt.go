@@ -0,0 +1,150 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package corrupt generates systematically corrupted variants of valid JSON
+// documents, for use in negative tests of code that consumes JSON.
+//
+// Each variant applies a single small corruption to the original input, such
+// as removing a comma or swapping a bracket, and records the category of
+// error a conforming parser is expected to report for it:
+//
+//	vs, err := corrupt.Variants(input)
+//	if err != nil {
+//	   log.Fatalf("Invalid input: %v", err)
+//	}
+//	for _, v := range vs {
+//	   if err := service.Load(v.Input); err == nil {
+//	      log.Printf("Variant %v: unexpectedly succeeded", v)
+//	   }
+//	}
+package corrupt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/creachadair/jtree"
+)
+
+// A Kind identifies the type of corruption applied to a Variant.
+type Kind int
+
+// Constants defining the valid Kind values.
+const (
+	DropComma      Kind = iota + 1 // a comma separating two elements is removed
+	DropColon                      // the colon following an object key is removed
+	TruncateString                 // the input is cut off in the middle of a string
+	SwapBracket                    // a closing bracket is replaced by the other kind
+)
+
+var kindStr = [...]string{
+	DropComma:      "drop-comma",
+	DropColon:      "drop-colon",
+	TruncateString: "truncate-string",
+	SwapBracket:    "swap-bracket",
+}
+
+func (k Kind) String() string {
+	if k <= 0 || int(k) >= len(kindStr) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindStr[k]
+}
+
+// A Category classifies the error a parser is expected to report when given
+// the input of a Variant.
+type Category int
+
+// Constants defining the valid Category values.
+const (
+	// Unexpected means the parser should report a token that is not valid at
+	// the location where it occurs.
+	Unexpected Category = iota + 1
+
+	// Incomplete means the parser should report that the input ended before
+	// the value was complete. Errors in this category from the jtree packages
+	// wrap io.EOF.
+	Incomplete
+)
+
+func (c Category) String() string {
+	switch c {
+	case Unexpected:
+		return "unexpected"
+	case Incomplete:
+		return "incomplete"
+	default:
+		return fmt.Sprintf("Category(%d)", int(c))
+	}
+}
+
+// A Variant is a corrupted copy of an input document.
+type Variant struct {
+	Kind     Kind     // the type of corruption applied
+	Category Category // the type of error expected for Input
+	Offset   int      // the byte offset of the corruption in the original
+	Input    []byte   // the corrupted input
+}
+
+func (v Variant) String() string { return fmt.Sprintf("%v@%d", v.Kind, v.Offset) }
+
+// Variants returns all the single-corruption variants of src, which must
+// contain valid JSON text without comments. The variants are ordered by the
+// position of the token they modify. Variants reports an error if src cannot
+// be scanned.
+func Variants(src []byte) ([]Variant, error) {
+	var out []Variant
+	s := jtree.NewScanner(bytes.NewReader(src))
+	for s.Next() == nil {
+		span := s.Span()
+		switch s.Token() {
+		case jtree.Comma:
+			out = append(out, Variant{
+				Kind:     DropComma,
+				Category: Unexpected,
+				Offset:   span.Pos,
+				Input:    splice(src, span.Pos, span.End, ""),
+			})
+		case jtree.Colon:
+			out = append(out, Variant{
+				Kind:     DropColon,
+				Category: Unexpected,
+				Offset:   span.Pos,
+				Input:    splice(src, span.Pos, span.End, ""),
+			})
+		case jtree.String:
+			// Cut the input in the middle of the string contents, after the
+			// opening quotation mark.
+			cut := span.Pos + 1 + (span.End-span.Pos-2)/2
+			out = append(out, Variant{
+				Kind:     TruncateString,
+				Category: Incomplete,
+				Offset:   cut,
+				Input:    append([]byte(nil), src[:cut]...),
+			})
+		case jtree.RBrace, jtree.RSquare:
+			swap := "]"
+			if s.Token() == jtree.RSquare {
+				swap = "}"
+			}
+			out = append(out, Variant{
+				Kind:     SwapBracket,
+				Category: Unexpected,
+				Offset:   span.Pos,
+				Input:    splice(src, span.Pos, span.End, swap),
+			})
+		}
+	}
+	if err := s.Err(); err != io.EOF {
+		return nil, err
+	}
+	return out, nil
+}
+
+// splice returns a copy of src with the range from pos to end replaced by s.
+func splice(src []byte, pos, end int, s string) []byte {
+	out := make([]byte, 0, len(src)-(end-pos)+len(s))
+	out = append(out, src[:pos]...)
+	out = append(out, s...)
+	return append(out, src[end:]...)
+}
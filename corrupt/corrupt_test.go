@@ -0,0 +1,52 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package corrupt_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/corrupt"
+)
+
+func TestVariants(t *testing.T) {
+	const input = `{"a": [1, "two", {"c": null}], "b": "", "d": {}}`
+
+	vs, err := corrupt.Variants([]byte(input))
+	if err != nil {
+		t.Fatalf("Variants: unexpected error: %v", err)
+	}
+
+	count := make(map[corrupt.Kind]int)
+	for _, v := range vs {
+		count[v.Kind]++
+		t.Run(v.String(), func(t *testing.T) {
+			_, err := ast.ParseSingle(bytes.NewReader(v.Input))
+			if err == nil {
+				t.Fatalf("Parse %#q: got nil, want error", v.Input)
+			}
+			if got := errors.Is(err, io.EOF); got != (v.Category == corrupt.Incomplete) {
+				t.Errorf("Parse %#q: got error %v, want category %v", v.Input, err, v.Category)
+			}
+		})
+	}
+
+	want := map[corrupt.Kind]int{
+		corrupt.DropComma:      4,
+		corrupt.DropColon:      4,
+		corrupt.TruncateString: 6,
+		corrupt.SwapBracket:    4,
+	}
+	for k, n := range want {
+		if count[k] != n {
+			t.Errorf("Kind %v: got %d variants, want %d", k, count[k], n)
+		}
+	}
+
+	if _, err := corrupt.Variants([]byte(`[1, 2 // nope`)); err == nil {
+		t.Error("Variants: got nil, want error for invalid input")
+	}
+}
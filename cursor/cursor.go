@@ -1,15 +1,24 @@
 // Copyright (C) 2023 Michael J. Fromberger. All Rights Reserved.
 
 // Package cursor implements traversal over the AST of a JSON value.
+//
+// A Cursor handles values from the ast and jwcc packages uniformly: All the
+// navigation, search, and editing methods accept either kind of value, and
+// preserve the comments of a JWCC value.
 package cursor
 
 import (
 	"errors"
 	"fmt"
+	"iter"
+	"strconv"
 	"strings"
 
 	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/escape"
 	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/tq"
+	"golang.org/x/exp/slices"
 )
 
 // ErrKeyNotFound is a sentinel error reported when a name or array index
@@ -36,6 +45,7 @@ func Path[T ast.Value](v ast.Value, path ...any) (T, error) {
 type Cursor struct {
 	org ast.Value
 	stk []ast.Value
+	pos []int // pos[i] is the offset of stk[i] in its parent, or -1
 	err error
 }
 
@@ -65,6 +75,62 @@ func (c *Cursor) Path() []ast.Value {
 	return append([]ast.Value{c.org}, c.stk...)
 }
 
+// PathKeys reports the sequence of object keys and array indices that lead
+// from the origin to the current value, in a form that can be passed to Down
+// to reach the same location. Object members are reported by key, with a
+// leading "%" doubled as described for Down, and a trailing nil indicates the
+// value of an object member.
+//
+// If the path includes a value computed by a function or query, the result
+// is relative to the last such value.
+func (c *Cursor) PathKeys() []any {
+	var out []any
+	for i, v := range c.stk {
+		switch {
+		case c.pos[i] < 0:
+			out = out[:0] // restart from the computed value
+		case isMember(v):
+			key := memberKey(v)
+			if strings.HasPrefix(key, "%") {
+				key = "%" + key
+			}
+			out = append(out, key)
+		case isMember(c.parent(i)):
+			// The value of a member; see below.
+		default:
+			out = append(out, c.pos[i])
+		}
+	}
+	if n := len(c.stk); n > 0 && c.pos[n-1] >= 0 && isMember(c.parent(n-1)) {
+		out = append(out, nil)
+	}
+	return out
+}
+
+// Pointer reports a JSON Pointer (RFC 6901) to the current value, relative to
+// the origin. An object member and its value have the same pointer.
+//
+// If the path includes a value computed by a function or query, the pointer
+// is relative to the last such value.
+func (c *Cursor) Pointer() string {
+	var sb strings.Builder
+	for i, v := range c.stk {
+		switch {
+		case c.pos[i] < 0:
+			sb.Reset() // restart from the computed value
+		case isMember(v):
+			sb.WriteByte('/')
+			sb.WriteString(escape.EscapeToken(memberKey(v)))
+		case isMember(c.parent(i)):
+			// The value of a member has the same pointer as the member.
+		default:
+			sb.WriteByte('/')
+			sb.WriteString(strconv.Itoa(c.pos[i]))
+		}
+	}
+	return sb.String()
+}
+
 // Err reports the error from the most recent traversal operation, if any.
 func (c *Cursor) Err() error { return c.err }
 
@@ -73,12 +139,178 @@ func (c *Cursor) Err() error { return c.err }
 func (c *Cursor) Up() *Cursor {
 	if n := len(c.stk); n > 0 {
 		c.stk = c.stk[:n-1]
+		c.pos = c.pos[:n-1]
+	}
+	return c
+}
+
+// NextSibling moves the cursor to the array element or object member that
+// follows the current value in its parent. If the current value is the value
+// of an object member, the cursor moves to the value of the following member.
+// If there is no such value, an error is recorded and the cursor remains in
+// place. Use Err to recover the error. It returns c to permit chaining.
+func (c *Cursor) NextSibling() *Cursor { return c.sibling(1) }
+
+// PrevSibling moves the cursor to the array element or object member that
+// precedes the current value in its parent, as described for NextSibling.
+// It returns c to permit chaining.
+func (c *Cursor) PrevSibling() *Cursor { return c.sibling(-1) }
+
+func (c *Cursor) sibling(delta int) *Cursor {
+	c.err = nil // reset error
+	i := len(c.stk) - 1
+	onValue := i >= 0 && isMember(c.parent(i))
+	if onValue {
+		i--
+	}
+	if i < 0 {
+		return c.setErrorf("the origin has no siblings")
+	} else if c.pos[i] < 0 {
+		return c.setErrorf("a computed value has no siblings")
+	}
+	j := c.pos[i] + delta
+	next, ok := childAt(c.parent(i), j)
+	if !ok {
+		return c.setErrorf("%w: no sibling at offset %d", ErrKeyNotFound, j)
+	}
+	c.stk, c.pos = c.stk[:i], c.pos[:i]
+	c.push(next, j)
+	if onValue {
+		c.push(memberValue(next), 0)
+	}
+	return c
+}
+
+// FirstChild moves the cursor to the first element of the current value,
+// which must be a non-empty array or object. If the current value is an
+// object member, the cursor moves to the first element of its value.
+// Otherwise, an error is recorded and the cursor remains in place. Use Err to
+// recover the error. It returns c to permit chaining.
+func (c *Cursor) FirstChild() *Cursor {
+	c.err = nil // reset error
+	cur := c.Value()
+	mv := memberValue(cur)
+	next, ok := childAt(mv, 0)
+	if !ok {
+		return c.setErrorf("%w: %T has no children", ErrKeyNotFound, mv)
+	}
+	if isMember(cur) {
+		c.push(mv, 0)
+	}
+	c.push(next, 0)
+	return c
+}
+
+// Children returns an iterator over cursors positioned at each element of the
+// current value, in order, as if by FirstChild and NextSibling. Each cursor
+// is a separate copy, and c itself does not move. If the current value is not
+// an array or object, the sequence is empty.
+func (c *Cursor) Children() iter.Seq[*Cursor] {
+	return func(yield func(*Cursor) bool) {
+		base := c.clone()
+		if cur := base.Value(); isMember(cur) {
+			base.push(memberValue(cur), 0)
+		}
+		for i := 0; ; i++ {
+			next, ok := childAt(base.Value(), i)
+			if !ok {
+				return
+			}
+			cc := base.clone()
+			cc.push(next, i)
+			if !yield(cc) {
+				return
+			}
+		}
+	}
+}
+
+// Find moves the cursor to the first descendant of the current value, in
+// preorder, for which match reports true. The descendants of an object
+// include both its members and their values. If no descendant matches, an
+// error is recorded and the cursor remains in place. Use Err to recover the
+// error. It returns c to permit chaining.
+func (c *Cursor) Find(match func(ast.Value) bool) *Cursor {
+	c.err = nil // reset error
+	if c.search(match, func() bool { return false }) {
+		return c.setErrorf("%w: no matching value", ErrKeyNotFound)
+	}
+	return c
+}
+
+// FindAll returns cursors positioned at each descendant of the current value
+// for which match reports true, in the order visited by Find. Each cursor is a
+// separate copy, and c itself does not move.
+func (c *Cursor) FindAll(match func(ast.Value) bool) []*Cursor {
+	var out []*Cursor
+	d := c.clone()
+	d.search(match, func() bool { out = append(out, d.clone()); return true })
+	return out
+}
+
+// search visits the descendants of the current value in preorder, and calls
+// found with the cursor positioned at each one that satisfies match. If found
+// returns false, search stops and returns false, leaving the cursor in place.
+// Otherwise search returns true with the cursor where it began.
+func (c *Cursor) search(match func(ast.Value) bool, found func() bool) bool {
+	cur := c.Value()
+	if isMember(cur) {
+		v := memberValue(cur)
+		c.push(v, 0)
+		if (match(v) && !found()) || !c.search(match, found) {
+			return false
+		}
+		c.Up()
+		return true
+	}
+	for i := 0; ; i++ {
+		next, ok := childAt(cur, i)
+		if !ok {
+			return true
+		}
+		c.push(next, i)
+		if (match(next) && !found()) || !c.search(match, found) {
+			return false
+		}
+		c.Up()
 	}
+}
+
+// Clone returns a new cursor with the same origin, position, and error as c.
+// Moving either cursor does not affect the other, but they share the
+// underlying values, so edits made through one are visible to the other.
+func (c *Cursor) Clone() *Cursor {
+	d := c.clone()
+	d.err = c.err
+	return d
+}
+
+// A Bookmark records a position of a cursor. The zero Bookmark denotes the
+// origin.
+type Bookmark struct {
+	stk []ast.Value
+	pos []int
+}
+
+// Bookmark returns a bookmark for the current position of c. Use Return to
+// restore c to this position.
+func (c *Cursor) Bookmark() Bookmark {
+	return Bookmark{stk: slices.Clone(c.stk), pos: slices.Clone(c.pos)}
+}
+
+// Return moves c to the position recorded by b, and clears its error.
+// The bookmark must have been created by c, or a clone of c. If the structure
+// of the value was edited after b was created, the resulting position is
+// unspecified. It returns c to permit chaining.
+func (c *Cursor) Return(b Bookmark) *Cursor {
+	c.stk = append(c.stk[:0], b.stk...)
+	c.pos = append(c.pos[:0], b.pos...)
+	c.err = nil
 	return c
 }
 
 // Reset resets the cursor to its origin and clears its error.
-func (c *Cursor) Reset() { c.stk = c.stk[:0]; c.err = nil }
+func (c *Cursor) Reset() { c.stk = c.stk[:0]; c.pos = c.pos[:0]; c.err = nil }
 
 // Down traverses a sequential path into the structure of c starting from the
 // current value, where path elements are either strings (denoting object
@@ -124,26 +356,26 @@ func (c *Cursor) Down(path ...any) *Cursor {
 		// path element relative to the value of that member.
 		switch m := cur.(type) {
 		case *ast.Member:
-			cur = c.push(m.Value)
+			cur = c.push(m.Value, 0)
 		case *jwcc.Member:
-			cur = c.push(m.Value)
+			cur = c.push(m.Value, 0)
 		}
 
 		switch t := elt.(type) {
 		case string:
 			switch e := cur.(type) {
 			case ast.Object:
-				m := e.FindKey(keyMatch(t))
-				if m == nil {
+				i := e.IndexKey(keyMatch(t))
+				if i < 0 {
 					return c.setErrorf("%w: %q", ErrKeyNotFound, t)
 				}
-				cur = c.push(m)
+				cur = c.push(e[i], i)
 			case *jwcc.Object:
-				m := e.FindKey(keyMatch(t))
-				if m == nil {
+				i := e.IndexKey(keyMatch(t))
+				if i < 0 {
 					return c.setErrorf("%w: %q", ErrKeyNotFound, t)
 				}
-				cur = c.push(m)
+				cur = c.push(e.Members[i], i)
 			default:
 				return c.setErrorf("cannot traverse %T with %q", cur, elt)
 			}
@@ -155,25 +387,25 @@ func (c *Cursor) Down(path ...any) *Cursor {
 				if !ok {
 					return c.setErrorf("%w: array index %d out of bounds (n=%d)", ErrKeyNotFound, i, len(e))
 				}
-				cur = c.push(e[i])
+				cur = c.push(e[i], i)
 			case *jwcc.Array:
 				i, ok := fixArrayBound(len(e.Values), t)
 				if !ok {
 					return c.setErrorf("%w: array index %d out of bounds (n=%d)", ErrKeyNotFound, i, len(e.Values))
 				}
-				cur = c.push(e.Values[i])
+				cur = c.push(e.Values[i], i)
 			case ast.Object:
 				i, ok := fixArrayBound(len(e), t)
 				if !ok {
 					return c.setErrorf("%w: object index %d out of bounds (n=%d)", ErrKeyNotFound, i, len(e))
 				}
-				cur = c.push(e[i])
+				cur = c.push(e[i], i)
 			case *jwcc.Object:
 				i, ok := fixArrayBound(len(e.Members), t)
 				if !ok {
 					return c.setErrorf("%w: object index %d out of bounds (n=%d)", ErrKeyNotFound, i, len(e.Members))
 				}
-				cur = c.push(e.Members[i])
+				cur = c.push(e.Members[i], i)
 			default:
 				return c.setErrorf("cannot traverse %T with %v", cur, elt)
 			}
@@ -181,17 +413,17 @@ func (c *Cursor) Down(path ...any) *Cursor {
 		case func(ast.Text) bool:
 			switch e := cur.(type) {
 			case ast.Object:
-				m := e.FindKey(t)
-				if m == nil {
+				i := e.IndexKey(t)
+				if i < 0 {
 					return c.setErrorf("%w: no matching member", ErrKeyNotFound)
 				}
-				cur = c.push(m)
+				cur = c.push(e[i], i)
 			case *jwcc.Object:
-				m := e.FindKey(t)
-				if m == nil {
+				i := e.IndexKey(t)
+				if i < 0 {
 					return c.setErrorf("%w: no matching member", ErrKeyNotFound)
 				}
-				cur = c.push(m)
+				cur = c.push(e.Members[i], i)
 			default:
 				return c.setErrorf("cannot traverse %T with %T", cur, elt)
 			}
@@ -202,7 +434,7 @@ func (c *Cursor) Down(path ...any) *Cursor {
 				c.err = err
 				return c
 			}
-			cur = c.push(next)
+			cur = c.push(next, -1)
 
 		case nil:
 			// Do nothing. This case supports indirecting through a member at the
@@ -215,7 +447,91 @@ func (c *Cursor) Down(path ...any) *Cursor {
 	return c
 }
 
-func (c *Cursor) push(v ast.Value) ast.Value { c.stk = append(c.stk, v); return v }
+// Apply evaluates q on the current value of c, and if it succeeds, moves the
+// cursor to the result. If the current value is an object member, q is
+// evaluated on the value of the member. If q fails, the error is recorded and
+// the cursor remains in place. Use Err to recover the error.  It returns c to
+// permit chaining.
+//
+// Use tq.FromCursorPath to convert a path for Down into a query.
+func (c *Cursor) Apply(q tq.Query) *Cursor {
+	c.err = nil // reset error
+	cur, isMember := c.Value(), true
+	switch m := cur.(type) {
+	case *ast.Member:
+		cur = m.Value
+	case *jwcc.Member:
+		cur = m.Value
+	default:
+		isMember = false
+	}
+	next, err := tq.Eval[ast.Value](cur, q)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	if isMember {
+		c.push(cur, 0)
+	}
+	c.push(next, -1)
+	return c
+}
+
+func (c *Cursor) push(v ast.Value, pos int) ast.Value {
+	c.stk = append(c.stk, v)
+	c.pos = append(c.pos, pos)
+	return v
+}
+
+func (c *Cursor) clone() *Cursor {
+	return &Cursor{org: c.org, stk: slices.Clone(c.stk), pos: slices.Clone(c.pos)}
+}
+
+// childAt returns the element at offset i of v, if v is an array or object and
+// i is in range.
+func childAt(v ast.Value, i int) (ast.Value, bool) {
+	if i < 0 {
+		return nil, false
+	}
+	switch t := v.(type) {
+	case ast.Array:
+		if i < len(t) {
+			return t[i], true
+		}
+	case ast.Object:
+		if i < len(t) {
+			return t[i], true
+		}
+	case *jwcc.Array:
+		if i < len(t.Values) {
+			return t.Values[i], true
+		}
+	case *jwcc.Object:
+		if i < len(t.Members) {
+			return t.Members[i], true
+		}
+	}
+	return nil, false
+}
+
+// memberKey returns the key of v, which must be an object member.
+func memberKey(v ast.Value) string {
+	if m, ok := v.(*ast.Member); ok {
+		return m.Key.String()
+	}
+	return v.(*jwcc.Member).Key.String()
+}
+
+// memberValue returns the value of v if v is an object member, or else v.
+func memberValue(v ast.Value) ast.Value {
+	switch t := v.(type) {
+	case *ast.Member:
+		return t.Value
+	case *jwcc.Member:
+		return t.Value
+	}
+	return v
+}
 
 func (c *Cursor) setErrorf(msg string, args ...any) *Cursor {
 	c.err = fmt.Errorf(msg, args...)
@@ -11,6 +11,7 @@ import (
 	"github.com/creachadair/jtree/cursor"
 	"github.com/creachadair/jtree/internal/testutil"
 	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/tq"
 	"github.com/google/go-cmp/cmp"
 
 	_ "embed"
@@ -135,6 +136,11 @@ func TestCursorJWCC(t *testing.T) {
 			doc.Value.(*jwcc.Object).Find("xyz").Value.(*jwcc.Object).Find("d").Value,
 			false,
 		},
+		{"ObjFold", []any{"%XYZ", "%D", nil},
+			doc.Value.(*jwcc.Object).Find("xyz").Value.(*jwcc.Object).Find("d").Value,
+			false,
+		},
+		{"ObjFoldEscape", []any{"%%xyz"}, doc.Value, true},
 
 		{"FuncArray", []any{"o", testPathFunc}, jwcc.ToValue(2), false},
 		{"FuncObj", []any{"xyz", testPathFunc}, jwcc.ToValue(3), false},
@@ -179,6 +185,59 @@ func TestCursorJWCC(t *testing.T) {
 	}
 }
 
+func TestApply(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(testJSON))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	tests := []struct {
+		path []any
+		want string
+		fail bool
+	}{
+		{[]any{"list", -1, "x"}, `2`, false},
+		{[]any{"%Y", "%%hello"}, ``, true},
+		{[]any{"%Y", "hello"}, `"there"`, false},
+		{[]any{"xyz", ast.TextEqualFold("Q")}, `false`, false},
+		{[]any{"o", testPathFunc, nil}, `2`, false},
+		{[]any{"Y"}, ``, true},
+	}
+	for _, tc := range tests {
+		// The query should agree with the cursor.
+		c := cursor.New(v).Down(tc.path...)
+		if tc.fail != (c.Err() != nil) {
+			t.Errorf("Down %+v: got err=%v, want fail=%v", tc.path, c.Err(), tc.fail)
+		}
+
+		c = cursor.New(v).Apply(tq.FromCursorPath(tc.path...))
+		if err := c.Err(); err != nil {
+			if !tc.fail {
+				t.Errorf("Apply %+v: unexpected error: %v", tc.path, err)
+			}
+			if !c.AtOrigin() {
+				t.Errorf("Apply %+v: cursor moved after error", tc.path)
+			}
+			continue
+		} else if tc.fail {
+			t.Errorf("Apply %+v: got %s, want error", tc.path, c.Value().JSON())
+			continue
+		}
+		if got := c.Value().JSON(); got != tc.want {
+			t.Errorf("Apply %+v: got %#q, want %#q", tc.path, got, tc.want)
+		}
+	}
+
+	// Applying a query to a member applies it to the member's value.
+	c := cursor.New(v).Down("y").Apply(tq.Path("hello"))
+	if err := c.Err(); err != nil {
+		t.Fatalf("Apply: unexpected error: %v", err)
+	}
+	if got, want := len(c.Path()), 4; got != want {
+		t.Errorf("Path: got %d elements, want %d", got, want)
+	}
+}
+
 func testPathFunc(v ast.Value) (ast.Value, error) {
 	switch t := v.(type) {
 	case ast.Array:
@@ -193,3 +252,290 @@ func testPathFunc(v ast.Value) (ast.Value, error) {
 		return nil, errors.New("not a thing with length")
 	}
 }
+
+func TestEdit(t *testing.T) {
+	mustParse := func(t *testing.T, s string) ast.Value {
+		t.Helper()
+		v, err := ast.ParseSingle(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		return v
+	}
+	checkJSON := func(t *testing.T, c *cursor.Cursor, want string) {
+		t.Helper()
+		if err := c.Err(); err != nil {
+			t.Fatalf("Edit: unexpected error: %v", err)
+		}
+		if got := c.Origin().JSON(); got != want {
+			t.Errorf("Origin: got %#q, want %#q", got, want)
+		}
+	}
+	const input = `{"a":[1,2,3],"b":{"c":true}}`
+
+	t.Run("SetValue", func(t *testing.T) {
+		c := cursor.New(mustParse(t, input))
+		checkJSON(t, c.Down("a", 1).SetValue(ast.Int(5)), `{"a":[1,5,3],"b":{"c":true}}`)
+		checkJSON(t, reset(c).Down("b", "c").SetValue(ast.Null), `{"a":[1,5,3],"b":{"c":null}}`)
+		checkJSON(t, reset(c).Down("b", "c", nil).SetValue(ast.String("x")), `{"a":[1,5,3],"b":{"c":"x"}}`)
+		checkJSON(t, reset(c).SetValue(ast.Bool(false)), `false`)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		c := cursor.New(mustParse(t, input)).Down("a", 0).Delete()
+		checkJSON(t, c, `{"a":[2,3],"b":{"c":true}}`)
+		if got := c.Value().JSON(); got != `[2,3]` {
+			t.Errorf("Value after Delete: got %#q, want [2,3]", got)
+		}
+		checkJSON(t, reset(c).Down("b", "c").Delete(), `{"a":[2,3],"b":{}}`)
+		checkJSON(t, reset(c).Down("a", nil).Delete(), `{"b":{}}`)
+		if reset(c).Delete(); c.Err() == nil {
+			t.Error("Delete at origin: got nil, want error")
+		}
+	})
+
+	t.Run("Insert", func(t *testing.T) {
+		c := cursor.New(mustParse(t, input))
+		checkJSON(t, c.Down("a", 1).InsertBefore(ast.Int(9)), `{"a":[1,9,2,3],"b":{"c":true}}`)
+		if got := c.Value().JSON(); got != `2` {
+			t.Errorf("Value after InsertBefore: got %#q, want 2", got)
+		}
+		checkJSON(t, c.InsertAfter(ast.Int(8)), `{"a":[1,9,2,8,3],"b":{"c":true}}`)
+		checkJSON(t, reset(c).Down("b").InsertAfter(ast.Field("d", 1)), `{"a":[1,9,2,8,3],"b":{"c":true},"d":1}`)
+		if reset(c).Down("b").InsertBefore(ast.Int(1)); c.Err() == nil {
+			t.Error("Insert non-member into object: got nil, want error")
+		}
+	})
+
+	t.Run("Computed", func(t *testing.T) {
+		c := cursor.New(mustParse(t, input)).Down("a", testPathFunc)
+		if c.SetValue(ast.Int(0)); c.Err() == nil {
+			t.Error("SetValue on a computed value: got nil, want error")
+		}
+		if c.Delete(); c.Err() == nil {
+			t.Error("Delete on a computed value: got nil, want error")
+		}
+	})
+
+	t.Run("JWCC", func(t *testing.T) {
+		doc, err := jwcc.Parse(strings.NewReader(`{
+  "a": [1, 2], // the list
+  "b": true,
+}`))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		c := cursor.New(doc.Value)
+		checkJSON(t, c.Down("a", 0).InsertAfter(ast.Int(3)), `{"a":[1,3,2],"b":true}`)
+		checkJSON(t, c.Delete(), `{"a":[3,2],"b":true}`)
+		checkJSON(t, reset(c).Down("b").SetValue(ast.String("x")), `{"a":[3,2],"b":"x"}`)
+		checkJSON(t, c.InsertBefore(ast.Field("c", nil)), `{"a":[3,2],"c":null,"b":"x"}`)
+
+		// Edits to a JWCC value are made in place, and comments are preserved.
+		if got, want := doc.JSON(), `{"a":[3,2],"c":null,"b":"x"}`; got != want {
+			t.Errorf("Document: got %#q, want %#q", got, want)
+		}
+		if com := doc.Value.(*jwcc.Object).Find("a").Comments(); com.Line == "" {
+			t.Error("Comment on member was lost")
+		}
+	})
+}
+
+func reset(c *cursor.Cursor) *cursor.Cursor { c.Reset(); return c }
+
+func TestNavigate(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(`{"a": [1, 2, 3], "b": {"c": true, "d": null}, "e": "x"}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	check := func(t *testing.T, c *cursor.Cursor, want string) {
+		t.Helper()
+		if err := c.Err(); err != nil {
+			t.Fatalf("Navigate: unexpected error: %v", err)
+		}
+		if got := c.Value().JSON(); got != want {
+			t.Errorf("Value: got %#q, want %#q", got, want)
+		}
+	}
+
+	c := cursor.New(v)
+	check(t, c.FirstChild(), `"a":[1,2,3]`)
+	check(t, c.FirstChild(), `1`)
+	check(t, c.NextSibling().NextSibling(), `3`)
+	if c.NextSibling(); !errors.Is(c.Err(), cursor.ErrKeyNotFound) {
+		t.Errorf("NextSibling at end: got %v, want %v", c.Err(), cursor.ErrKeyNotFound)
+	}
+	check(t, c.PrevSibling(), `2`)
+	check(t, c.Up().NextSibling(), `{"c":true,"d":null}`)
+	check(t, c.PrevSibling(), `[1,2,3]`)
+	check(t, c.Up().NextSibling(), `"b":{"c":true,"d":null}`)
+
+	// Siblings of a member's value are the values of adjacent members.
+	check(t, reset(c).Down("b", "c", nil), `true`)
+	check(t, c.NextSibling(), `null`)
+	check(t, c.Up(), `"d":null`)
+
+	if reset(c).PrevSibling(); c.Err() == nil {
+		t.Error("PrevSibling at origin: got nil, want error")
+	}
+	if c.Down("e").FirstChild(); c.Err() == nil {
+		t.Error("FirstChild of a string: got nil, want error")
+	}
+
+	var got []string
+	for cc := range reset(c).Down("b").Children() {
+		got = append(got, cc.Value().JSON())
+	}
+	if diff := cmp.Diff(got, []string{`"c":true`, `"d":null`}); diff != "" {
+		t.Errorf("Children (-got, +want):\n%s", diff)
+	}
+	if got := c.Value().JSON(); got != `"b":{"c":true,"d":null}` {
+		t.Errorf("Children moved the cursor to %#q", got)
+	}
+
+	// Children of a JWCC array.
+	doc, err := jwcc.Parse(strings.NewReader(`[1, /* two */ 2]`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got = got[:0]
+	for cc := range cursor.New(doc.Value).Children() {
+		got = append(got, cc.Value().JSON())
+		if !cc.Up().AtOrigin() {
+			t.Error("Up from child did not return to origin")
+		}
+	}
+	if diff := cmp.Diff(got, []string{`1`, `2`}); diff != "" {
+		t.Errorf("Children (-got, +want):\n%s", diff)
+	}
+}
+
+func TestFind(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(`{"a": [1, {"x": 2}], "b": {"x": 3, "y": [4, 5]}}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	isX := func(v ast.Value) bool {
+		m, ok := v.(*ast.Member)
+		return ok && m.Key.String() == "x"
+	}
+	isBig := func(v ast.Value) bool {
+		n, ok := v.(ast.Number)
+		return ok && n.Int() > 3
+	}
+
+	c := cursor.New(v).Find(isX)
+	if err := c.Err(); err != nil {
+		t.Fatalf("Find: unexpected error: %v", err)
+	}
+	if got := c.Value().JSON(); got != `"x":2` {
+		t.Errorf("Find: got %#q, want %#q", got, `"x":2`)
+	}
+	if got, want := len(c.Path()), 5; got != want {
+		t.Errorf("Find: path has %d elements, want %d", got, want)
+	}
+
+	// A failed search leaves the cursor in place.
+	if c.Find(isX); !errors.Is(c.Err(), cursor.ErrKeyNotFound) {
+		t.Errorf("Find: got %v, want %v", c.Err(), cursor.ErrKeyNotFound)
+	}
+	if got := c.Value().JSON(); got != `"x":2` {
+		t.Errorf("Find moved the cursor to %#q", got)
+	}
+
+	var got []string
+	for _, fc := range reset(c).FindAll(isX) {
+		got = append(got, fc.Up().Value().JSON())
+	}
+	if diff := cmp.Diff(got, []string{`{"x":2}`, `{"x":3,"y":[4,5]}`}); diff != "" {
+		t.Errorf("FindAll (-got, +want):\n%s", diff)
+	}
+
+	got = got[:0]
+	for _, fc := range cursor.New(v).Down("b").FindAll(isBig) {
+		got = append(got, fc.Value().JSON())
+	}
+	if diff := cmp.Diff(got, []string{`4`, `5`}); diff != "" {
+		t.Errorf("FindAll (-got, +want):\n%s", diff)
+	}
+}
+
+func TestPointer(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(`{"a/b": [1, {"%x": true, "~": null}], "c": [2]}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tests := []struct {
+		path    []any
+		pointer string
+		keys    []any
+	}{
+		{nil, "", nil},
+		{[]any{"a/b"}, "/a~1b", []any{"a/b"}},
+		{[]any{"a/b", nil}, "/a~1b", []any{"a/b", nil}},
+		{[]any{"a/b", -1}, "/a~1b/1", []any{"a/b", 1}},
+		{[]any{"a/b", 1, "%%x"}, "/a~1b/1/%x", []any{"a/b", 1, "%%x"}},
+		{[]any{"a/b", 1, "~", nil}, "/a~1b/1/~0", []any{"a/b", 1, "~", nil}},
+		{[]any{"a/b", 1, 1}, "/a~1b/1/~0", []any{"a/b", 1, "~"}},
+		{[]any{testPathFunc}, "", nil},
+	}
+	for _, tc := range tests {
+		c := cursor.New(v).Down(tc.path...)
+		if err := c.Err(); err != nil {
+			t.Fatalf("Down %v: unexpected error: %v", tc.path, err)
+		}
+		if got := c.Pointer(); got != tc.pointer {
+			t.Errorf("Down %v: pointer is %q, want %q", tc.path, got, tc.pointer)
+		}
+		keys := c.PathKeys()
+		if diff := cmp.Diff(keys, tc.keys); diff != "" {
+			t.Errorf("Down %v: keys (-got, +want):\n%s", tc.path, diff)
+		}
+
+		// The keys lead back to the same place.
+		if len(keys) != 0 {
+			d := cursor.New(v).Down(keys...)
+			if got, want := d.Value().JSON(), c.Value().JSON(); got != want {
+				t.Errorf("Down %v: got %#q, want %#q", keys, got, want)
+			}
+		}
+	}
+}
+
+func TestCloneBookmark(t *testing.T) {
+	v, err := ast.ParseSingle(strings.NewReader(`{"a": [1, {"b": 2}], "c": 3}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c := cursor.New(v).Down("a", 1, "b")
+	d := c.Clone()
+	d.Up().Up().Down(0)
+	if got := c.Value().JSON(); got != `"b":2` {
+		t.Errorf("Original: got %#q, want %#q", got, `"b":2`)
+	}
+	if got := d.Value().JSON(); got != `1` {
+		t.Errorf("Clone: got %#q, want 1", got)
+	}
+
+	mark := c.Bookmark()
+
+	// A clone carries the error of the original.
+	if c.Down("nonesuch"); c.Clone().Err() == nil {
+		t.Error("Clone: error was not copied")
+	}
+
+	origin := reset(c).Bookmark()
+	c.Down("c")
+	if got := c.Return(mark).Value().JSON(); got != `"b":2` {
+		t.Errorf("Return: got %#q, want %#q", got, `"b":2`)
+	}
+	if err := c.Err(); err != nil {
+		t.Errorf("Return: unexpected error: %v", err)
+	}
+	if !c.Return(origin).AtOrigin() {
+		t.Error("Return to origin: cursor is not at origin")
+	}
+	if c.Down("a").Return(cursor.Bookmark{}); !c.AtOrigin() {
+		t.Error("Return to zero bookmark: cursor is not at origin")
+	}
+}
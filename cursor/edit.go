@@ -0,0 +1,199 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package cursor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/jwcc"
+	"golang.org/x/exp/slices"
+)
+
+// errComputed is reported for an edit to a value that was computed by a
+// function or query rather than reached by traversing the structure.
+var errComputed = errors.New("cannot edit a computed value")
+
+// SetValue replaces the current value of c with v. If the current value is an
+// object member, SetValue replaces the value of the member. If the cursor is
+// at its origin, the origin is replaced. It returns c to permit chaining.
+//
+// Arrays and object members are updated in place. In a JWCC value, if v is
+// not already a jwcc.Value it is decorated with empty comments.
+func (c *Cursor) SetValue(v ast.Value) *Cursor {
+	c.err = nil // reset error
+	switch m := c.Value().(type) {
+	case *ast.Member:
+		m.Value = v
+		return c
+	case *jwcc.Member:
+		m.Value = decorate(v)
+		return c
+	}
+	if err := c.store(len(c.stk)-1, v); err != nil {
+		c.err = err
+	}
+	return c
+}
+
+// Delete removes the current value of c from its parent, and moves the cursor
+// to the parent. The current value must be an array element or an object
+// member. If the current value is the value of an object member, the member
+// is removed. It returns c to permit chaining.
+//
+// JWCC arrays and objects are updated in place. Other arrays and objects are
+// copied, and the copy replaces the original in its own parent.
+func (c *Cursor) Delete() *Cursor {
+	c.err = nil // reset error
+	i := len(c.stk) - 1
+	if i >= 0 && isMember(c.parent(i)) {
+		i--
+	}
+	if i < 0 {
+		return c.setErrorf("cannot delete the origin")
+	} else if c.pos[i] < 0 {
+		c.err = errComputed
+		return c
+	}
+	j := c.pos[i]
+
+	var up ast.Value // the updated parent, if it was copied
+	switch p := c.parent(i).(type) {
+	case ast.Array:
+		up = append(p[:j:j], p[j+1:]...)
+	case ast.Object:
+		up = append(p[:j:j], p[j+1:]...)
+	case *jwcc.Array:
+		p.Values = slices.Delete(p.Values, j, j+1)
+	case *jwcc.Object:
+		p.Members = slices.Delete(p.Members, j, j+1)
+	default:
+		return c.setErrorf("cannot delete from %T", p)
+	}
+	if up != nil {
+		if err := c.store(i-1, up); err != nil {
+			c.err = err
+			return c
+		}
+	}
+	c.stk, c.pos = c.stk[:i], c.pos[:i]
+	return c
+}
+
+// InsertBefore inserts v into the parent of c immediately before the current
+// value, which must be an array element or an object member. If the current
+// value is an object member, v must be an object member of the same kind
+// (*ast.Member or *jwcc.Member), except that an *ast.Member is decorated for
+// insertion into a JWCC object. The cursor remains at the current value.
+// It returns c to permit chaining.
+//
+// JWCC arrays and objects are updated in place. Other arrays and objects are
+// copied, and the copy replaces the original in its own parent.
+func (c *Cursor) InsertBefore(v ast.Value) *Cursor { return c.insert(v, 0) }
+
+// InsertAfter inserts v into the parent of c immediately after the current
+// value, as described for InsertBefore. It returns c to permit chaining.
+func (c *Cursor) InsertAfter(v ast.Value) *Cursor { return c.insert(v, 1) }
+
+func (c *Cursor) insert(v ast.Value, off int) *Cursor {
+	c.err = nil // reset error
+	i := len(c.stk) - 1
+	if i < 0 {
+		return c.setErrorf("cannot insert beside the origin")
+	} else if c.pos[i] < 0 {
+		c.err = errComputed
+		return c
+	}
+	at := c.pos[i] + off
+
+	var up ast.Value // the updated parent, if it was copied
+	switch p := c.parent(i).(type) {
+	case ast.Array:
+		up = slices.Insert(p[:len(p):len(p)], at, v)
+	case ast.Object:
+		m, ok := v.(*ast.Member)
+		if !ok {
+			return c.setErrorf("cannot insert %T into an object", v)
+		}
+		up = slices.Insert(p[:len(p):len(p)], at, m)
+	case *jwcc.Array:
+		p.Values = slices.Insert(p.Values, at, decorate(v))
+	case *jwcc.Object:
+		var m *jwcc.Member
+		switch t := v.(type) {
+		case *jwcc.Member:
+			m = t
+		case *ast.Member:
+			m = &jwcc.Member{Key: t.Key, Value: decorate(t.Value)}
+		default:
+			return c.setErrorf("cannot insert %T into an object", v)
+		}
+		p.Members = slices.Insert(p.Members, at, m)
+	default:
+		return c.setErrorf("cannot insert into %T", p)
+	}
+	if up != nil {
+		if err := c.store(i-1, up); err != nil {
+			c.err = err
+			return c
+		}
+	}
+	if off == 0 {
+		c.pos[i]++ // the current value moved over by one
+	}
+	return c
+}
+
+// parent returns the parent of the value at offset i of the stack.
+func (c *Cursor) parent(i int) ast.Value {
+	if i == 0 {
+		return c.org
+	}
+	return c.stk[i-1]
+}
+
+// store replaces the value at offset i of the stack, or the origin if i < 0,
+// with v, and updates its parent to refer to v.
+func (c *Cursor) store(i int, v ast.Value) error {
+	if i < 0 {
+		c.org = v
+		return nil
+	}
+	j := c.pos[i]
+	if j < 0 {
+		return errComputed
+	}
+	switch p := c.parent(i).(type) {
+	case *ast.Member:
+		p.Value = v
+	case *jwcc.Member:
+		jv := decorate(v)
+		p.Value, v = jv, jv
+	case ast.Array:
+		p[j] = v
+	case *jwcc.Array:
+		jv := decorate(v)
+		p.Values[j], v = jv, jv
+	default:
+		return fmt.Errorf("cannot replace a value in %T", p)
+	}
+	c.stk[i] = v
+	return nil
+}
+
+func isMember(v ast.Value) bool {
+	switch v.(type) {
+	case *ast.Member, *jwcc.Member:
+		return true
+	}
+	return false
+}
+
+// decorate returns v as a jwcc.Value, decorating it if necessary.
+func decorate(v ast.Value) jwcc.Value {
+	if jv, ok := v.(jwcc.Value); ok {
+		return jv
+	}
+	return jwcc.Decorate(v)
+}
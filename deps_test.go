@@ -0,0 +1,142 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree_test
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const modulePath = "github.com/creachadair/jtree"
+
+// allowedDeps records, for each package in the module, the other packages of
+// the module it is permitted to import (non-test files only). The root package
+// is denoted "". A new package must be added here before it can be built into
+// the module, so that its dependencies are a deliberate choice.
+var allowedDeps = map[string][]string{
+	// Core packages.
+	"":                {"internal/escape"},
+	"ast":             {"", "internal/escape"},
+	"internal/escape": nil,
+
+	// Optional subsystems.
+	"cmd/jtq":           {"ast", "jwcc", "repl", "tq"},
+	"cmd/jminify":       {""},
+	"cmd/jtree-repl":    {"repl"},
+	"cmd/jwccfmt":       {"jwcc"},
+	"corrupt":           {""},
+	"cursor":            {"ast", "internal/escape", "jwcc", "tq"},
+	"digest":            {"ast", "internal/escape", "jwcc", "tq"},
+	"internal/testutil": {"ast"},
+	"jwcc":              {"", "ast", "internal/escape"},
+	"msgpack":           {"", "ast"},
+	"persist":           {"ast"},
+	"policy":            {"ast", "internal/escape", "jwcc"},
+	"report":            {"", "ast", "internal/escape", "jwcc"},
+	"repl":              {"ast", "cursor", "jwcc", "tq"},
+	"cbor":              {"", "ast"},
+	"sample":            {"ast", "jwcc"},
+	"schema":            {"", "ast", "internal/escape", "jwcc"},
+	"stdjson":           {""},
+	"tq":                {"ast", "jwcc", "persist"},
+	"yaml":              {"ast", "jwcc"},
+}
+
+// corePackages are the packages that a program using only the scanner,
+// stream parser, and syntax tree depends on. They may not import any module
+// outside the standard library other than those listed in coreExternal.
+var corePackages = []string{"", "ast", "internal/escape"}
+
+var coreExternal = []string{"go4.org/mem"}
+
+func TestDependencies(t *testing.T) {
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if !d.IsDir() {
+			return nil
+		} else if name := d.Name(); path != "." && (name == "testdata" || strings.HasPrefix(name, ".")) {
+			return fs.SkipDir
+		}
+		pkg, err := build.ImportDir(path, 0)
+		if _, ok := err.(*build.NoGoError); ok {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		rel := filepath.ToSlash(path)
+		if rel == "." {
+			rel = ""
+		}
+		seen[rel] = true
+		allowed, ok := allowedDeps[rel]
+		if !ok {
+			t.Errorf("Package %q is not listed in allowedDeps", rel)
+			return nil
+		}
+		isCore := slices.Contains(corePackages, rel)
+		for _, imp := range pkg.Imports {
+			if dep, ok := moduleRel(imp); ok {
+				if !slices.Contains(allowed, dep) {
+					t.Errorf("Package %q imports %q, which is not allowed", rel, imp)
+				}
+			} else if isCore && !isStdlib(imp) && !slices.Contains(coreExternal, imp) {
+				t.Errorf("Core package %q imports external package %q", rel, imp)
+			}
+		}
+
+		// Packages must not register anything at init time, so that importing
+		// a package costs nothing a program does not use.
+		if rel != "internal/testutil" {
+			for _, name := range pkg.GoFiles {
+				checkNoInit(t, filepath.Join(path, name))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walking module: %v", err)
+	}
+	for pkg := range allowedDeps {
+		if !seen[pkg] {
+			t.Errorf("Package %q is listed in allowedDeps but was not found", pkg)
+		}
+	}
+}
+
+// moduleRel reports whether imp is a package in this module, and if so
+// returns its path relative to the module root.
+func moduleRel(imp string) (string, bool) {
+	if imp == modulePath {
+		return "", true
+	}
+	rel, ok := strings.CutPrefix(imp, modulePath+"/")
+	return rel, ok
+}
+
+func isStdlib(imp string) bool {
+	first, _, _ := strings.Cut(imp, "/")
+	return !strings.Contains(first, ".")
+}
+
+func checkNoInit(t *testing.T, path string) {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		t.Errorf("Parse %q: %v", path, err)
+		return
+	}
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == "init" {
+			t.Errorf("File %q defines an init function", path)
+		}
+	}
+}
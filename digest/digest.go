@@ -0,0 +1,352 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package digest computes canonical digests of JSON values and of selected
+// parts of a larger document, for detached integrity checks.
+//
+// The canonical form of a value follows the JSON Canonicalization Scheme (RFC
+// 8785): object members are sorted by key, insignificant whitespace is
+// removed, and strings and numbers are written in a single normal form. Two
+// values that differ only in formatting, member order, or comments have the
+// same canonical form, and hence the same digest.
+//
+// A Signature records the digests of the values at a set of JSON Pointers
+// (RFC 6901). A signature can be embedded in the document it describes as
+// an object member, and verified later:
+//
+//	signed, err := digest.Embed(doc, "signature", "/server", "/users")
+//	...
+//	if err := digest.VerifyEmbedded(signed, "signature"); err != nil {
+//	   log.Fatalf("Document was modified: %v", err)
+//	}
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/escape"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/tq"
+	"golang.org/x/exp/slices"
+)
+
+// Algorithm is the name of the digest algorithm used by this package.
+const Algorithm = "sha256"
+
+// ErrMismatch is a sentinel error reported by Verify when the digest of a
+// value does not match its signature.
+var ErrMismatch = errors.New("digest mismatch")
+
+// Canonical returns the canonical encoding of v. If v is a jwcc.Value, its
+// comments are ignored. It reports an error if v contains a number that
+// cannot be represented as a float64.
+func Canonical(v ast.Value) ([]byte, error) {
+	return appendCanonical(nil, v)
+}
+
+// Sum returns the hex-encoded SHA-256 digest of the canonical encoding of v.
+func Sum(v ast.Value) (string, error) {
+	data, err := Canonical(v)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// SumQuery returns the digest of the value selected by evaluating q on root.
+func SumQuery(root ast.Value, q tq.Query) (string, error) {
+	v, err := tq.Eval[ast.Value](undecorate(root), q)
+	if err != nil {
+		return "", err
+	}
+	return Sum(v)
+}
+
+// A Signature records the digests of the values at a set of locations in a
+// document.
+type Signature struct {
+	Alg     string  // the digest algorithm; see Algorithm
+	Digests []Entry // in order of signing
+}
+
+// An Entry is the digest of the value at a single location.
+type Entry struct {
+	Pointer string // a JSON Pointer (RFC 6901); "" is the whole document
+	Digest  string // hex-encoded
+}
+
+// Sign computes a signature for the values of root at the given JSON
+// Pointers. If no pointers are given, the whole of root is signed.
+func Sign(root ast.Value, pointers ...string) (*Signature, error) {
+	if len(pointers) == 0 {
+		pointers = []string{""}
+	}
+	root = undecorate(root)
+	sig := &Signature{Alg: Algorithm}
+	for _, p := range pointers {
+		v, err := Resolve(root, p)
+		if err != nil {
+			return nil, err
+		}
+		d, err := Sum(v)
+		if err != nil {
+			return nil, fmt.Errorf("pointer %q: %w", p, err)
+		}
+		sig.Digests = append(sig.Digests, Entry{Pointer: p, Digest: d})
+	}
+	return sig, nil
+}
+
+// Verify checks the digests of s against the values of root. If any value is
+// missing or does not match, Verify reports an error that wraps ErrMismatch
+// for each mismatched value.
+func (s *Signature) Verify(root ast.Value) error {
+	if s.Alg != Algorithm {
+		return fmt.Errorf("unsupported algorithm %q", s.Alg)
+	}
+	root = undecorate(root)
+	var errs []error
+	for _, e := range s.Digests {
+		v, err := Resolve(root, e.Pointer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		d, err := Sum(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pointer %q: %w", e.Pointer, err))
+		} else if d != e.Digest {
+			errs = append(errs, fmt.Errorf("pointer %q: %w", e.Pointer, ErrMismatch))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ToValue encodes s as a JSON object of the form
+//
+//	{"alg": "sha256", "digests": {"/pointer": "hex-digest", ...}}
+func (s *Signature) ToValue() ast.Value {
+	ds := make(ast.Object, len(s.Digests))
+	for i, e := range s.Digests {
+		ds[i] = ast.Field(e.Pointer, e.Digest)
+	}
+	return ast.Object{
+		ast.Field("alg", s.Alg),
+		ast.Field("digests", ds),
+	}
+}
+
+// ParseSignature decodes a signature from a value in the format produced by
+// the ToValue method.
+func ParseSignature(v ast.Value) (*Signature, error) {
+	obj, ok := undecorate(v).(ast.Object)
+	if !ok {
+		return nil, errors.New("signature is not an object")
+	}
+	sig := new(Signature)
+	for _, m := range obj {
+		switch key := m.Key.String(); key {
+		case "alg":
+			s, ok := m.Value.(ast.Text)
+			if !ok {
+				return nil, errors.New("alg must be a string")
+			}
+			sig.Alg = s.String()
+		case "digests":
+			ds, ok := m.Value.(ast.Object)
+			if !ok {
+				return nil, errors.New("digests must be an object")
+			}
+			for _, d := range ds {
+				s, ok := d.Value.(ast.Text)
+				if !ok {
+					return nil, fmt.Errorf("digest for %q must be a string", d.Key.String())
+				}
+				sig.Digests = append(sig.Digests, Entry{Pointer: d.Key.String(), Digest: s.String()})
+			}
+		default:
+			return nil, fmt.Errorf("unknown signature field %q", key)
+		}
+	}
+	if sig.Alg == "" {
+		return nil, errors.New("missing alg")
+	}
+	return sig, nil
+}
+
+// Embed signs the values of root at the given JSON Pointers, and returns a
+// copy of root with the signature added as a member with the given key.
+// Any existing member with that key is replaced, and is not included in
+// the values signed.
+func Embed(root ast.Object, key string, pointers ...string) (ast.Object, error) {
+	base := without(root, key)
+	sig, err := Sign(base, pointers...)
+	if err != nil {
+		return nil, err
+	}
+	return append(base, ast.Field(key, sig.ToValue())), nil
+}
+
+// VerifyEmbedded verifies the signature stored in the member of root with
+// the given key, as added by Embed.
+func VerifyEmbedded(root ast.Value, key string) error {
+	obj, ok := undecorate(root).(ast.Object)
+	if !ok {
+		return fmt.Errorf("value is %T, not an object", root)
+	}
+	m := obj.FindKey(ast.TextEqual(key))
+	if m == nil {
+		return fmt.Errorf("missing signature %q", key)
+	}
+	sig, err := ParseSignature(m.Value)
+	if err != nil {
+		return fmt.Errorf("signature %q: %w", key, err)
+	}
+	return sig.Verify(without(obj, key))
+}
+
+// without returns a copy of obj without members whose key equals key.
+func without(obj ast.Object, key string) ast.Object {
+	return slices.DeleteFunc(slices.Clone(obj), func(m *ast.Member) bool {
+		return m.Key.String() == key
+	})
+}
+
+func undecorate(v ast.Value) ast.Value {
+	if jv, ok := v.(jwcc.Value); ok {
+		return jv.Undecorate()
+	}
+	return v
+}
+
+// Resolve returns the value of root at the given JSON Pointer.
+func Resolve(root ast.Value, pointer string) (ast.Value, error) {
+	toks, err := escape.SplitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	cur := undecorate(root)
+	for _, tok := range toks {
+		switch t := cur.(type) {
+		case ast.Object:
+			m := t.FindKey(ast.TextEqual(tok))
+			if m == nil {
+				return nil, fmt.Errorf("pointer %q: key %q not found", pointer, tok)
+			}
+			cur = m.Value
+		case ast.Array:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(t) || (tok != "0" && tok[0] == '0') {
+				return nil, fmt.Errorf("pointer %q: invalid array index %q", pointer, tok)
+			}
+			cur = t[i]
+		default:
+			return nil, fmt.Errorf("pointer %q: cannot index %T", pointer, cur)
+		}
+	}
+	return cur, nil
+}
+
+func appendCanonical(buf []byte, v ast.Value) ([]byte, error) {
+	switch t := undecorate(v).(type) {
+	case ast.Object:
+		ms := slices.Clone(t)
+		slices.SortStableFunc(ms, func(a, b *ast.Member) int {
+			return compareUTF16(a.Key.String(), b.Key.String())
+		})
+		buf = append(buf, '{')
+		for i, m := range ms {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendString(buf, m.Key.String())
+			buf = append(buf, ':')
+			var err error
+			buf, err = appendCanonical(buf, m.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+	case ast.Array:
+		buf = append(buf, '[')
+		for i, elt := range t {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			buf, err = appendCanonical(buf, elt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+	case ast.Text:
+		return appendString(buf, t.String()), nil
+	case ast.Number:
+		f, err := strconv.ParseFloat(t.JSON(), 64)
+		if err != nil || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("number %s is out of range", t.JSON())
+		}
+		return append(buf, formatNumber(f)...), nil
+	default:
+		return append(buf, t.JSON()...), nil // true, false, null
+	}
+}
+
+// formatNumber formats f as ECMAScript does, per RFC 8785 section 3.2.2.3.
+func formatNumber(f float64) string {
+	if f == 0 {
+		return "0" // including -0
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	mant, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	sign, digits := exp[:1], strings.TrimLeft(exp[1:], "0")
+	return mant + "e" + sign + digits
+}
+
+// appendString appends the canonical quoted form of s, per RFC 8785 section
+// 3.2.2.2.
+func appendString(buf []byte, s string) []byte {
+	const hexDigits = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			buf = append(buf, '\\', c)
+		case '\b':
+			buf = append(buf, '\\', 'b')
+		case '\f':
+			buf = append(buf, '\\', 'f')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if c < 0x20 {
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			} else {
+				buf = append(buf, c)
+			}
+		}
+	}
+	return append(buf, '"')
+}
+
+// compareUTF16 compares a and b by their UTF-16 code units, as required for
+// sorting object keys by RFC 8785 section 3.2.3.
+func compareUTF16(a, b string) int {
+	return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+}
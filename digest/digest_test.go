@@ -0,0 +1,106 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package digest_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/digest"
+	"github.com/creachadair/jtree/internal/testutil"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/tq"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{`null`, `null`},
+		{`[true, false, null]`, `[true,false,null]`},
+		{`{"b": 1, "a": [2, {"d": 4, "c": 3}]}`, `{"a":[2,{"c":3,"d":4}],"b":1}`},
+		{`"\u0041\n\u001f\u00e9\/"`, "\"A\\n\\u001f\u00e9/\""},
+
+		// Numbers (RFC 8785 appendix B).
+		{`[0, -0, 1.0, 100, 1e2, 1E+21, 1e-7, 0.000001, 333333333.33333329, 1e23, -5e-324]`,
+			`[0,0,1,100,100,1e+21,1e-7,0.000001,333333333.3333333,1e+23,-5e-324]`},
+
+		// Keys are sorted by UTF-16 code units (RFC 8785 section 3.2.3).
+		{"{\"\u20ac\": 1, \"\U0001f600\": 2, \"\\r\": 3, \"1\": 4, \"\\u00f6\": 5, \"\uff61\": 6}",
+			"{\"\\r\":3,\"1\":4,\"\u00f6\":5,\"\u20ac\":1,\"\U0001f600\":2,\"\uff61\":6}"},
+	}
+	for _, tc := range tests {
+		got, err := digest.Canonical(testutil.MustParse(t, tc.input))
+		if err != nil {
+			t.Errorf("Canonical %#q: unexpected error: %v", tc.input, err)
+		} else if string(got) != tc.want {
+			t.Errorf("Canonical %#q:\n got: %s\nwant: %s", tc.input, got, tc.want)
+		}
+	}
+
+	if got, err := digest.Canonical(testutil.MustParse(t, `[1e400]`)); err == nil {
+		t.Errorf("Canonical: got %s, want error", got)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	doc, err := jwcc.Parse(strings.NewReader(`{
+  // The server configuration.
+  "server": {"host": "localhost", "port": 8080},
+  "users": ["alice", "bob"],
+  "debug": false,
+}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	root := doc.Undecorate().(ast.Object)
+
+	signed, err := digest.Embed(root, "signature", "/server", "/users/1")
+	if err != nil {
+		t.Fatalf("Embed: unexpected error: %v", err)
+	}
+	t.Logf("Signed: %s", signed.JSON())
+	if err := digest.VerifyEmbedded(signed, "signature"); err != nil {
+		t.Errorf("VerifyEmbedded: unexpected error: %v", err)
+	}
+
+	// Formatting and member order do not affect the signature.
+	reordered := testutil.MustParse(t, `{"debug":true, "users":["carol","bob"],
+   "server": {"port": 8080.0, "host": "localhost"},
+   "signature": `+signed.Find("signature").Value.JSON()+`}`)
+	if err := digest.VerifyEmbedded(reordered, "signature"); err != nil {
+		t.Errorf("VerifyEmbedded: unexpected error: %v", err)
+	}
+
+	// Modifying a signed value is detected.
+	changed := testutil.MustParse(t, strings.Replace(signed.JSON(), "8080", "8081", 1))
+	if err := digest.VerifyEmbedded(changed, "signature"); !errors.Is(err, digest.ErrMismatch) {
+		t.Errorf("VerifyEmbedded: got %v, want %v", err, digest.ErrMismatch)
+	}
+
+	// A whole-document signature does not include the signature itself.
+	whole, err := digest.Embed(root, "sig")
+	if err != nil {
+		t.Fatalf("Embed: unexpected error: %v", err)
+	}
+	if err := digest.VerifyEmbedded(whole, "sig"); err != nil {
+		t.Errorf("VerifyEmbedded: unexpected error: %v", err)
+	}
+
+	// Digests computed by query agree with those computed by pointer.
+	sig, err := digest.Sign(doc, "/server")
+	if err != nil {
+		t.Fatalf("Sign: unexpected error: %v", err)
+	}
+	if got, err := digest.SumQuery(doc, tq.Path("server")); err != nil {
+		t.Errorf("SumQuery: unexpected error: %v", err)
+	} else if got != sig.Digests[0].Digest {
+		t.Errorf("SumQuery: got %s, want %s", got, sig.Digests[0].Digest)
+	}
+
+	if _, err := digest.Sign(root, "/users/2"); err == nil {
+		t.Error("Sign with a bad pointer: got nil, want error")
+	}
+}
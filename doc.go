@@ -66,4 +66,17 @@
 //
 // The parser ensures that corresponding Begin and End methods are correctly
 // paired, or that a SyntaxError is reported.
+//
+// # Packages
+//
+// This package and the ast package form the core of the module: a scanner,
+// a stream parser, and a syntax tree. They depend only on the standard library
+// and go4.org/mem. Larger optional subsystems, such as the JWCC syntax (jwcc),
+// queries (tq), and the tools built on them, are in separate packages that a
+// program pays for only if it imports them. No package in the module does any
+// work or registration at init time, so importing a package has no effect on
+// the behavior of the others.
+//
+// The permitted dependencies among packages are enforced by a test; a new
+// package must declare its dependencies there.
 package jtree
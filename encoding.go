@@ -14,6 +14,48 @@ import (
 // double quotation marks are added.
 func Quote(src string) string { return escape.Quote(mem.S(src)).StringCopy() }
 
+// QuoteOptions are settings for the QuoteWith function. A nil *QuoteOptions
+// is ready for use, and escapes only what JSON requires, as Quote does.
+type QuoteOptions struct {
+	// Escape the characters <, >, and & as \u003c, \u003e, and \u0026, so
+	// that the string can be safely embedded in HTML or a <script> element.
+	// This matches the default behavior of the encoding/json package.
+	EscapeHTML bool
+
+	// Escape all non-ASCII characters as \u sequences, using UTF-16 surrogate
+	// pairs for characters outside the Basic Multilingual Plane, so that the
+	// output is plain ASCII.
+	ASCIIOnly bool
+}
+
+func (o *QuoteOptions) mode() escape.Mode {
+	var m escape.Mode
+	if o != nil && o.EscapeHTML {
+		m |= escape.HTML
+	}
+	if o != nil && o.ASCIIOnly {
+		m |= escape.ASCII
+	}
+	return m
+}
+
+// QuoteWith encodes src as a JSON string value, as Quote does, with the
+// additional escapes selected by opts.
+func QuoteWith(src string, opts *QuoteOptions) string {
+	return escape.QuoteMode(mem.S(src), opts.mode()).StringCopy()
+}
+
+// AppendQuote appends the encoding of src as a JSON string value, as
+// generated by Quote, to dst and returns the extended slice. It allocates
+// only if dst does not have enough capacity for the result.
+func AppendQuote(dst []byte, src string) []byte { return escape.AppendQuote(dst, mem.S(src), 0) }
+
+// AppendQuoteWith appends the encoding of src as a JSON string value, as
+// generated by QuoteWith, to dst and returns the extended slice.
+func AppendQuoteWith(dst []byte, src string, opts *QuoteOptions) []byte {
+	return escape.AppendQuote(dst, mem.S(src), opts.mode())
+}
+
 // Unquote decodes a JSON string value.  Double quotation marks are removed,
 // and escape sequences are replaced with their unescaped equivalents.
 //
@@ -28,16 +70,55 @@ func Unquote(src []byte) ([]byte, error) { return unquoteMem(mem.B(src)) }
 // reports an error for an incomplete escape sequence.
 func UnquoteString(src string) ([]byte, error) { return unquoteMem(mem.S(src)) }
 
-var dquote = mem.S(`"`)
+// UnquoteStrict decodes a JSON string value as Unquote does, but reports an
+// error for an invalid escape sequence instead of replacing it. Only the
+// escapes defined by JSON (\", \\, \/, \b, \f, \n, \r, \t, and \u) are
+// accepted, and src must be enclosed in double quotes. In particular, a \u
+// escape for half of a UTF-16 surrogate pair must be followed by a \u escape
+// for the other half.
+func UnquoteStrict(src []byte) ([]byte, error) {
+	if !isQuoted(mem.B(src)) {
+		return nil, errors.New("missing quotations")
+	}
+	return escape.UnquoteStrict(mem.B(src[1 : len(src)-1]))
+}
+
+// AppendUnquote appends the decoding of the JSON string value src, as
+// produced by Unquote, to dst and returns the extended slice. It allocates
+// only if dst does not have enough capacity for the result. If src is not a
+// valid string, AppendUnquote reports an error and returns dst unmodified.
+func AppendUnquote(dst, src []byte) ([]byte, error) {
+	if !isQuoted(mem.B(src)) {
+		return dst, errors.New("missing quotations")
+	}
+	return escape.AppendUnquote(dst, mem.B(src[1:len(src)-1]))
+}
 
 func unquoteMem(src mem.RO) ([]byte, error) {
-	if src.Len() < 2 || !mem.HasPrefix(src, dquote) || !mem.HasSuffix(src, dquote) {
+	if !isQuoted(src) {
 		return nil, errors.New("missing quotations")
 	}
 	return escape.Unquote(src.Slice(1, src.Len()-1))
 }
 
-// Interner is a deduplicating string interning map.
+// isQuoted reports whether src is enclosed in double quotation marks.
+func isQuoted(src mem.RO) bool {
+	n := src.Len()
+	return n >= 2 && src.At(0) == '"' && src.At(n-1) == '"'
+}
+
+// unquoteJSON5 decodes a JSON5 string value, enclosed in double or single
+// quotation marks, including the additional escape sequences of JSON5.
+func unquoteJSON5(src []byte) ([]byte, error) {
+	n := len(src)
+	if n < 2 || src[0] != src[n-1] || (src[0] != '"' && src[0] != '\'') {
+		return nil, errors.New("missing quotations")
+	}
+	return escape.UnquoteJSON5(mem.B(src[1 : n-1]))
+}
+
+// Interner is a deduplicating string interning map. It is not safe for
+// concurrent use; see InternPool for an interner that is.
 type Interner map[string]string
 
 // Intern returns text as a string, ensuring that only one string is allocated
@@ -0,0 +1,200 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree/internal/escape"
+)
+
+// Extract reads a single JSON value from r, and returns the source text of
+// the values within it at the specified paths, which are JSON Pointers (RFC
+// 6901). The result has one element for each path, in the same order, which
+// is nil if the value does not contain that path. The empty path "" denotes
+// the complete value. If an object has more than one member with the same
+// key, only the first is considered.
+//
+// Extract does not construct the values it reads, and records the source
+// text only of the values it returns. It stops reading once all the paths
+// have been found, so extracting a value near the start of a large input is
+// fast. Because it may stop early, Extract does not report syntax errors that
+// follow the last value found.
+func Extract(r io.Reader, paths ...string) ([][]byte, error) {
+	x := &extractor{
+		rec:  &recorder{r: r},
+		want: make(map[string][]int),
+		out:  make([][]byte, len(paths)),
+	}
+	for i, p := range paths {
+		if p != "" && !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("invalid pointer %q", p)
+		}
+		x.want[p] = append(x.want[p], i)
+		x.maxDepth = max(x.maxDepth, strings.Count(p, "/"))
+	}
+	if len(paths) == 0 {
+		return x.out, nil
+	}
+	err := NewStream(x.rec).ParseOne(x)
+	if err == errExtractDone || err == io.EOF {
+		return x.out, nil
+	}
+	return x.out, err
+}
+
+var errExtractDone = errors.New("extraction complete")
+
+// An extractor is a Handler that records the source text of values at
+// selected paths.
+type extractor struct {
+	NopHandler
+
+	rec      *recorder
+	want     map[string][]int // path → offsets in out
+	maxDepth int              // the length of the longest path wanted
+	out      [][]byte
+	found    int // number of paths found
+
+	stk  []extractFrame   // enclosing containers, innermost last
+	caps []extractCapture // values being recorded, innermost last
+}
+
+// An extractFrame records the state of a container being read.
+type extractFrame struct {
+	path  string // the path of the container, if tracked
+	deep  bool   // the container is too deep to contain a wanted path
+	array bool   // the container is an array
+	next  int    // the index of the next array element
+	key   string // the escaped key of the current object member
+}
+
+// An extractCapture records a container whose text is wanted.
+type extractCapture struct {
+	depth int   // the stack depth of the container
+	pos   int   // the start offset of the container
+	outs  []int // offsets in out
+}
+
+// begin reports the start of a value, and returns its path and the offsets
+// in out of the paths it matches, if any. Values nested too deeply to be
+// wanted are not assigned paths.
+func (x *extractor) begin() (string, []int) {
+	var path string
+	if n := len(x.stk); n != 0 {
+		top := &x.stk[n-1]
+		if top.deep {
+			return "", nil
+		} else if top.array {
+			path = top.path + "/" + strconv.Itoa(top.next)
+			top.next++
+		} else {
+			path = top.path + "/" + top.key
+		}
+	}
+	outs := x.want[path]
+	delete(x.want, path) // only the first match counts
+	return path, outs
+}
+
+// record records text as the value for each of outs, and reports
+// errExtractDone if all the paths wanted have been found.
+func (x *extractor) record(outs []int, text []byte) error {
+	for _, i := range outs {
+		x.out[i] = text
+		x.found++
+	}
+	if x.found == len(x.out) {
+		return errExtractDone
+	}
+	return nil
+}
+
+// trim discards recorded input before loc, if no value is being recorded.
+func (x *extractor) trim(loc Anchor) {
+	if len(x.caps) == 0 {
+		x.rec.discard(loc.Location().Span.Pos)
+	}
+}
+
+func (x *extractor) beginContainer(loc Anchor, array bool) error {
+	x.trim(loc)
+	path, outs := x.begin()
+	x.stk = append(x.stk, extractFrame{path: path, deep: len(x.stk) >= x.maxDepth, array: array})
+	if outs != nil {
+		x.caps = append(x.caps, extractCapture{
+			depth: len(x.stk),
+			pos:   loc.Location().Span.Pos,
+			outs:  outs,
+		})
+	}
+	return nil
+}
+
+func (x *extractor) endContainer(loc Anchor) error {
+	if n := len(x.caps); n != 0 && x.caps[n-1].depth == len(x.stk) {
+		c := x.caps[n-1]
+		x.caps = x.caps[:n-1]
+		if err := x.record(c.outs, x.rec.text(c.pos, loc.Location().Span.End)); err != nil {
+			return err
+		}
+	}
+	x.stk = x.stk[:len(x.stk)-1]
+	return nil
+}
+
+func (x *extractor) BeginObject(loc Anchor) error { return x.beginContainer(loc, false) }
+func (x *extractor) EndObject(loc Anchor) error   { return x.endContainer(loc) }
+func (x *extractor) BeginArray(loc Anchor) error  { return x.beginContainer(loc, true) }
+func (x *extractor) EndArray(loc Anchor) error    { return x.endContainer(loc) }
+
+func (x *extractor) BeginMember(loc Anchor) error {
+	x.trim(loc)
+	if top := &x.stk[len(x.stk)-1]; !top.deep {
+		key, err := AnchorUnquote(loc)
+		if err != nil {
+			return err
+		}
+		top.key = escape.EscapeToken(string(key))
+	}
+	return nil
+}
+
+func (x *extractor) Value(loc Anchor) error {
+	x.trim(loc)
+	if _, outs := x.begin(); outs != nil {
+		return x.record(outs, loc.Copy())
+	}
+	return nil
+}
+
+// A recorder is an io.Reader that retains the data it has read from r since
+// a given offset, so that the source text of a value can be recovered.
+type recorder struct {
+	r    io.Reader
+	buf  []byte // data read from r since base
+	base int    // the offset of buf[0] in the input
+}
+
+func (r *recorder) Read(data []byte) (int, error) {
+	nr, err := r.r.Read(data)
+	r.buf = append(r.buf, data[:nr]...)
+	return nr, err
+}
+
+// discard discards the data before offset pos.
+func (r *recorder) discard(pos int) {
+	if pos > r.base {
+		r.buf = r.buf[pos-r.base:]
+		r.base = pos
+	}
+}
+
+// text returns a copy of the data between offsets pos and end.
+func (r *recorder) text(pos, end int) []byte {
+	return append([]byte(nil), r.buf[pos-r.base:end-r.base]...)
+}
@@ -7,6 +7,7 @@ toolchain go1.23.2
 require (
 	github.com/google/go-cmp v0.6.0
 	go4.org/mem v0.0.0-20240501181205-ae6ca9944745
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
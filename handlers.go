@@ -0,0 +1,353 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NopHandler is a Handler whose methods do nothing and report no error. It
+// may be embedded in a handler type that needs to implement only some of the
+// methods of a Handler.
+//
+// For example, a handler that counts the values in the input:
+//
+//	type counter struct {
+//	   jtree.NopHandler
+//	   n int
+//	}
+//
+//	func (c *counter) Value(jtree.Anchor) error { c.n++; return nil }
+type NopHandler struct{}
+
+func (NopHandler) BeginObject(Anchor) error { return nil }
+func (NopHandler) EndObject(Anchor) error   { return nil }
+func (NopHandler) BeginArray(Anchor) error  { return nil }
+func (NopHandler) EndArray(Anchor) error    { return nil }
+func (NopHandler) BeginMember(Anchor) error { return nil }
+func (NopHandler) EndMember(Anchor) error   { return nil }
+func (NopHandler) Value(Anchor) error       { return nil }
+func (NopHandler) EndOfInput(Anchor)        {}
+
+// TeeHandler returns a Handler that delivers each event to each of hs in
+// order. If a handler reports an error for an event, the event is not
+// delivered to the handlers after it, and the error is returned.
+//
+// The result implements the optional handler interfaces CommentInfoHandler,
+// ExtHandler, DocumentHandler, and SkipHandler defined by this package, and
+// delivers each optional event to those of hs that implement the
+// corresponding interface. A comment is delivered to a CommentHandler that
+// does not implement CommentInfoHandler via its Comment method.
+func TeeHandler(hs ...Handler) Handler { return teeHandler(hs) }
+
+type teeHandler []Handler
+
+func (t teeHandler) each(f func(Handler) error) error {
+	for _, h := range t {
+		if err := f(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t teeHandler) BeginObject(loc Anchor) error {
+	return t.each(func(h Handler) error { return h.BeginObject(loc) })
+}
+
+func (t teeHandler) EndObject(loc Anchor) error {
+	return t.each(func(h Handler) error { return h.EndObject(loc) })
+}
+
+func (t teeHandler) BeginArray(loc Anchor) error {
+	return t.each(func(h Handler) error { return h.BeginArray(loc) })
+}
+
+func (t teeHandler) EndArray(loc Anchor) error {
+	return t.each(func(h Handler) error { return h.EndArray(loc) })
+}
+
+func (t teeHandler) BeginMember(loc Anchor) error {
+	return t.each(func(h Handler) error { return h.BeginMember(loc) })
+}
+
+func (t teeHandler) EndMember(loc Anchor) error {
+	return t.each(func(h Handler) error { return h.EndMember(loc) })
+}
+
+func (t teeHandler) Value(loc Anchor) error {
+	return t.each(func(h Handler) error { return h.Value(loc) })
+}
+
+func (t teeHandler) EndOfInput(loc Anchor) {
+	for _, h := range t {
+		h.EndOfInput(loc)
+	}
+}
+
+func (t teeHandler) CommentInfo(loc Anchor, info CommentInfo) {
+	for _, h := range t {
+		switch ch := h.(type) {
+		case CommentInfoHandler:
+			ch.CommentInfo(loc, info)
+		case CommentHandler:
+			ch.Comment(loc)
+		}
+	}
+}
+
+func (t teeHandler) Punct(loc Anchor) error {
+	return t.each(func(h Handler) error {
+		if eh, ok := h.(ExtHandler); ok {
+			return eh.Punct(loc)
+		}
+		return nil
+	})
+}
+
+func (t teeHandler) TrailingComma(loc Anchor) error {
+	return t.each(func(h Handler) error {
+		if eh, ok := h.(ExtHandler); ok {
+			return eh.TrailingComma(loc)
+		}
+		return nil
+	})
+}
+
+func (t teeHandler) BeginDocument(loc Anchor) error {
+	return t.each(func(h Handler) error {
+		if dh, ok := h.(DocumentHandler); ok {
+			return dh.BeginDocument(loc)
+		}
+		return nil
+	})
+}
+
+func (t teeHandler) EndDocument(loc Anchor) error {
+	return t.each(func(h Handler) error {
+		if dh, ok := h.(DocumentHandler); ok {
+			return dh.EndDocument(loc)
+		}
+		return nil
+	})
+}
+
+func (t teeHandler) Skipped(loc Anchor) {
+	for _, h := range t {
+		if sh, ok := h.(SkipHandler); ok {
+			sh.Skipped(loc)
+		}
+	}
+}
+
+// FilterHandler returns a Handler that delivers to h only the events for the
+// values selected by pred, which is called with the path of each value that
+// is not nested within a selected value. A path is a sequence of object keys
+// (as strings) and array offsets (as ints), as for ast.Walk. The path is only
+// valid for the duration of the call to pred. If pred reports true, all the
+// events for the value are delivered to h, as for a top-level value: If the
+// value is an object member, its BeginMember and EndMember events are not.
+// EndOfInput is always delivered.
+//
+// For example, the following selects the elements of the array "items" in
+// the top-level object, and constructs an ast.Value for each:
+//
+//	var b ast.Builder
+//	h := jtree.FilterHandler(func(path []any) bool {
+//	   return len(path) == 2 && path[0] == "items"
+//	}, &b)
+func FilterHandler(pred func(path []any) bool, h Handler) Handler {
+	return &filterHandler{pred: pred, h: h}
+}
+
+// A pathTracker tracks the path of the current value from the events of a
+// stream, for handlers that report paths.
+type pathTracker struct {
+	path []any       // the path of the current value
+	stk  []pathFrame // enclosing containers, innermost last
+}
+
+type pathFrame struct {
+	array bool // the container is an array
+	next  int  // the offset of the next array element
+}
+
+// value reports the start of a value, and returns its path.
+func (p *pathTracker) value() []any {
+	n := len(p.stk)
+	if n > 0 {
+		if top := &p.stk[n-1]; top.array {
+			p.path = append(p.path[:n-1], top.next)
+			top.next++
+		}
+	}
+	return p.path[:n]
+}
+
+// push reports the start of an object or array, following a call to value.
+func (p *pathTracker) push(array bool) { p.stk = append(p.stk, pathFrame{array: array}) }
+
+// pop reports the end of an object or array.
+func (p *pathTracker) pop() { p.stk = p.stk[:len(p.stk)-1] }
+
+// member reports the start of an object member whose key is at loc.
+func (p *pathTracker) member(loc Anchor) error {
+	key, err := AnchorUnquote(loc)
+	if err != nil {
+		return err
+	}
+	p.path = append(p.path[:len(p.stk)-1], string(key))
+	return nil
+}
+
+type filterHandler struct {
+	pathTracker
+	pred func([]any) bool
+	h    Handler
+	fwd  int // nesting depth within a selected value
+}
+
+func (f *filterHandler) begin(array bool, forward func() error) error {
+	if f.fwd > 0 {
+		f.fwd++
+		return forward()
+	}
+	sel := f.pred(f.value())
+	f.push(array)
+	if sel {
+		f.fwd = 1
+		return forward()
+	}
+	return nil
+}
+
+func (f *filterHandler) end(forward func() error) error {
+	if f.fwd > 0 {
+		if f.fwd--; f.fwd == 0 {
+			f.pop() // the end of the selected value
+		}
+		return forward()
+	}
+	f.pop()
+	return nil
+}
+
+func (f *filterHandler) BeginObject(loc Anchor) error {
+	return f.begin(false, func() error { return f.h.BeginObject(loc) })
+}
+
+func (f *filterHandler) EndObject(loc Anchor) error {
+	return f.end(func() error { return f.h.EndObject(loc) })
+}
+
+func (f *filterHandler) BeginArray(loc Anchor) error {
+	return f.begin(true, func() error { return f.h.BeginArray(loc) })
+}
+
+func (f *filterHandler) EndArray(loc Anchor) error {
+	return f.end(func() error { return f.h.EndArray(loc) })
+}
+
+func (f *filterHandler) BeginMember(loc Anchor) error {
+	if f.fwd > 0 {
+		return f.h.BeginMember(loc)
+	}
+	return f.member(loc)
+}
+
+func (f *filterHandler) EndMember(loc Anchor) error {
+	if f.fwd > 0 {
+		return f.h.EndMember(loc)
+	}
+	return nil
+}
+
+func (f *filterHandler) Value(loc Anchor) error {
+	if f.fwd > 0 || f.pred(f.value()) {
+		return f.h.Value(loc)
+	}
+	return nil
+}
+
+func (f *filterHandler) EndOfInput(loc Anchor) { f.h.EndOfInput(loc) }
+
+// ValueHandler returns a Handler that calls f with the path and location of
+// each string, number, Boolean, and null value in the input. A path is a
+// sequence of object keys (as strings) and array offsets (as ints), as for
+// FilterHandler, and is only valid for the duration of the call to f. If f
+// reports an error, parsing stops and the error is returned.
+//
+// For example, to print each value with its path:
+//
+//	h := jtree.ValueHandler(func(path []any, loc jtree.Anchor) error {
+//	   fmt.Println(path, string(loc.Text()))
+//	   return nil
+//	})
+func ValueHandler(f func(path []any, loc Anchor) error) Handler {
+	return &valueHandler{f: f}
+}
+
+type valueHandler struct {
+	NopHandler
+	pathTracker
+	f func([]any, Anchor) error
+}
+
+func (v *valueHandler) BeginObject(Anchor) error { v.value(); v.push(false); return nil }
+func (v *valueHandler) EndObject(Anchor) error   { v.pop(); return nil }
+func (v *valueHandler) BeginArray(Anchor) error  { v.value(); v.push(true); return nil }
+func (v *valueHandler) EndArray(Anchor) error    { v.pop(); return nil }
+
+func (v *valueHandler) BeginMember(loc Anchor) error { return v.member(loc) }
+
+func (v *valueHandler) Value(loc Anchor) error { return v.f(v.value(), loc) }
+
+// ErrDepthLimit is reported by a handler constructed by DepthLimitHandler if
+// the input is nested too deeply.
+var ErrDepthLimit = errors.New("nesting depth limit exceeded")
+
+// DepthLimitHandler returns a Handler that delivers each event to h, but
+// reports an error wrapping ErrDepthLimit if objects and arrays in the input
+// are nested more than max levels deep. Like TeeHandler, the result delivers
+// optional events to h if it implements the corresponding interfaces.
+func DepthLimitHandler(max int, h Handler) Handler {
+	return &depthLimitHandler{teeHandler: teeHandler{h}, max: max}
+}
+
+type depthLimitHandler struct {
+	teeHandler
+	max, depth int
+}
+
+func (d *depthLimitHandler) enter() error {
+	d.depth++
+	if d.depth > d.max {
+		return fmt.Errorf("%w: depth exceeds %d", ErrDepthLimit, d.max)
+	}
+	return nil
+}
+
+func (d *depthLimitHandler) BeginObject(loc Anchor) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	return d.teeHandler.BeginObject(loc)
+}
+
+func (d *depthLimitHandler) EndObject(loc Anchor) error {
+	d.depth--
+	return d.teeHandler.EndObject(loc)
+}
+
+func (d *depthLimitHandler) BeginArray(loc Anchor) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	return d.teeHandler.BeginArray(loc)
+}
+
+func (d *depthLimitHandler) EndArray(loc Anchor) error {
+	d.depth--
+	return d.teeHandler.EndArray(loc)
+}
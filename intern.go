@@ -0,0 +1,83 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import (
+	"container/list"
+	"sync"
+)
+
+// A StringInterner converts text to strings, ensuring that equal texts share
+// a single string. Both Interner and *InternPool implement this interface.
+type StringInterner interface {
+	Intern(text []byte) string
+}
+
+// An InternPool is a deduplicating string interner that is safe for
+// concurrent use by multiple goroutines, so that one pool can be shared by
+// many parsers. Unlike an Interner, a pool may be bounded: When it holds the
+// maximum number of strings, interning a new string evicts the least recently
+// used one. This keeps the memory used by a long-lived pool in proportion to
+// the working set of distinct strings, rather than to all the strings it has
+// ever seen.
+type InternPool struct {
+	mu    sync.Mutex
+	max   int
+	elts  map[string]*list.Element
+	lru   list.List // of string, most recently used first
+	stats InternStats
+}
+
+// InternStats records statistics for an InternPool.
+type InternStats struct {
+	Len       int   // the number of strings currently held
+	Hits      int64 // calls to Intern that returned an existing string
+	Misses    int64 // calls to Intern that allocated a new string
+	Evictions int64 // strings discarded to respect the bound
+}
+
+// NewInternPool constructs a new empty InternPool that holds at most max
+// strings. If max ≤ 0, the pool is unbounded.
+func NewInternPool(max int) *InternPool {
+	return &InternPool{max: max, elts: make(map[string]*list.Element)}
+}
+
+// Intern returns text as a string, ensuring that only one string is allocated
+// for each unique text held by p.
+func (p *InternPool) Intern(text []byte) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.elts[string(text)]; ok { // N.B. lookup special-cased by the compiler
+		p.stats.Hits++
+		p.lru.MoveToFront(e)
+		return e.Value.(string)
+	}
+	p.stats.Misses++
+	s := string(text)
+	p.elts[s] = p.lru.PushFront(s)
+	if p.max > 0 && p.lru.Len() > p.max {
+		old := p.lru.Remove(p.lru.Back()).(string)
+		delete(p.elts, old)
+		p.stats.Evictions++
+	}
+	return s
+}
+
+// Stats returns the current statistics for p.
+func (p *InternPool) Stats() InternStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	s.Len = p.lru.Len()
+	return s
+}
+
+// Reset discards all the strings held by p and zeroes its statistics. The
+// strings previously returned by p remain valid.
+func (p *InternPool) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.elts)
+	p.lru.Init()
+	p.stats = InternStats{}
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package escape
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// EscapeToken escapes s for use as a JSON Pointer reference token (RFC 6901).
+func EscapeToken(s string) string { return pointerEscaper.Replace(s) }
+
+// UnescapeToken decodes a JSON Pointer reference token (RFC 6901).
+func UnescapeToken(s string) string { return pointerUnescaper.Replace(s) }
+
+// SplitPointer splits a JSON Pointer into its unescaped reference tokens.
+// The empty pointer has no tokens. SplitPointer reports an error if a
+// non-empty pointer does not begin with "/".
+func SplitPointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	} else if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", p)
+	}
+	toks := strings.Split(p[1:], "/")
+	for i, tok := range toks {
+		toks[i] = UnescapeToken(tok)
+	}
+	return toks, nil
+}
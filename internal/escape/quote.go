@@ -3,6 +3,7 @@
 package escape
 
 import (
+	"unicode/utf16"
 	"unicode/utf8"
 
 	"go4.org/mem"
@@ -19,9 +20,31 @@ var controlEsc = [...]byte{
 
 var hexDigit = []byte("0123456789abcdef")
 
+// A Mode is a set of additional escaping rules for QuoteMode.
+type Mode uint8
+
+const (
+	// HTML escapes the characters <, >, and & as \u003c, \u003e, and \u0026,
+	// so that the string is safe to embed in HTML.
+	HTML Mode = 1 << iota
+
+	// ASCII escapes all non-ASCII characters, using UTF-16 surrogate pairs for
+	// characters outside the Basic Multilingual Plane.
+	ASCII
+)
+
 // Quote encodes a string to escape characters for inclusion in a JSON string.
-func Quote(src mem.RO) mem.RO {
-	buf := make([]byte, 0, src.Len()+2)
+func Quote(src mem.RO) mem.RO { return QuoteMode(src, 0) }
+
+// QuoteMode encodes a string as Quote does, with the additional escapes
+// specified by mode.
+func QuoteMode(src mem.RO, mode Mode) mem.RO {
+	return mem.B(AppendQuote(make([]byte, 0, src.Len()+2), src, mode))
+}
+
+// AppendQuote appends the encoding of src as a JSON string, with the
+// additional escapes specified by mode, to buf and returns the extended slice.
+func AppendQuote(buf []byte, src mem.RO, mode Mode) []byte {
 	putByte := func(bs ...byte) { buf = append(buf, bs...) }
 	putByte('"')
 
@@ -37,6 +60,8 @@ func Quote(src mem.RO) mem.RO {
 				}
 			} else if r == '\\' || r == '"' {
 				putByte('\\', byte(r))
+			} else if mode&HTML != 0 && (r == '<' || r == '>' || r == '&') {
+				putByte('\\', 'u', '0', '0', hexDigit[int(r>>4)], hexDigit[int(r&15)])
 			} else {
 				putByte(byte(r))
 			}
@@ -44,6 +69,15 @@ func Quote(src mem.RO) mem.RO {
 			continue
 		}
 
+		if mode&ASCII != 0 {
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				buf = appendU(appendU(buf, r1), r2)
+			} else {
+				buf = appendU(buf, r)
+			}
+			src = src.SliceFrom(n)
+			continue
+		}
 		switch r {
 		case '\ufffd': // replacement rune
 			buf = append(buf, `\ufffd`...)
@@ -60,5 +94,11 @@ func Quote(src mem.RO) mem.RO {
 		src = src.SliceFrom(n)
 	}
 	putByte('"')
-	return mem.B(buf)
+	return buf
+}
+
+// appendU appends a \u escape for r, which must be less than 0x10000.
+func appendU(buf []byte, r rune) []byte {
+	return append(buf, '\\', 'u',
+		hexDigit[int(r>>12)&15], hexDigit[int(r>>8)&15], hexDigit[int(r>>4)&15], hexDigit[int(r&15)])
 }
@@ -1,11 +1,13 @@
 // Copyright (C) 2023 Michael J. Fromberger. All Rights Reserved.
 
-// Package escape handles quoting and unquoting of JSON strings.
+// Package escape handles quoting and unquoting of JSON strings, and escaping
+// of JSON Pointer reference tokens.
 package escape
 
 import (
 	"errors"
 	"fmt"
+	"unicode/utf16"
 	"unicode/utf8"
 
 	"go4.org/mem"
@@ -14,11 +16,58 @@ import (
 // Unquote decodes a byte slice containing the JSON encoding of a string. The
 // input must have the enclosing double quotation marks already removed.
 //
-// Escape sequences are replaced with their unescaped equivalents. Invalid
-// escapes are replaced by the Unicode replacement rune. Unquote reports an
-// error for an incomplete escape sequence.
+// Escape sequences are replaced with their unescaped equivalents, and a pair
+// of escapes for a UTF-16 surrogate pair is replaced by the rune it encodes.
+// Invalid escapes, including unpaired surrogates, are replaced by the Unicode
+// replacement rune. Unquote reports an error for an incomplete escape
+// sequence.
 func Unquote(src mem.RO) ([]byte, error) {
-	dec := make([]byte, 0, src.Len())
+	dec, err := AppendUnquote(make([]byte, 0, src.Len()), src)
+	if err != nil {
+		return nil, err
+	}
+	return dec, nil
+}
+
+// UnquoteStrict decodes src as Unquote does, but reports an error for an
+// invalid escape, including an unpaired surrogate, instead of replacing it.
+func UnquoteStrict(src mem.RO) ([]byte, error) {
+	dec, err := appendUnquote(make([]byte, 0, src.Len()), src, strict)
+	if err != nil {
+		return nil, err
+	}
+	return dec, nil
+}
+
+// AppendUnquote appends the decoding of src, as described by Unquote, to dec
+// and returns the extended slice. If src contains an incomplete escape
+// sequence, AppendUnquote reports an error, and returns dec with its
+// original length.
+func AppendUnquote(dec []byte, src mem.RO) ([]byte, error) { return appendUnquote(dec, src, 0) }
+
+// UnquoteJSON5 decodes src as Unquote does, but also decodes the additional
+// escapes of JSON5: \', \v, \0, and \xHH stand for the corresponding
+// characters, an escaped line break is removed, and an escaped character with
+// no other meaning stands for itself.
+func UnquoteJSON5(src mem.RO) ([]byte, error) {
+	dec, err := appendUnquote(make([]byte, 0, src.Len()), src, json5)
+	if err != nil {
+		return nil, err
+	}
+	return dec, nil
+}
+
+// An unquoteMode selects the rules applied by appendUnquote.
+type unquoteMode uint8
+
+const (
+	strict unquoteMode = 1 << iota // report invalid escapes as errors
+	json5                          // decode the additional escapes of JSON5
+)
+
+// appendUnquote implements AppendUnquote, with the rules selected by mode.
+func appendUnquote(dec []byte, src mem.RO, mode unquoteMode) ([]byte, error) {
+	start := len(dec)
 	i := mem.IndexByte(src, '\\')
 	if i < 0 {
 		dec = mem.Append(dec, src)
@@ -39,7 +88,7 @@ func Unquote(src mem.RO) ([]byte, error) {
 		// replacement runes (utf8.RuneError == '\ufffd').
 		src = src.SliceFrom(i + 1)
 		if src.Len() == 0 {
-			return nil, errors.New("incomplete escape sequence")
+			return dec[:start], errors.New("incomplete escape sequence")
 		}
 		r, n := mem.DecodeRune(src)
 		if n == 0 {
@@ -62,16 +111,34 @@ func Unquote(src mem.RO) ([]byte, error) {
 			putByte('\t')
 		case 'u':
 			if src.Len() < 4 {
-				return nil, errors.New("incomplete Unicode escape")
+				return dec[:start], errors.New("incomplete Unicode escape")
 			}
 			v, err := parseHex(src.SliceTo(4))
-			if err != nil {
+			src = src.SliceFrom(4)
+			if err != nil && mode&strict != 0 {
+				return dec[:start], fmt.Errorf("invalid Unicode escape: %w", err)
+			} else if err != nil {
 				putRune(utf8.RuneError)
+			} else if utf16.IsSurrogate(rune(v)) {
+				r := decodePair(rune(v), &src)
+				if r == utf8.RuneError && mode&strict != 0 {
+					return dec[:start], fmt.Errorf("unpaired surrogate \\u%04x", v)
+				}
+				putRune(r)
 			} else {
 				putRune(rune(v))
 			}
-			src = src.SliceFrom(4)
 		default:
+			if mode&json5 != 0 {
+				var err error
+				dec, src, err = appendJSON5Escape(dec, r, src)
+				if err != nil {
+					return dec[:start], err
+				}
+				break
+			} else if mode&strict != 0 {
+				return dec[:start], fmt.Errorf("invalid escape %q", r)
+			}
 			putRune(utf8.RuneError)
 		}
 
@@ -86,6 +153,59 @@ func Unquote(src mem.RO) ([]byte, error) {
 	return dec, nil
 }
 
+// appendJSON5Escape appends the decoding of a JSON5 escape sequence to dec,
+// where r is the rune following the backslash and src is the input after r.
+// It returns the extended slice and the remaining input.
+func appendJSON5Escape(dec []byte, r rune, src mem.RO) ([]byte, mem.RO, error) {
+	switch r {
+	case '\'':
+		dec = append(dec, '\'')
+	case 'v':
+		dec = append(dec, '\v')
+	case '0':
+		dec = append(dec, 0)
+	case 'x':
+		if src.Len() < 2 {
+			return dec, src, errors.New("incomplete hex escape")
+		}
+		if v, err := parseHex(src.SliceTo(2)); err != nil {
+			dec = utf8.AppendRune(dec, utf8.RuneError)
+		} else {
+			dec = utf8.AppendRune(dec, rune(v))
+		}
+		src = src.SliceFrom(2)
+	case '\r':
+		if src.Len() != 0 && src.At(0) == '\n' {
+			src = src.SliceFrom(1)
+		}
+	case '\n', '\u2028', '\u2029':
+		// An escaped line break is removed.
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9', utf8.RuneError:
+		dec = utf8.AppendRune(dec, utf8.RuneError)
+	default:
+		dec = utf8.AppendRune(dec, r)
+	}
+	return dec, src, nil
+}
+
+// decodePair decodes a UTF-16 surrogate pair whose first half is r1. If the
+// front of *src is a \u escape for the second half, it is consumed and the
+// combined rune is returned; otherwise decodePair returns utf8.RuneError.
+func decodePair(r1 rune, src *mem.RO) rune {
+	if src.Len() < 6 || src.At(0) != '\\' || src.At(1) != 'u' {
+		return utf8.RuneError
+	}
+	v, err := parseHex(src.Slice(2, 6))
+	if err != nil {
+		return utf8.RuneError
+	}
+	r := utf16.DecodeRune(r1, rune(v))
+	if r != utf8.RuneError {
+		*src = src.SliceFrom(6)
+	}
+	return r
+}
+
 func parseHex(data mem.RO) (int64, error) {
 	var v int64
 	for i := 0; i < data.Len(); i++ {
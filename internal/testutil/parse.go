@@ -0,0 +1,21 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package testutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+)
+
+// MustParse parses s as a single JSON value, and fails t if that is not
+// possible.
+func MustParse(t testing.TB, s string) ast.Value {
+	t.Helper()
+	v, err := ast.ParseSingle(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Parse %q: %v", s, err)
+	}
+	return v
+}
@@ -0,0 +1,241 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import (
+	"bytes"
+	"io"
+	"unicode"
+)
+
+// This file implements the extensions to the lexical grammar of JSON defined
+// by JSON5 (https://spec.json5.org), which the scanner accepts when the
+// AllowJSON5 option is enabled.
+
+// scanNumber5 scans a JSON5 number, which may also have a leading "+", a
+// leading or trailing decimal point, or hexadecimal digits, or be Infinity or
+// NaN. Hexadecimal numbers are reported as Integer.
+func (s *Scanner) scanNumber5(start rune) error {
+	s.put(start)
+	ch := start
+	if start == '+' || start == '-' {
+		next, err := s.rune()
+		if err != nil {
+			return s.failf("want number, got error: %w", err)
+		}
+		s.put(next)
+		ch = next
+	}
+
+	s.tok = Integer
+	switch {
+	case ch == 'I' || ch == 'N':
+		return s.scanNonFinite(-1) // already recorded
+
+	case ch == '0':
+		next, err := s.peek()
+		if err != nil && err != io.EOF {
+			return s.fail(err)
+		} else if next == 'x' || next == 'X' {
+			s.rune()
+			s.put(next)
+			if nr, err := s.readRun(isHexDigit); err != nil {
+				return err
+			} else if nr == 0 {
+				return s.failf("no digits after %q", "0x")
+			}
+			return nil
+		} else if isDigit(next) {
+			return s.failf("extra leading zeroes")
+		}
+
+	case isDigit(ch):
+		if _, err := s.readRun(isDigit); err != nil {
+			return err
+		}
+
+	case ch == '.':
+		s.tok = Number
+		if nr, err := s.readRun(isDigit); err != nil {
+			return err
+		} else if nr == 0 {
+			return s.failf("no digits after decimal point")
+		}
+		return s.scanExponent5()
+
+	default:
+		return s.failf("got %q, want digit", ch)
+	}
+
+	// Digits after a decimal point are optional.
+	if next, err := s.peek(); err != nil && err != io.EOF {
+		return s.fail(err)
+	} else if next == '.' {
+		s.rune()
+		s.put(next)
+		s.tok = Number
+		if _, err := s.readRun(isDigit); err != nil {
+			return err
+		}
+	}
+	return s.scanExponent5()
+}
+
+// scanExponent5 scans the exponent of a JSON5 number, if there is one.
+func (s *Scanner) scanExponent5() error {
+	next, err := s.peek()
+	if err == io.EOF || (err == nil && next != 'e' && next != 'E') {
+		return nil
+	} else if err != nil {
+		return s.fail(err)
+	}
+	s.rune()
+	s.put(next)
+	s.tok = Number
+	ch, err := s.require(isExpStart, "sign or digit")
+	if err != nil {
+		return err
+	}
+	s.put(ch)
+	if nr, err := s.readRun(isDigit); err != nil {
+		return err
+	} else if nr == 0 && (ch == '-' || ch == '+') {
+		return s.failf("missing exponent digits")
+	}
+	return nil
+}
+
+// scanIdent scans an identifier. The identifiers true, false, and null are
+// reported as constants, and Infinity and NaN as numbers if those are allowed.
+// Any other identifier is reported as a String, and is valid only as an object
+// key.
+func (s *Scanner) scanIdent(first rune) error {
+	s.put(first)
+	if _, err := s.readRun(isIdentRune); err != nil {
+		return err
+	}
+	s.ident = true
+	switch string(s.Text()) {
+	case "true":
+		s.tok = True
+	case "false":
+		s.tok = False
+	case "null":
+		s.tok = Null
+	case "Infinity":
+		s.tok = Infinity
+	case "NaN":
+		s.tok = NaN
+	default:
+		s.tok = String
+	}
+	if (s.tok == Infinity || s.tok == NaN) && !s.json5 && !s.nonfin {
+		s.tok = String // a key that happens to be the name of a number
+	}
+	return nil
+}
+
+// scanNonFinite scans NaN, Infinity, or -Infinity, beginning with first. If
+// first < 0, it has already been recorded. A leading "+" is accepted only for
+// JSON5, and a sign for NaN is ignored.
+func (s *Scanner) scanNonFinite(first rune) error {
+	if first >= 0 {
+		s.put(first)
+	}
+	if _, err := s.readRun(isIdentRune); err != nil {
+		return err
+	}
+	text := s.Text()
+	name := string(bytes.TrimLeft(text, "+-"))
+	if len(name) < len(text)-1 || (text[0] == '+' && !s.json5) {
+		return s.failf("invalid number %q", text)
+	}
+	switch {
+	case name == "NaN":
+		s.tok = NaN
+	case name == "Infinity" && text[0] == '-':
+		s.tok = NegInfinity
+	case name == "Infinity":
+		s.tok = Infinity
+	default:
+		return s.failf("invalid number %q", text)
+	}
+	return nil
+}
+
+// escape5 handles the character ch following a backslash in a JSON5 string.
+// It reports false if ch does not begin a valid escape.
+func (s *Scanner) escape5(ch rune) (bool, error) {
+	switch {
+	case ch == '\'' || ch == 'v' || ch == '0':
+		s.put(ch)
+		if ch == '0' {
+			if next, err := s.peek(); err == nil && isDigit(next) {
+				return false, nil
+			}
+		}
+	case ch == 'x':
+		s.put(ch)
+		for i := 0; i < 2; i++ {
+			hex, err := s.require(isHexDigit, "hex digit")
+			if err != nil {
+				return false, err
+			}
+			s.put(hex)
+		}
+	case ch == '\n' || ch == '\r' || ch == '\u2028' || ch == '\u2029':
+		s.put(ch) // a line continuation
+		if ch == '\r' {
+			if next, err := s.peek(); err == nil && next == '\n' {
+				s.rune()
+				s.put(next)
+				ch = next
+			}
+		}
+		if ch == '\n' {
+			s.eline++
+			s.ecol = 0
+		}
+	case isDigit(ch):
+		return false, nil
+	default:
+		s.put(ch) // any other character escapes itself
+	}
+	return true, nil
+}
+
+// readRun consumes runes matching f from the input, stopping before the first
+// rune that does not match, and reports the number of runes consumed.
+func (s *Scanner) readRun(f func(rune) bool) (int, error) {
+	nr, _, err := s.readWhile(f)
+	if err == io.EOF {
+		return nr, nil
+	} else if err != nil {
+		return nr, err
+	}
+	s.unrune()
+	return nr, nil
+}
+
+// peek returns the next rune of the input without consuming it.
+func (s *Scanner) peek() (rune, error) {
+	ch, err := s.rune()
+	if err == nil {
+		s.unrune()
+	}
+	return ch, err
+}
+
+func isSpace5(ch rune) bool {
+	return isSpace(ch) || ch == '\v' || ch == '\f' || ch == '\uFEFF' || unicode.Is(unicode.Zs, ch) ||
+		ch == '\u2028' || ch == '\u2029'
+}
+
+func isNumStart5(ch rune) bool { return isNumStart(ch) || ch == '+' || ch == '.' }
+
+func isIdentStart(ch rune) bool { return ch == '$' || ch == '_' || unicode.IsLetter(ch) }
+
+func isIdentRune(ch rune) bool {
+	return isIdentStart(ch) || unicode.In(ch, unicode.Nd, unicode.Mn, unicode.Mc, unicode.Pc) ||
+		ch == '\u200C' || ch == '\u200D'
+}
@@ -4,6 +4,7 @@ package jwcc
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
@@ -32,15 +33,20 @@ func (a Array) JSON() string {
 	if len(a.Values) == 0 {
 		return "[]"
 	}
-	var sb strings.Builder
-	sb.WriteString("[")
-	sb.WriteString(a.Values[0].JSON())
-	for _, elt := range a.Values[1:] {
-		sb.WriteByte(',')
-		sb.WriteString(elt.JSON())
+	return string(a.AppendJSON(nil))
+}
+
+// AppendJSON appends the JSON encoding of a to buf, as described by
+// ast.AppendJSON.
+func (a Array) AppendJSON(buf []byte) []byte {
+	buf = append(buf, '[')
+	for i, v := range a.Values {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = ast.AppendJSON(buf, v)
 	}
-	sb.WriteByte(']')
-	return sb.String()
+	return append(buf, ']')
 }
 
 func (a Array) String() string { return fmt.Sprintf("Array(len=%d)", len(a.Values)) }
@@ -58,6 +64,10 @@ func (d *Datum) Comments() *Comments { return &d.com }
 
 func (d *Datum) Undecorate() ast.Value { return d.Value }
 
+// AppendJSON appends the JSON encoding of d to buf, as described by
+// ast.AppendJSON.
+func (d *Datum) AppendJSON(buf []byte) []byte { return ast.AppendJSON(buf, d.Value) }
+
 // A Document is a single value with optional trailing comments.
 type Document struct {
 	Value
@@ -69,6 +79,10 @@ func (d *Document) Comments() *Comments { return &d.com }
 
 func (d *Document) Undecorate() ast.Value { return d.Value.Undecorate() }
 
+// AppendJSON appends the JSON encoding of d to buf, as described by
+// ast.AppendJSON.
+func (d *Document) AppendJSON(buf []byte) []byte { return ast.AppendJSON(buf, d.Value) }
+
 // A Member is a key-value pair in an object.
 type Member struct {
 	Key   ast.Text
@@ -83,14 +97,14 @@ func (m *Member) Undecorate() ast.Value {
 	return &ast.Member{Key: m.Key, Value: m.Value.Undecorate()}
 }
 
-func (m Member) JSON() string {
-	k := m.Key.Quote().JSON()
-	v := m.Value.JSON()
-	buf := make([]byte, len(k)+len(v)+1)
-	n := copy(buf, k)
-	buf[n] = ':'
-	copy(buf[n+1:], v)
-	return string(buf)
+func (m Member) JSON() string { return string(m.AppendJSON(nil)) }
+
+// AppendJSON appends the JSON encoding of m to buf, as described by
+// ast.AppendJSON.
+func (m Member) AppendJSON(buf []byte) []byte {
+	buf = ast.AppendJSON(buf, m.Key.Quote())
+	buf = append(buf, ':')
+	return ast.AppendJSON(buf, m.Value)
 }
 
 func (m Member) String() string { return fmt.Sprintf("Member(key=%q)", m.Key) }
@@ -101,6 +115,103 @@ func Field(key string, value any) *Member {
 	return &Member{Key: ast.String(key), Value: ToValue(value)}
 }
 
+// ObjectOf constructs an object from alternating keys and values. Each key
+// must be a string, and each value must be a string, int, float, bool, nil,
+// or ast.Value. Values that are not already jwcc.Value are decorated with
+// empty comments. It panics if the arguments do not have this form.
+func ObjectOf(pairs ...any) *Object {
+	if len(pairs)%2 != 0 {
+		panic("odd number of arguments to ObjectOf")
+	}
+	out := &Object{Members: make([]*Member, len(pairs)/2)}
+	for i := range out.Members {
+		key, ok := pairs[2*i].(string)
+		if !ok {
+			panic(fmt.Sprintf("invalid key %T", pairs[2*i]))
+		}
+		out.Members[i] = &Member{Key: ast.String(key), Value: decorateAny(pairs[2*i+1])}
+	}
+	return out
+}
+
+// ArrayOf constructs an array of the given values, each of which must be a
+// string, int, float, bool, nil, or ast.Value. Values that are not already
+// jwcc.Value are decorated with empty comments.
+func ArrayOf(vs ...any) *Array {
+	out := &Array{Values: make([]Value, len(vs))}
+	for i, v := range vs {
+		out.Values[i] = decorateAny(v)
+	}
+	return out
+}
+
+// decorateAny converts v to a Value, as ToValue does, except that an
+// ast.Object or ast.Array is decorated recursively.
+func decorateAny(v any) Value {
+	if av, ok := v.(ast.Value); ok {
+		if _, ok := av.(Value); !ok {
+			return Decorate(av)
+		}
+	}
+	return ToValue(v)
+}
+
+// An ObjectBuilder constructs an object one member at a time. Each method
+// returns the builder, so that calls can be chained:
+//
+//	obj := jwcc.BuildObject().
+//	   Set("name", "example").SetComment("The name of the service.").
+//	   Set("port", 8080).SetLineComment("default").
+//	   Object()
+type ObjectBuilder struct {
+	obj  *Object
+	last *Member // the most recently set member, or nil
+}
+
+// BuildObject returns a new ObjectBuilder for an empty object.
+func BuildObject() *ObjectBuilder { return &ObjectBuilder{obj: new(Object)} }
+
+// Set sets the value of the member with the given key, adding it at the end
+// of the object if it does not already exist. The value must be a string,
+// int, float, bool, nil, or ast.Value, as for ObjectOf. Subsequent comments
+// apply to this member.
+func (b *ObjectBuilder) Set(key string, value any) *ObjectBuilder {
+	if i := b.obj.IndexKey(ast.TextEqual(key)); i >= 0 {
+		b.last = b.obj.Members[i]
+		b.last.Value = decorateAny(value)
+	} else {
+		b.last = &Member{Key: ast.String(key), Value: decorateAny(value)}
+		b.obj.Members = append(b.obj.Members, b.last)
+	}
+	return b
+}
+
+// SetComment sets the comments before the most recently set member, or before
+// the object itself if no member has been set. Each argument is a separate
+// comment; comment markers are added if they are not present.
+func (b *ObjectBuilder) SetComment(text ...string) *ObjectBuilder {
+	b.comments().Before = text
+	return b
+}
+
+// SetLineComment sets the comment at the end of the line of the most recently
+// set member, or of the object itself if no member has been set.
+func (b *ObjectBuilder) SetLineComment(text string) *ObjectBuilder {
+	b.comments().Line = text
+	return b
+}
+
+func (b *ObjectBuilder) comments() *Comments {
+	if b.last != nil {
+		return b.last.Comments()
+	}
+	return b.obj.Comments()
+}
+
+// Object returns the object constructed by b. The builder retains the object,
+// so later calls to b modify it.
+func (b *ObjectBuilder) Object() *Object { return b.obj }
+
 // An Object is a collection of key-value members.
 type Object struct {
 	Members []*Member
@@ -146,15 +257,20 @@ func (o Object) JSON() string {
 	if len(o.Members) == 0 {
 		return "{}"
 	}
-	var sb strings.Builder
-	sb.WriteByte('{')
-	sb.WriteString(o.Members[0].JSON())
-	for _, elt := range o.Members[1:] {
-		sb.WriteByte(',')
-		sb.WriteString(elt.JSON())
+	return string(o.AppendJSON(nil))
+}
+
+// AppendJSON appends the JSON encoding of o to buf, as described by
+// ast.AppendJSON.
+func (o Object) AppendJSON(buf []byte) []byte {
+	buf = append(buf, '{')
+	for i, m := range o.Members {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = m.AppendJSON(buf)
 	}
-	sb.WriteByte('}')
-	return sb.String()
+	return append(buf, '}')
 }
 
 func (o Object) String() string { return fmt.Sprintf("Object(len=%d)", len(o.Members)) }
@@ -166,6 +282,179 @@ func (o Object) Sort() {
 	})
 }
 
+// SortStable sorts the object in ascending order by key, keeping members with
+// equal keys in their original order.
+func (o Object) SortStable() {
+	sort.SliceStable(o.Members, func(i, j int) bool {
+		return o.Members[i].Key.String() < o.Members[j].Key.String()
+	})
+}
+
+// SortBy sorts the object in the order defined by less, keeping members that
+// are not ordered by less in their original order.
+func (o Object) SortBy(less func(a, b *Member) bool) {
+	sort.SliceStable(o.Members, func(i, j int) bool { return less(o.Members[i], o.Members[j]) })
+}
+
+// SortKeys sorts the object so that members whose keys are listed in order
+// come first, in the order listed. The remaining members follow in their
+// original order. Keys are compared case-sensitively. Comments remain
+// attached to their members.
+func (o Object) SortKeys(order []string) {
+	pos := make(map[string]int, len(order))
+	for i, key := range order {
+		if _, ok := pos[key]; !ok {
+			pos[key] = i
+		}
+	}
+	rank := func(m *Member) int {
+		if i, ok := pos[m.Key.String()]; ok {
+			return i
+		}
+		return len(order)
+	}
+	o.SortBy(func(a, b *Member) bool { return rank(a) < rank(b) })
+}
+
+// SortPreservingGroups sorts the object in the order defined by less, as
+// SortBy does, but only within each group of members. If less == nil, members
+// are sorted by key. A group is a run of consecutive members not separated by
+// a blank line in the source from which the object was parsed. Members that
+// have no recorded location, for example because they were constructed
+// rather than parsed, are in the same group as the member before them.
+//
+// Comments remain attached to their members, except that if the first member
+// of a group has comments separated from it by a blank line, those comments
+// are treated as a heading for the group, and remain at the start of the
+// group after sorting.
+func (o Object) SortPreservingGroups(less func(a, b *Member) bool) {
+	if less == nil {
+		less = func(a, b *Member) bool { return a.Key.String() < b.Key.String() }
+	}
+	for start := 0; start < len(o.Members); {
+		end := start + 1
+		for end < len(o.Members) && !startsGroup(o.Members[end-1], o.Members[end]) {
+			end++
+		}
+		grp := o.Members[start:end]
+		var head []string
+		if c := grp[0].Comments(); len(c.Before) != 0 && c.Before[len(c.Before)-1] == "" {
+			head, c.Before = c.Before, nil
+		}
+		sort.SliceStable(grp, func(i, j int) bool { return less(grp[i], grp[j]) })
+		if head != nil {
+			c := grp[0].Comments()
+			c.Before = append(head, c.Before...)
+		}
+		start = end
+	}
+}
+
+// startsGroup reports whether m is separated from prev by a blank line in the
+// source, accounting for the comments before m.
+func startsGroup(prev, m *Member) bool {
+	ploc, mloc := ValueLocation(prev), ValueLocation(m)
+	if ploc.Last.Line == 0 || mloc.First.Line == 0 {
+		return false // no location recorded
+	}
+	first := mloc.First.Line
+	for _, c := range m.Comments().Before {
+		first -= strings.Count(strings.TrimSuffix(c, "\n"), "\n") + 1
+	}
+	return first > ploc.Last.Line+1
+}
+
+// Set sets the value of the first member of o whose key is exactly key, and
+// returns that member. If o has no such member, a new member is added at the
+// end of o. The value must be a string, int, float, bool, nil, or ast.Value,
+// as for ObjectOf. If the new value has no comments, it takes the comments
+// of the value it replaces.
+func (o *Object) Set(key string, value any) *Member {
+	return o.SetAt(len(o.Members), key, value)
+}
+
+// SetAt is as Set, except that if o has no member with the given key, the new
+// member is inserted at offset i of o, or at the end if i >= len(o.Members).
+// An existing member is not moved.
+func (o *Object) SetAt(i int, key string, value any) *Member {
+	v := decorateAny(value)
+	if j := o.IndexKey(ast.TextEqual(key)); j >= 0 {
+		m := o.Members[j]
+		m.Value = replace(m.Value, v)
+		return m
+	}
+	m := &Member{Key: ast.String(key), Value: v}
+	o.Members = slices.Insert(o.Members, min(max(i, 0), len(o.Members)), m)
+	return m
+}
+
+// Delete removes the first member of o whose key is exactly key, and returns
+// it, or returns nil if o has no such member. If keepComments is true, the
+// comments before the deleted member are moved to the member that follows
+// it, or to the end of o if it was the last member, so that a comment
+// introducing a group of members is not lost with the first of them.
+func (o *Object) Delete(key string, keepComments bool) *Member {
+	i := o.IndexKey(ast.TextEqual(key))
+	if i < 0 {
+		return nil
+	}
+	m := o.Members[i]
+	o.Members = slices.Delete(o.Members, i, i+1)
+	if keepComments {
+		var next Value
+		if i < len(o.Members) {
+			next = o.Members[i]
+		}
+		keepBefore(m, next, o.Comments())
+	}
+	return m
+}
+
+// Insert inserts the given values into a at offset i, which must be between
+// 0 and len(a.Values) inclusive. Each value must be a string, int, float,
+// bool, nil, or ast.Value, as for ArrayOf.
+func (a *Array) Insert(i int, vs ...any) {
+	dv := make([]Value, len(vs))
+	for j, v := range vs {
+		dv[j] = decorateAny(v)
+	}
+	a.Values = slices.Insert(a.Values, i, dv...)
+}
+
+// Remove removes and returns the element of a at offset i, which must be
+// between 0 and len(a.Values)-1 inclusive. If keepComments is true, the
+// comments before the removed element are moved to the element that follows
+// it, or to the end of a if it was the last element.
+func (a *Array) Remove(i int, keepComments bool) Value {
+	v := a.Values[i]
+	a.Values = slices.Delete(a.Values, i, i+1)
+	if keepComments {
+		var next Value
+		if i < len(a.Values) {
+			next = a.Values[i]
+		}
+		keepBefore(v, next, a.Comments())
+	}
+	return v
+}
+
+// keepBefore moves the comments before v to the beginning of the comments
+// before next or, if next == nil, to the end comments of the container whose
+// comments are end.
+func keepBefore(v, next Value, end *Comments) {
+	com := v.Comments()
+	if len(com.Before) == 0 {
+		return
+	}
+	if next != nil {
+		nc := next.Comments()
+		nc.Before = append(slices.Clip(com.Before), nc.Before...)
+	} else {
+		end.End = append(end.End, com.Before...)
+	}
+	com.Before = nil
+}
+
 // commentStub is a stack placeholder for a comment seen during parsing.
 // This type does not appear in a completed AST.
 type commentStub struct {
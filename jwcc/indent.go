@@ -8,15 +8,122 @@ import (
 	"io"
 	"strings"
 	"text/tabwriter"
+	"unicode/utf8"
 )
 
 // A Formatter carries the settings for pretty-printing JWCC values.
 // A zero value is ready for use with default settings.
-type Formatter struct{}
+type Formatter struct {
+	// Indent is the text added for each level of indentation.
+	// If empty, two spaces are used, or a tab if UseTabs is true.
+	Indent string
+
+	// UseTabs, if true, pads aligned object values to their column with tabs
+	// rather than spaces, and makes a tab the default indentation.
+	UseTabs bool
+
+	// MaxInlineItems is the maximum number of elements an array may have to be
+	// rendered on a single line. If zero, a default of 3 is used.  If
+	// negative, non-empty arrays and objects are never rendered on one line.
+	MaxInlineItems int
+
+	// MaxLineWidth, if positive, is the maximum width in runes of an array or
+	// object rendered on a single line, not counting indentation or the key of
+	// its member. An array or object whose single-line rendering would be
+	// wider than this is rendered on multiple lines, even if it has no more
+	// than MaxInlineItems elements. If zero or negative, width is not limited.
+	MaxLineWidth int
+
+	// NoAlign, if true, disables aligning the values of consecutive
+	// single-line object members in a column. Alignment is enabled by default
+	// so that a zero Formatter produces the conventional layout.
+	NoAlign bool
+
+	// NoTrailingCommas, if true, omits the comma after the last element of an
+	// array or object rendered on multiple lines. If the value has no
+	// comments, the result is then valid JSON.
+	NoTrailingCommas bool
+
+	// NoBlankLines, if true, omits the blank line that otherwise separates an
+	// object member from its neighbors when either of them is rendered on
+	// multiple lines. Blank lines recorded in comments are kept.
+	NoBlankLines bool
+}
+
+func (f Formatter) indent() string {
+	ind := f.Indent
+	if ind == "" {
+		ind = "  "
+		if f.UseTabs {
+			ind = "\t"
+		}
+	}
+	// Escape the indentation so the tabwriter does not interpret any tabs in
+	// it as column separators.
+	const esc = "\xff" // tabwriter.Escape
+	return esc + ind + esc
+}
+
+func (f Formatter) maxLineItems() int {
+	if f.MaxInlineItems == 0 {
+		return 3
+	}
+	return f.MaxInlineItems
+}
 
-func (f Formatter) indent() string { return "  " }
+// Preset returns a Formatter with the settings of a named formatting style,
+// and reports whether name is a known preset. The names are:
+//
+//	"default"  -- the settings of a zero Formatter
+//	"hujson"   -- tab indentation, in the style of the Tailscale hujson package
+//	"prettier" -- two-space indentation without alignment, similar to Prettier
+//	"vscode"   -- four-space indentation without alignment, similar to VS Code
+//
+// The "hujson", "prettier", and "vscode" presets omit trailing commas, and do
+// not separate object members with blank lines. For the "prettier" and
+// "vscode" presets, non-empty arrays and objects are always rendered on
+// multiple lines. Note that for a document with comments, hujson.Format adds
+// trailing commas, which the "hujson" preset does not.
+func Preset(name string) (Formatter, bool) {
+	switch name {
+	case "default":
+		return Formatter{}, true
+	case "hujson":
+		return Formatter{Indent: "\t", NoBlankLines: true, NoTrailingCommas: true}, true
+	case "prettier":
+		return Formatter{Indent: "  ", MaxInlineItems: -1, NoAlign: true, NoBlankLines: true, NoTrailingCommas: true}, true
+	case "vscode":
+		return Formatter{Indent: "    ", MaxInlineItems: -1, NoAlign: true, NoBlankLines: true, NoTrailingCommas: true}, true
+	}
+	return Formatter{}, false
+}
 
-func (f Formatter) maxLineItems() int { return 3 }
+// A Hint is a set of flags that override the Formatter settings for a single
+// value. Hints are stored alongside the comments of a value, and are copied
+// along with them, but are not comments and are not affected by Clear.
+type Hint byte
+
+const (
+	// HintInline requests that an array or object be rendered on a single
+	// line regardless of its length, if neither it nor any of the values it
+	// contains have comments other than a line comment on the value itself.
+	HintInline Hint = 1 << iota
+
+	// HintMultiline requests that a non-empty array or object be rendered on
+	// multiple lines, even if it could fit on one. It takes precedence over
+	// HintInline.
+	HintMultiline
+
+	// HintNoAlign requests that the values of the members of an object not be
+	// aligned in a column, as if NoAlign were set for that object only.
+	HintNoAlign
+)
+
+// Hint returns the formatting hints recorded in c.
+func (c Comments) Hint() Hint { return c.hint }
+
+// SetHint replaces the formatting hints recorded in c with h.
+func (c *Comments) SetHint(h Hint) { c.hint = h }
 
 // Format renders a pretty-printed representation of v to w with default
 // settings.
@@ -38,7 +145,11 @@ func FormatToString(v Value) string {
 // Format renders a pretty-printed representation of v to w using the settings
 // from f.
 func (f Formatter) Format(w io.Writer, v Value) error {
-	tw := tabwriter.NewWriter(w, 4, 4, 1, ' ', 0)
+	pad := byte(' ')
+	if f.UseTabs {
+		pad = '\t'
+	}
+	tw := tabwriter.NewWriter(w, 4, 4, 1, pad, tabwriter.StripEscape)
 	f.formatValue(tw, v, "", "", true)
 	return tw.Flush()
 }
@@ -76,28 +187,22 @@ func (f Formatter) formatValue(w writeFlusher, v Value, init, indent string, lin
 
 func (f Formatter) formatArray(w writeFlusher, a *Array, init, indent string) bool {
 	if f.isBoring(a) {
-		io.WriteString(w, "[")
-		for i, v := range a.Values {
-			if i > 0 {
-				io.WriteString(w, ", ")
-			}
-			io.WriteString(w, v.JSON())
-		}
-		io.WriteString(w, "]")
+		fmt.Fprint(w, init, inlineText(a))
 		return true
 	}
 
 	// Before comments were already written.
 	fmt.Fprint(w, init, "[\n")
 	adent := indent + f.indent()
-	for _, v := range a.Values {
+	for i, v := range a.Values {
 		f.formatValue(w, v, adent, adent, false)
 
 		// Render a line comment (if there is one) outside the comma.
+		comma := f.comma(i, len(a.Values))
 		if ln := v.Comments().Line; ln != "" {
-			fmt.Fprint(w, ",", indentComment(ln, "\t"), "\n")
+			fmt.Fprint(w, comma, indentComment(ln, "\t"), "\n")
 		} else {
-			fmt.Fprint(w, ",\n")
+			fmt.Fprint(w, comma, "\n")
 		}
 	}
 
@@ -115,14 +220,7 @@ func (f Formatter) formatArray(w writeFlusher, a *Array, init, indent string) bo
 
 func (f Formatter) formatObject(w writeFlusher, o *Object, init, indent string) bool {
 	if f.isBoring(o) {
-		fmt.Fprint(w, "{")
-		for i, m := range o.Members {
-			if i > 0 {
-				io.WriteString(w, ", ")
-			}
-			fmt.Fprint(w, m.Key.JSON(), ": ", m.Value.JSON())
-		}
-		io.WriteString(w, "}")
+		fmt.Fprint(w, init, inlineText(o))
 		return true
 	}
 
@@ -135,12 +233,12 @@ func (f Formatter) formatObject(w writeFlusher, o *Object, init, indent string)
 		// predecessor was non-boring.
 		prevBoring, curBoring = curBoring, f.isBoring(m)
 
-		if i != 0 && !(prevBoring && curBoring) {
+		if i != 0 && !(prevBoring && curBoring) && !f.NoBlankLines {
 			io.WriteString(w, "\n")
 		}
 
 		f.indentComments(w, m.Comments().Before, mdent, false)
-		fmt.Fprint(w, mdent, m.Key.JSON(), f.objSep(m.Value))
+		fmt.Fprint(w, mdent, m.Key.JSON(), f.objSep(o, m.Value))
 
 		if len(m.Value.Comments().Before) == 0 {
 			f.formatValue(w, m.Value, "", mdent, false)
@@ -150,10 +248,11 @@ func (f Formatter) formatObject(w writeFlusher, o *Object, init, indent string)
 		}
 
 		// Render a line comment (if there is one) outside the comma.
+		comma := f.comma(i, len(o.Members))
 		if ln := m.Comments().Line; ln != "" {
-			fmt.Fprint(w, ",", indentComment(ln, "\t"), "\n")
+			fmt.Fprint(w, comma, indentComment(ln, "\t"), "\n")
 		} else {
-			fmt.Fprint(w, ",\n")
+			fmt.Fprint(w, comma, "\n")
 		}
 		if ec := m.Comments().End; len(ec) != 0 {
 			f.indentComments(w, ec, mdent, false)
@@ -172,11 +271,55 @@ func (f Formatter) formatObject(w writeFlusher, o *Object, init, indent string)
 	return false
 }
 
-// objSep returns a key-value separator for the given value.
+// comma returns the separator to write after element i of n in a multi-line
+// array or object.
+func (f Formatter) comma(i, n int) string {
+	if f.NoTrailingCommas && i == n-1 {
+		return ""
+	}
+	return ","
+}
+
+// inlineText returns the single-line rendering of v, with a space after each
+// comma and colon, as for an array or object whose contents are boring.
+func inlineText(v Value) string {
+	var sb strings.Builder
+	writeInline(&sb, v)
+	return sb.String()
+}
+
+func writeInline(sb *strings.Builder, v Value) {
+	switch t := v.(type) {
+	case *Array:
+		sb.WriteString("[")
+		for i, e := range t.Values {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeInline(sb, e)
+		}
+		sb.WriteString("]")
+	case *Object:
+		sb.WriteString("{")
+		for i, m := range t.Members {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(m.Key.JSON())
+			sb.WriteString(": ")
+			writeInline(sb, m.Value)
+		}
+		sb.WriteString("}")
+	default:
+		sb.WriteString(v.JSON())
+	}
+}
+
+// objSep returns a key-value separator for the given value of a member of o.
 // Boring values get indented so they line up in columns;
 // non-boring values are stapled directly to the key.
-func (f Formatter) objSep(v Value) string {
-	if f.isBoring(v) {
+func (f Formatter) objSep(o *Object, v Value) string {
+	if !f.NoAlign && o.com.hint&HintNoAlign == 0 && f.isBoring(v) {
 		return ":\t"
 	}
 	return ": "
@@ -199,13 +342,17 @@ func (f Formatter) isBoring(v Value) bool {
 	case *Array:
 		if len(com.Before) != 0 || len(com.End) != 0 {
 			return false
+		} else if com.hint&HintMultiline != 0 && len(t.Values) != 0 {
+			return false
+		} else if com.hint&HintInline != 0 {
+			return noComments(t)
 		}
 		for i, v := range t.Values {
 			if !f.isBoring(v) || i >= f.maxLineItems() {
 				return false
 			}
 		}
-		return true
+		return f.fitsLine(t)
 	case *Datum:
 		return t.Comments().IsEmpty()
 	case *Member:
@@ -213,9 +360,13 @@ func (f Formatter) isBoring(v Value) bool {
 	case *Object:
 		if len(com.Before) != 0 || len(com.End) != 0 {
 			return false
+		} else if com.hint&HintMultiline != 0 && len(t.Members) != 0 {
+			return false
+		} else if com.hint&HintInline != 0 {
+			return noComments(t)
 		}
-		if len(t.Members) == 1 {
-			return t.Members[0].Comments().IsEmpty() && f.isBoring(t.Members[0].Value)
+		if len(t.Members) == 1 && f.maxLineItems() > 0 {
+			return t.Members[0].Comments().IsEmpty() && f.isBoring(t.Members[0].Value) && f.fitsLine(t)
 		}
 		return len(t.Members) == 0
 	default:
@@ -223,6 +374,32 @@ func (f Formatter) isBoring(v Value) bool {
 	}
 }
 
+// fitsLine reports whether the single-line rendering of v is no wider than
+// the limit set by f.MaxLineWidth.
+func (f Formatter) fitsLine(v Value) bool {
+	return f.MaxLineWidth <= 0 || utf8.RuneCountInString(inlineText(v)) <= f.MaxLineWidth
+}
+
+// noComments reports whether the contents of v have no comments.
+// Comments on v itself are not considered.
+func noComments(v Value) bool {
+	switch t := v.(type) {
+	case *Array:
+		for _, e := range t.Values {
+			if !e.Comments().IsEmpty() || !noComments(e) {
+				return false
+			}
+		}
+	case *Object:
+		for _, m := range t.Members {
+			if !m.Comments().IsEmpty() || !m.Value.Comments().IsEmpty() || !noComments(m.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (f Formatter) indentComments(w writeFlusher, ss []string, indent string, inlineOK bool) {
 	if inlineOK && f.canInlineComment(ss) {
 		fmt.Fprint(w, indentComment(ss[0], indent), " ")
@@ -6,6 +6,7 @@ package jwcc
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 
@@ -66,6 +67,7 @@ type Comments struct {
 	End    []string
 
 	vloc jtree.Location // the location of the value this is attached to
+	hint Hint           // formatting hints for the value
 }
 
 // IsEmpty reports whether c is "empty", meaning it has no non-empty comment
@@ -83,16 +85,66 @@ func ValueLocation(v Value) jtree.Location { return v.Comments().vloc }
 // Parse parses and returns a single JWCC value from r.  If r contains data
 // after the first value, apart from comments and whitespace, Parse returns the
 // first value along with an ast.ErrExtraInput error.
-func Parse(r io.Reader) (*Document, error) {
+//
+// If parsing fails partway through the value, Parse returns a document
+// containing the portion of the value that was complete before the error,
+// along with an error that wraps both ast.ErrIncomplete and the underlying
+// error. In the partial value, objects and arrays that were not closed contain
+// only their members and elements that were complete before the error, and
+// comments following the last complete value are discarded.
+func Parse(r io.Reader) (*Document, error) { return ParseWithOptions(r, nil) }
+
+// FormatError renders a diagnostic for err, an error reported by parsing src,
+// as described by ast.FormatError.
+func FormatError(err error, src []byte) string { return ast.FormatError(err, src) }
+
+// ParseOptions are settings for the ParseWithOptions function.
+// A nil *ParseOptions is ready for use, and accepts only JWCC.
+type ParseOptions struct {
+	// Accept JSON5 syntax, as described by jtree.Scanner.AllowJSON5. The
+	// values constructed from JSON5 input are converted to standard JSON, so
+	// formatting the result produces JWCC.
+	JSON5 bool
+
+	// Accept unquoted identifiers as object keys, as described by
+	// jtree.Scanner.AllowUnquotedKeys. The keys are quoted in the result.
+	UnquotedKeys bool
+
+	// If non-nil, use this to intern the keys of objects, as described by
+	// ast.Parser.SetInterner.
+	Interner jtree.StringInterner
+}
+
+func (o *ParseOptions) json5() bool { return o != nil && o.JSON5 }
+
+func (o *ParseOptions) unquotedKeys() bool { return o != nil && o.UnquotedKeys }
+
+func (o *ParseOptions) interner() jtree.StringInterner {
+	if o == nil || o.Interner == nil {
+		return make(jtree.Interner)
+	}
+	return o.Interner
+}
+
+// ParseWithOptions parses and returns a single JWCC value from r, as Parse
+// does, subject to the given options.
+func ParseWithOptions(r io.Reader, opts *ParseOptions) (*Document, error) {
 	st := jtree.NewStream(r)
 	st.AllowComments(true)
 	st.AllowTrailingCommas(true)
+	st.AllowJSON5(opts.json5())
+	st.AllowUnquotedKeys(opts.unquotedKeys())
 
-	h := &parseHandler{ic: make(jtree.Interner)}
-	if err := st.ParseOne(h); err != nil {
+	h := &Builder{ic: opts.interner()}
+	if err := st.ParseOne(h); err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		if v := h.partial(); v != nil {
+			return &Document{Value: v}, fmt.Errorf("%w: %w", ast.ErrIncomplete, err)
+		}
 		return nil, err
 	} else if len(h.stk) != 1 {
-		return nil, errors.New("incomplete value")
+		return nil, ast.ErrIncomplete
 	}
 	v := h.stk[0]
 	d := &Document{Value: v}
@@ -106,26 +158,91 @@ func Parse(r io.Reader) (*Document, error) {
 		d.com.End = com
 		d.com.vloc.Span.End = loc.Span.End
 		d.com.vloc.Last = loc.Last
-		if err != nil && !errors.Is(err, io.EOF) {
+		if err == nil {
+			return d, ast.ErrExtraInput // another complete value
+		} else if !errors.Is(err, io.EOF) {
 			return d, errors.Join(ast.ErrExtraInput, err)
 		}
 	}
 	return d, nil
 }
 
-// parseHandler implements the jtree.Handler interface for JWCC values.
-type parseHandler struct {
+// A Builder implements the jtree.Handler and jtree.CommentHandler interfaces
+// to construct JWCC values with their comments. A zero Builder is ready for
+// use. To record comments, the stream driving the Builder must be configured
+// to allow comments.
+//
+// A Builder may be driven by a jtree.Stream directly, or by another handler
+// that forwards the events it receives, for example one that adds its own
+// processing or error recovery. Each event must be forwarded to the Builder
+// in the order received.
+type Builder struct {
 	stk []Value
-	ic  jtree.Interner
+	ic  jtree.StringInterner
 	eof bool
 }
 
-func (h *parseHandler) BeginObject(loc jtree.Anchor) error {
+// NewBuilder constructs a new empty Builder.
+func NewBuilder() *Builder { return new(Builder) }
+
+// Result returns the first value constructed since the Builder was created or
+// last reset, as a Document, and removes it from the Builder. If no other
+// value follows it, any comments following the value are removed and recorded
+// as the End comments of the Document.
+//
+// If the value is not yet complete, Result reports ast.ErrIncomplete and has
+// no effect. If no value has been constructed, Result reports io.EOF.
+func (h *Builder) Result() (*Document, error) {
+	i := 0
+	for i < len(h.stk) {
+		if _, ok := h.stk[i].(commentStub); !ok {
+			break
+		}
+		i++
+	}
+	if i == len(h.stk) {
+		return nil, io.EOF
+	}
+	for _, v := range h.stk[i:] {
+		if isStub(v) {
+			return nil, ast.ErrIncomplete
+		}
+	}
+	v := h.stk[i]
+	d := &Document{Value: v}
+	vc := v.Comments()
+	d.com.vloc = vc.vloc
+	h.stk = append(h.stk[:i], h.stk[i+1:]...)
+
+	if !isCommentsOnly(h.stk[i:]) {
+		return d, nil // another value follows
+	}
+	_, loc, com := h.consumeComments()
+	d.com.End = com
+	d.com.vloc.Span.End = loc.Span.End
+	d.com.vloc.Last = loc.Last
+	return d, nil
+}
+
+// Reset discards all values and comments constructed so far, including
+// incomplete values.
+func (h *Builder) Reset() { h.stk = h.stk[:0]; h.eof = false }
+
+func isCommentsOnly(vs []Value) bool {
+	for _, v := range vs {
+		if _, ok := v.(commentStub); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Builder) BeginObject(loc jtree.Anchor) error {
 	h.pushValue(loc, &objectStub{})
 	return nil
 }
 
-func (h *parseHandler) EndObject(loc jtree.Anchor) error {
+func (h *Builder) EndObject(loc jtree.Anchor) error {
 	_, _, com := h.consumeComments() // trailing comments at the end of the object
 	for i := len(h.stk) - 1; i >= 0; i-- {
 		if stub, ok := h.stk[i].(*objectStub); ok {
@@ -150,12 +267,12 @@ func (h *parseHandler) EndObject(loc jtree.Anchor) error {
 	panic("unbalanced EndObject")
 }
 
-func (h *parseHandler) BeginArray(loc jtree.Anchor) error {
+func (h *Builder) BeginArray(loc jtree.Anchor) error {
 	h.pushValue(loc, &arrayStub{})
 	return nil
 }
 
-func (h *parseHandler) EndArray(loc jtree.Anchor) error {
+func (h *Builder) EndArray(loc jtree.Anchor) error {
 	_, _, com := h.consumeComments()
 	for i := len(h.stk) - 1; i >= 0; i-- {
 		if stub, ok := h.stk[i].(*arrayStub); ok {
@@ -179,16 +296,19 @@ func (h *parseHandler) EndArray(loc jtree.Anchor) error {
 	panic("unbalanced EndArray")
 }
 
-func (h *parseHandler) BeginMember(loc jtree.Anchor) error {
+func (h *Builder) BeginMember(loc jtree.Anchor) error {
 	// Note: Because we have not shifted the key, the stack already has all the
 	// comments that occurred in the input before the colon separator.
 	// We move them all above the key when recording.
 
-	h.pushValue(loc, &Member{Key: ast.Quoted(h.ic.Intern(loc.Text()))})
+	if h.ic == nil {
+		h.ic = make(jtree.Interner)
+	}
+	h.pushValue(loc, &Member{Key: ast.AnchorKey(loc, h.ic)})
 	return nil
 }
 
-func (h *parseHandler) EndMember(loc jtree.Anchor) error {
+func (h *Builder) EndMember(loc jtree.Anchor) error {
 	// Stack: ... [incomplete-member] [value] [comment...]
 	_, _, com := h.consumeComments()
 	n := len(h.stk)
@@ -204,7 +324,7 @@ func (h *parseHandler) EndMember(loc jtree.Anchor) error {
 	return nil
 }
 
-func (h *parseHandler) Value(loc jtree.Anchor) error {
+func (h *Builder) Value(loc jtree.Anchor) error {
 	v, err := ast.AnchorValue(loc)
 	if err != nil {
 		return err
@@ -213,9 +333,69 @@ func (h *parseHandler) Value(loc jtree.Anchor) error {
 	return nil
 }
 
-func (h *parseHandler) EndOfInput(loc jtree.Anchor) { h.eof = true }
+func (h *Builder) EndOfInput(loc jtree.Anchor) { h.eof = true }
+
+// partial closes any objects and arrays left open on the stack by a failed
+// parse, discarding incomplete members and unattached comments, and returns
+// the resulting value. It returns nil if no value was found on the stack.
+func (h *Builder) partial() Value {
+	for {
+		i := len(h.stk) - 1
+		for i >= 0 && !isStub(h.stk[i]) {
+			i--
+		}
+		if i < 0 {
+			break
+		}
+
+		var items []Value
+		for _, v := range h.stk[i+1:] {
+			if _, ok := v.(commentStub); !ok {
+				items = append(items, v)
+			}
+		}
+		switch stub := h.stk[i].(type) {
+		case *objectStub:
+			var ms []*Member
+			for j := 0; j < len(items); j++ {
+				m, ok := items[j].(*Member)
+				if !ok {
+					continue
+				} else if m.Value == nil {
+					// The value of an incomplete member may follow it on the stack.
+					if j+1 == len(items) {
+						continue
+					} else if _, ok := items[j+1].(*Member); ok {
+						continue
+					}
+					m.Value = items[j+1]
+					j++
+				}
+				ms = append(ms, m)
+			}
+			h.stk[i] = &Object{Members: ms, com: stub.com}
+		case *arrayStub:
+			h.stk[i] = &Array{Values: items, com: stub.com}
+		}
+		h.stk = h.stk[:i+1]
+	}
+	for _, v := range h.stk {
+		if _, ok := v.(commentStub); !ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func isStub(v Value) bool {
+	switch v.(type) {
+	case *objectStub, *arrayStub:
+		return true
+	}
+	return false
+}
 
-func (h *parseHandler) Comment(loc jtree.Anchor) { h.pushComment(loc) }
+func (h *Builder) Comment(loc jtree.Anchor) { h.pushComment(loc) }
 
 /*
   Attachment rules for comments:
@@ -256,7 +436,7 @@ func (h *parseHandler) Comment(loc jtree.Anchor) { h.pushComment(loc) }
 // consumeComments removes all comments from the top of the stack to form a
 // group. It returns nil if no comments were found atop the stack, otherwise
 // it returns the last line of the group.
-func (h *parseHandler) consumeComments() (int, jtree.Location, []string) {
+func (h *Builder) consumeComments() (int, jtree.Location, []string) {
 	var grp []string
 
 	// As we scan comments, keep track of gaps between runs of comment lines and
@@ -313,7 +493,7 @@ func (h *parseHandler) consumeComments() (int, jtree.Location, []string) {
 
 // pushComment handles a comment token by either adjoining it to the grammar
 // phrase atop the stack as its line comment, or shifting it.
-func (h *parseHandler) pushComment(loc jtree.Anchor) {
+func (h *Builder) pushComment(loc jtree.Anchor) {
 	if i := len(h.stk) - 1; i >= 0 && loc.Token() == jtree.LineComment {
 		switch h.stk[i].(type) {
 		case *arrayStub, *objectStub, commentStub:
@@ -349,7 +529,7 @@ func (h *parseHandler) pushComment(loc jtree.Anchor) {
 }
 
 // pushValue pushes v atop the stack after handling any pending comments.
-func (h *parseHandler) pushValue(loc jtree.Anchor, v Value) {
+func (h *Builder) pushValue(loc jtree.Anchor, v Value) {
 	last, _, com := h.consumeComments() // do this first, it may update the stack
 	c := v.Comments()
 	vp := loc.Location()
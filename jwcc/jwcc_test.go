@@ -3,6 +3,8 @@
 package jwcc_test
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -10,10 +12,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/creachadair/jtree"
 	"github.com/creachadair/jtree/ast"
 	"github.com/creachadair/jtree/cursor"
 	"github.com/creachadair/jtree/jwcc"
 	"github.com/google/go-cmp/cmp"
+	"github.com/tailscale/hujson"
 
 	_ "embed"
 )
@@ -147,3 +151,951 @@ func TestDecorate(t *testing.T) {
 	}
 	t.Logf("Result:\n%s", jwcc.FormatToString(out))
 }
+
+func TestParsePartial(t *testing.T) {
+	const input = `// leading
+{
+  "a": 1, // one
+  "b": [true, /* x */ {"c": null, "d"
+`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if !errors.Is(err, ast.ErrIncomplete) {
+		t.Errorf("Parse: got err=%v, want %v", err, ast.ErrIncomplete)
+	}
+	if d == nil {
+		t.Fatal("Parse: got nil, want partial document")
+	}
+	const wantJSON = `{"a":1,"b":[true,{"c":null}]}`
+	if got := d.JSON(); got != wantJSON {
+		t.Errorf("Result: got %#q, want %#q", got, wantJSON)
+	}
+	if diff := cmp.Diff([]string{"// leading\n"}, d.Value.Comments().Before); diff != "" {
+		t.Errorf("Comments (-want, +got):\n%s", diff)
+	}
+}
+
+func TestPreset(t *testing.T) {
+	const input = `{"a": 1, "bcd": [1, 2], "e": {"f": true}}`
+	tests := []struct {
+		name, want string
+	}{
+		{"default", "{\n  \"a\":   1,\n  \"bcd\": [1, 2],\n  \"e\":   {\"f\": true},\n}"},
+		{"hujson", "{\n\t\"a\":   1,\n\t\"bcd\": [1, 2],\n\t\"e\":   {\"f\": true}\n}"},
+		{"prettier", "{\n  \"a\": 1,\n  \"bcd\": [\n    1,\n    2\n  ],\n  \"e\": {\n    \"f\": true\n  }\n}"},
+		{"vscode", "{\n    \"a\": 1,\n    \"bcd\": [\n        1,\n        2\n    ],\n    \"e\": {\n        \"f\": true\n    }\n}"},
+	}
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	for _, test := range tests {
+		f, ok := jwcc.Preset(test.name)
+		if !ok {
+			t.Errorf("Preset(%q): not found", test.name)
+			continue
+		}
+		var sb strings.Builder
+		if err := f.Format(&sb, d); err != nil {
+			t.Errorf("Format %q: %v", test.name, err)
+		} else if diff := cmp.Diff(test.want, sb.String()); diff != "" {
+			t.Errorf("Format %q (-want, +got):\n%s", test.name, diff)
+		}
+	}
+
+	if f, ok := jwcc.Preset("nonesuch"); ok {
+		t.Errorf("Preset(nonesuch): got %+v, want not found", f)
+	}
+
+	t.Run("HuJSON", func(t *testing.T) {
+		// The hujson package keeps the line structure of its input, so check
+		// that it does not change the layout chosen by the preset.
+		f, _ := jwcc.Preset("hujson")
+		for _, input := range []string{
+			`{"a":1,"bcd":[1,2],"e":{"f":"g"}}`,
+			`{"name": "x", "list": [{"a": 1}, {"b": 2, "c": 3}, [], [1, 2, 3, 4]], "z": {"y": {"x": null}}}`,
+		} {
+			d, err := jwcc.Parse(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			var sb strings.Builder
+			if err := f.Format(&sb, d); err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			want, err := hujson.Format([]byte(sb.String()))
+			if err != nil {
+				t.Fatalf("hujson.Format: %v", err)
+			}
+			if diff := cmp.Diff(strings.TrimSuffix(string(want), "\n"), sb.String()); diff != "" {
+				t.Errorf("Format %q (-hujson, +got):\n%s", input, diff)
+			}
+		}
+	})
+}
+
+func TestUseTabs(t *testing.T) {
+	const input = `{"a": 1, "bcdef": [1, 2], "g": {"h": {"i": true, "j": false}}}`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tests := []struct {
+		f    jwcc.Formatter
+		want string
+	}{
+		{jwcc.Formatter{UseTabs: true},
+			"{\n\t\"a\":\t\t1,\n\t\"bcdef\":\t[1, 2],\n\n\t\"g\": {\n\t\t\"h\": {\n\t\t\t\"i\":\ttrue,\n\t\t\t\"j\":\tfalse,\n\t\t},\n\t},\n}"},
+		{jwcc.Formatter{UseTabs: true, Indent: "   "},
+			"{\n   \"a\":\t\t1,\n   \"bcdef\":\t[1, 2],\n\n   \"g\": {\n      \"h\": {\n         \"i\":\ttrue,\n         \"j\":\tfalse,\n      },\n   },\n}"},
+		{jwcc.Formatter{UseTabs: true, NoAlign: true, MaxInlineItems: 1},
+			"{\n\t\"a\": 1,\n\n\t\"bcdef\": [\n\t\t1,\n\t\t2,\n\t],\n\n\t\"g\": {\n\t\t\"h\": {\n\t\t\t\"i\": true,\n\t\t\t\"j\": false,\n\t\t},\n\t},\n}"},
+	}
+	for _, test := range tests {
+		var sb strings.Builder
+		if err := test.f.Format(&sb, d); err != nil {
+			t.Errorf("Format %+v: %v", test.f, err)
+		} else if diff := cmp.Diff(test.want, sb.String()); diff != "" {
+			t.Errorf("Format %+v (-want, +got):\n%s", test.f, diff)
+		}
+	}
+}
+
+func TestMaxLineWidth(t *testing.T) {
+	const input = `{
+  "short": ["a", "b"],
+  "long": ["alpha bravo", "charlie delta"],
+  "obj": {"key": "a long string value"},
+  "hint": ["alpha bravo", "charlie delta", "echo foxtrot"],
+}`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	d.Value.(*jwcc.Object).Find("hint").Value.Comments().SetHint(jwcc.HintInline)
+
+	const want = `{
+  "short": ["a", "b"],
+
+  "long": [
+    "alpha bravo",
+    "charlie delta",
+  ],
+
+  "obj": {
+    "key": "a long string value",
+  },
+
+  "hint": ["alpha bravo", "charlie delta", "echo foxtrot"],
+}`
+	f := jwcc.Formatter{MaxLineWidth: 20}
+	var sb strings.Builder
+	if err := f.Format(&sb, d); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("Format (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNoTrailingCommas(t *testing.T) {
+	const input = `{
+  "a": 1,
+  "b": [true, false, null, "x"], // line
+  "c": {"d": 2, "e": 3},
+}`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	const want = `{
+  "a": 1,
+
+  "b": [
+    true,
+    false,
+    null,
+    "x"
+  ], // line
+
+  "c": {
+    "d": 2,
+    "e": 3
+  }
+}`
+	f := jwcc.Formatter{NoTrailingCommas: true, NoAlign: true}
+	var sb strings.Builder
+	if err := f.Format(&sb, d); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("Format (-want, +got):\n%s", diff)
+	}
+
+	// Without comments, the result is valid JSON.
+	d.Value.(*jwcc.Object).Find("b").Comments().Clear()
+	sb.Reset()
+	if err := f.Format(&sb, d); err != nil {
+		t.Fatalf("Format: %v", err)
+	} else if !json.Valid([]byte(sb.String())) {
+		t.Errorf("Format: invalid JSON:\n%s", sb.String())
+	}
+}
+
+func TestStandardize(t *testing.T) {
+	const input = `// Leading comment.
+{
+  "b": 1.50, // line
+  /* before */ "a": ["\u0041", 1e3,],
+
+  // trailing
+}
+`
+	const want = `{"b":1.50,"a":["\u0041",1e3]}`
+	got, err := jwcc.Standardize(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Standardize: unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Standardize: got %#q, want %#q", got, want)
+	}
+	if !json.Valid(got) {
+		t.Errorf("Standardize: invalid JSON: %#q", got)
+	}
+
+	if got, err := jwcc.Standardize(strings.NewReader(`{"a":`)); err == nil {
+		t.Errorf("Standardize: got %#q, want error", got)
+	}
+}
+
+func TestEditSource(t *testing.T) {
+	const input = `// Service configuration.
+{
+	"name":    "alpha",  // the name
+	"port":    8080,
+	"tags":    ["x", "y", "z"],
+
+	// Obsolete settings.
+	"legacy":  true,
+	"limits": {"cpu": 2, "mem": "1G"},
+	"empty": {},
+}
+`
+	tests := []struct {
+		name string
+		edit func(*jwcc.SourceEditor)
+		want string
+	}{
+		{"NoEdits", func(*jwcc.SourceEditor) {}, input},
+		{"SetScalar", func(e *jwcc.SourceEditor) {
+			e.Set("/port", ast.Int(9090))
+			e.Set("/name", ast.String("beta"))
+		}, strings.Replace(strings.Replace(input, "8080", "9090", 1), `"alpha"`, `"beta"`, 1)},
+		{"SetNested", func(e *jwcc.SourceEditor) {
+			e.Set("/tags/1", nil)
+			e.Set("/limits/mem", ast.String("2G"))
+		}, strings.Replace(strings.Replace(input, `"y"`, "null", 1), `"1G"`, `"2G"`, 1)},
+		{"SetObject", func(e *jwcc.SourceEditor) {
+			e.Set("/legacy", ast.ObjectOf("a", 1, "b", ast.ArrayOf(1, 2, 3, 4)))
+		}, strings.Replace(input, "true,", "{\n\t\t\"a\": 1,\n\n\t\t\"b\": [\n\t\t\t1,\n\t\t\t2,\n\t\t\t3,\n\t\t\t4,\n\t\t],\n\t},", 1)},
+		{"DeleteLines", func(e *jwcc.SourceEditor) {
+			e.Delete("/legacy")
+			e.Delete("/name")
+		}, strings.Replace(strings.Replace(input, "\t\"name\":    \"alpha\",  // the name\n", "", 1),
+			"\t// Obsolete settings.\n\t\"legacy\":  true,\n", "", 1)},
+		{"DeleteInline", func(e *jwcc.SourceEditor) {
+			e.Delete("/tags/0")
+			e.Delete("/limits/mem")
+		}, strings.Replace(strings.Replace(input, `["x", "y", "z"]`, `["y", "z"]`, 1), `{"cpu": 2, "mem": "1G"}`, `{"cpu": 2}`, 1)},
+		{"DeleteAdjacent", func(e *jwcc.SourceEditor) {
+			e.Delete("/tags/0")
+			e.Delete("/tags/1")
+		}, strings.Replace(input, `["x", "y", "z"]`, `["z"]`, 1)},
+		{"AddMembers", func(e *jwcc.SourceEditor) {
+			e.Set("/limits/disk", ast.String("10G"))
+			e.Set("/new", ast.Bool(false))
+			e.Set("/empty/a~1b", ast.Int(1))
+		}, strings.Replace(strings.Replace(strings.Replace(input,
+			`"1G"}`, `"1G", "disk": "10G"}`, 1),
+			`"empty": {},`, "\"empty\": {\n\t\t\"a/b\": 1\n\t},", 1),
+			"},\n}", "},\n\t\"new\": false,\n}", 1),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := jwcc.EditSource([]byte(input), test.edit)
+			if err != nil {
+				t.Fatalf("EditSource: unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("EditSource (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("StrictJSON", func(t *testing.T) {
+		const input = "{\n  \"a\": 1,\n  \"b\": 2\n}\n"
+		got, err := jwcc.EditSource([]byte(input), func(e *jwcc.SourceEditor) {
+			e.Delete("/b")
+			e.Set("/c", ast.Int(3))
+		})
+		if err != nil {
+			t.Fatalf("EditSource: unexpected error: %v", err)
+		}
+		const want = "{\n  \"a\": 1,\n  \"c\": 3\n}\n"
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("EditSource (-want, +got):\n%s", diff)
+		}
+	})
+
+	for _, bad := range []func(*jwcc.SourceEditor){
+		func(e *jwcc.SourceEditor) { e.Delete("") },
+		func(e *jwcc.SourceEditor) { e.Set("port", nil) },
+		func(e *jwcc.SourceEditor) { e.Delete("/nonesuch") },
+		func(e *jwcc.SourceEditor) { e.Set("/nonesuch/x", nil) },
+		func(e *jwcc.SourceEditor) { e.Set("/tags/3", nil) },
+		func(e *jwcc.SourceEditor) { e.Set("/port/x", nil) },
+		func(e *jwcc.SourceEditor) { e.Set("/limits", nil); e.Set("/limits/cpu", nil) },
+		func(e *jwcc.SourceEditor) { e.Set("/b", ast.Int(3)); e.Set("/b", ast.Int(4)) },
+	} {
+		if got, err := jwcc.EditSource([]byte(input), bad); err == nil {
+			t.Errorf("EditSource: got %#q, want error", got)
+		}
+	}
+}
+
+func TestMutate(t *testing.T) {
+	const input = `{
+  "a": 1,
+
+  // Group comment.
+  "b": 2, // line b
+  "c": [1, /* two */ 2, 3],
+}`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	obj := d.Value.(*jwcc.Object)
+
+	// Replacing a value keeps its comments; new members go at the end or at
+	// the requested position.
+	if m := obj.Set("b", "two"); m.Comments().Line != "// line b\n" {
+		t.Errorf("Set b: member comments lost: %+v", m.Comments())
+	}
+	obj.Set("d", true)
+	obj.SetAt(0, "z", nil)
+	obj.SetAt(0, "a", 100) // exists, does not move
+
+	// Deleting a member can keep its Before comments.
+	if m := obj.Delete("b", true); m == nil {
+		t.Error("Delete b: not found")
+	}
+	if m := obj.Delete("nonesuch", true); m != nil {
+		t.Errorf("Delete nonesuch: got %v, want nil", m)
+	}
+
+	arr := obj.Find("c").Value.(*jwcc.Array)
+	arr.Insert(0, "first")
+	arr.Insert(len(arr.Values), 4, 5)
+	if v := arr.Remove(2, true); v.JSON() != "2" {
+		t.Errorf("Remove 2: got %v, want 2", v.JSON())
+	}
+	if v := arr.Remove(1, false); v.JSON() != "1" {
+		t.Errorf("Remove 1: got %v, want 1", v.JSON())
+	}
+
+	const want = `{"z":null,"a":100,"c":["first",3,4,5],"d":true}`
+	if got := d.JSON(); got != want {
+		t.Errorf("Result: got %#q, want %#q", got, want)
+	}
+	if diff := cmp.Diff([]string{"// Group comment.\n"}, obj.Find("c").Comments().Before); diff != "" {
+		t.Errorf("Moved comments (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"/* two */"}, arr.Values[1].Comments().Before); diff != "" {
+		t.Errorf("Moved comments (-want, +got):\n%s", diff)
+	}
+
+	// Comments on the last member or element move to the end.
+	obj.Find("d").Comments().Before = []string{"// last"}
+	obj.Delete("d", true)
+	if diff := cmp.Diff([]string{"// last"}, obj.Comments().End); diff != "" {
+		t.Errorf("End comments (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	const base = `{
+  // The service name.
+  "name": "base",
+  "port": 80,
+  "tags": ["a"],
+  "limits": {
+    "cpu": 1, // cores
+    "mem": "1G",
+  },
+}`
+	const over = `{
+  "port": 8080, // overridden
+  "tags": ["b", "c"],
+  "limits": {"mem": "2G", "disk": "10G"},
+  // Added.
+  "debug": true,
+}`
+	parse := func(s string) *jwcc.Object {
+		t.Helper()
+		d, err := jwcc.Parse(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		return d.Value.(*jwcc.Object)
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		dst := parse(base)
+		jwcc.Merge(dst, parse(over), nil)
+
+		const want = `{
+  // The service name.
+  "name": "base",
+
+  "port": 8080, // overridden
+  "tags": ["b", "c"],
+
+  "limits": {
+    "cpu":  1,  // cores
+    "mem":  "2G",
+    "disk": "10G",
+  },
+
+  // Added.
+  "debug": true,
+}`
+		if diff := cmp.Diff(want, jwcc.FormatToString(dst)); diff != "" {
+			t.Errorf("Merge (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		dst := parse(base)
+		var paths []string
+		jwcc.Merge(dst, parse(over), &jwcc.MergeOptions{
+			Shallow: true,
+			Resolve: func(path []string, dst, src jwcc.Value) jwcc.Value {
+				paths = append(paths, strings.Join(path, "/"))
+				switch path[0] {
+				case "tags":
+					return &jwcc.Array{Values: append(dst.(*jwcc.Array).Values, src.(*jwcc.Array).Values...)}
+				case "port":
+					return nil
+				}
+				return src
+			},
+		})
+		const want = `{"name":"base","tags":["a","b","c"],"limits":{"mem":"2G","disk":"10G"},"debug":true}`
+		if got := dst.JSON(); got != want {
+			t.Errorf("Merge: got %#q, want %#q", got, want)
+		}
+		if diff := cmp.Diff([]string{"port", "tags", "limits"}, paths); diff != "" {
+			t.Errorf("Resolve paths (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestSortPreservingGroups(t *testing.T) {
+	const input = `{
+  // Identity.
+
+  "name": "x",
+  "id": 1, // the ID
+
+  // Network settings.
+
+  "port": 80,
+  // The host.
+  "host": "h",
+  "bind": "0.0.0.0",
+
+  "zeta": true,
+  "alpha": false,
+}`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	obj := d.Value.(*jwcc.Object)
+	obj.Set("added", 0) // no location, stays in the last group
+	obj.SortPreservingGroups(nil)
+
+	const want = `{
+  // Identity.
+
+  "id": 1,  // the ID
+
+  "name": "x",
+
+  // Network settings.
+
+  "bind": "0.0.0.0",
+
+  // The host.
+  "host": "h",
+
+  "port":  80,
+  "added": 0,
+  "alpha": false,
+  "zeta":  true,
+}`
+	if diff := cmp.Diff(want, jwcc.FormatToString(d)); diff != "" {
+		t.Errorf("Sort (-want, +got):\n%s", diff)
+	}
+}
+
+func TestDecorateWith(t *testing.T) {
+	data := ast.ObjectOf(
+		"name", "svc",
+		"a/b", ast.ArrayOf(1, 2),
+		"limits", ast.ObjectOf("cpu", 2),
+	)
+	v := jwcc.DecorateWith(data, map[string]jwcc.Comments{
+		"":             {Before: []string{"Generated configuration."}},
+		"/name":        {Line: "required"},
+		"/a~1b/1":      {Line: "second"},
+		"/limits":      {Before: []string{"Resource limits."}},
+		"/limits/cpu":  {Line: "cores"},
+		"/nonesuch":    {Line: "ignored"},
+		"/limits/cpu/": {Line: "ignored"},
+	})
+	const want = `// Generated configuration.
+{
+  "name": "svc", // required
+
+  "a/b": [
+    1,
+    2, // second
+  ],
+
+  // Resource limits.
+  "limits": {
+    "cpu": 2,  // cores
+  },
+}`
+	if diff := cmp.Diff(want, jwcc.FormatToString(v)); diff != "" {
+		t.Errorf("DecorateWith (-want, +got):\n%s", diff)
+	}
+}
+
+func TestLint(t *testing.T) {
+	const input = `{
+  "a": 1,
+  "b": {"x": 1, "X": 2},
+  //
+  "a": 2,
+  "c": [/**/ 1],
+} // end
+{"extra": true}
+`
+	ds, err := jwcc.LintSource(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("LintSource: unexpected error: %v", err)
+	}
+	type diag struct{ Kind, Path, Loc string }
+	var got []diag
+	for _, d := range ds {
+		t.Logf("Diagnostic: %v", d)
+		got = append(got, diag{d.Kind, d.Path, d.Loc.First.String()})
+	}
+	want := []diag{
+		{jwcc.LintShadowedKey, "/b/X", "3:16"},
+		{jwcc.LintDuplicateKey, "/a", "5:2"},
+		{jwcc.LintEmptyComment, "/a", "5:2"},
+		{jwcc.LintEmptyComment, "/c/0", "6:13"},
+		{jwcc.LintExtraInput, "", "7:1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LintSource (-want, +got):\n%s", diff)
+	}
+
+	if _, err := jwcc.LintSource(strings.NewReader(`{"a":`)); err == nil {
+		t.Error("LintSource: got nil, want error")
+	}
+}
+
+func TestParseJSON5(t *testing.T) {
+	const input = `// JSON5 config
+{
+  name: 'demo', // the name
+  port: 0x1F90,
+  ratio: .5,
+  tags: ['a', "b",],
+}
+`
+	if _, err := jwcc.Parse(strings.NewReader(input)); err == nil {
+		t.Error("Parse: got nil, want error for JSON5 input")
+	}
+	d, err := jwcc.ParseWithOptions(strings.NewReader(input), &jwcc.ParseOptions{JSON5: true})
+	if err != nil {
+		t.Fatalf("ParseWithOptions: unexpected error: %v", err)
+	}
+	var sb strings.Builder
+	if err := jwcc.Format(&sb, d); err != nil {
+		t.Fatalf("Format: unexpected error: %v", err)
+	}
+	t.Logf("Formatted:\n%s", sb.String())
+
+	// The result is valid JWCC, with the comments preserved.
+	if _, err := jwcc.Parse(strings.NewReader(sb.String())); err != nil {
+		t.Errorf("Parse formatted: unexpected error: %v", err)
+	}
+	if got, want := d.Undecorate().JSON(), `{"name":"demo","port":8080,"ratio":0.5,"tags":["a","b"]}`; got != want {
+		t.Errorf("JSON: got %s, want %s", got, want)
+	}
+	if got := d.Value.Comments().Before; len(got) == 0 || !strings.Contains(got[0], "JSON5 config") {
+		t.Errorf("Object comments: got %q, want header", got)
+	}
+	if got := d.Value.(*jwcc.Object).Members[0].Comments().Line; !strings.Contains(got, "the name") {
+		t.Errorf("Line comment: got %q, want %q", got, "// the name")
+	}
+}
+
+func TestParseUnquotedKeys(t *testing.T) {
+	const input = `{
+  // Access control lists.
+  acls: [
+    {action: "accept", src: ["*"], dst: ["*:*"]},
+  ],
+  tagOwners: {"tag:ci": ["autogroup:admin"]},
+}
+`
+	opts := &jwcc.ParseOptions{UnquotedKeys: true}
+	d, err := jwcc.ParseWithOptions(strings.NewReader(input), opts)
+	if err != nil {
+		t.Fatalf("ParseWithOptions: unexpected error: %v", err)
+	}
+	const want = `{"acls":[{"action":"accept","src":["*"],"dst":["*:*"]}],"tagOwners":{"tag:ci":["autogroup:admin"]}}`
+	if got := d.Undecorate().JSON(); got != want {
+		t.Errorf("JSON:\ngot:  %s\nwant: %s", got, want)
+	}
+	if m := d.Value.(*jwcc.Object).Find("acls"); m == nil {
+		t.Error("Find acls: not found")
+	} else if got := m.Comments().Before; len(got) == 0 || !strings.Contains(got[0], "Access control") {
+		t.Errorf("acls comments: got %q", got)
+	}
+	if _, err := jwcc.ParseWithOptions(strings.NewReader(input), nil); err == nil {
+		t.Error("ParseWithOptions(nil): got nil, want error")
+	}
+}
+
+func TestHints(t *testing.T) {
+	const input = `{
+  "versions": [1, 2, 3, 4, 5],
+  "point": {"x": 1},
+  "opts": {"a": 1, "long": 2},
+}`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	root := d.Value.(*jwcc.Object)
+	root.Find("versions").Value.Comments().SetHint(jwcc.HintInline)
+	root.Find("point").Value.Comments().SetHint(jwcc.HintMultiline)
+	root.Find("opts").Value.Comments().SetHint(jwcc.HintNoAlign | jwcc.HintMultiline)
+
+	const want = `{
+  "versions": [1, 2, 3, 4, 5],
+
+  "point": {
+    "x": 1,
+  },
+
+  "opts": {
+    "a": 1,
+    "long": 2,
+  },
+}`
+	if diff := cmp.Diff(want, jwcc.FormatToString(d)); diff != "" {
+		t.Errorf("Format (-want, +got):\n%s", diff)
+	}
+
+	// An inline hint does not apply if the contents have comments.
+	v := root.Find("versions").Value.(*jwcc.Array)
+	v.Values[0].Comments().Line = "// first"
+	if got := jwcc.FormatToString(v); !strings.Contains(got, "\n") {
+		t.Errorf("Format: got %#q, want multiple lines", got)
+	}
+}
+
+func TestCommentMap(t *testing.T) {
+	const input = `// Top of file.
+{
+  // The name.
+  "name": "x", // line
+
+  "a/b": [
+    1, // first
+    /* second */ 2,
+  ],
+}
+// End of file.
+`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := jwcc.FormatToString(d)
+
+	v, cm := jwcc.UndecorateWithComments(d)
+	if _, ok := v.(ast.Object); !ok {
+		t.Fatalf("Undecorate: got %T, want ast.Object", v)
+	}
+	for _, key := range []string{"", "/name", "/a~1b/0", "/a~1b/1"} {
+		if _, ok := cm[key]; !ok {
+			t.Errorf("CommentMap: missing key %q", key)
+		}
+	}
+
+	got := jwcc.FormatToString(jwcc.DecorateWithComments(v, cm))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Round trip (-want, +got):\n%s", diff)
+	}
+}
+
+// countValues is a handler that counts the values it sees, and forwards all
+// events to a Builder.
+type countValues struct {
+	*jwcc.Builder
+	n int
+}
+
+func (c *countValues) Value(loc jtree.Anchor) error { c.n++; return c.Builder.Value(loc) }
+
+func TestBuilder(t *testing.T) {
+	t.Run("Basic", func(t *testing.T) {
+		want, err := jwcc.Parse(strings.NewReader(basicInput))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+
+		st := jtree.NewStream(strings.NewReader(basicInput))
+		st.AllowComments(true)
+		st.AllowTrailingCommas(true)
+		h := &countValues{Builder: jwcc.NewBuilder()}
+		if err := st.Parse(h); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		got, err := h.Result()
+		if err != nil {
+			t.Fatalf("Result: %v", err)
+		}
+		if h.n == 0 {
+			t.Error("Handler did not see any values")
+		}
+		if diff := cmp.Diff(jwcc.FormatToString(want), jwcc.FormatToString(got)); diff != "" {
+			t.Errorf("Result (-want, +got):\n%s", diff)
+		}
+		if d, err := h.Result(); err != io.EOF {
+			t.Errorf("Result: got (%v, %v), want %v", d, err, io.EOF)
+		}
+	})
+
+	t.Run("Multi", func(t *testing.T) {
+		st := jtree.NewStream(strings.NewReader("/* a */ 1 // b\n/* c */ [2]\n// d\n"))
+		st.AllowComments(true)
+		var b jwcc.Builder
+		if err := st.Parse(&b); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+
+		d1, err := b.Result()
+		if err != nil {
+			t.Fatalf("Result 1: %v", err)
+		}
+		if got := d1.Value.Comments().Before; len(got) != 1 || got[0] != "/* a */" {
+			t.Errorf("Value 1 comments: got %q, want [/* a */]", got)
+		}
+		if got := d1.Value.Comments().Line; got != "// b\n" {
+			t.Errorf("Value 1 line comment: got %q, want %q", got, "// b\n")
+		}
+		if len(d1.Comments().End) != 0 {
+			t.Errorf("Value 1 end comments: got %q, want none", d1.Comments().End)
+		}
+
+		d2, err := b.Result()
+		if err != nil {
+			t.Fatalf("Result 2: %v", err)
+		}
+		if got := d2.Value.Comments().Before; len(got) != 1 || got[0] != "/* c */" {
+			t.Errorf("Value 2 comments: got %q, want [/* c */]", got)
+		}
+		if got := d2.Comments().End; len(got) != 1 || got[0] != "// d\n" {
+			t.Errorf("Value 2 end comments: got %q, want [// d]", got)
+		}
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		var b jwcc.Builder
+		st := jtree.NewStream(strings.NewReader(`[1, {"a": 2`))
+		if err := st.Parse(&b); err == nil {
+			t.Fatal("Parse: got nil, want error")
+		}
+		if d, err := b.Result(); !errors.Is(err, ast.ErrIncomplete) {
+			t.Errorf("Result: got (%v, %v), want %v", d, err, ast.ErrIncomplete)
+		}
+		b.Reset()
+		if d, err := b.Result(); err != io.EOF {
+			t.Errorf("Result: got (%v, %v), want %v", d, err, io.EOF)
+		}
+	})
+}
+
+func TestRewrite(t *testing.T) {
+	const input = `// Configuration.
+{
+  // The current release.
+  "version": "1.2.3", // bump me
+
+  "deps": [
+    "a", // first
+    /* second */ "b",
+    "c",
+  ],
+  "other": {"x": 1}, // untouched
+}
+`
+	d, err := jwcc.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	orig := jwcc.FormatToString(d)
+
+	got := jwcc.Rewrite(d, func(v jwcc.Value) (jwcc.Value, bool) {
+		if m, ok := v.(*jwcc.Member); ok && m.Key.String() == "version" {
+			return jwcc.ToValue("1.3.0"), true // replaces the value of the member
+		}
+		if d, ok := v.(*jwcc.Datum); ok {
+			switch d.Value.JSON() {
+			case `"b"`:
+				return nil, true // delete
+			case `"c"`:
+				return jwcc.ToValue("d"), true
+			}
+		}
+		return nil, false
+	})
+	const want = `// Configuration.
+{
+  // The current release.
+  "version": "1.3.0", // bump me
+
+  "deps": [
+    "a", // first
+    "d",
+  ],
+
+  "other": {"x": 1}, // untouched
+}`
+	if diff := cmp.Diff(want, jwcc.FormatToString(got)); diff != "" {
+		t.Errorf("Rewrite (-want, +got):\n%s", diff)
+	}
+
+	// The input is unchanged, and untouched values are shared.
+	if diff := cmp.Diff(orig, jwcc.FormatToString(d)); diff != "" {
+		t.Errorf("Input was modified (-want, +got):\n%s", diff)
+	}
+	in := d.Value.(*jwcc.Object).Find("other")
+	out := got.(*jwcc.Document).Value.(*jwcc.Object).Find("other")
+	if in != out {
+		t.Error("Untouched member was not shared")
+	}
+
+	// A rewrite that replaces nothing returns its input.
+	if got := jwcc.Rewrite(d, func(jwcc.Value) (jwcc.Value, bool) { return nil, false }); got != d {
+		t.Errorf("Rewrite: got %p, want %p", got, d)
+	}
+
+	// Replacing a member with a member keeps the comments of both.
+	got = jwcc.Rewrite(d.Value, func(v jwcc.Value) (jwcc.Value, bool) {
+		if m, ok := v.(*jwcc.Member); ok && m.Key.String() == "version" {
+			return jwcc.Field("release", "2.0"), true
+		}
+		return nil, false
+	})
+	m := got.(*jwcc.Object).Members[0]
+	if m.Key.String() != "release" || len(m.Comments().Before) != 1 || strings.TrimSpace(m.Comments().Line) != "// bump me" {
+		t.Errorf("Replaced member: got %s, comments %+v", m.JSON(), m.Comments())
+	}
+}
+
+func TestObjectBuilder(t *testing.T) {
+	obj := jwcc.BuildObject().
+		SetComment("Server settings.").
+		Set("name", "example").SetComment("The name of the service.").
+		Set("port", 8000).SetLineComment("default").
+		Set("tags", jwcc.ArrayOf("a", "b")).
+		Set("limits", ast.ObjectOf("cpu", 2)).
+		Set("port", 8080). // replaces the value, keeps the comment
+		Object()
+
+	const want = `// Server settings.
+{
+  // The name of the service.
+  "name": "example",
+
+  "port":   8080, // default
+  "tags":   ["a", "b"],
+  "limits": {"cpu": 2},
+}`
+	if diff := cmp.Diff(want, jwcc.FormatToString(obj)); diff != "" {
+		t.Errorf("Builder (-want, +got):\n%s", diff)
+	}
+
+	if got, want := jwcc.ObjectOf("a", 1, "b", ast.ArrayOf(true)).JSON(), `{"a":1,"b":[true]}`; got != want {
+		t.Errorf("ObjectOf: got %s, want %s", got, want)
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	d, err := jwcc.Parse(strings.NewReader(`{
+  "name": "x", // the name
+  // The version.
+  "version": 2,
+  "a": true,
+}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	d.Value.(*jwcc.Object).SortKeys([]string{"version", "name"})
+	const want = `{
+  // The version.
+  "version": 2,
+
+  "name": "x", // the name
+  "a":    true,
+}`
+	if diff := cmp.Diff(want, jwcc.FormatToString(d)); diff != "" {
+		t.Errorf("SortKeys (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	d, err := jwcc.Parse(strings.NewReader(`{
+  "user": "alice",
+  "password": "hunter2", // do not log
+  "list": [{"token": "abc"}],
+}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	orig := jwcc.FormatToString(d)
+	got := jwcc.Redact(d, ast.MatchKeys("password", "token"), ast.String("REDACTED"))
+	const want = `{
+  "user":     "alice",
+  "password": "REDACTED", // do not log
+  "list":     [{"token": "REDACTED"}],
+}`
+	if diff := cmp.Diff(want, jwcc.FormatToString(got)); diff != "" {
+		t.Errorf("Redact (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(orig, jwcc.FormatToString(d)); diff != "" {
+		t.Errorf("Input was modified (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jwcc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/ast"
+)
+
+// A Diagnostic describes a problem found by Lint.
+type Diagnostic struct {
+	Kind    string         // the kind of problem, one of the Lint* constants
+	Path    string         // a JSON Pointer (RFC 6901) to the value concerned
+	Loc     jtree.Location // the location of the value in the input
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Loc.First, d.Kind, d.Message)
+}
+
+// Kinds of problem reported by Lint.
+const (
+	LintDuplicateKey = "duplicate-key" // a key repeated exactly in an object
+	LintShadowedKey  = "shadowed-key"  // a key that differs from an earlier one only in case
+	LintEmptyComment = "empty-comment" // a comment with no text
+	LintExtraInput   = "extra-input"   // data following the document
+)
+
+// Lint checks doc for constructs that are valid JWCC but likely to be
+// mistakes in a hand-edited file, and returns a diagnostic for each problem
+// found, in order of location. It reports:
+//
+//   - An object member whose key exactly matches an earlier member of the
+//     same object (LintDuplicateKey).
+//   - An object member whose key differs from an earlier member of the same
+//     object only in case, which case-insensitive lookups such as Find will
+//     not see (LintShadowedKey).
+//   - A comment without any text, such as "//" or "/* */" (LintEmptyComment).
+//     Since comments do not record their own locations, the location reported
+//     is that of the value to which the comment is attached.
+//
+// Locations are meaningful only for a document returned by Parse.
+func Lint(doc *Document) []Diagnostic {
+	var out []Diagnostic
+	lintComments(&out, "", doc)
+	lintValue(&out, "", doc.Value)
+	slices.SortStableFunc(out, func(a, b Diagnostic) int { return a.Loc.Span.Pos - b.Loc.Span.Pos })
+	return out
+}
+
+// LintSource parses a JWCC document from r and checks it as Lint does. In
+// addition to the problems reported by Lint, it reports data following the
+// document (LintExtraInput). It reports an error if r does not contain a
+// valid document.
+func LintSource(r io.Reader) ([]Diagnostic, error) {
+	doc, err := Parse(r)
+	var extra error
+	if errors.Is(err, ast.ErrExtraInput) {
+		extra, err = err, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := Lint(doc)
+	if extra != nil {
+		vloc := ValueLocation(doc.Value)
+		out = append(out, Diagnostic{
+			Kind: LintExtraInput,
+			Loc: jtree.Location{
+				Span:  jtree.Span{Pos: vloc.Span.End, End: vloc.Span.End},
+				First: vloc.Last,
+				Last:  vloc.Last,
+			},
+			Message: strings.ReplaceAll(extra.Error(), "\n", ": "),
+		})
+	}
+	return out, nil
+}
+
+func lintValue(out *[]Diagnostic, path string, v Value) {
+	lintComments(out, path, v)
+	switch t := v.(type) {
+	case *Object:
+		first := make(map[string]*Member)   // exact key → first member
+		folded := make(map[string][]string) // lower-case key → keys seen
+		for _, m := range t.Members {
+			key := m.Key.String()
+			mp := path + "/" + escapePointer(key)
+			if prev, ok := first[key]; ok {
+				*out = append(*out, Diagnostic{
+					Kind:    LintDuplicateKey,
+					Path:    mp,
+					Loc:     ValueLocation(m),
+					Message: fmt.Sprintf("duplicate key %q (first at %s)", key, ValueLocation(prev).First),
+				})
+			} else {
+				first[key] = m
+				lk := strings.ToLower(key)
+				for _, pk := range folded[lk] {
+					if strings.EqualFold(pk, key) {
+						*out = append(*out, Diagnostic{
+							Kind:    LintShadowedKey,
+							Path:    mp,
+							Loc:     ValueLocation(m),
+							Message: fmt.Sprintf("key %q differs only in case from %q (at %s)", key, pk, ValueLocation(first[pk]).First),
+						})
+						break
+					}
+				}
+				folded[lk] = append(folded[lk], key)
+			}
+			lintComments(out, mp, m)
+			lintValue(out, mp, m.Value)
+		}
+	case *Array:
+		for i, e := range t.Values {
+			lintValue(out, path+"/"+strconv.Itoa(i), e)
+		}
+	}
+}
+
+// lintComments reports empty comments attached to v.
+func lintComments(out *[]Diagnostic, path string, v Value) {
+	c := v.Comments()
+	all := append(append(slices.Clip(c.Before), c.Line), c.End...)
+	for _, com := range all {
+		if com == "" {
+			continue // a blank line, or no line comment
+		}
+		if _, text := classifyComment(com); text == "" {
+			*out = append(*out, Diagnostic{
+				Kind:    LintEmptyComment,
+				Path:    path,
+				Loc:     ValueLocation(v),
+				Message: fmt.Sprintf("empty comment %q", strings.TrimSpace(com)),
+			})
+		}
+	}
+}
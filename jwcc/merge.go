@@ -0,0 +1,82 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jwcc
+
+import "github.com/creachadair/jtree/ast"
+
+// MergeOptions are settings for the Merge function.
+// A nil *MergeOptions is ready for use, and provides default settings.
+type MergeOptions struct {
+	// If true, a member of src whose key is also in dst replaces the member of
+	// dst even if both values are objects. By default, such objects are merged
+	// recursively.
+	Shallow bool
+
+	// If non-nil, Resolve is called when a member of src has the same key as a
+	// member of dst, and their values are not merged recursively. The path
+	// gives the keys from the root of dst to the member. The value returned by
+	// Resolve becomes the value of the member; if it returns nil, the member is
+	// removed from dst. By default, the value from src is used.
+	Resolve func(path []string, dst, src Value) Value
+}
+
+func (o *MergeOptions) shallow() bool { return o != nil && o.Shallow }
+
+func (o *MergeOptions) resolve(path []string, dst, src Value) Value {
+	if o == nil || o.Resolve == nil {
+		return src
+	}
+	return o.Resolve(path, dst, src)
+}
+
+// Merge merges the members of src into dst, modifying dst in place. Keys are
+// compared exactly. A member of src whose key is not in dst is added at the
+// end of dst. A member whose key is already in dst replaces the value of the
+// existing member at its original position, or, if both values are objects,
+// is merged into it recursively, subject to opts. The result may share values
+// with src.
+//
+// Comments are carried from both inputs: A member or value of dst that is not
+// affected by the merge keeps its comments. Where a member or value of src
+// replaces or is merged into one from dst, the comments of src are used if
+// it has any; otherwise the comments of dst are kept. Thus comments in src
+// take precedence, but a src without comments does not erase those of dst.
+func Merge(dst, src *Object, opts *MergeOptions) {
+	mergeObject(nil, dst, src, opts)
+}
+
+func mergeObject(path []string, dst, src *Object, opts *MergeOptions) {
+	mergeComments(dst, src)
+	for _, sm := range src.Members {
+		i := dst.IndexKey(ast.TextEqual(sm.Key.String()))
+		if i < 0 {
+			dst.Members = append(dst.Members, sm)
+			continue
+		}
+		dm := dst.Members[i]
+		mpath := append(path, sm.Key.String())
+		do, dok := dm.Value.(*Object)
+		so, sok := sm.Value.(*Object)
+		if dok && sok && !opts.shallow() {
+			mergeObject(mpath, do, so, opts)
+		} else if v := opts.resolve(mpath, dm.Value, sm.Value); v == nil {
+			dst.Members = append(dst.Members[:i], dst.Members[i+1:]...)
+			continue
+		} else if v != dm.Value {
+			dm.Value = replace(dm.Value, v)
+		}
+		mergeComments(dm, sm)
+	}
+}
+
+// mergeComments replaces the comments of dst with those of src, if src has
+// any comments. Formatting hints are handled in the same way.
+func mergeComments(dst, src Value) {
+	dc, sc := dst.Comments(), src.Comments()
+	if !sc.IsEmpty() {
+		dc.Before, dc.Line, dc.End = sc.Before, sc.Line, sc.End
+	}
+	if sc.hint != 0 {
+		dc.hint = sc.hint
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jwcc
+
+import "github.com/creachadair/jtree/ast"
+
+// Rewrite returns a copy of v in which values chosen by f are replaced. It
+// calls f for v and each value nested within it in preorder, including object
+// members. If f reports true, the value it returns replaces the original and
+// the values nested within the original are not visited; otherwise the
+// traversal continues into the original value.
+//
+// If f replaces an object member with a value that is not a *Member, that
+// value replaces the value of the member instead. If f replaces an object
+// member or array element with nil, it is removed. If f replaces v itself
+// with nil, Rewrite returns nil.
+//
+// Rewrite does not modify v. An object or array is copied, with its comments,
+// only if a value nested within it is replaced, so the result shares all the
+// parts of v that f did not change.
+//
+// To preserve the commentary on a replaced value, if a replacement has no
+// comments of its own, the comments of the original are copied to it. For a
+// replacement member, this applies also to the value of the member.
+func Rewrite(v Value, f func(Value) (Value, bool)) Value {
+	if d, ok := v.(*Document); ok {
+		w := rewrite(d.Value, f)
+		if w == nil {
+			return nil
+		} else if w == d.Value {
+			return d
+		}
+		return &Document{Value: w, com: d.com}
+	}
+	return rewrite(v, f)
+}
+
+func rewrite(v Value, f func(Value) (Value, bool)) Value {
+	if w, ok := f(v); ok {
+		return replace(v, w)
+	}
+	switch t := v.(type) {
+	case *Array:
+		var out *Array // allocated at the first change
+		for i, elt := range t.Values {
+			w := rewrite(elt, f)
+			if out == nil {
+				if w == elt {
+					continue
+				}
+				out = &Array{Values: append(make([]Value, 0, len(t.Values)), t.Values[:i]...), com: t.com}
+			}
+			if w != nil {
+				out.Values = append(out.Values, w)
+			}
+		}
+		if out != nil {
+			return out
+		}
+	case *Object:
+		var out *Object // allocated at the first change
+		for i, m := range t.Members {
+			w := rewriteMember(m, f)
+			if out == nil {
+				if w == m {
+					continue
+				}
+				out = &Object{Members: append(make([]*Member, 0, len(t.Members)), t.Members[:i]...), com: t.com}
+			}
+			if w != nil {
+				out.Members = append(out.Members, w)
+			}
+		}
+		if out != nil {
+			return out
+		}
+	}
+	return v
+}
+
+func rewriteMember(m *Member, f func(Value) (Value, bool)) *Member {
+	w, ok := f(m)
+	if !ok {
+		mv := rewrite(m.Value, f)
+		if mv == m.Value {
+			return m
+		} else if mv == nil {
+			return nil
+		}
+		return &Member{Key: m.Key, Value: mv, com: m.com}
+	} else if w == nil {
+		return nil
+	} else if wm, ok := w.(*Member); ok {
+		replace(m, wm)
+		replace(m.Value, wm.Value)
+		return wm
+	}
+	return &Member{Key: m.Key, Value: replace(m.Value, w), com: m.com}
+}
+
+// replace returns w, after copying the comments of v to it if it has none.
+func replace(v, w Value) Value {
+	if w != nil && v != nil && isBlank(*w.Comments()) {
+		*w.Comments() = *v.Comments()
+	}
+	return w
+}
+
+// Redact returns a copy of v in which each value for which match reports true
+// is replaced by replacement, as described for ast.Redact. The value passed
+// to match is the jwcc.Value at that path, so ast.MatchKeys may be used to
+// redact members by key. Each replacement is decorated with the comments of
+// the value it replaces. Like Rewrite, Redact does not modify v, and the
+// result shares any part of v that was not redacted. If replacement is nil,
+// Null is used.
+func Redact(v Value, match func(path []any, v ast.Value) bool, replacement ast.Value) Value {
+	if replacement == nil {
+		replacement = ast.Null
+	}
+	if d, ok := v.(*Document); ok {
+		w := redact(nil, d.Value, match, replacement)
+		if w == d.Value {
+			return d
+		}
+		return &Document{Value: w, com: d.com}
+	}
+	return redact(nil, v, match, replacement)
+}
+
+func redact(path []any, v Value, match func([]any, ast.Value) bool, repl ast.Value) Value {
+	if match(path, v) {
+		if jv, ok := repl.(Value); ok {
+			repl = jv.Undecorate() // so each replacement has its own comments
+		}
+		w := Decorate(repl)
+		*w.Comments() = *v.Comments()
+		return w
+	}
+	switch t := v.(type) {
+	case *Array:
+		var out *Array // allocated at the first change
+		for i, elt := range t.Values {
+			w := redact(append(path, i), elt, match, repl)
+			if out == nil {
+				if w == elt {
+					continue
+				}
+				out = &Array{Values: append(make([]Value, 0, len(t.Values)), t.Values[:i]...), com: t.com}
+			}
+			out.Values = append(out.Values, w)
+		}
+		if out != nil {
+			return out
+		}
+	case *Object:
+		var out *Object // allocated at the first change
+		for i, m := range t.Members {
+			w := redact(append(path, m.Key.String()), m.Value, match, repl)
+			if out == nil {
+				if w == m.Value {
+					continue
+				}
+				out = &Object{Members: append(make([]*Member, 0, len(t.Members)), t.Members[:i]...), com: t.com}
+			}
+			if w == m.Value {
+				out.Members = append(out.Members, m)
+			} else {
+				out.Members = append(out.Members, &Member{Key: m.Key, Value: w, com: m.com})
+			}
+		}
+		if out != nil {
+			return out
+		}
+	}
+	return v
+}
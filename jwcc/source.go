@@ -0,0 +1,487 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jwcc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/escape"
+)
+
+// A SourceEditor records a batch of edits to the source text of a JWCC
+// document. Use EditSource to construct a SourceEditor and apply its edits.
+//
+// Each edit is addressed by a JSON Pointer (RFC 6901) relative to the root of
+// the document. Object keys in a pointer match exactly, and if an object has
+// more than one member with the same key, the first is used.
+type SourceEditor struct {
+	edits []sourceEdit
+	errs  []error
+}
+
+type sourceEdit struct {
+	pointer string
+	value   ast.Value // the new value, if !del
+	del     bool
+}
+
+// EditSource calls f with a SourceEditor to record a batch of edits to the
+// JWCC document in src, then applies the edits and returns the resulting
+// source text. Unlike formatting the edited document, EditSource rewrites
+// only the text of the values affected by the edits, using their locations
+// in src, so that the whitespace, comments, and layout of the rest of the
+// document are left as they were. The src slice is not modified.
+//
+// New values are formatted with default settings, indented to match the line
+// where they are placed. If v is a Value, its comments are included.
+//
+// The edits must not overlap: It is an error for one edit to address a
+// location within a value replaced or deleted by another, or for two edits to
+// address the same location.
+func EditSource(src []byte, f func(e *SourceEditor)) ([]byte, error) {
+	var e SourceEditor
+	f(&e)
+	if err := errors.Join(e.errs...); err != nil {
+		return nil, err
+	}
+	doc, err := Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &sourcePatcher{
+		src:     src,
+		unit:    guessIndent(src, doc.Value),
+		deleted: make(map[Value]bool),
+	}
+	if p.unit == "" {
+		p.unit = "  "
+	}
+	for _, ed := range e.edits {
+		if err := p.plan(doc.Value, ed); err != nil {
+			return nil, err
+		}
+	}
+	for _, add := range p.adds {
+		if err := p.addMembers(add); err != nil {
+			return nil, err
+		}
+	}
+	return p.apply()
+}
+
+// Set replaces the value at pointer with v. If pointer addresses an object
+// member that does not exist, a member with that key is added to the end of
+// the object. If v == nil, null is used.
+func (e *SourceEditor) Set(pointer string, v ast.Value) {
+	if v == nil {
+		v = ast.Null
+	}
+	e.add(sourceEdit{pointer: pointer, value: v})
+}
+
+// Delete removes the object member or array element at pointer. If the member
+// or element is on a line by itself, the whole line is removed, along with any
+// line comments on the lines immediately before it.
+func (e *SourceEditor) Delete(pointer string) {
+	if pointer == "" {
+		e.errs = append(e.errs, errors.New("cannot delete the root"))
+		return
+	}
+	e.add(sourceEdit{pointer: pointer, del: true})
+}
+
+func (e *SourceEditor) add(ed sourceEdit) {
+	if ed.pointer != "" && !strings.HasPrefix(ed.pointer, "/") {
+		e.errs = append(e.errs, fmt.Errorf("invalid pointer %q", ed.pointer))
+		return
+	}
+	e.edits = append(e.edits, ed)
+}
+
+// A splice replaces the source text from pos to end with text.
+type splice struct {
+	pos, end int
+	text     string
+	pointer  string // the edit that produced the splice, for errors
+}
+
+// A memberAdd records members to add to an existing object.
+type memberAdd struct {
+	obj      *Object
+	pointers []string
+	members  []*ast.Member
+}
+
+type sourcePatcher struct {
+	src  []byte
+	unit string // the unit of indentation for new values
+
+	splices []splice
+	adds    []*memberAdd   // in order of first use
+	deleted map[Value]bool // members and elements being deleted
+}
+
+// plan resolves the location of ed in root and records the splices needed to
+// apply it.
+func (p *sourcePatcher) plan(root Value, ed sourceEdit) error {
+	if ed.pointer == "" {
+		p.replace(root, ed)
+		return nil
+	}
+	toks, err := escape.SplitPointer(ed.pointer)
+	if err != nil {
+		return err
+	}
+	cur := root
+	for i, tok := range toks {
+		last := i == len(toks)-1
+		switch t := cur.(type) {
+		case *Object:
+			j := t.IndexKey(ast.TextEqual(tok))
+			if j < 0 {
+				if last && !ed.del {
+					return p.addMember(t, tok, ed)
+				}
+				return fmt.Errorf("edit %q: no member %q", ed.pointer, tok)
+			} else if !last {
+				cur = t.Members[j].Value
+			} else if ed.del {
+				p.deleteItem(memberValues(t.Members), j, ed.pointer)
+			} else {
+				p.replace(t.Members[j].Value, ed)
+			}
+		case *Array:
+			j, err := strconv.Atoi(tok)
+			if err != nil || j < 0 || j >= len(t.Values) {
+				return fmt.Errorf("edit %q: invalid array index %q", ed.pointer, tok)
+			} else if !last {
+				cur = t.Values[j]
+			} else if ed.del {
+				p.deleteItem(t.Values, j, ed.pointer)
+			} else {
+				p.replace(t.Values[j], ed)
+			}
+		default:
+			return fmt.Errorf("edit %q: %q is not an object or array", ed.pointer, tok)
+		}
+	}
+	return nil
+}
+
+func memberValues(ms []*Member) []Value {
+	out := make([]Value, len(ms))
+	for i, m := range ms {
+		out[i] = m
+	}
+	return out
+}
+
+// replace records a splice to replace the text of v with the new value of ed.
+func (p *sourcePatcher) replace(v Value, ed sourceEdit) {
+	span := ValueLocation(v).Span
+	p.splices = append(p.splices, splice{
+		pos:     span.Pos,
+		end:     span.End,
+		text:    p.render(ed.value, p.lineIndent(span.Pos)),
+		pointer: ed.pointer,
+	})
+}
+
+// addMember records that a member with the given key and the value of ed is
+// to be added to o. It is an error to add the same key twice.
+func (p *sourcePatcher) addMember(o *Object, key string, ed sourceEdit) error {
+	i := slices.IndexFunc(p.adds, func(a *memberAdd) bool { return a.obj == o })
+	if i < 0 {
+		i = len(p.adds)
+		p.adds = append(p.adds, &memberAdd{obj: o})
+	}
+	add := p.adds[i]
+	if j := slices.IndexFunc(add.members, func(m *ast.Member) bool { return m.Key.String() == key }); j >= 0 {
+		return fmt.Errorf("edits %q and %q overlap", add.pointers[j], ed.pointer)
+	}
+	add.pointers = append(add.pointers, ed.pointer)
+	add.members = append(add.members, &ast.Member{Key: ast.String(key), Value: ed.value})
+	return nil
+}
+
+// addMembers records the splices to add new members to an existing object.
+// If the last member of the object is on a line of its own, the new members
+// are added on new lines after it; otherwise they are added inline.
+func (p *sourcePatcher) addMembers(add *memberAdd) error {
+	oloc := ValueLocation(add.obj).Span
+	ptr := add.pointers[0]
+	n := len(add.obj.Members)
+	if n == 0 {
+		base := p.lineIndent(oloc.Pos)
+		texts := p.renderMembers(add.members, base+p.unit)
+		if inner := p.src[oloc.Pos+1 : oloc.End-1]; len(bytes.TrimSpace(inner)) == 0 {
+			p.splices = append(p.splices, splice{
+				pos:     oloc.Pos + 1,
+				end:     oloc.End - 1,
+				text:    "\n" + base + p.unit + strings.Join(texts, ",\n"+base+p.unit) + "\n" + base,
+				pointer: ptr,
+			})
+		} else {
+			// The object contains only comments; add the members after the brace.
+			p.splices = append(p.splices, splice{
+				pos:     oloc.Pos + 1,
+				end:     oloc.Pos + 1,
+				text:    "\n" + base + p.unit + strings.Join(texts, ",\n"+base+p.unit) + ",",
+				pointer: ptr,
+			})
+		}
+		return nil
+	}
+
+	// The layout follows the last member that is not being deleted, but the
+	// comma style follows the last member of the original.
+	k := n - 1
+	for k >= 0 && p.deleted[add.obj.Members[k]] {
+		k--
+	}
+	if k < 0 {
+		return fmt.Errorf("edit %q: cannot add to an object whose members are all deleted", ptr)
+	}
+	orig := ValueLocation(add.obj.Members[n-1]).Span
+	comma := p.skipSpace(orig.End)
+	hasComma := comma < len(p.src) && p.src[comma] == ','
+
+	last := ValueLocation(add.obj.Members[k]).Span
+	nl := bytes.IndexByte(p.src[last.End:oloc.End], '\n')
+	if p.ownLine(last.Pos) && nl >= 0 {
+		// Add the new members on lines following the last member. If the last
+		// member does not have a trailing comma, add one and omit it from the
+		// new members too.
+		ind := p.lineIndent(last.Pos)
+		texts := p.renderMembers(add.members, ind)
+		if !hasComma {
+			if k == n-1 {
+				p.splices = append(p.splices, splice{pos: last.End, end: last.End, text: ",", pointer: ptr})
+			} else {
+				// Keep the comma that deleting the last member would remove.
+				c := p.skipSpace(last.End)
+				p.splices = slices.DeleteFunc(p.splices, func(s splice) bool {
+					return s.pos == c && s.end == c+1 && s.text == ""
+				})
+			}
+		}
+		var sb strings.Builder
+		for i, text := range texts {
+			sb.WriteString("\n" + ind + text)
+			if hasComma || i < len(texts)-1 {
+				sb.WriteString(",")
+			}
+		}
+		pos := last.End + nl
+		if pos > 0 && p.src[pos-1] == '\r' {
+			pos-- // keep CRLF line endings intact
+		}
+		p.splices = append(p.splices, splice{pos: pos, end: pos, text: sb.String(), pointer: ptr})
+		return nil
+	}
+	texts := p.renderMembers(add.members, p.lineIndent(last.Pos))
+	p.splices = append(p.splices, splice{
+		pos:     orig.End,
+		end:     orig.End,
+		text:    ", " + strings.Join(texts, ", "),
+		pointer: ptr,
+	})
+	return nil
+}
+
+// deleteItem records the splices to delete item i of vs, which are the
+// members or elements of an object or array.
+func (p *sourcePatcher) deleteItem(vs []Value, i int, pointer string) {
+	p.deleted[vs[i]] = true
+	span := ValueLocation(vs[i]).Span
+	if p.ownLine(span.Pos) {
+		if end, ok := p.restOfLine(span.End); ok {
+			start := p.lineStart(span.Pos)
+			if len(vs[i].Comments().Before) != 0 {
+				start = p.lineCommentsBefore(start)
+			}
+			p.splices = append(p.splices, splice{pos: start, end: end, text: "", pointer: pointer})
+
+			// If this was the last item and it had no comma, remove the comma
+			// from the item before it, so that none is left trailing.
+			if i == len(vs)-1 && i > 0 {
+				if c := p.skipSpace(span.End); c >= len(p.src) || p.src[c] != ',' {
+					prev := ValueLocation(vs[i-1]).Span
+					if c := p.skipSpace(prev.End); c < len(p.src) && p.src[c] == ',' {
+						p.splices = append(p.splices, splice{pos: c, end: c + 1, text: "", pointer: pointer})
+					}
+				}
+			}
+			return
+		}
+	}
+
+	// The item shares its line with something else: Remove it along with the
+	// separator after it or, for the last item, before it.
+	switch {
+	case i < len(vs)-1:
+		p.splices = append(p.splices, splice{pos: span.Pos, end: ValueLocation(vs[i+1]).Span.Pos, pointer: pointer})
+	case i > 0:
+		p.splices = append(p.splices, splice{pos: ValueLocation(vs[i-1]).Span.End, end: span.End, pointer: pointer})
+	default:
+		end := span.End
+		if c := p.skipSpace(end); c < len(p.src) && p.src[c] == ',' {
+			end = c + 1
+		}
+		p.splices = append(p.splices, splice{pos: span.Pos, end: end, pointer: pointer})
+	}
+}
+
+// apply applies the splices to the source text and returns the result.
+// Overlapping deletions are merged; any other overlap is an error.
+func (p *sourcePatcher) apply() ([]byte, error) {
+	slices.SortStableFunc(p.splices, func(a, b splice) int { return a.pos - b.pos })
+	out := make([]byte, 0, len(p.src))
+	next := 0
+	var prev splice
+	for i, s := range p.splices {
+		if i > 0 && (s.pos < prev.end || (s.pos == prev.pos && s.pointer != prev.pointer)) {
+			if s.text != "" || prev.text != "" {
+				return nil, fmt.Errorf("edits %q and %q overlap", prev.pointer, s.pointer)
+			}
+			s.pos = prev.end // the deletions overlap; delete the rest of s
+			if s.end < s.pos {
+				s.end = s.pos
+			}
+		}
+		out = append(out, p.src[next:s.pos]...)
+		out = append(out, s.text...)
+		next, prev = s.end, s
+	}
+	return append(out, p.src[next:]...), nil
+}
+
+// render returns the formatted text of v, with each line after the first
+// indented by indent.
+func (p *sourcePatcher) render(v ast.Value, indent string) string {
+	jv, ok := v.(Value)
+	if !ok {
+		jv = Decorate(v)
+	}
+	var sb strings.Builder
+	Formatter{Indent: p.unit}.Format(&sb, jv)
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines[1:] {
+		if line != "" {
+			lines[i+1] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *sourcePatcher) renderMembers(ms []*ast.Member, indent string) []string {
+	out := make([]string, len(ms))
+	for i, m := range ms {
+		out[i] = m.Key.Quote().JSON() + ": " + p.render(m.Value, indent)
+	}
+	return out
+}
+
+// lineStart returns the offset of the start of the line containing pos.
+func (p *sourcePatcher) lineStart(pos int) int {
+	return bytes.LastIndexByte(p.src[:pos], '\n') + 1
+}
+
+// lineIndent returns the leading whitespace of the line containing pos.
+func (p *sourcePatcher) lineIndent(pos int) string {
+	start := p.lineStart(pos)
+	end := start
+	for end < len(p.src) && (p.src[end] == ' ' || p.src[end] == '\t') {
+		end++
+	}
+	return string(p.src[start:end])
+}
+
+// ownLine reports whether only whitespace precedes pos on its line.
+func (p *sourcePatcher) ownLine(pos int) bool {
+	return len(bytes.TrimSpace(p.src[p.lineStart(pos):pos])) == 0
+}
+
+// skipSpace returns the offset of the first non-blank byte at or after pos
+// on the same line, or the end of the line.
+func (p *sourcePatcher) skipSpace(pos int) int {
+	for pos < len(p.src) && (p.src[pos] == ' ' || p.src[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+// restOfLine reports whether the line containing pos has nothing after pos
+// but an optional comma and line comment. If so, it returns the offset of the
+// start of the next line.
+func (p *sourcePatcher) restOfLine(pos int) (int, bool) {
+	pos = p.skipSpace(pos)
+	if pos < len(p.src) && p.src[pos] == ',' {
+		pos = p.skipSpace(pos + 1)
+	}
+	rest := p.src[pos:]
+	nl := bytes.IndexByte(rest, '\n')
+	if nl < 0 {
+		nl = len(rest)
+	} else {
+		nl++ // include the newline
+	}
+	if line := bytes.TrimSpace(rest[:nl]); len(line) != 0 && !bytes.HasPrefix(line, []byte("//")) {
+		return 0, false
+	}
+	return pos + nl, true
+}
+
+// lineCommentsBefore returns the start of the run of lines containing only
+// line comments immediately before the line starting at start.
+func (p *sourcePatcher) lineCommentsBefore(start int) int {
+	for start > 0 {
+		prev := p.lineStart(start - 1)
+		if !bytes.HasPrefix(bytes.TrimSpace(p.src[prev:start]), []byte("//")) {
+			break
+		}
+		start = prev
+	}
+	return start
+}
+
+// guessIndent returns the unit of indentation used in src, judging by the
+// first object or array in v whose first item is on a line of its own. If
+// there is none, it returns "".
+func guessIndent(src []byte, v Value) string {
+	var items []Value
+	switch t := v.(type) {
+	case *Object:
+		items = memberValues(t.Members)
+	case *Array:
+		items = t.Values
+	default:
+		return ""
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	p := &sourcePatcher{src: src}
+	pos, ipos := ValueLocation(v).Span.Pos, ValueLocation(items[0]).Span.Pos
+	if p.lineStart(pos) != p.lineStart(ipos) && p.ownLine(ipos) {
+		outer, inner := p.lineIndent(pos), p.lineIndent(ipos)
+		if unit, ok := strings.CutPrefix(inner, outer); ok && unit != "" {
+			return unit
+		}
+	}
+	for _, item := range items {
+		if m, ok := item.(*Member); ok {
+			item = m.Value
+		}
+		if unit := guessIndent(src, item); unit != "" {
+			return unit
+		}
+	}
+	return ""
+}
@@ -3,9 +3,12 @@
 package jwcc
 
 import (
+	"io"
+	"strconv"
 	"strings"
 
 	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/escape"
 )
 
 // CleanComments combines and removes comment markers from the given comments,
@@ -46,3 +49,177 @@ func Decorate(v ast.Value) Value {
 		return &Datum{Value: v}
 	}
 }
+
+// A CommentMap records the comments of a JWCC value, keyed by the JSON Pointer
+// (RFC 6901) of the value to which they are attached. The root value has the
+// empty pointer "". Only locations with comments or hints are recorded.
+type CommentMap map[string]NodeComments
+
+// NodeComments records the comments attached at a single location.
+type NodeComments struct {
+	Value  Comments // comments on the value
+	Member Comments // comments on the object member whose value this is
+	Doc    Comments // comments on the enclosing document (root only)
+}
+
+func (n NodeComments) isEmpty() bool {
+	return isBlank(n.Value) && isBlank(n.Member) && isBlank(n.Doc)
+}
+
+// isBlank reports whether c has neither comments nor hints.
+func isBlank(c Comments) bool { return c.IsEmpty() && c.hint == 0 }
+
+// UndecorateWithComments converts v into an equivalent ast.Value, like its
+// Undecorate method, and returns a CommentMap of the comments that were
+// discarded in the conversion. If the result is not modified, passing it
+// and the map to DecorateWithComments recovers a value equivalent to v.
+func UndecorateWithComments(v Value) (ast.Value, CommentMap) {
+	if v == nil {
+		return nil, nil
+	}
+	cm := make(CommentMap)
+	var root NodeComments
+	if d, ok := v.(*Document); ok {
+		root.Doc = d.com
+		v = d.Value
+	}
+	root.Value = *v.Comments()
+	if !root.isEmpty() {
+		cm[""] = root
+	}
+	cm.record("", v)
+	return v.Undecorate(), cm
+}
+
+// record adds the comments of the contents of v, whose pointer is path, to cm.
+func (cm CommentMap) record(path string, v Value) {
+	switch t := v.(type) {
+	case *Object:
+		for _, m := range t.Members {
+			mp := path + "/" + escapePointer(m.Key.String())
+			if nc := (NodeComments{Value: *m.Value.Comments(), Member: m.com}); !nc.isEmpty() {
+				cm[mp] = nc
+			}
+			cm.record(mp, m.Value)
+		}
+	case *Array:
+		for i, e := range t.Values {
+			ep := path + "/" + strconv.Itoa(i)
+			if nc := (NodeComments{Value: *e.Comments()}); !nc.isEmpty() {
+				cm[ep] = nc
+			}
+			cm.record(ep, e)
+		}
+	}
+}
+
+// DecorateWithComments converts an ast.Value into an equivalent jwcc.Value,
+// like Decorate, and attaches the comments recorded in cm at the corresponding
+// locations. Entries of cm that do not match a location in v are ignored. If
+// cm has document comments for the root, the result is a *Document.
+func DecorateWithComments(v ast.Value, cm CommentMap) Value {
+	out := Decorate(v)
+	if out == nil {
+		return nil
+	}
+	cm.attach("", out)
+	root := cm[""]
+	*out.Comments() = root.Value
+	if !isBlank(root.Doc) {
+		d := &Document{Value: out}
+		d.com = root.Doc
+		return d
+	}
+	return out
+}
+
+// attach sets the comments of the contents of v, whose pointer is path, from
+// the entries of cm.
+func (cm CommentMap) attach(path string, v Value) {
+	switch t := v.(type) {
+	case *Object:
+		for _, m := range t.Members {
+			mp := path + "/" + escapePointer(m.Key.String())
+			if nc, ok := cm[mp]; ok {
+				m.com = nc.Member
+				*m.Value.Comments() = nc.Value
+			}
+			cm.attach(mp, m.Value)
+		}
+	case *Array:
+		for i, e := range t.Values {
+			ep := path + "/" + strconv.Itoa(i)
+			if nc, ok := cm[ep]; ok {
+				*e.Comments() = nc.Value
+			}
+			cm.attach(ep, e)
+		}
+	}
+}
+
+// DecorateWith converts an ast.Value into an equivalent jwcc.Value, like
+// Decorate, and attaches the comments from the given map, whose keys are JSON
+// Pointers (RFC 6901) to locations in v. Comments for an object member are
+// attached to the member, so that they are rendered with its key; other
+// comments are attached to the value at that location. Entries of comments
+// that do not match a location in v are ignored.
+//
+// This is useful to generate a commented file from plain data and a table of
+// documentation. Comment markers are added to text that does not have them:
+//
+//	v := jwcc.DecorateWith(data, map[string]jwcc.Comments{
+//	   "/port": {Before: []string{"The port to listen on."}},
+//	})
+func DecorateWith(v ast.Value, comments map[string]Comments) Value {
+	out := Decorate(v)
+	if out == nil {
+		return nil
+	}
+	if c, ok := comments[""]; ok {
+		*out.Comments() = c
+	}
+	attachComments("", out, comments)
+	return out
+}
+
+// attachComments sets the comments of the contents of v, whose pointer is
+// path, from the entries of cs.
+func attachComments(path string, v Value, cs map[string]Comments) {
+	switch t := v.(type) {
+	case *Object:
+		for _, m := range t.Members {
+			mp := path + "/" + escapePointer(m.Key.String())
+			if c, ok := cs[mp]; ok {
+				m.com = c
+			}
+			attachComments(mp, m.Value, cs)
+		}
+	case *Array:
+		for i, e := range t.Values {
+			ep := path + "/" + strconv.Itoa(i)
+			if c, ok := cs[ep]; ok {
+				*e.Comments() = c
+			}
+			attachComments(ep, e, cs)
+		}
+	}
+}
+
+// escapePointer escapes s for use as a JSON Pointer reference token.
+func escapePointer(s string) string { return escape.EscapeToken(s) }
+
+// Minimize returns the text of v as strict JSON, without comments, trailing
+// commas, or insignificant whitespace. Members and elements appear in their
+// original order, and numbers and strings keep the spelling they had in the
+// input from which v was parsed.
+func Minimize(v Value) []byte { return []byte(v.JSON()) }
+
+// Standardize parses a JWCC document from r and returns it as strict JSON, as
+// for Minimize.
+func Standardize(r io.Reader) ([]byte, error) {
+	d, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return Minimize(d), nil
+}
@@ -3,8 +3,11 @@
 package jtree
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // A Span describes a contiguous span of a source input.
@@ -24,7 +27,7 @@ func (s Span) String() string {
 // source text.
 type LineCol struct {
 	Line   int // line number, 1-based
-	Column int // byte offset of column in line, 0-based
+	Column int // byte (or rune) offset of column in line, 0-based
 }
 
 func (lc LineCol) String() string { return fmt.Sprintf("%d:%d", lc.Line, lc.Column) }
@@ -42,3 +45,58 @@ func (loc Location) String() string {
 	}
 	return loc.First.String() + "-" + loc.Last.String()
 }
+
+// Snippet renders a diagnostic for the source text at loc, for display to a
+// human reader. The result gives the line and column of loc, followed by the
+// line of source text containing loc, and a line of "^" markers under the
+// text spanned by loc on that line. For example:
+//
+//	line 2, column 9:
+//	  "key": tru,
+//	         ^^^
+//
+// The column is reported 1-based, as most editors display it.
+//
+// The input src may be the complete source, or a window of it: offset is the
+// position of src[0] in the source text. If src does not contain the start of
+// loc, only the line and column are reported.
+func Snippet(src []byte, offset int, loc Location) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "line %d, column %d", loc.First.Line, loc.First.Column+1)
+	if text, marks, ok := snippetLines(src, offset, loc.Span); ok {
+		fmt.Fprintf(&sb, ":\n%s\n%s", text, marks)
+	}
+	return sb.String()
+}
+
+// snippetLines returns the line of src containing the start of span, and a
+// line of markers under the portion of it spanned. It reports false if src
+// does not contain the start of span.
+func snippetLines(src []byte, offset int, span Span) (text, marks string, ok bool) {
+	pos := span.Pos - offset
+	if pos < 0 || pos > len(src) {
+		return "", "", false
+	}
+	start := bytes.LastIndexByte(src[:pos], '\n') + 1
+	end := bytes.IndexByte(src[pos:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += pos
+	}
+	line := bytes.TrimSuffix(src[start:end], []byte("\r"))
+
+	// Pad the markers to the start of the span, keeping tabs so that the
+	// markers line up with the text.
+	var mb strings.Builder
+	for _, r := range string(line[:min(pos-start, len(line))]) {
+		if r == '\t' {
+			mb.WriteByte('\t')
+		} else {
+			mb.WriteByte(' ')
+		}
+	}
+	stop := min(span.End-offset, start+len(line))
+	mb.WriteString(strings.Repeat("^", max(utf8.RuneCount(src[min(pos, stop):stop]), 1)))
+	return string(line), mb.String(), true
+}
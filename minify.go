@@ -0,0 +1,191 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// MinifyOptions are settings for the MinifyWith function. A nil
+// *MinifyOptions is ready for use, and removes all comments and trailing
+// commas.
+type MinifyOptions struct {
+	// Retain comments in the output. Each line comment is followed by a
+	// newline, even if it was the last line of the input.
+	KeepComments bool
+
+	// Retain commas following the last member of an object or the last
+	// element of an array.
+	KeepTrailingCommas bool
+}
+
+func (o *MinifyOptions) keepComments() bool { return o != nil && o.KeepComments }
+
+func (o *MinifyOptions) keepTrailingCommas() bool { return o != nil && o.KeepTrailingCommas }
+
+// Minify copies the JSON or JWCC text from src to dst, without insignificant
+// whitespace, comments, or trailing commas. It is shorthand for MinifyWith
+// with nil options.
+func Minify(dst io.Writer, src io.Reader) error { return MinifyWith(dst, src, nil) }
+
+// MinifyWith copies the JSON or JWCC text from src to dst, without
+// insignificant whitespace, and without comments or trailing commas unless
+// opts says to keep them. The text of each token is copied exactly, so
+// strings and numbers keep the spelling they had in the input. Multiple
+// values in the input are separated by newlines in the output.
+//
+// MinifyWith works directly on the tokens of the input, and checks only that
+// they are lexically valid. It does not check the structure of the input:
+// If src does not contain valid JSON, neither will dst.
+func MinifyWith(dst io.Writer, src io.Reader, opts *MinifyOptions) error {
+	s := NewScanner(src)
+	s.AllowComments(true)
+	keepCom, keepComma := opts.keepComments(), opts.keepTrailingCommas()
+
+	w := bufio.NewWriter(dst)
+	var depth int
+	var comma bool // a comma is pending
+	var done bool  // a top-level value has been written
+	for {
+		if err := s.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		switch tok := s.Token(); tok {
+		case LineComment, BlockComment:
+			if keepCom {
+				text := s.Text()
+				w.Write(text)
+				if tok == LineComment && !bytes.HasSuffix(text, []byte("\n")) {
+					w.WriteByte('\n')
+				}
+			}
+			continue
+
+		case Comma:
+			if keepComma {
+				w.WriteByte(',')
+			} else {
+				comma = true // hold it until we know it is not trailing
+			}
+			continue
+
+		case RBrace, RSquare:
+			depth--
+
+		case Colon:
+
+		default:
+			if depth == 0 && done {
+				w.WriteByte('\n')
+			}
+			if tok == LBrace || tok == LSquare {
+				depth++
+			}
+		}
+		if comma && s.Token() != RBrace && s.Token() != RSquare {
+			w.WriteByte(',')
+		}
+		comma = false
+		w.Write(s.Text())
+		if depth == 0 && s.Token() != Colon {
+			done = true
+		}
+	}
+	if comma {
+		w.WriteByte(',') // not followed by anything
+	}
+	return w.Flush()
+}
+
+// Indent copies the JSON or JWCC text from src to dst, indented in the manner
+// of json.Indent from the standard library. Each member of an object and each
+// element of an array begins on a new line beginning with prefix followed by
+// one or more copies of indent according to its nesting depth. The first line
+// of the output does not begin with prefix. Empty objects and arrays are
+// rendered as "{}" and "[]". Multiple values in the input are separated by
+// newlines in the output.
+//
+// Unlike json.Indent, Indent accepts comments and trailing commas, which are
+// copied to the output. A comment that follows another token on the same line
+// of the input remains on that line; any other comment begins a new line.
+//
+// Like MinifyWith, Indent works directly on the tokens of the input, and uses
+// constant memory apart from the text of individual tokens. It checks only that
+// the tokens are lexically valid, not the structure of the input.
+func Indent(dst io.Writer, src io.Reader, prefix, indent string) error {
+	s := NewScanner(src)
+	s.AllowComments(true)
+
+	w := bufio.NewWriter(dst)
+	newline := func(depth int) {
+		w.WriteByte('\n')
+		w.WriteString(prefix)
+		for range depth {
+			w.WriteString(indent)
+		}
+	}
+	var depth int
+	var started bool // some output has been written
+	var needNL bool  // the next token begins a new line
+	var space bool   // the next token is preceded by a space, if not a newline
+	var empty bool   // the last token opened an object or array
+	lastLine := -1   // the input line where the last token ended
+	for {
+		if err := s.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		tok, loc := s.Token(), s.Location()
+		switch tok {
+		case LineComment, BlockComment:
+			if started && loc.First.Line == lastLine {
+				w.WriteByte(' ') // a comment on the line of the previous token
+			} else if started {
+				newline(depth)
+			}
+			w.Write(bytes.TrimSuffix(s.Text(), []byte("\n")))
+			if tok == LineComment {
+				needNL, lastLine = true, -1
+			} else {
+				space, lastLine = true, loc.Last.Line
+			}
+			started, empty = true, false
+			continue
+
+		case RBrace, RSquare:
+			depth--
+			if !empty {
+				newline(depth)
+			}
+			needNL = depth == 0
+
+		case Colon:
+			space = true
+
+		case Comma:
+			needNL = true
+
+		default:
+			if needNL {
+				newline(depth)
+			} else if space {
+				w.WriteByte(' ')
+			}
+			needNL = depth == 0
+			if tok == LBrace || tok == LSquare {
+				depth++
+				needNL = true
+			}
+		}
+		w.Write(s.Text())
+		started, empty = true, tok == LBrace || tok == LSquare
+		space = tok == Colon
+		lastLine = loc.Last.Line
+	}
+	return w.Flush()
+}
@@ -0,0 +1,416 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package msgpack converts JSON values to and from the MessagePack format
+// (https://msgpack.org).
+//
+// Encode maps each ast.Value to the corresponding MessagePack value, using the
+// smallest representation for each: An object becomes a map whose keys are
+// strings, in the order of its members; an array, string, Boolean, or null
+// becomes the value of the same kind. An integer becomes a MessagePack
+// integer, and any other number becomes a float, so the distinction between
+// integers and floats survives a round trip.
+//
+// Decode accepts any well-formed MessagePack value and maps it onto the
+// nearest JSON value, as the cbor package does: Binary data become
+// base64url-encoded strings, integer map keys become their decimal text, and
+// timestamps become strings in RFC 3339 format. Other extension types are not
+// supported.
+//
+// Together with the tq and cursor packages, this allows MessagePack payloads
+// to be queried and edited as syntax trees, without a detour through
+// map[string]any.
+package msgpack
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/ast"
+)
+
+// maxDepth is the maximum nesting depth of arrays and maps accepted by Decode,
+// to bound the stack used by hostile input.
+const maxDepth = 10000
+
+// extTimestamp is the extension type of a timestamp.
+const extTimestamp = -1
+
+// Encode returns the MessagePack encoding of v. It reports an error if v
+// contains a value that is not an ast.Object, ast.Array, ast.Text, ast.Number,
+// ast.Bool, or ast.Null, or an integer that does not fit in 64 bits.
+func Encode(v ast.Value) ([]byte, error) { return Append(nil, v) }
+
+// Append appends the MessagePack encoding of v to buf, and returns the
+// extended slice. It reports an error if v contains a value that cannot be
+// encoded, as Encode does.
+func Append(buf []byte, v ast.Value) ([]byte, error) {
+	switch t := v.(type) {
+	case ast.Object:
+		buf = appendLen(buf, len(t), 0x80, 15, 0xde)
+		for _, m := range t {
+			buf = appendString(buf, m.Key.String())
+			var err error
+			buf, err = Append(buf, m.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case ast.Array:
+		buf = appendLen(buf, len(t), 0x90, 15, 0xdc)
+		for _, elt := range t {
+			var err error
+			buf, err = Append(buf, elt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case ast.Bool:
+		if t {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case ast.Number:
+		return appendNumber(buf, t)
+	case ast.Text:
+		return appendString(buf, t.String()), nil
+	}
+	if v == ast.Null {
+		return append(buf, 0xc0), nil
+	}
+	return nil, fmt.Errorf("cannot encode %T as MessagePack", v)
+}
+
+// appendLen appends the header of a string, array, or map of length n. If n
+// is at most fixMax, the header is a single byte fix|n. Otherwise code is the
+// header byte for a 16-bit length, and code+1 for a 32-bit length.
+func appendLen(buf []byte, n int, fix byte, fixMax int, code byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, code), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, code+1), uint32(n))
+	}
+}
+
+func appendString(buf []byte, s string) []byte {
+	if n := len(s); n > 31 && n <= math.MaxUint8 {
+		buf = append(buf, 0xd9, byte(n)) // str8 has no counterpart for arrays and maps
+	} else {
+		buf = appendLen(buf, n, 0xa0, 31, 0xda)
+	}
+	return append(buf, s...)
+}
+
+func appendNumber(buf []byte, n ast.Number) ([]byte, error) {
+	switch t := n.(type) {
+	case ast.Int:
+		return appendInt(buf, int64(t)), nil
+	case ast.Float:
+		return appendFloat(buf, float64(t)), nil
+	}
+	if !n.IsInt() {
+		// A number parsed from JSON text may be out of range for a float64.
+		text := n.JSON()
+		f, err := jtree.ParseFloat([]byte(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("number %s out of range for MessagePack", text)
+		}
+		return appendFloat(buf, f), nil
+	}
+
+	// An integer parsed from JSON text may not fit in an int64.
+	text := n.JSON()
+	if z, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return appendInt(buf, z), nil
+	}
+	u, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("integer %s out of range for MessagePack", text)
+	}
+	return binary.BigEndian.AppendUint64(append(buf, 0xcf), u), nil
+}
+
+func appendInt(buf []byte, z int64) []byte {
+	switch {
+	case z >= 0 && z <= math.MaxInt8:
+		return append(buf, byte(z)) // positive fixint
+	case z >= -32 && z < 0:
+		return append(buf, byte(z)) // negative fixint
+	case z >= 0 && z <= math.MaxUint8:
+		return append(buf, 0xcc, byte(z))
+	case z >= 0 && z <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xcd), uint16(z))
+	case z >= 0 && z <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xce), uint32(z))
+	case z >= 0:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcf), uint64(z))
+	case z >= math.MinInt8:
+		return append(buf, 0xd0, byte(z))
+	case z >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(z))
+	case z >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(z))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(z))
+	}
+}
+
+// appendFloat appends f as a float32 if that is exact, and otherwise as a
+// float64.
+func appendFloat(buf []byte, f float64) []byte {
+	if f32 := float32(f); float64(f32) == f || math.IsNaN(f) {
+		return binary.BigEndian.AppendUint32(append(buf, 0xca), math.Float32bits(f32))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(f))
+}
+
+// Decode decodes a single MessagePack value from data and returns the
+// corresponding JSON value. It reports an error if data is not well-formed,
+// or contains anything other than a single value.
+func Decode(data []byte) (ast.Value, error) {
+	d := &decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("offset %d: extra data after value", d.pos)
+	}
+	return v, nil
+}
+
+// A decoder decodes MessagePack values from a buffer.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) fail(msg string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", d.pos, fmt.Sprintf(msg, args...))
+}
+
+// next consumes and returns the next n bytes of input.
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, d.fail("unexpected end of input")
+	}
+	out := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return out, nil
+}
+
+// uint consumes and returns a big-endian unsigned integer of size bytes.
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// length consumes a length of size bytes, and checks that it could be
+// satisfied by the remaining input, with each item taking at least unit
+// bytes.
+func (d *decoder) length(size, unit int) (int, error) {
+	n, err := d.uint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.pos)/uint64(unit) {
+		return 0, d.fail("length %d exceeds the input", n)
+	}
+	return int(n), nil
+}
+
+func (d *decoder) value(depth int) (ast.Value, error) {
+	if depth > maxDepth {
+		return nil, d.fail("value nested too deeply")
+	}
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	switch c := b[0]; {
+	case c <= 0x7f: // positive fixint
+		return ast.Int(c), nil
+	case c >= 0xe0: // negative fixint
+		return ast.Int(int8(c)), nil
+	case c <= 0x8f: // fixmap
+		return d.object(int(c&0x0f), depth)
+	case c <= 0x9f: // fixarray
+		return d.array(int(c&0x0f), depth)
+	case c <= 0xbf: // fixstr
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c := b[0]; c {
+	case 0xc0:
+		return ast.Null, nil
+	case 0xc2:
+		return ast.Bool(false), nil
+	case 0xc3:
+		return ast.Bool(true), nil
+
+	case 0xc4, 0xc5, 0xc6: // bin 8, 16, 32
+		n, err := d.length(1<<(c-0xc4), 1)
+		if err != nil {
+			return nil, err
+		}
+		bin, _ := d.next(n)
+		return ast.String(base64.RawURLEncoding.EncodeToString(bin)), nil
+
+	case 0xc7, 0xc8, 0xc9: // ext 8, 16, 32
+		n, err := d.length(1<<(c-0xc7), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(n)
+
+	case 0xca:
+		u, err := d.uint(4)
+		return ast.Float(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return ast.Float(math.Float64frombits(u)), err
+
+	case 0xcc, 0xcd, 0xce, 0xcf: // uint 8, 16, 32, 64
+		u, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		} else if u > math.MaxInt64 {
+			return ast.ParseSingle(strings.NewReader(strconv.FormatUint(u, 10)))
+		}
+		return ast.Int(u), nil
+
+	case 0xd0, 0xd1, 0xd2, 0xd3: // int 8, 16, 32, 64
+		size := 1 << (c - 0xd0)
+		u, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*size
+		return ast.Int(int64(u<<shift) >> shift), nil // sign-extend
+
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: // fixext 1, 2, 4, 8, 16
+		return d.ext(1 << (c - 0xd4))
+
+	case 0xd9, 0xda, 0xdb: // str 8, 16, 32
+		n, err := d.length(1<<(c-0xd9), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+
+	case 0xdc, 0xdd: // array 16, 32
+		n, err := d.length(2<<(c-0xdc), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.array(n, depth)
+
+	case 0xde, 0xdf: // map 16, 32
+		n, err := d.length(2<<(c-0xde), 2)
+		if err != nil {
+			return nil, err
+		}
+		return d.object(n, depth)
+	}
+	d.pos--
+	return nil, d.fail("invalid type byte 0x%02x", b[0])
+}
+
+func (d *decoder) str(n int) (ast.Value, error) {
+	s, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return ast.String(s), nil
+}
+
+func (d *decoder) array(n, depth int) (ast.Value, error) {
+	out := make(ast.Array, n)
+	for i := range out {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (d *decoder) object(n, depth int) (ast.Value, error) {
+	out := make(ast.Object, n)
+	for i := range out {
+		kpos := d.pos
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		var key ast.String
+		switch t := k.(type) {
+		case ast.String:
+			key = t
+		case ast.Int:
+			key = ast.String(t.JSON())
+		default:
+			d.pos = kpos
+			return nil, d.fail("unsupported map key %s", k.JSON())
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = &ast.Member{Key: key, Value: v}
+	}
+	return out, nil
+}
+
+// ext decodes an extension value with n bytes of data, whose type has not yet
+// been read. Only timestamps are supported.
+func (d *decoder) ext(n int) (ast.Value, error) {
+	start := d.pos
+	tb, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(tb[0]) != extTimestamp {
+		d.pos = start
+		return nil, d.fail("unsupported extension type %d", int8(tb[0]))
+	}
+	var sec int64
+	var nsec uint32
+	switch n {
+	case 4:
+		sec = int64(binary.BigEndian.Uint32(data))
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		sec, nsec = int64(v&(1<<34-1)), uint32(v>>34)
+	case 12:
+		nsec = binary.BigEndian.Uint32(data)
+		sec = int64(binary.BigEndian.Uint64(data[4:]))
+	default:
+		d.pos = start
+		return nil, d.fail("invalid timestamp length %d", n)
+	}
+	t := time.Unix(sec, int64(nsec)).UTC()
+	return ast.String(t.Format(time.RFC3339Nano)), nil
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package msgpack_test
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/testutil"
+	"github.com/creachadair/jtree/msgpack"
+)
+
+func TestEncode(t *testing.T) {
+	long := strings.Repeat("x", 40)
+	tests := []struct {
+		input ast.Value
+		want  string // hex
+	}{
+		{ast.Int(0), "00"},
+		{ast.Int(127), "7f"},
+		{ast.Int(128), "cc80"},
+		{ast.Int(1000), "cd03e8"},
+		{ast.Int(1 << 20), "ce00100000"},
+		{ast.Int(1 << 40), "cf0000010000000000"},
+		{ast.Int(-1), "ff"},
+		{ast.Int(-32), "e0"},
+		{ast.Int(-33), "d0df"},
+		{ast.Int(-1000), "d1fc18"},
+		{ast.Int(-1 << 20), "d2fff00000"},
+		{ast.Int(math.MinInt64), "d38000000000000000"},
+		{ast.Float(1.5), "ca3fc00000"},
+		{ast.Float(1.1), "cb3ff199999999999a"},
+		{ast.Float(2), "ca40000000"},
+		{ast.Bool(false), "c2"},
+		{ast.Bool(true), "c3"},
+		{ast.Null, "c0"},
+		{ast.String(""), "a0"},
+		{ast.String("abc"), "a3616263"},
+		{ast.String(long), "d928" + strings.Repeat("78", 40)},
+		{ast.Array{}, "90"},
+		{ast.ArrayOf(1, ast.ArrayOf(2, 3)), "9201920203"},
+		{ast.ObjectOf("b", 1, "a", ast.ArrayOf(2)), "82a16201a1619102"},
+
+		// Numbers parsed from text.
+		{testutil.MustParse(t, `25`), "19"},
+		{testutil.MustParse(t, `2.0`), "ca40000000"},
+		{testutil.MustParse(t, `18446744073709551615`), "cfffffffffffffffff"},
+	}
+	for _, tc := range tests {
+		got, err := msgpack.Encode(tc.input)
+		if err != nil {
+			t.Errorf("Encode %s: unexpected error: %v", tc.input.JSON(), err)
+			continue
+		}
+		if h := hex.EncodeToString(got); h != tc.want {
+			t.Errorf("Encode %s: got %s, want %s", tc.input.JSON(), h, tc.want)
+		}
+
+		// Every encoding should decode to a value with the same encoding.
+		dec, err := msgpack.Decode(got)
+		if err != nil {
+			t.Errorf("Decode %s: unexpected error: %v", tc.want, err)
+			continue
+		}
+		if re, err := msgpack.Encode(dec); err != nil {
+			t.Errorf("Encode %s: unexpected error: %v", dec.JSON(), err)
+		} else if h := hex.EncodeToString(re); h != tc.want {
+			t.Errorf("Round trip %s: got %s", tc.want, h)
+		}
+	}
+
+	for _, bad := range []ast.Value{
+		ast.ArrayOf(1, badValue{}),
+		testutil.MustParse(t, `18446744073709551616`),
+		testutil.MustParse(t, `-9223372036854775809`),
+		testutil.MustParse(t, `1e400`),
+		testutil.MustParse(t, `-1e400`),
+	} {
+		if _, err := msgpack.Encode(bad); err == nil {
+			t.Errorf("Encode %s: got nil, want error", bad.JSON())
+		}
+	}
+}
+
+type badValue struct{}
+
+func (badValue) JSON() string   { return "bad" }
+func (badValue) String() string { return "bad" }
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		input string // hex
+		want  string // JSON
+	}{
+		{"dc0002 01 02", "[1,2]"},
+		{"de0001 a161 c3", `{"a":true}`},
+		{"82 01 02 a3616263 c0", `{"1":2,"abc":null}`},
+		{"c40401020304", `"AQIDBA"`},
+		{"da0002 6869", `"hi"`},
+		{"d0ff", "-1"},
+		{"d1ff00", "-256"},
+		{"d6ff 514b67b0", `"2013-03-21T20:04:00Z"`},
+		{"c70cff 0000000a 00000000514b67b0", `"2013-03-21T20:04:00.00000001Z"`},
+		{"cb4000000000000000", "2"},
+	}
+	for _, tc := range tests {
+		data, err := hex.DecodeString(strings.ReplaceAll(tc.input, " ", ""))
+		if err != nil {
+			t.Fatalf("Invalid test input %q: %v", tc.input, err)
+		}
+		got, err := msgpack.Decode(data)
+		if err != nil {
+			t.Errorf("Decode %s: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if s := got.JSON(); s != tc.want {
+			t.Errorf("Decode %s: got %s, want %s", tc.input, s, tc.want)
+		}
+	}
+
+	if v, _ := msgpack.Decode([]byte{0xca, 0x40, 0, 0, 0}); v.(ast.Number).IsInt() {
+		t.Error("Decode float 2.0: got an integer, want a float")
+	}
+
+	for _, bad := range []string{
+		"",           // empty
+		"cd01",       // truncated integer
+		"a3 6162",    // truncated string
+		"ddffffffff", // length beyond input
+		"0000",       // extra data
+		"c1",         // never used
+		"81 c3 01",   // unsupported key
+		"d401 00",    // unsupported extension
+		"d5ff 0000",  // invalid timestamp
+	} {
+		data, _ := hex.DecodeString(strings.ReplaceAll(bad, " ", ""))
+		if v, err := msgpack.Decode(data); err == nil {
+			t.Errorf("Decode %s: got %v, want error", bad, v)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package persist implements persistent (immutable) variants of JSON objects
+// and arrays.
+//
+// An Object or Array is never modified in place. Instead, each update returns
+// a new value that shares most of its structure with the original, so that an
+// update costs O(log n) time and space rather than the O(n) needed to copy an
+// ast.Object or ast.Array. This makes them suitable for applying many small
+// edits to a large value, for example with tq.Set and tq.Delete:
+//
+//	obj := persist.NewObject(big)
+//	for _, key := range stale {
+//	   obj = obj.Delete(key)
+//	}
+//	result := obj.Object() // convert back to an ast.Object
+//
+// Both types implement ast.Value. The zero value of each is empty and ready
+// for use.
+package persist
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/creachadair/jtree/ast"
+)
+
+// An Object is a persistent collection of key-value members, in order.
+// Unlike an ast.Object, an Object has at most one member with a given key.
+// Keys are compared exactly by Get, Set, and Delete, and case-insensitively
+// by Find, which matches the first member as ast.Object.Find does.
+type Object struct {
+	keys *node[string, int64]   // key → sequence number
+	fold *node[string, []int64] // folded key → sequence numbers, ascending
+	mems *node[int64, member]   // sequence number → member
+	next int64                  // the next unused sequence number
+}
+
+type member struct {
+	key   ast.Text
+	value ast.Value
+}
+
+// NewObject constructs an Object with the members of o, in order. If o has
+// more than one member with the same key, the value of the last of them is
+// kept, at the position of the first.
+func NewObject(o ast.Object) Object {
+	var out Object
+	seqs := make([]int64, 0, len(o))
+	mems := make([]member, 0, len(o))
+	for _, m := range o {
+		key := m.Key.String()
+		if seq, ok := get(out.keys, key, strings.Compare); ok {
+			mems[seq] = member{m.Key, m.Value}
+			continue
+		}
+		out.keys = put(out.keys, key, out.next, strings.Compare)
+		out.fold = addFold(out.fold, key, out.next)
+		seqs = append(seqs, out.next)
+		mems = append(mems, member{m.Key, m.Value})
+		out.next++
+	}
+	out.mems = build(seqs, mems)
+	return out
+}
+
+// Len reports the number of members in o.
+func (o Object) Len() int { return o.mems.getSize() }
+
+// Get reports the value of the member of o with the given key, and whether
+// such a member exists.
+func (o Object) Get(key string) (ast.Value, bool) {
+	seq, ok := get(o.keys, key, strings.Compare)
+	if !ok {
+		return nil, false
+	}
+	m, _ := get(o.mems, seq, cmp.Compare[int64])
+	return m.value, true
+}
+
+// Set returns a copy of o in which the member with the given key has value v.
+// If o has no such member, it is added at the end.
+func (o Object) Set(key string, v ast.Value) Object {
+	seq, ok := get(o.keys, key, strings.Compare)
+	if !ok {
+		seq = o.next
+		o.keys = put(o.keys, key, seq, strings.Compare)
+		o.fold = addFold(o.fold, key, seq)
+		o.next++
+	}
+	o.mems = put(o.mems, seq, member{ast.String(key), v}, cmp.Compare[int64])
+	return o
+}
+
+// Delete returns a copy of o without the member with the given key.
+// If o has no such member, Delete returns o unchanged.
+func (o Object) Delete(key string) Object {
+	seq, ok := get(o.keys, key, strings.Compare)
+	if !ok {
+		return o
+	}
+	o.keys = remove(o.keys, key, strings.Compare)
+	o.fold = removeFold(o.fold, key, seq)
+	o.mems = remove(o.mems, seq, cmp.Compare[int64])
+	return o
+}
+
+// Find reports the key and value of the first member of o whose key is
+// case-insensitively equal to key, and whether such a member exists.
+func (o Object) Find(key string) (string, ast.Value, bool) {
+	seqs, ok := get(o.fold, ast.FoldKey(key), strings.Compare)
+	if !ok {
+		return "", nil, false
+	}
+	m, _ := get(o.mems, seqs[0], cmp.Compare[int64])
+	return m.key.String(), m.value, true
+}
+
+// Replace returns a copy of o in which the member with key old is replaced
+// by a member with the given key and value v, at the same position. If o has
+// no member with key old, Replace is equivalent to Set(key, v). Otherwise, any
+// other member with the given key is removed.
+func (o Object) Replace(old, key string, v ast.Value) Object {
+	seq, ok := get(o.keys, old, strings.Compare)
+	if !ok {
+		return o.Set(key, v)
+	}
+	if key != old {
+		o = o.Delete(key)
+		o.keys = remove(o.keys, old, strings.Compare)
+		o.fold = removeFold(o.fold, old, seq)
+		o.keys = put(o.keys, key, seq, strings.Compare)
+		o.fold = addFold(o.fold, key, seq)
+	}
+	o.mems = put(o.mems, seq, member{ast.String(key), v}, cmp.Compare[int64])
+	return o
+}
+
+// addFold returns a copy of fold in which seq is recorded for key.
+func addFold(fold *node[string, []int64], key string, seq int64) *node[string, []int64] {
+	fk := ast.FoldKey(key)
+	seqs, _ := get(fold, fk, strings.Compare)
+	i, _ := slices.BinarySearch(seqs, seq)
+	return put(fold, fk, slices.Insert(slices.Clip(seqs), i, seq), strings.Compare)
+}
+
+// removeFold returns a copy of fold in which seq is not recorded for key.
+func removeFold(fold *node[string, []int64], key string, seq int64) *node[string, []int64] {
+	fk := ast.FoldKey(key)
+	seqs, _ := get(fold, fk, strings.Compare)
+	i, ok := slices.BinarySearch(seqs, seq)
+	if !ok {
+		return fold
+	} else if len(seqs) == 1 {
+		return remove(fold, fk, strings.Compare)
+	}
+	return put(fold, fk, slices.Delete(slices.Clone(seqs), i, i+1), strings.Compare)
+}
+
+// All returns an iterator over the keys and values of the members of o, in
+// order.
+func (o Object) All() iter.Seq2[string, ast.Value] {
+	return func(yield func(string, ast.Value) bool) {
+		o.mems.all(func(_ int64, m member) bool { return yield(m.key.String(), m.value) })
+	}
+}
+
+// Object returns an ast.Object with the members of o, in order.
+func (o Object) Object() ast.Object {
+	out := make(ast.Object, 0, o.Len())
+	o.mems.all(func(_ int64, m member) bool {
+		out = append(out, &ast.Member{Key: m.key, Value: m.value})
+		return true
+	})
+	return out
+}
+
+// JSON renders o as JSON text.
+func (o Object) JSON() string { return o.Object().JSON() }
+
+func (o Object) String() string { return fmt.Sprintf("persist.Object(len=%d)", o.Len()) }
+
+// An Array is a persistent sequence of values.
+type Array struct {
+	root *node[struct{}, ast.Value]
+}
+
+// NewArray constructs an Array with the elements of a, in order.
+func NewArray(a ast.Array) Array {
+	return Array{root: build[struct{}](nil, a)}
+}
+
+// Len reports the number of elements in a.
+func (a Array) Len() int { return a.root.getSize() }
+
+// At returns the element at offset i of a. It panics if i is out of range.
+func (a Array) At(i int) ast.Value {
+	a.checkIndex(i, a.Len())
+	return at(a.root, i).val
+}
+
+// Set returns a copy of a in which the element at offset i is v.
+// It panics if i is out of range.
+func (a Array) Set(i int, v ast.Value) Array {
+	a.checkIndex(i, a.Len())
+	return Array{root: setAt(a.root, i, v)}
+}
+
+// Insert returns a copy of a in which v is inserted at offset i, with later
+// elements moved over. An offset equal to the length of a appends v.
+// It panics if i is out of range.
+func (a Array) Insert(i int, v ast.Value) Array {
+	a.checkIndex(i, a.Len()+1)
+	return Array{root: insertAt(a.root, i, struct{}{}, v)}
+}
+
+// Append returns a copy of a with v added at the end.
+func (a Array) Append(v ast.Value) Array { return a.Insert(a.Len(), v) }
+
+// Delete returns a copy of a without the element at offset i.
+// It panics if i is out of range.
+func (a Array) Delete(i int) Array {
+	a.checkIndex(i, a.Len())
+	return Array{root: removeAt(a.root, i)}
+}
+
+func (a Array) checkIndex(i, n int) {
+	if i < 0 || i >= n {
+		panic(fmt.Sprintf("index %d out of range (n=%d)", i, a.Len()))
+	}
+}
+
+// All returns an iterator over the offsets and elements of a, in order.
+func (a Array) All() iter.Seq2[int, ast.Value] {
+	return func(yield func(int, ast.Value) bool) {
+		i := 0
+		a.root.all(func(_ struct{}, v ast.Value) bool {
+			i++
+			return yield(i-1, v)
+		})
+	}
+}
+
+// Array returns an ast.Array with the elements of a, in order.
+func (a Array) Array() ast.Array {
+	out := make(ast.Array, 0, a.Len())
+	a.root.all(func(_ struct{}, v ast.Value) bool {
+		out = append(out, v)
+		return true
+	})
+	return out
+}
+
+// JSON renders a as JSON text.
+func (a Array) JSON() string { return a.Array().JSON() }
+
+func (a Array) String() string { return fmt.Sprintf("persist.Array(len=%d)", a.Len()) }
@@ -0,0 +1,199 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package persist_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/persist"
+)
+
+func TestObject(t *testing.T) {
+	var o persist.Object
+	if o.Len() != 0 || o.JSON() != "{}" {
+		t.Errorf("Zero Object: got %s (len %d), want {}", o.JSON(), o.Len())
+	}
+
+	o1 := o.Set("a", ast.Int(1)).Set("b", ast.Int(2)).Set("c", ast.Int(3))
+	o2 := o1.Set("b", ast.String("x")).Delete("a").Set("d", ast.Null).Delete("nonesuch")
+	o3 := o2.Set("a", ast.Bool(true))
+
+	for _, tc := range []struct {
+		o    persist.Object
+		want string
+	}{
+		{o, `{}`},
+		{o1, `{"a":1,"b":2,"c":3}`},
+		{o2, `{"b":"x","c":3,"d":null}`},
+		{o3, `{"b":"x","c":3,"d":null,"a":true}`},
+	} {
+		if got := tc.o.JSON(); got != tc.want {
+			t.Errorf("JSON: got %#q, want %#q", got, tc.want)
+		}
+		if got, want := tc.o.Len(), len(tc.o.Object()); got != want {
+			t.Errorf("Len: got %d, want %d", got, want)
+		}
+	}
+	if v, ok := o2.Get("b"); !ok || v.JSON() != `"x"` {
+		t.Errorf(`Get "b": got %v, %v; want "x", true`, v, ok)
+	}
+	if v, ok := o2.Get("a"); ok {
+		t.Errorf(`Get "a": got %v, want not found`, v)
+	}
+
+	// Duplicate keys keep the first position and the last value.
+	p := persist.NewObject(ast.Object{
+		ast.Field("x", 1), ast.Field("y", 2), ast.Field("x", 3),
+	})
+	if got, want := p.JSON(), `{"x":3,"y":2}`; got != want {
+		t.Errorf("NewObject: got %#q, want %#q", got, want)
+	}
+
+	var keys []string
+	for k := range o3.All() {
+		keys = append(keys, k)
+	}
+	if got := fmt.Sprint(keys); got != "[b c d a]" {
+		t.Errorf("All: got %v, want [b c d a]", got)
+	}
+}
+
+func TestObjectFind(t *testing.T) {
+	o := persist.NewObject(ast.Object{
+		ast.Field("Name", 1), ast.Field("x", 2), ast.Field("name", 3),
+	})
+	check := func(o persist.Object, key, wantKey, wantVal string) {
+		t.Helper()
+		k, v, ok := o.Find(key)
+		if wantKey == "" {
+			if ok {
+				t.Errorf("Find %q: got %q, %v; want not found", key, k, v)
+			}
+		} else if !ok || k != wantKey || v.JSON() != wantVal {
+			t.Errorf("Find %q: got %q, %v, %v; want %q, %s", key, k, v, ok, wantKey, wantVal)
+		}
+	}
+	check(o, "NAME", "Name", "1")
+	check(o, "X", "x", "2")
+	check(o, "nonesuch", "", "")
+
+	// Deleting the first match exposes the next one.
+	o2 := o.Delete("Name")
+	check(o2, "nAmE", "name", "3")
+	check(o2.Delete("name"), "name", "", "")
+	check(o, "name", "Name", "1") // unchanged
+
+	// Replace keeps the position of the member it replaces.
+	o3 := o.Replace("Name", "NAME", ast.Int(4))
+	if got, want := o3.JSON(), `{"NAME":4,"x":2,"name":3}`; got != want {
+		t.Errorf("Replace: got %#q, want %#q", got, want)
+	}
+	check(o3, "name", "NAME", "4")
+	if got, want := o.Replace("x", "name", ast.Int(5)).JSON(), `{"Name":1,"name":5}`; got != want {
+		t.Errorf("Replace: got %#q, want %#q", got, want)
+	}
+	if got, want := o.Replace("y", "z", ast.Int(6)).JSON(), `{"Name":1,"x":2,"name":3,"z":6}`; got != want {
+		t.Errorf("Replace: got %#q, want %#q", got, want)
+	}
+}
+
+func TestArray(t *testing.T) {
+	// Apply random edits to a persistent array and a slice in parallel, and
+	// check that they agree, and that earlier versions are not affected.
+	rng := rand.New(rand.NewPCG(1, 2))
+	var want ast.Array
+	a := persist.NewArray(ast.Array{})
+	type version struct {
+		a    persist.Array
+		want string
+	}
+	var vs []version
+	for i := range 2000 {
+		switch op := rng.IntN(4); {
+		case op == 0 && len(want) > 0:
+			j := rng.IntN(len(want))
+			a = a.Delete(j)
+			want = append(want[:j:j], want[j+1:]...)
+		case op == 1 && len(want) > 0:
+			j := rng.IntN(len(want))
+			a = a.Set(j, ast.Int(i))
+			want = append(ast.Array(nil), want...)
+			want[j] = ast.Int(i)
+		default:
+			j := rng.IntN(len(want) + 1)
+			a = a.Insert(j, ast.Int(i))
+			want = append(want[:j:j], append(ast.Array{ast.Int(i)}, want[j:]...)...)
+		}
+		if i%100 == 0 {
+			vs = append(vs, version{a, want.JSON()})
+		}
+	}
+	if got := a.JSON(); got != want.JSON() {
+		t.Errorf("Final array:\n got %s\nwant %s", got, want.JSON())
+	}
+	for i, v := range vs {
+		if got := v.a.JSON(); got != v.want {
+			t.Errorf("Version %d:\n got %s\nwant %s", i, got, v.want)
+		}
+	}
+	for i, v := range a.All() {
+		if got := v.JSON(); got != want[i].JSON() {
+			t.Errorf("All: element %d is %s, want %s", i, got, want[i].JSON())
+		}
+	}
+
+	b := persist.NewArray(ast.Array{ast.Int(1), ast.Int(2)}).Append(ast.Int(3))
+	if got, want := b.JSON(), `[1,2,3]`; got != want {
+		t.Errorf("Append: got %#q, want %#q", got, want)
+	}
+	if got := b.At(2).JSON(); got != "3" {
+		t.Errorf("At(2): got %s, want 3", got)
+	}
+}
+
+func TestRandomObject(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	var o persist.Object
+	want := make(map[string]int)
+	for i := range 5000 {
+		key := fmt.Sprintf("k%d", rng.IntN(300))
+		if rng.IntN(3) == 0 {
+			o = o.Delete(key)
+			delete(want, key)
+		} else {
+			o = o.Set(key, ast.Int(i))
+			want[key] = i
+		}
+	}
+	if o.Len() != len(want) {
+		t.Errorf("Len: got %d, want %d", o.Len(), len(want))
+	}
+	for key, val := range want {
+		if v, ok := o.Get(key); !ok || v.(ast.Int) != ast.Int(val) {
+			t.Errorf("Get %q: got %v, %v; want %d", key, v, ok, val)
+		}
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	obj := make(ast.Object, 10000)
+	for i := range obj {
+		obj[i] = ast.Field(fmt.Sprintf("key%d", i), i)
+	}
+	b.Run("Persist", func(b *testing.B) {
+		o := persist.NewObject(obj)
+		for i := range b.N {
+			o = o.Set(fmt.Sprintf("key%d", i%len(obj)), ast.Int(i))
+		}
+	})
+	b.Run("Copy", func(b *testing.B) {
+		o := obj
+		for i := range b.N {
+			o = append(ast.Object(nil), o...)
+			o[i%len(o)] = ast.Field(o[i%len(o)].Key.String(), i)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package persist
+
+// A node is a node of an immutable AVL tree, augmented with subtree sizes so
+// that it can be indexed by position as well as by key. A nil *node is an
+// empty tree. Operations that modify a tree return a new tree that shares all
+// the unmodified nodes of the original.
+type node[K, V any] struct {
+	left, right *node[K, V]
+	key         K
+	val         V
+	height      int
+	size        int
+}
+
+func (n *node[K, V]) getHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *node[K, V]) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func mk[K, V any](l *node[K, V], k K, v V, r *node[K, V]) *node[K, V] {
+	return &node[K, V]{
+		left: l, right: r, key: k, val: v,
+		height: max(l.getHeight(), r.getHeight()) + 1,
+		size:   l.getSize() + r.getSize() + 1,
+	}
+}
+
+// bal is like mk, but restores the balance of the result if the heights of l
+// and r differ by at most 2.
+func bal[K, V any](l *node[K, V], k K, v V, r *node[K, V]) *node[K, V] {
+	hl, hr := l.getHeight(), r.getHeight()
+	if hl > hr+1 {
+		if l.left.getHeight() >= l.right.getHeight() {
+			return mk(l.left, l.key, l.val, mk(l.right, k, v, r))
+		}
+		lr := l.right
+		return mk(mk(l.left, l.key, l.val, lr.left), lr.key, lr.val, mk(lr.right, k, v, r))
+	}
+	if hr > hl+1 {
+		if r.right.getHeight() >= r.left.getHeight() {
+			return mk(mk(l, k, v, r.left), r.key, r.val, r.right)
+		}
+		rl := r.left
+		return mk(mk(l, k, v, rl.left), rl.key, rl.val, mk(rl.right, r.key, r.val, r.right))
+	}
+	return mk(l, k, v, r)
+}
+
+// merge returns a tree containing the elements of l followed by those of r,
+// whose heights differ by at most 1.
+func merge[K, V any](l, r *node[K, V]) *node[K, V] {
+	if l == nil {
+		return r
+	} else if r == nil {
+		return l
+	}
+	m := r
+	for m.left != nil {
+		m = m.left
+	}
+	return bal(l, m.key, m.val, removeMin(r))
+}
+
+func removeMin[K, V any](n *node[K, V]) *node[K, V] {
+	if n.left == nil {
+		return n.right
+	}
+	return bal(removeMin(n.left), n.key, n.val, n.right)
+}
+
+// build constructs a balanced tree from the elements of keys and vals, in
+// order. If keys == nil, the zero key is used for all elements.
+func build[K, V any](keys []K, vals []V) *node[K, V] {
+	if len(vals) == 0 {
+		return nil
+	}
+	mid := len(vals) / 2
+	var k K
+	var lk, rk []K
+	if keys != nil {
+		k, lk, rk = keys[mid], keys[:mid], keys[mid+1:]
+	}
+	return mk(build(lk, vals[:mid]), k, vals[mid], build(rk, vals[mid+1:]))
+}
+
+// all calls f for each element of n in order, and reports whether every
+// call returned true.
+func (n *node[K, V]) all(f func(K, V) bool) bool {
+	for n != nil {
+		if !n.left.all(f) || !f(n.key, n.val) {
+			return false
+		}
+		n = n.right
+	}
+	return true
+}
+
+// Operations on trees ordered by key.
+
+func get[K, V any](n *node[K, V], k K, cmp func(a, b K) int) (V, bool) {
+	for n != nil {
+		c := cmp(k, n.key)
+		if c == 0 {
+			return n.val, true
+		} else if c < 0 {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+func put[K, V any](n *node[K, V], k K, v V, cmp func(a, b K) int) *node[K, V] {
+	if n == nil {
+		return mk(nil, k, v, nil)
+	}
+	c := cmp(k, n.key)
+	if c == 0 {
+		return mk(n.left, k, v, n.right)
+	} else if c < 0 {
+		return bal(put(n.left, k, v, cmp), n.key, n.val, n.right)
+	}
+	return bal(n.left, n.key, n.val, put(n.right, k, v, cmp))
+}
+
+func remove[K, V any](n *node[K, V], k K, cmp func(a, b K) int) *node[K, V] {
+	if n == nil {
+		return nil
+	}
+	c := cmp(k, n.key)
+	if c == 0 {
+		return merge(n.left, n.right)
+	} else if c < 0 {
+		return bal(remove(n.left, k, cmp), n.key, n.val, n.right)
+	}
+	return bal(n.left, n.key, n.val, remove(n.right, k, cmp))
+}
+
+// Operations on trees ordered by position. The caller must ensure that
+// positions are in range.
+
+func at[K, V any](n *node[K, V], i int) *node[K, V] {
+	for {
+		ls := n.left.getSize()
+		if i < ls {
+			n = n.left
+		} else if i == ls {
+			return n
+		} else {
+			i -= ls + 1
+			n = n.right
+		}
+	}
+}
+
+func setAt[K, V any](n *node[K, V], i int, v V) *node[K, V] {
+	ls := n.left.getSize()
+	if i < ls {
+		return mk(setAt(n.left, i, v), n.key, n.val, n.right)
+	} else if i == ls {
+		return mk(n.left, n.key, v, n.right)
+	}
+	return mk(n.left, n.key, n.val, setAt(n.right, i-ls-1, v))
+}
+
+func insertAt[K, V any](n *node[K, V], i int, k K, v V) *node[K, V] {
+	if n == nil {
+		return mk(nil, k, v, nil)
+	}
+	ls := n.left.getSize()
+	if i <= ls {
+		return bal(insertAt(n.left, i, k, v), n.key, n.val, n.right)
+	}
+	return bal(n.left, n.key, n.val, insertAt(n.right, i-ls-1, k, v))
+}
+
+func removeAt[K, V any](n *node[K, V], i int) *node[K, V] {
+	ls := n.left.getSize()
+	if i < ls {
+		return bal(removeAt(n.left, i), n.key, n.val, n.right)
+	} else if i == ls {
+		return merge(n.left, n.right)
+	}
+	return bal(n.left, n.key, n.val, removeAt(n.right, i-ls-1))
+}
@@ -0,0 +1,333 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package policy implements field-level access control for JSON values.
+//
+// A Policy is an ordered list of rules that allow or deny access to the parts
+// of a value selected by path patterns. A policy can filter or mask a value
+// to remove the parts a caller may not see, and can check that a JSON Patch
+// (RFC 6902) touches only the parts a caller may modify:
+//
+//	p, err := policy.Parse(strings.NewReader(`{
+//	  "default": "allow",
+//	  "rules": [
+//	    {"deny": "/users/*/password"},
+//	  ],
+//	}`))
+//	...
+//	visible := p.Filter(config)
+//
+// # Patterns
+//
+// A pattern is a JSON Pointer (RFC 6901) in which the reference token "*"
+// matches any single object key or array index, and "**" matches any
+// sequence of zero or more of them. The empty pattern "" denotes the root.
+// A rule applies to each value whose path matches its pattern, and to all
+// the values nested within it.
+//
+// When more than one rule applies to a value, the rule that appears last in
+// the policy takes precedence. If no rule applies, the policy default is
+// used.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/escape"
+	"github.com/creachadair/jtree/jwcc"
+)
+
+// A Rule allows or denies access to the values selected by a pattern.
+type Rule struct {
+	Pattern string // a path pattern, as described in the package comment
+	Allow   bool   // whether matching values are allowed (true) or denied (false)
+}
+
+func (r Rule) String() string {
+	if r.Allow {
+		return fmt.Sprintf("allow %q", r.Pattern)
+	}
+	return fmt.Sprintf("deny %q", r.Pattern)
+}
+
+// A Policy is an ordered collection of access rules.
+type Policy struct {
+	Rules []Rule
+
+	// DefaultAllow reports whether a value to which no rule applies is
+	// allowed. By default, such values are denied.
+	DefaultAllow bool
+}
+
+// Allowed reports whether p allows access to the value at path, which is a
+// JSON Pointer.
+func (p *Policy) Allowed(path string) bool { return p.allowed(splitPointer(path)) }
+
+func (p *Policy) allowed(path []string) bool {
+	for i := len(p.Rules) - 1; i >= 0; i-- {
+		if matchPrefix(splitPointer(p.Rules[i].Pattern), path) {
+			return p.Rules[i].Allow
+		}
+	}
+	return p.DefaultAllow
+}
+
+// Filter returns a copy of v from which the values p does not allow have been
+// removed. Objects and arrays that are denied are retained if they contain
+// any allowed values. Filter returns nil if no part of v is allowed.
+// Note that removing array elements shifts the indices of later elements.
+// The result may share unmodified substructure with v.
+func (p *Policy) Filter(v ast.Value) ast.Value {
+	if out, ok := p.filter(nil, v, nil); ok {
+		return out
+	}
+	return nil
+}
+
+// Mask returns a copy of v in which the values p does not allow have been
+// replaced by mask, or by null if mask == nil. Objects and arrays that are
+// denied are retained if they contain any allowed values, with their denied
+// contents masked. The result may share unmodified substructure with v.
+func (p *Policy) Mask(v ast.Value, mask ast.Value) ast.Value {
+	if mask == nil {
+		mask = ast.Null
+	}
+	if out, ok := p.filter(nil, v, mask); ok {
+		return out
+	}
+	return mask
+}
+
+// filter returns the allowed portion of v, whose path is path, and reports
+// whether any part of v is allowed. If mask != nil, denied values nested in v
+// are replaced by mask rather than removed.
+func (p *Policy) filter(path []string, v ast.Value, mask ast.Value) (ast.Value, bool) {
+	ok := p.allowed(path)
+	if ok && !p.restricted(path) {
+		return v, true // nothing under v is denied
+	}
+
+	keep := ok
+	switch t := v.(type) {
+	case ast.Object:
+		out := make(ast.Object, 0, len(t))
+		for _, m := range t {
+			if w, wok := p.filter(append(path, m.Key.String()), m.Value, mask); wok {
+				out = append(out, &ast.Member{Key: m.Key, Value: w})
+				keep = true
+			} else if mask != nil {
+				out = append(out, &ast.Member{Key: m.Key, Value: mask})
+			}
+		}
+		return out, keep
+	case ast.Array:
+		out := make(ast.Array, 0, len(t))
+		for i, e := range t {
+			if w, wok := p.filter(append(path, strconv.Itoa(i)), e, mask); wok {
+				out = append(out, w)
+				keep = true
+			} else if mask != nil {
+				out = append(out, mask)
+			}
+		}
+		return out, keep
+	default:
+		return v, ok
+	}
+}
+
+// restricted reports whether any rule of p could deny a value strictly
+// beneath path.
+func (p *Policy) restricted(path []string) bool {
+	for _, r := range p.Rules {
+		if !r.Allow && extends(splitPointer(r.Pattern), path) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPatch reports an error if patch, a JSON Patch document (RFC 6902),
+// includes an operation that p does not permit. An operation that modifies a
+// location (add, remove, replace, and the source of move) requires that the
+// location be allowed and that no rule could deny any value beneath it. An
+// operation that only reads a location (test, and the source of copy)
+// requires that the location be allowed.
+func (p *Policy) CheckPatch(patch ast.Value) error {
+	ops, ok := patch.(ast.Array)
+	if !ok {
+		return fmt.Errorf("patch is %T, not an array", patch)
+	}
+	for i, elt := range ops {
+		op, ok := elt.(ast.Object)
+		if !ok {
+			return fmt.Errorf("patch operation %d is %T, not an object", i, elt)
+		}
+		name, err := opString(op, "op")
+		if err != nil {
+			return fmt.Errorf("patch operation %d: %w", i, err)
+		}
+		path, err := opString(op, "path")
+		if err != nil {
+			return fmt.Errorf("patch operation %d: %w", i, err)
+		}
+
+		var write, read []string
+		switch name {
+		case "add", "remove", "replace":
+			write = append(write, path)
+		case "test":
+			read = append(read, path)
+		case "move", "copy":
+			from, err := opString(op, "from")
+			if err != nil {
+				return fmt.Errorf("patch operation %d: %w", i, err)
+			}
+			write = append(write, path)
+			if name == "move" {
+				write = append(write, from)
+			} else {
+				read = append(read, from)
+			}
+		default:
+			return fmt.Errorf("patch operation %d: unknown op %q", i, name)
+		}
+		for _, w := range write {
+			if tw := splitPointer(w); !p.allowed(tw) || p.restricted(tw) {
+				return fmt.Errorf("patch operation %d: %s %q is not permitted", i, name, w)
+			}
+		}
+		for _, r := range read {
+			if !p.Allowed(r) {
+				return fmt.Errorf("patch operation %d: %s from %q is not permitted", i, name, r)
+			}
+		}
+	}
+	return nil
+}
+
+func opString(op ast.Object, key string) (string, error) {
+	m := op.FindKey(ast.TextEqual(key))
+	if m == nil {
+		return "", fmt.Errorf("missing %q", key)
+	}
+	s, ok := m.Value.(ast.Text)
+	if !ok {
+		return "", fmt.Errorf("%q is %T, not a string", key, m.Value)
+	}
+	return s.String(), nil
+}
+
+// Parse parses a policy from a JWCC document in this format:
+//
+//	{
+//	  "default": "deny",  // optional: "allow" or "deny"
+//	  "rules": [
+//	    {"allow": "/public/**"},
+//	    {"deny": "/public/secrets"},
+//	  ],
+//	}
+//
+// Each rule must have exactly one of "allow" or "deny", and the rules are
+// kept in the order given.
+func Parse(r io.Reader) (*Policy, error) {
+	doc, err := jwcc.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := doc.Undecorate().(ast.Object)
+	if !ok {
+		return nil, errors.New("policy is not an object")
+	}
+	p := new(Policy)
+	for _, m := range obj {
+		switch key := m.Key.String(); key {
+		case "default":
+			s, ok := m.Value.(ast.Text)
+			if !ok || (s.String() != "allow" && s.String() != "deny") {
+				return nil, errors.New(`default must be "allow" or "deny"`)
+			}
+			p.DefaultAllow = s.String() == "allow"
+		case "rules":
+			rules, ok := m.Value.(ast.Array)
+			if !ok {
+				return nil, errors.New("rules must be an array")
+			}
+			for i, elt := range rules {
+				rule, err := parseRule(elt)
+				if err != nil {
+					return nil, fmt.Errorf("rule %d: %w", i, err)
+				}
+				p.Rules = append(p.Rules, rule)
+			}
+		default:
+			return nil, fmt.Errorf("unknown policy field %q", key)
+		}
+	}
+	return p, nil
+}
+
+func parseRule(v ast.Value) (Rule, error) {
+	obj, ok := v.(ast.Object)
+	if !ok || len(obj) != 1 {
+		return Rule{}, errors.New(`rule must be an object with one "allow" or "deny" field`)
+	}
+	pat, ok := obj[0].Value.(ast.Text)
+	if !ok {
+		return Rule{}, fmt.Errorf("pattern is %T, not a string", obj[0].Value)
+	}
+	if s := pat.String(); s != "" && !strings.HasPrefix(s, "/") {
+		return Rule{}, fmt.Errorf("invalid pattern %q", s)
+	}
+	switch key := obj[0].Key.String(); key {
+	case "allow":
+		return Rule{Pattern: pat.String(), Allow: true}, nil
+	case "deny":
+		return Rule{Pattern: pat.String()}, nil
+	default:
+		return Rule{}, fmt.Errorf("unknown rule type %q", key)
+	}
+}
+
+// splitPointer splits a JSON Pointer into its unescaped reference tokens. A
+// missing leading slash is tolerated.
+func splitPointer(s string) []string {
+	if s != "" && !strings.HasPrefix(s, "/") {
+		s = "/" + s
+	}
+	toks, _ := escape.SplitPointer(s)
+	return toks
+}
+
+// matchPrefix reports whether pat matches path or a prefix of path.
+func matchPrefix(pat, path []string) bool {
+	if len(pat) == 0 {
+		return true
+	} else if pat[0] == "**" {
+		return matchPrefix(pat[1:], path) || (len(path) != 0 && matchPrefix(pat, path[1:]))
+	} else if len(path) == 0 {
+		return false
+	} else if pat[0] == "*" || pat[0] == path[0] {
+		return matchPrefix(pat[1:], path[1:])
+	}
+	return false
+}
+
+// extends reports whether pat could match a path strictly longer than path
+// that has path as a prefix.
+func extends(pat, path []string) bool {
+	if len(path) == 0 {
+		return len(pat) != 0
+	} else if len(pat) == 0 {
+		return false
+	} else if pat[0] == "**" {
+		return extends(pat[1:], path) || extends(pat, path[1:])
+	} else if pat[0] == "*" || pat[0] == path[0] {
+		return extends(pat[1:], path[1:])
+	}
+	return false
+}
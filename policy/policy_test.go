@@ -0,0 +1,158 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package policy_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/testutil"
+	"github.com/creachadair/jtree/policy"
+)
+
+const testPolicy = `// A policy for tests.
+{
+  "default": "deny",
+  "rules": [
+    {"allow": "/name"},
+    {"allow": "/users/*"},
+    {"deny": "/users/*/password"},
+    {"allow": "/tags"},
+  ],
+}`
+
+func TestPolicy(t *testing.T) {
+	p, err := policy.Parse(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse policy: %v", err)
+	}
+	if len(p.Rules) != 4 || p.DefaultAllow {
+		t.Fatalf("Parse policy: got %+v", p)
+	}
+
+	val := testutil.MustParse(t, `{
+  "name": "config",
+  "secret": "xyzzy",
+  "users": [
+    {"id": "alice", "password": "hunter2"},
+    {"id": "bob", "password": "swordfish", "roles": ["admin"]}
+  ],
+  "tags": ["a", "b"]
+}`)
+
+	t.Run("Allowed", func(t *testing.T) {
+		tests := []struct {
+			path string
+			want bool
+		}{
+			{"", false},
+			{"/name", true},
+			{"/secret", false},
+			{"/users", false},
+			{"/users/0", true},
+			{"/users/1/roles/0", true},
+			{"/users/1/password", false},
+			{"/tags/1", true},
+		}
+		for _, test := range tests {
+			if got := p.Allowed(test.path); got != test.want {
+				t.Errorf("Allowed(%q): got %v, want %v", test.path, got, test.want)
+			}
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		const want = `{"name":"config","users":[{"id":"alice"},{"id":"bob","roles":["admin"]}],"tags":["a","b"]}`
+		if got := p.Filter(val).JSON(); got != want {
+			t.Errorf("Filter: got %#q, want %#q", got, want)
+		}
+		if got := p.Filter(ast.ToValue("x")); got != nil {
+			t.Errorf("Filter: got %v, want nil", got)
+		}
+	})
+
+	t.Run("Mask", func(t *testing.T) {
+		const want = `{"name":"config","secret":"***","users":[{"id":"alice","password":"***"},` +
+			`{"id":"bob","password":"***","roles":["admin"]}],"tags":["a","b"]}`
+		if got := p.Mask(val, ast.String("***")).JSON(); got != want {
+			t.Errorf("Mask: got %#q, want %#q", got, want)
+		}
+		if got := p.Mask(ast.ToValue("x"), nil); got != ast.Null {
+			t.Errorf("Mask: got %v, want null", got)
+		}
+	})
+
+	t.Run("CheckPatch", func(t *testing.T) {
+		tests := []struct {
+			patch string
+			ok    bool
+		}{
+			{`[{"op": "replace", "path": "/name", "value": "x"}]`, true},
+			{`[{"op": "add", "path": "/users/0/email", "value": "a@b"}]`, true},
+			{`[{"op": "copy", "from": "/users/0/id", "path": "/tags/-"}]`, true},
+			{`[{"op": "test", "path": "/users/0/id", "value": "alice"}]`, true},
+
+			{`[{"op": "replace", "path": "/secret", "value": "x"}]`, false},
+			{`[{"op": "replace", "path": "/users/0/password", "value": "x"}]`, false},
+			{`[{"op": "remove", "path": "/users/0"}]`, false}, // contains a password
+			{`[{"op": "copy", "from": "/secret", "path": "/name"}]`, false},
+			{`[{"op": "move", "from": "/name", "path": "/secret"}]`, false},
+			{`[{"op": "frob", "path": "/name"}]`, false},
+			{`{"op": "add"}`, false},
+		}
+		for _, test := range tests {
+			err := p.CheckPatch(testutil.MustParse(t, test.patch))
+			if test.ok && err != nil {
+				t.Errorf("CheckPatch %s: unexpected error: %v", test.patch, err)
+			} else if !test.ok && err == nil {
+				t.Errorf("CheckPatch %s: got nil, want error", test.patch)
+			}
+		}
+	})
+}
+
+func TestPatterns(t *testing.T) {
+	p := &policy.Policy{
+		DefaultAllow: true,
+		Rules: []policy.Rule{
+			{Pattern: "/**/secret"},
+			{Pattern: "/a~1b/*"},
+			{Pattern: "/a~1b/ok", Allow: true},
+		},
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/x", true},
+		{"/secret", false},
+		{"/x/y/secret/z", false},
+		{"/x/secrets", true},
+		{"/a~1b", true},
+		{"/a~1b/c", false},
+		{"/a~1b/ok/d", true},
+	}
+	for _, test := range tests {
+		if got := p.Allowed(test.path); got != test.want {
+			t.Errorf("Allowed(%q): got %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		`[]`,
+		`{"default": "maybe"}`,
+		`{"rules": {}}`,
+		`{"rules": [{"allow": "/a", "deny": "/b"}]}`,
+		`{"rules": [{"permit": "/a"}]}`,
+		`{"rules": [{"allow": "a"}]}`,
+		`{"other": true}`,
+	}
+	for _, test := range tests {
+		if p, err := policy.Parse(strings.NewReader(test)); err == nil {
+			t.Errorf("Parse %s: got %+v, want error", test, p)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree/tq"
+)
+
+// A step is a single step of a parsed path expression.
+type step struct {
+	key   any     // a string or int, for a single-valued step
+	pick  []int   // for a union of indices
+	slice *[2]int // for a slice
+	recur string  // for a recursive descent ("..name")
+	glob  bool    // for a wildcard
+	call  string  // for a call of a named query
+}
+
+// ParseQuery parses a path expression in a subset of JSONPath syntax and
+// returns an equivalent query. The expression may begin with "$" or "@",
+// which are ignored; it is evaluated relative to its input. The supported
+// steps are:
+//
+//	.name  ['name']  ["name"]   -- an object member
+//	[n]                         -- an array element; n < 0 counts from the end
+//	.*  [*]                     -- all members or elements
+//	..name                      -- all members named name, at any depth
+//	[lo:hi]                     -- a slice of an array; lo and hi are optional
+//	[i,j,...]                   -- the selected array elements
+//	.name()                     -- the query named name, applied to the value
+//
+// A step that selects multiple values produces an array, and the remaining
+// steps are applied to each of its elements. Unlike JSONPath, the results of
+// nested multi-valued steps are not flattened.
+//
+// The names of called queries are resolved in tq.DefaultRegistry when the
+// query is evaluated, so a query may call names that are defined after it is
+// parsed, including its own name.
+func ParseQuery(s string) (tq.Query, error) {
+	steps, err := parseSteps(s)
+	if err != nil {
+		return nil, err
+	}
+	return buildQuery(steps), nil
+}
+
+// parsePath parses a path expression that consists only of single-valued
+// steps, and returns the keys and offsets in the format of cursor.Down.
+func parsePath(s string) ([]any, error) {
+	steps, err := parseSteps(s)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]any, len(steps))
+	for i, st := range steps {
+		if st.key == nil {
+			return nil, errors.New("path must not contain wildcards, slices, unions, or calls")
+		}
+		if s, ok := st.key.(string); ok && strings.HasPrefix(s, "%") {
+			st.key = "%" + s // escape case-insensitive matching
+		}
+		keys[i] = st.key
+	}
+	return keys, nil
+}
+
+func buildQuery(steps []step) tq.Query {
+	var keys []any
+	for i, st := range steps {
+		if st.key != nil {
+			if s, ok := st.key.(string); ok && strings.HasPrefix(s, "$") {
+				st.key = "$" + s // escape variable references
+			}
+			keys = append(keys, st.key)
+			continue
+		}
+		if st.call != "" {
+			keys = append(keys, tq.Call(st.call))
+			continue
+		}
+		var q tq.Query
+		switch {
+		case st.glob:
+			q = tq.Glob()
+		case st.recur != "":
+			// The steps following a recursive descent are applied to each
+			// match by the recursion itself.
+			rest := append([]step{{key: st.recur}}, steps[i+1:]...)
+			return tq.Path(append(keys, tq.Recur(buildQuery(rest)))...)
+		case st.slice != nil:
+			q = tq.Slice(st.slice[0], st.slice[1])
+		default:
+			q = tq.Pick(st.pick...)
+		}
+		keys = append(keys, q)
+		if rest := steps[i+1:]; len(rest) != 0 {
+			keys = append(keys, tq.Each(buildQuery(rest)))
+		}
+		return tq.Path(keys...)
+	}
+	return tq.Path(keys...)
+}
+
+func parseSteps(s string) ([]step, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "$") || strings.HasPrefix(s, "@") {
+		s = s[1:]
+	} else if s != "" && s[0] != '.' && s[0] != '[' {
+		s = "." + s // allow a leading name without a dot
+	}
+	var out []step
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			name, rest := scanName(s[2:])
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %q: missing name after %q", orig, "..")
+			}
+			out = append(out, step{recur: name})
+			s = rest
+
+		case strings.HasPrefix(s, ".*"):
+			out = append(out, step{glob: true})
+			s = s[2:]
+
+		case s[0] == '.':
+			name, rest := scanName(s[1:])
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %q: missing name after %q", orig, ".")
+			}
+			if args, ok := strings.CutPrefix(rest, "("); ok {
+				if !strings.HasPrefix(args, ")") {
+					return nil, fmt.Errorf("invalid path %q: call of %q must have no arguments", orig, name)
+				}
+				out = append(out, step{call: name})
+				s = args[1:]
+			} else {
+				out = append(out, step{key: name})
+				s = rest
+			}
+
+		case s[0] == '[':
+			end := bracketEnd(s)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed %q", orig, "[")
+			}
+			st, err := parseBracket(s[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", orig, err)
+			}
+			out = append(out, st)
+			s = s[end+1:]
+
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", orig, s[:1])
+		}
+	}
+	return out, nil
+}
+
+// scanName scans an unquoted member name from the front of s.
+func scanName(s string) (name, rest string) {
+	i := strings.IndexAny(s, ".[]() ")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// bracketEnd returns the offset of the "]" closing the "[" at the front of s,
+// or -1. Brackets inside quoted names are not counted.
+func bracketEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func parseBracket(s string) (step, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "*":
+		return step{glob: true}, nil
+
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		if len(s) < 2 || s[len(s)-1] != s[0] {
+			return step{}, fmt.Errorf("unterminated name %s", s)
+		}
+		if s[0] == '"' {
+			name, err := strconv.Unquote(s)
+			if err != nil {
+				return step{}, fmt.Errorf("invalid name %s", s)
+			}
+			return step{key: name}, nil
+		}
+		return step{key: strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`)}, nil
+
+	case strings.Contains(s, ":"):
+		lo, hi, _ := strings.Cut(s, ":")
+		var bounds [2]int
+		for i, b := range []string{lo, hi} {
+			if b = strings.TrimSpace(b); b == "" {
+				continue
+			}
+			z, err := strconv.Atoi(b)
+			if err != nil {
+				return step{}, fmt.Errorf("invalid slice bound %q", b)
+			}
+			bounds[i] = z
+		}
+		return step{slice: &bounds}, nil
+
+	case strings.Contains(s, ","):
+		var pick []int
+		for _, p := range strings.Split(s, ",") {
+			z, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil {
+				return step{}, fmt.Errorf("invalid index %q", p)
+			}
+			pick = append(pick, z)
+		}
+		return step{pick: pick}, nil
+
+	default:
+		z, err := strconv.Atoi(s)
+		if err != nil {
+			return step{}, fmt.Errorf("invalid index %q", s)
+		}
+		return step{key: z}, nil
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package repl implements an interactive read-eval-print loop for exploring
+// JSON and JWCC documents.
+//
+// A Session holds a document and a cursor within it. Each line of input is a
+// command that moves the cursor, or evaluates a path expression and prints
+// the result:
+//
+//	> load testdata/config.json
+//	> cd .servers[0]
+//	> p .name
+//	"alpha"
+//	> pwd
+//	/servers/0
+//
+// Path expressions use a subset of JSONPath syntax, as described for
+// ParseQuery, and are translated into tq queries. An expression beginning
+// with "$" is evaluated relative to the document root; otherwise it is
+// evaluated relative to the value at the cursor.
+//
+// The command set is deliberately small, and the implementation of each
+// command is a few lines using the cursor, tq, and jwcc packages, so the
+// source of this package also serves as an example of their use.
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/cursor"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/tq"
+)
+
+// ErrQuit is reported by Exec for a command that ends the session.
+var ErrQuit = errors.New("quit")
+
+// A Session is the state of an interactive session.
+type Session struct {
+	// Out is where the output of commands is written.
+	Out io.Writer
+
+	// If positive, the output of a command is paginated by Run in pages of
+	// this many lines. If zero or negative, output is not paginated.
+	PageSize int
+
+	// Prompt is printed by Run before reading each command.
+	// If empty, "> " is used.
+	Prompt string
+
+	doc *jwcc.Document
+	cur *cursor.Cursor
+}
+
+// NewSession constructs a new empty session that writes its output to out.
+func NewSession(out io.Writer) *Session { return &Session{Out: out} }
+
+// Load reads a JSON or JWCC document from r and makes it the current
+// document of s, with the cursor at its root.
+func (s *Session) Load(r io.Reader) error {
+	doc, err := jwcc.Parse(r)
+	if err != nil {
+		return err
+	}
+	s.doc = doc
+	s.cur = cursor.New(doc.Undecorate())
+	return nil
+}
+
+// Run reads commands from in and executes them until the input is exhausted
+// or a command ends the session. Errors from commands are printed, and do not
+// end the session. Run reports an error only if reading or writing fails.
+func (s *Session) Run(in io.Reader) error {
+	lines := bufio.NewScanner(in)
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = "> "
+	}
+	for {
+		fmt.Fprint(s.Out, prompt)
+		if !lines.Scan() {
+			fmt.Fprintln(s.Out)
+			return lines.Err()
+		}
+		var buf bytes.Buffer
+		err := s.exec(&buf, lines.Text())
+		if errors.Is(err, ErrQuit) {
+			return nil
+		} else if err != nil {
+			fmt.Fprintf(&buf, "error: %v\n", err)
+		}
+		if err := s.page(&buf, lines); err != nil {
+			return err
+		}
+	}
+}
+
+// page copies the contents of buf to the output of s in pages, prompting
+// for input from lines before each page after the first.
+func (s *Session) page(buf *bytes.Buffer, lines *bufio.Scanner) error {
+	for n := 0; buf.Len() != 0; n++ {
+		if s.PageSize > 0 && n != 0 && n%s.PageSize == 0 {
+			fmt.Fprint(s.Out, "-- more (enter to continue, q to stop) -- ")
+			if !lines.Scan() || strings.TrimSpace(lines.Text()) == "q" {
+				return lines.Err()
+			}
+		}
+		line, err := buf.ReadBytes('\n')
+		if _, err := s.Out.Write(line); err != nil {
+			return err
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return nil
+}
+
+// Exec executes a single command, and writes its output to s.Out. It reports
+// ErrQuit if the command ends the session.
+func (s *Session) Exec(line string) error { return s.exec(s.Out, line) }
+
+// A command is the implementation of a REPL command. The argument is the
+// rest of the command line after the name, with surrounding space removed.
+type command struct {
+	name, args, help string
+	run              func(s *Session, w io.Writer, arg string) error
+	noDoc            bool // the command does not require a document
+}
+
+func (s *Session) exec(w io.Writer, line string) error {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	if name == "" {
+		return nil
+	} else if name == "help" {
+		return cmdHelp(w) // special case, since it refers to commands
+	}
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		} else if !c.noDoc && s.doc == nil {
+			return errors.New("no document loaded (use load)")
+		}
+		return c.run(s, w, strings.TrimSpace(arg))
+	}
+	return fmt.Errorf("unknown command %q (try help)", name)
+}
+
+var commands = []command{
+	{name: "help", help: "print this help"},
+	{name: "quit", help: "end the session", noDoc: true, run: cmdQuit},
+	{name: "load", args: "file", help: "load a JSON or JWCC document", noDoc: true, run: cmdLoad},
+	{name: "pwd", help: "print the location of the cursor as a JSON Pointer", run: cmdPwd},
+	{name: "cd", args: "[path]", help: "move the cursor to path, or to the root", run: cmdCd},
+	{name: "up", help: "move the cursor to its parent", run: cmdUp},
+	{name: "ls", help: "list the keys or offsets of the value at the cursor", run: cmdLs},
+	{name: "p", args: "[expr]", help: "print the value of expr, or the value at the cursor", run: cmdPrint},
+	{name: "type", args: "[expr]", help: "print the type of the value of expr", run: cmdType},
+	{name: "len", args: "[expr]", help: "print the length of the value of expr", run: cmdLen},
+}
+
+func cmdHelp(w io.Writer) error {
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", strings.TrimSpace(c.name+" "+c.args), c.help)
+	}
+	fmt.Fprintln(w, "\nPaths and expressions use JSONPath syntax, e.g., .a.b[0] or $..name.")
+	return nil
+}
+
+func cmdQuit(*Session, io.Writer, string) error { return ErrQuit }
+
+func cmdLoad(s *Session, _ io.Writer, arg string) error {
+	if arg == "" {
+		return errors.New("usage: load <file>")
+	}
+	f, err := os.Open(arg)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.Load(f)
+}
+
+func cmdPwd(s *Session, w io.Writer, _ string) error {
+	if p := s.cur.Pointer(); p == "" {
+		fmt.Fprintln(w, "/ (root)")
+	} else {
+		fmt.Fprintln(w, p)
+	}
+	return nil
+}
+
+func cmdCd(s *Session, _ io.Writer, arg string) error {
+	if arg == "" || arg == "$" {
+		s.cur.Reset()
+		return nil
+	}
+	keys, err := parsePath(arg)
+	if err != nil {
+		return err
+	}
+	c := s.cur.Clone()
+	if strings.HasPrefix(arg, "$") {
+		c.Reset()
+	} else if !c.AtOrigin() && isMember(c.Value()) {
+		c.Down(nil) // step into the value of a member
+	}
+	if err := c.Down(keys...).Err(); err != nil {
+		return err
+	}
+	if isMember(c.Value()) {
+		c.Down(nil) // stop at the value, not the member
+	}
+	s.cur = c
+	return nil
+}
+
+func cmdUp(s *Session, _ io.Writer, _ string) error {
+	if s.cur.AtOrigin() {
+		return errors.New("already at the root")
+	}
+	s.cur.Up()
+	if !s.cur.AtOrigin() && isMember(s.cur.Value()) {
+		s.cur.Up() // skip over the member to its object
+	}
+	return nil
+}
+
+func cmdLs(s *Session, w io.Writer, _ string) error {
+	switch t := s.cur.Value().(type) {
+	case ast.Object:
+		for _, m := range t {
+			fmt.Fprintf(w, "%s\t%s\n", m.Key.Quote().JSON(), kindOf(m.Value))
+		}
+	case ast.Array:
+		for i, v := range t {
+			fmt.Fprintf(w, "[%d]\t%s\n", i, kindOf(v))
+		}
+	default:
+		return fmt.Errorf("%s has no keys", kindOf(t))
+	}
+	return nil
+}
+
+func cmdPrint(s *Session, w io.Writer, arg string) error {
+	v, err := s.eval(arg)
+	if err != nil {
+		return err
+	}
+	if err := jwcc.Format(w, jwcc.Decorate(v)); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+func cmdType(s *Session, w io.Writer, arg string) error {
+	v, err := s.eval(arg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, kindOf(v))
+	return nil
+}
+
+func cmdLen(s *Session, w io.Writer, arg string) error {
+	v, err := s.eval(arg)
+	if err != nil {
+		return err
+	}
+	n, err := tq.Eval[ast.Int](v, tq.Len())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, n)
+	return nil
+}
+
+// eval evaluates a path expression relative to the cursor, or to the root
+// of the document if it begins with "$".
+func (s *Session) eval(expr string) (ast.Value, error) {
+	root := s.cur.Value()
+	if strings.HasPrefix(expr, "$") {
+		root = s.cur.Origin()
+	}
+	if expr == "" {
+		return root, nil
+	}
+	q, err := ParseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	return tq.Eval[ast.Value](root, q)
+}
+
+func isMember(v ast.Value) bool { _, ok := v.(*ast.Member); return ok }
+
+// kindOf returns a short description of the type of v.
+func kindOf(v ast.Value) string {
+	switch t := v.(type) {
+	case ast.Object:
+		return fmt.Sprintf("object (%d members)", len(t))
+	case ast.Array:
+		return fmt.Sprintf("array (%d elements)", len(t))
+	case ast.Text:
+		return "string"
+	case ast.Number:
+		return "number"
+	case ast.Bool:
+		return "bool"
+	case nil:
+		return "nothing"
+	}
+	if v == ast.Null {
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package repl_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/repl"
+	"github.com/creachadair/jtree/tq"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseQuery(t *testing.T) {
+	f, err := os.Open("../testdata/jsonpath.json")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	root, err := ast.ParseSingle(f)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// Named queries are resolved in the default registry.
+	titles, err := repl.ParseQuery("book[*].title")
+	if err != nil {
+		t.Fatalf("ParseQuery: %v", err)
+	}
+	tq.Define("testTitles", titles)
+	tq.Define("testAuthor", tq.Path("author"))
+
+	tests := []struct {
+		expr, want string
+	}{
+		{"$", ""},
+		{"$.store.bicycle.color", `"red"`},
+		{"store.bicycle['color']", `"red"`},
+		{`@["store"].book[-1].title`, `"The Lord of the Rings"`},
+		{"$.store.book[*].author", `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+		{"$..author", `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+		{"$.store..price", `[8.95,12.99,8.99,22.99,19.95]`},
+		{"$..book[2].price", `[8.99]`},
+		{"$..book[0,1]", ``},
+		{"$..book[0].title", `["Sayings of the Century"]`},
+		{"$.store.book[1:3].price", `[12.99,8.99]`},
+		{"$.store.book[-1:].author", `["J. R. R. Tolkien"]`},
+		{"$.store.book[:1].author", `["Nigel Rees"]`},
+		{"$.store.book[0,3].price", `[8.95,22.99]`},
+		{"$.store.bicycle.*", `["red",19.95]`},
+		{"$.store.testTitles()[1]", `"Sword of Honour"`},
+		{"store.testTitles()", `["Sayings of the Century","Sword of Honour","Moby Dick","The Lord of the Rings"]`},
+		{"$.store.book[0,3].testAuthor()", `["Nigel Rees","J. R. R. Tolkien"]`},
+	}
+	for _, tc := range tests {
+		q, err := repl.ParseQuery(tc.expr)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): unexpected error: %v", tc.expr, err)
+			continue
+		}
+		got, err := tq.Eval[ast.Value](root, q)
+		if err != nil {
+			t.Errorf("Eval(%q): unexpected error: %v", tc.expr, err)
+			continue
+		}
+		if tc.want != "" && got.JSON() != tc.want {
+			t.Errorf("Eval(%q): got %s, want %s", tc.expr, got.JSON(), tc.want)
+		}
+	}
+
+	for _, bad := range []string{"$.", "$..", "$[", "$[x]", "$['a]", "$[1:x]", "$.a b", "$.a]",
+		"$.f(", "$.f(x)", "$..f()", "$.()"} {
+		if q, err := repl.ParseQuery(bad); err == nil {
+			t.Errorf("ParseQuery(%q): got %v, want error", bad, q)
+		}
+	}
+
+	// A call of an undefined name fails when it is evaluated.
+	if q, err := repl.ParseQuery("$.store.nonesuch()"); err != nil {
+		t.Errorf("ParseQuery: unexpected error: %v", err)
+	} else if v, err := tq.Eval[ast.Value](root, q); err == nil {
+		t.Errorf("Eval undefined call: got %v, want error", v)
+	}
+}
+
+func TestSession(t *testing.T) {
+	var out strings.Builder
+	s := repl.NewSession(&out)
+	s.Prompt = "> "
+	script := strings.Join([]string{
+		"pwd", // error: no document
+		"load ../testdata/jsonpath.json",
+		"cd store.book[1]",
+		"pwd",
+		"p .author",
+		"up",
+		"pwd",
+		"len",
+		"type $.store.bicycle",
+		"cd .nonesuch", // error
+		"cd $.store.bicycle",
+		"ls",
+		"p",
+		"", // continue paging
+		"cd",
+		"pwd",
+		"bogus", // error
+		"quit",
+		"pwd", // not reached
+	}, "\n")
+	s.PageSize = 2
+	if err := s.Run(strings.NewReader(script)); err != nil {
+		t.Fatalf("Run: unexpected error: %v", err)
+	}
+	const want = `> error: no document loaded (use load)
+> > > /store/book/1
+> "Evelyn Waugh"
+> > /store/book
+> 4
+> object (2 members)
+> error: cannot traverse ast.Array with "nonesuch"
+> > "color"	string
+"price"	number
+> {
+  "color": "red",
+-- more (enter to continue, q to stop) --   "price": 19.95,
+}
+> > / (root)
+> error: unknown command "bogus" (try help)
+> `
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("Output (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package report generates summary statistics for JSON and JWCC documents,
+// as an aid to reviewing changes to configuration files.
+//
+// A Report describes the size and shape of a document, the number of values
+// under each top-level key, how much of the document is commented, and any
+// duplicate keys or suspicious values found in it:
+//
+//	r, err := report.Analyze(input)
+//	if err != nil {
+//	   log.Fatalf("Invalid input: %v", err)
+//	}
+//	r.WriteText(os.Stdout)
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/escape"
+	"github.com/creachadair/jtree/jwcc"
+)
+
+// A Report summarizes the contents of a document.
+type Report struct {
+	Bytes   int // the size of the input in bytes
+	Lines   int // the number of lines in the input
+	Depth   int // the maximum nesting depth (0 for a scalar)
+	Values  int // the total number of values, including nested values
+	Members int // the total number of object members
+
+	// If the document is an object, Keys has one entry for each of its
+	// members, in input order.
+	Keys []KeyStats
+
+	Comments   Coverage  // comment coverage of object members
+	Duplicates []Finding // keys repeated within the same object
+	Suspicious []Finding // values that may not be what the author intended
+}
+
+// KeyStats summarizes the value of a top-level object member.
+type KeyStats struct {
+	Key     string
+	Type    string // "object", "array", "string", "number", "boolean", or "null"
+	Bytes   int    // the size of the value in the input, in bytes
+	Depth   int    // the nesting depth of the value
+	Values  int    // the number of values, including the value itself
+	Members int    // the number of object members within the value
+}
+
+// Coverage summarizes the comments attached to object members.
+type Coverage struct {
+	Members   int // the number of object members
+	Commented int // the number of members with at least one comment
+	Comments  int // the total number of comments
+}
+
+// Ratio reports the fraction of members that are commented, or 0 if there
+// are no members.
+func (c Coverage) Ratio() float64 {
+	if c.Members == 0 {
+		return 0
+	}
+	return float64(c.Commented) / float64(c.Members)
+}
+
+// A Finding describes a potential problem at a location in the document.
+type Finding struct {
+	Path    string         // a JSON Pointer (RFC 6901) to the value
+	Loc     jtree.Location // the location of the value in the input
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Loc.First, pathString(f.Path), f.Message)
+}
+
+// Analyze parses a JSON or JWCC document from r and reports its statistics.
+// If the input is not valid, Analyze reports an error.
+func Analyze(r io.Reader) (*Report, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := jwcc.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	rep := &Report{Bytes: len(data), Lines: bytes.Count(data, []byte("\n"))}
+	if len(data) != 0 && data[len(data)-1] != '\n' {
+		rep.Lines++
+	}
+	var s stats
+	rep.Depth = rep.walk(&s, "", doc.Value)
+	rep.Values, rep.Members = s.values, s.members
+
+	if o, ok := doc.Value.(*jwcc.Object); ok {
+		for _, m := range o.Members {
+			var ks stats
+			depth := rep.walkSilent(&ks, m.Value)
+			loc := jwcc.ValueLocation(m.Value)
+			rep.Keys = append(rep.Keys, KeyStats{
+				Key:     m.Key.String(),
+				Type:    typeName(m.Value.Undecorate()),
+				Bytes:   loc.Span.End - loc.Span.Pos,
+				Depth:   depth,
+				Values:  ks.values,
+				Members: ks.members,
+			})
+		}
+	}
+	return rep, nil
+}
+
+// stats accumulates counts of values and members.
+type stats struct{ values, members int }
+
+// walkSilent updates s with the counts for v and returns its depth, without
+// recording comments or findings.
+func (r *Report) walkSilent(s *stats, v jwcc.Value) int {
+	var tmp Report
+	return tmp.walk(s, "", v)
+}
+
+// walk updates s with the counts for v, whose path is path, records comment
+// coverage and findings in r, and returns the depth of v.
+func (r *Report) walk(s *stats, path string, v jwcc.Value) int {
+	s.values++
+	switch t := v.(type) {
+	case *jwcc.Object:
+		depth := 0
+		seen := make(map[string]*jwcc.Member)
+		fold := make(map[string]string)
+		for _, m := range t.Members {
+			s.members++
+			r.countComments(m)
+			key := m.Key.String()
+			mpath := path + "/" + escapePointer(key)
+			mloc := jwcc.ValueLocation(m)
+			if prev, ok := seen[key]; ok {
+				r.Duplicates = append(r.Duplicates, Finding{
+					Path: mpath, Loc: mloc,
+					Message: fmt.Sprintf("duplicate key %q (first at %s)", key, jwcc.ValueLocation(prev).First),
+				})
+			} else {
+				seen[key] = m
+				lkey := strings.ToLower(key)
+				if other, ok := fold[lkey]; ok {
+					r.suspect(mpath, mloc, "key %q differs only in case from %q", key, other)
+				} else {
+					fold[lkey] = key
+				}
+			}
+			if key == "" {
+				r.suspect(mpath, mloc, "empty key")
+			} else if strings.TrimSpace(key) != key {
+				r.suspect(mpath, mloc, "key has leading or trailing space")
+			}
+			depth = max(depth, r.walk(s, mpath, m.Value))
+		}
+		return depth + 1
+	case *jwcc.Array:
+		depth := 0
+		for i, elt := range t.Values {
+			depth = max(depth, r.walk(s, path+"/"+strconv.Itoa(i), elt))
+		}
+		return depth + 1
+	case *jwcc.Datum:
+		r.checkDatum(path, jwcc.ValueLocation(t), t.Value)
+	}
+	return 0
+}
+
+// countComments records the comments attached to m and its value.
+func (r *Report) countComments(m *jwcc.Member) {
+	n := numComments(m.Comments()) + numComments(m.Value.Comments())
+	r.Comments.Members++
+	r.Comments.Comments += n
+	if n != 0 {
+		r.Comments.Commented++
+	}
+}
+
+func numComments(c *jwcc.Comments) int {
+	n := len(c.Before) + len(c.End)
+	if c.Line != "" {
+		n++
+	}
+	return n
+}
+
+// maxSafeInt is the largest magnitude of an integer that can be represented
+// exactly as a float64, which many JSON decoders use for all numbers.
+const maxSafeInt = 1 << 53
+
+func (r *Report) checkDatum(path string, loc jtree.Location, v ast.Value) {
+	switch t := v.(type) {
+	case ast.Text:
+		s := t.String()
+		switch {
+		case s != "" && strings.TrimSpace(s) != s:
+			r.suspect(path, loc, "string has leading or trailing space")
+		case s == "true" || s == "false":
+			r.suspect(path, loc, "string %q looks like a Boolean", s)
+		case s == "null":
+			r.suspect(path, loc, "string %q looks like null", s)
+		case isNumeric(s):
+			r.suspect(path, loc, "string %q looks like a number", s)
+		}
+	case ast.Number:
+		if t.IsInt() {
+			z, err := strconv.ParseInt(t.JSON(), 10, 64)
+			if err != nil || z > maxSafeInt || z < -maxSafeInt {
+				r.suspect(path, loc, "integer %s cannot be represented exactly as a float64", t.JSON())
+			}
+		} else if f, err := strconv.ParseFloat(t.JSON(), 64); err != nil || math.IsInf(f, 0) {
+			r.suspect(path, loc, "number %s is out of range for a float64", t.JSON())
+		}
+	}
+}
+
+func (r *Report) suspect(path string, loc jtree.Location, msg string, args ...any) {
+	r.Suspicious = append(r.Suspicious, Finding{
+		Path: path, Loc: loc, Message: fmt.Sprintf(msg, args...),
+	})
+}
+
+// isNumeric reports whether s has the syntax of a JSON number.
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	st := jtree.NewScanner(strings.NewReader(s))
+	if err := st.Next(); err != nil {
+		return false
+	}
+	tok := st.Token()
+	return (tok == jtree.Integer || tok == jtree.Number) && st.Next() == io.EOF
+}
+
+func typeName(v ast.Value) string {
+	switch v.(type) {
+	case ast.Object:
+		return "object"
+	case ast.Array:
+		return "array"
+	case ast.Text:
+		return "string"
+	case ast.Number:
+		return "number"
+	case ast.Bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+func escapePointer(s string) string { return escape.EscapeToken(s) }
+
+func pathString(p string) string {
+	if p == "" {
+		return "(root)"
+	}
+	return p
+}
+
+// WriteText writes a human-readable rendering of r to w.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Size:\t%d bytes, %d lines\n", r.Bytes, r.Lines)
+	fmt.Fprintf(tw, "Depth:\t%d\n", r.Depth)
+	fmt.Fprintf(tw, "Values:\t%d (%d members)\n", r.Values, r.Members)
+	fmt.Fprintf(tw, "Comments:\t%d of %d members commented (%.0f%%), %d comments\n",
+		r.Comments.Commented, r.Comments.Members, 100*r.Comments.Ratio(), r.Comments.Comments)
+	if len(r.Keys) != 0 {
+		fmt.Fprintln(tw, "\nKEY\tTYPE\tBYTES\tDEPTH\tVALUES\tMEMBERS")
+		for _, k := range r.Keys {
+			fmt.Fprintf(tw, "%q\t%s\t%d\t%d\t%d\t%d\n", k.Key, k.Type, k.Bytes, k.Depth, k.Values, k.Members)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, sec := range []struct {
+		label string
+		fs    []Finding
+	}{
+		{"Duplicate keys", r.Duplicates},
+		{"Suspicious values", r.Suspicious},
+	} {
+		if len(sec.fs) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "\n%s:\n", sec.label); err != nil {
+			return err
+		}
+		for _, f := range sec.fs {
+			if _, err := fmt.Fprintf(w, "  %s\n", f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ToValue returns a JSON representation of r.
+func (r *Report) ToValue() ast.Value {
+	keys := make(ast.Array, len(r.Keys))
+	for i, k := range r.Keys {
+		keys[i] = ast.Object{
+			ast.Field("key", k.Key),
+			ast.Field("type", k.Type),
+			ast.Field("bytes", k.Bytes),
+			ast.Field("depth", k.Depth),
+			ast.Field("values", k.Values),
+			ast.Field("members", k.Members),
+		}
+	}
+	return ast.Object{
+		ast.Field("bytes", r.Bytes),
+		ast.Field("lines", r.Lines),
+		ast.Field("depth", r.Depth),
+		ast.Field("values", r.Values),
+		ast.Field("members", r.Members),
+		ast.Field("keys", keys),
+		ast.Field("comments", ast.Object{
+			ast.Field("members", r.Comments.Members),
+			ast.Field("commented", r.Comments.Commented),
+			ast.Field("comments", r.Comments.Comments),
+		}),
+		ast.Field("duplicates", findingsValue(r.Duplicates)),
+		ast.Field("suspicious", findingsValue(r.Suspicious)),
+	}
+}
+
+func findingsValue(fs []Finding) ast.Array {
+	out := make(ast.Array, len(fs))
+	for i, f := range fs {
+		out[i] = ast.Object{
+			ast.Field("path", f.Path),
+			ast.Field("line", f.Loc.First.Line),
+			ast.Field("column", f.Loc.First.Column),
+			ast.Field("message", f.Message),
+		}
+	}
+	return out
+}
+
+// WriteJSON writes a JSON rendering of r to w.
+func (r *Report) WriteJSON(w io.Writer) error {
+	return jwcc.Format(w, jwcc.Decorate(r.ToValue()))
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/report"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testInput = `{
+  // The service name.
+  "name": "frob",
+  "port": "8080",
+  "limits": {"cpu": 2, "mem": [1, 2, {"x": null}]},
+  "Name": " spaced ",
+  "id": 12345678901234567890,
+  "name": "again",  // oops
+}
+`
+
+func TestAnalyze(t *testing.T) {
+	r, err := report.Analyze(strings.NewReader(testInput))
+	if err != nil {
+		t.Fatalf("Analyze: unexpected error: %v", err)
+	}
+
+	if r.Bytes != len(testInput) {
+		t.Errorf("Bytes: got %d, want %d", r.Bytes, len(testInput))
+	}
+	if r.Lines != 9 {
+		t.Errorf("Lines: got %d, want 9", r.Lines)
+	}
+	if r.Depth != 4 {
+		t.Errorf("Depth: got %d, want 4", r.Depth)
+	}
+	if r.Values != 13 || r.Members != 9 {
+		t.Errorf("Values, Members: got %d, %d; want 13, 9", r.Values, r.Members)
+	}
+	if diff := cmp.Diff(r.Comments, report.Coverage{Members: 9, Commented: 2, Comments: 2}); diff != "" {
+		t.Errorf("Comments (-got, +want):\n%s", diff)
+	}
+
+	var keys []string
+	for _, k := range r.Keys {
+		keys = append(keys, k.Key+":"+k.Type)
+	}
+	if diff := cmp.Diff(keys, []string{
+		"name:string", "port:string", "limits:object", "Name:string", "id:number", "name:string",
+	}); diff != "" {
+		t.Errorf("Keys (-got, +want):\n%s", diff)
+	}
+	if lim := r.Keys[2]; lim.Depth != 3 || lim.Values != 7 || lim.Members != 3 {
+		t.Errorf("Keys[limits]: got %+v, want depth 3, values 7, members 3", lim)
+	}
+
+	findings := func(fs []report.Finding) []string {
+		var out []string
+		for _, f := range fs {
+			out = append(out, f.Path+" "+f.Message)
+		}
+		return out
+	}
+	if diff := cmp.Diff(findings(r.Duplicates), []string{
+		`/name duplicate key "name" (first at 3:2)`,
+	}); diff != "" {
+		t.Errorf("Duplicates (-got, +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(findings(r.Suspicious), []string{
+		`/port string "8080" looks like a number`,
+		`/Name key "Name" differs only in case from "name"`,
+		`/Name string has leading or trailing space`,
+		`/id integer 12345678901234567890 cannot be represented exactly as a float64`,
+	}); diff != "" {
+		t.Errorf("Suspicious (-got, +want):\n%s", diff)
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := r.WriteText(&buf); err != nil {
+			t.Fatalf("WriteText: unexpected error: %v", err)
+		}
+		t.Logf("Text report:\n%s", buf.String())
+		for _, want := range []string{
+			"Depth:     4\n",
+			"2 of 9 members commented (22%), 2 comments",
+			`"limits"  object`,
+			"Duplicate keys:\n  8:2: /name: duplicate key",
+		} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("Text report is missing %q", want)
+			}
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := r.WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON: unexpected error: %v", err)
+		}
+		doc, err := jwcc.Parse(&buf)
+		if err != nil {
+			t.Fatalf("Parse JSON report: %v", err)
+		}
+		if got, want := doc.JSON(), r.ToValue().JSON(); got != want {
+			t.Errorf("JSON report: got %s, want %s", got, want)
+		}
+	})
+}
+
+func TestScalar(t *testing.T) {
+	r, err := report.Analyze(strings.NewReader(`"true"`))
+	if err != nil {
+		t.Fatalf("Analyze: unexpected error: %v", err)
+	}
+	if r.Depth != 0 || r.Values != 1 || r.Lines != 1 || len(r.Keys) != 0 {
+		t.Errorf("Analyze: got %+v, want depth 0, 1 value, 1 line, no keys", r)
+	}
+	if len(r.Suspicious) != 1 || r.Suspicious[0].Path != "" {
+		t.Errorf("Suspicious: got %v, want one finding at the root", r.Suspicious)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	if r, err := report.Analyze(strings.NewReader(`{"a": 1,,}`)); err == nil {
+		t.Errorf("Analyze: got %+v, want error", r)
+	}
+}
@@ -0,0 +1,388 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package sample generates random synthetic JSON values that follow a schema,
+// for use in load tests and benchmarks of code that consumes JSON.
+//
+// A Schema describes the values to generate as a weighted choice among kinds
+// of value, with constraints for each kind. A schema can be written directly,
+// or inferred from example values, in which case the generated values follow
+// the proportions observed in the examples:
+//
+//	s := sample.Infer(examples...)
+//	g := sample.NewGenerator(s, &sample.Options{Seed: 1})
+//	for range 1000 {
+//	   doc := g.Next()
+//	   ...
+//	}
+//
+// A generator is deterministic: Two generators with the same schema and
+// options produce the same sequence of values.
+package sample
+
+import (
+	"math"
+	"math/rand/v2"
+	"strconv"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/jwcc"
+)
+
+// A Kind identifies a kind of JSON value.
+type Kind int
+
+// Constants defining the valid Kind values.
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+
+	numKinds = iota
+)
+
+var kindStr = [...]string{
+	Null:   "null",
+	Bool:   "bool",
+	Number: "number",
+	String: "string",
+	Array:  "array",
+	Object: "object",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindStr) {
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return kindStr[k]
+}
+
+// A Schema describes a set of values to generate.
+type Schema struct {
+	// The relative weight of each kind of value. A value of kind k is chosen
+	// with probability Weight[k] divided by the sum of the weights. If no
+	// weight is positive, the value is null.
+	Weight map[Kind]float64
+
+	// The probability that a Bool value is true.
+	TrueRatio float64
+
+	// The range of Number values, inclusive. If Integer is true, only integer
+	// values are generated.
+	Min, Max float64
+	Integer  bool
+
+	// If non-empty, String values are chosen uniformly from this list, so a
+	// string that appears more than once is chosen more often. Otherwise,
+	// strings of random letters are generated with length between MinLen and
+	// MaxLen, inclusive.
+	Strings        []string
+	MinLen, MaxLen int
+
+	// The schema of the elements of an Array value. If nil, arrays are empty.
+	Elem *Schema
+
+	// The range of the number of elements in an Array value, inclusive.
+	MinItems, MaxItems int
+
+	// The members of an Object value, in order.
+	Fields []Field
+}
+
+// A Field describes a member of an object.
+type Field struct {
+	Key    string
+	Schema *Schema
+
+	// The probability that the member is present. A field with probability 0
+	// is treated as always present.
+	Prob float64
+}
+
+// Options are settings for a Generator. A nil *Options is ready for use.
+type Options struct {
+	// The seed for the pseudo-random generator.
+	Seed uint64
+
+	// If positive, the maximum nesting depth of generated objects and arrays.
+	// Objects and arrays at the maximum depth are generated empty. If zero or
+	// negative, a default of 16 is used.
+	MaxDepth int
+
+	// If positive, a factor by which the number of elements of each array is
+	// scaled, to make generated values larger or smaller than the schema
+	// describes. If zero or negative, a factor of 1 is used.
+	Scale float64
+
+	// If true, generate jwcc.Value values instead of ast.Value values.
+	JWCC bool
+}
+
+func (o *Options) seed() uint64 {
+	if o == nil {
+		return 0
+	}
+	return o.Seed
+}
+
+func (o *Options) maxDepth() int {
+	if o == nil || o.MaxDepth <= 0 {
+		return 16
+	}
+	return o.MaxDepth
+}
+
+func (o *Options) scale() float64 {
+	if o == nil || o.Scale <= 0 {
+		return 1
+	}
+	return o.Scale
+}
+
+func (o *Options) jwcc() bool { return o != nil && o.JWCC }
+
+// A Generator generates random values that follow a schema.
+type Generator struct {
+	s    *Schema
+	opts *Options
+	rng  *rand.Rand
+}
+
+// NewGenerator constructs a generator for values that follow s.
+func NewGenerator(s *Schema, opts *Options) *Generator {
+	seed := opts.seed()
+	return &Generator{s: s, opts: opts, rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// Next returns the next generated value. If the Options for g requested
+// JWCC values, the concrete type of the result is jwcc.Value.
+func (g *Generator) Next() ast.Value {
+	v := g.value(g.s, 0)
+	if g.opts.jwcc() {
+		return jwcc.Decorate(v)
+	}
+	return v
+}
+
+func (g *Generator) value(s *Schema, depth int) ast.Value {
+	switch g.kind(s) {
+	case Bool:
+		return ast.Bool(g.rng.Float64() < s.TrueRatio)
+	case Number:
+		f := s.Min + g.rng.Float64()*(s.Max-s.Min)
+		if s.Integer {
+			return ast.Int(math.Round(f))
+		}
+		return ast.Float(f)
+	case String:
+		if len(s.Strings) != 0 {
+			return ast.String(s.Strings[g.rng.IntN(len(s.Strings))])
+		}
+		const letters = "abcdefghijklmnopqrstuvwxyz"
+		buf := make([]byte, g.between(s.MinLen, s.MaxLen))
+		for i := range buf {
+			buf[i] = letters[g.rng.IntN(len(letters))]
+		}
+		return ast.String(buf)
+	case Array:
+		if s.Elem == nil || depth >= g.opts.maxDepth() {
+			return ast.Array{}
+		}
+		n := int(math.Round(float64(g.between(s.MinItems, s.MaxItems)) * g.opts.scale()))
+		out := make(ast.Array, n)
+		for i := range out {
+			out[i] = g.value(s.Elem, depth+1)
+		}
+		return out
+	case Object:
+		out := ast.Object{}
+		if depth >= g.opts.maxDepth() {
+			return out
+		}
+		for _, f := range s.Fields {
+			if f.Prob > 0 && g.rng.Float64() >= f.Prob {
+				continue
+			}
+			var v ast.Value = ast.Null
+			if f.Schema != nil {
+				v = g.value(f.Schema, depth+1)
+			}
+			out = append(out, &ast.Member{Key: ast.String(f.Key), Value: v})
+		}
+		return out
+	default:
+		return ast.Null
+	}
+}
+
+// kind chooses a kind of value at random according to the weights of s.
+func (g *Generator) kind(s *Schema) Kind {
+	var sum float64
+	for _, w := range s.Weight {
+		sum += max(w, 0)
+	}
+	if sum == 0 {
+		return Null
+	}
+	r := g.rng.Float64() * sum
+	for k := range Kind(numKinds) {
+		if w := s.Weight[k]; w <= 0 {
+			continue
+		} else if r < w {
+			return k
+		} else {
+			r -= w
+		}
+	}
+	return Null // not reached, except by rounding
+}
+
+// between returns a random integer between lo and hi, inclusive.
+func (g *Generator) between(lo, hi int) int {
+	if hi <= lo {
+		return max(lo, 0)
+	}
+	return lo + g.rng.IntN(hi-lo+1)
+}
+
+// maxStrings is the maximum number of example strings recorded by Infer for
+// a single location.
+const maxStrings = 64
+
+// Infer returns a schema describing the given example values. The weights of
+// the schema reflect how often each kind of value occurs in the examples, at
+// each location, and the probability of each object field reflects how often
+// it occurs among the objects at its location.
+//
+// Infer records up to 64 example strings at each location, and generated
+// strings are chosen from among them. If an example is a jwcc.Value, its
+// comments are ignored.
+func Infer(vs ...ast.Value) *Schema {
+	var inf inferrer
+	for _, v := range vs {
+		inf.add(v)
+	}
+	return inf.schema()
+}
+
+// An inferrer accumulates observations of the values at one location.
+type inferrer struct {
+	count    [numKinds]int
+	trues    int
+	min, max float64
+	integer  bool
+	strs     []string
+	minLen   int
+	maxLen   int
+	elem     *inferrer
+	minItems int
+	maxItems int
+	fields   []*fieldInferrer
+}
+
+type fieldInferrer struct {
+	key   string
+	count int
+	inferrer
+}
+
+func (f *inferrer) add(v ast.Value) {
+	if jv, ok := v.(jwcc.Value); ok {
+		v = jv.Undecorate()
+	}
+	switch t := v.(type) {
+	case ast.Bool:
+		f.count[Bool]++
+		if t {
+			f.trues++
+		}
+	case ast.Number:
+		x := float64(t.Float())
+		if f.count[Number] == 0 {
+			f.min, f.max, f.integer = x, x, true
+		}
+		f.min, f.max = min(f.min, x), max(f.max, x)
+		f.integer = f.integer && t.IsInt()
+		f.count[Number]++
+	case ast.Text:
+		s := t.String()
+		if f.count[String] == 0 {
+			f.minLen, f.maxLen = len(s), len(s)
+		}
+		f.minLen, f.maxLen = min(f.minLen, len(s)), max(f.maxLen, len(s))
+		if len(f.strs) < maxStrings {
+			f.strs = append(f.strs, s)
+		}
+		f.count[String]++
+	case ast.Array:
+		if f.count[Array] == 0 {
+			f.minItems, f.maxItems = len(t), len(t)
+		}
+		f.minItems, f.maxItems = min(f.minItems, len(t)), max(f.maxItems, len(t))
+		if len(t) != 0 && f.elem == nil {
+			f.elem = new(inferrer)
+		}
+		for _, elt := range t {
+			f.elem.add(elt)
+		}
+		f.count[Array]++
+	case ast.Object:
+		for _, m := range t {
+			key := m.Key.String()
+			fi := f.field(key)
+			fi.count++
+			fi.add(m.Value)
+		}
+		f.count[Object]++
+	default:
+		f.count[Null]++
+	}
+}
+
+// field returns the inferrer for the field with the given key, adding it if
+// necessary.
+func (f *inferrer) field(key string) *fieldInferrer {
+	for _, fi := range f.fields {
+		if fi.key == key {
+			return fi
+		}
+	}
+	fi := &fieldInferrer{key: key}
+	f.fields = append(f.fields, fi)
+	return fi
+}
+
+func (f *inferrer) schema() *Schema {
+	s := &Schema{
+		Min:      f.min,
+		Max:      f.max,
+		Integer:  f.integer,
+		Strings:  f.strs,
+		MinLen:   f.minLen,
+		MaxLen:   f.maxLen,
+		MinItems: f.minItems,
+		MaxItems: f.maxItems,
+	}
+	s.Weight = make(map[Kind]float64)
+	for k, n := range f.count {
+		if n != 0 {
+			s.Weight[Kind(k)] = float64(n)
+		}
+	}
+	if n := f.count[Bool]; n != 0 {
+		s.TrueRatio = float64(f.trues) / float64(n)
+	}
+	if f.elem != nil {
+		s.Elem = f.elem.schema()
+	}
+	for _, fi := range f.fields {
+		s.Fields = append(s.Fields, Field{
+			Key:    fi.key,
+			Schema: fi.schema(),
+			Prob:   float64(fi.count) / float64(f.count[Object]),
+		})
+	}
+	return s
+}
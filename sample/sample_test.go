@@ -0,0 +1,155 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package sample_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/testutil"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/sample"
+)
+
+func TestGenerator(t *testing.T) {
+	s := &sample.Schema{
+		Weight: map[sample.Kind]float64{sample.Object: 1},
+		Fields: []sample.Field{
+			{Key: "id", Schema: &sample.Schema{
+				Weight: map[sample.Kind]float64{sample.Number: 1},
+				Min:    1, Max: 100, Integer: true,
+			}},
+			{Key: "tags", Prob: 0.5, Schema: &sample.Schema{
+				Weight:   map[sample.Kind]float64{sample.Array: 1},
+				MinItems: 1, MaxItems: 4,
+				Elem: &sample.Schema{
+					Weight:  map[sample.Kind]float64{sample.String: 1},
+					Strings: []string{"red", "green", "blue"},
+				},
+			}},
+			{Key: "name", Schema: &sample.Schema{
+				Weight: map[sample.Kind]float64{sample.String: 3, sample.Null: 1},
+				MinLen: 2, MaxLen: 5,
+			}},
+		},
+	}
+
+	gen := func(opts *sample.Options) []string {
+		g := sample.NewGenerator(s, opts)
+		var out []string
+		for range 50 {
+			out = append(out, g.Next().JSON())
+		}
+		return out
+	}
+
+	t.Run("Deterministic", func(t *testing.T) {
+		a, b := gen(&sample.Options{Seed: 7}), gen(&sample.Options{Seed: 7})
+		if strings.Join(a, "\n") != strings.Join(b, "\n") {
+			t.Error("Generators with the same seed produced different values")
+		}
+		c := gen(&sample.Options{Seed: 8})
+		if strings.Join(a, "\n") == strings.Join(c, "\n") {
+			t.Error("Generators with different seeds produced the same values")
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		var hasTags, hasNull bool
+		for _, js := range gen(nil) {
+			obj := testutil.MustParse(t, js).(ast.Object)
+			id, ok := obj.Find("id").Value.(ast.Number)
+			if !ok || !id.IsInt() || id.Int() < 1 || id.Int() > 100 {
+				t.Errorf("Value %s: invalid id", js)
+			}
+			if m := obj.Find("tags"); m != nil {
+				hasTags = true
+				tags := m.Value.(ast.Array)
+				if len(tags) < 1 || len(tags) > 4 {
+					t.Errorf("Value %s: wrong number of tags", js)
+				}
+				for _, tag := range tags {
+					switch tag.(ast.Text).String() {
+					case "red", "green", "blue":
+					default:
+						t.Errorf("Value %s: unexpected tag %v", js, tag)
+					}
+				}
+			}
+			switch name := obj.Find("name").Value.(type) {
+			case ast.Text:
+				if n := len(name.String()); n < 2 || n > 5 {
+					t.Errorf("Value %s: wrong name length", js)
+				}
+			default:
+				hasNull = true
+			}
+		}
+		if !hasTags || !hasNull {
+			t.Errorf("Optional values not generated: tags=%v null=%v", hasTags, hasNull)
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		for _, js := range gen(&sample.Options{MaxDepth: 1}) {
+			if m := testutil.MustParse(t, js).(ast.Object).Find("tags"); m != nil && len(m.Value.(ast.Array)) != 0 {
+				t.Errorf("Value %s: array exceeds maximum depth", js)
+			}
+		}
+		for _, js := range gen(&sample.Options{Scale: 3}) {
+			if m := testutil.MustParse(t, js).(ast.Object).Find("tags"); m != nil && len(m.Value.(ast.Array)) < 3 {
+				t.Errorf("Value %s: array was not scaled", js)
+			}
+		}
+	})
+
+	t.Run("JWCC", func(t *testing.T) {
+		v := sample.NewGenerator(s, &sample.Options{JWCC: true}).Next()
+		if _, ok := v.(jwcc.Value); !ok {
+			t.Errorf("Next: got %T, want jwcc.Value", v)
+		}
+	})
+}
+
+func TestInfer(t *testing.T) {
+	s := sample.Infer(
+		testutil.MustParse(t, `{"id": 1, "ok": true, "tags": ["a", "b"], "x": 2.5}`),
+		testutil.MustParse(t, `{"id": 5, "ok": false, "tags": []}`),
+		testutil.MustParse(t, `{"id": 3, "ok": true, "tags": ["c"], "x": -1}`),
+		testutil.MustParse(t, `{"id": 9, "ok": true, "tags": ["a"]}`),
+	)
+	if s.Weight[sample.Object] != 4 {
+		t.Errorf("Object weight: got %v, want 4", s.Weight[sample.Object])
+	}
+	var keys []string
+	for _, f := range s.Fields {
+		keys = append(keys, f.Key)
+	}
+	if got := strings.Join(keys, ","); got != "id,ok,tags,x" {
+		t.Errorf("Fields: got %q, want id,ok,tags,x", got)
+	}
+	id, ok, tags, x := s.Fields[0].Schema, s.Fields[1].Schema, s.Fields[2].Schema, s.Fields[3]
+	if id.Min != 1 || id.Max != 9 || !id.Integer {
+		t.Errorf("id: got range %v..%v integer=%v, want 1..9 integer", id.Min, id.Max, id.Integer)
+	}
+	if ok.TrueRatio != 0.75 {
+		t.Errorf("ok: got true ratio %v, want 0.75", ok.TrueRatio)
+	}
+	if tags.MinItems != 0 || tags.MaxItems != 2 || tags.Elem == nil || len(tags.Elem.Strings) != 4 {
+		t.Errorf("tags: got %+v", tags)
+	}
+	if x.Prob != 0.5 || x.Schema.Integer || x.Schema.Min != -1 || x.Schema.Max != 2.5 {
+		t.Errorf("x: got prob %v, schema %+v", x.Prob, x.Schema)
+	}
+
+	// Generated values follow the observed examples.
+	g := sample.NewGenerator(s, nil)
+	for range 20 {
+		v := g.Next()
+		obj, isObj := v.(ast.Object)
+		if !isObj || obj.Find("id") == nil || obj.Find("ok") == nil || obj.Find("tags") == nil {
+			t.Errorf("Generated value %s is missing required fields", v.JSON())
+		}
+	}
+}
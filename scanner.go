@@ -5,10 +5,14 @@ package jtree
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strings"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"go4.org/mem"
 )
@@ -35,6 +39,10 @@ const (
 	BlockComment // comment: /* ... */
 	LineComment  // comment: // ... <LF>
 
+	NaN         // non-finite number: NaN
+	Infinity    // non-finite number: Infinity
+	NegInfinity // non-finite number: -Infinity
+
 	// Do not modify the order of these constants without updating the
 	// self-delimiting token check below.
 )
@@ -56,6 +64,10 @@ var tokenStr = [...]string{
 
 	BlockComment: "block commment",
 	LineComment:  "line comment",
+
+	NaN:         "NaN",
+	Infinity:    "Infinity",
+	NegInfinity: "-Infinity",
 }
 
 func (t Token) String() string {
@@ -70,9 +82,32 @@ func (t Token) String() string {
 // advances the scanner to the next token, or reports an error.
 type Scanner struct {
 	r        *bufio.Reader
+	ownR     bool         // r was allocated by the scanner, and may be reused
+	src      []byte       // input, if direct
+	base     int          // the input offset of src[0]
+	direct   bool         // read directly from src rather than r
+	push     bool         // src is fed by Write
+	final    bool         // Finish has been called
+	short    bool         // a read reached the end of input fed so far
 	comments bool         // allow comments
+	space    bool         // retain whitespace
+	strict   bool         // reject invalid UTF-8 and unpaired surrogates
+	bom      bool         // skip a leading byte-order mark
+	junk     bool         // skip leading input before the first value
+	begun    bool         // the first token has been scanned
+	skip     *textAnchor  // the input skipped before the first token, or nil
+	skipNew  bool         // skip has not yet been reported by a Stream
+	json5    bool         // accept JSON5 extensions
+	nonfin   bool         // accept NaN and Infinity
+	bareKeys bool         // accept identifiers as object keys
+	runeCols bool         // count columns in runes rather than bytes
+	ident    bool         // the current token is a JSON5 identifier
+	unread   bool         // the current token was pushed back by UnreadToken
 	buf      bytes.Buffer // current token
+	sbuf     bytes.Buffer // whitespace preceding current token
 	tbuf     [][]byte     // allocation pool
+	rbuf     [][]byte     // reusable allocation pool, see Release
+	reuse    bool         // Bytes allocates from rbuf
 	tok      Token
 	err      error
 
@@ -82,6 +117,10 @@ type Scanner struct {
 	// Apparent line and column offsets (0-based)
 	pline, pcol int
 	eline, ecol int
+
+	// The start offset, line, and column of the whitespace preceding the
+	// current token, which ends where the token begins.
+	spos, sline, scol int
 }
 
 // NewScanner constructs a new lexical scanner that consumes input from r.
@@ -90,7 +129,110 @@ func NewScanner(r io.Reader) *Scanner {
 	if !ok {
 		br = bufio.NewReader(r)
 	}
-	return &Scanner{r: br}
+	return &Scanner{r: br, ownR: !ok}
+}
+
+// NewScannerBytes constructs a new lexical scanner that consumes input
+// directly from data, without buffering. This is faster than NewScanner for
+// input that is already in memory, and the token text reported by Text and
+// Copy is a slice of data rather than a copy. The caller must not modify the
+// contents of data while the scanner, or any slice it returned, is in use.
+func NewScannerBytes(data []byte) *Scanner {
+	return &Scanner{src: data, direct: true}
+}
+
+// NewPushScanner constructs a new lexical scanner whose input is supplied by
+// calls to its Write method, for use where an io.Reader is awkward, such as
+// with a non-blocking network connection. The caller must call Finish after
+// writing the last of the input. Until then, Next reports ErrNeedMoreInput
+// when the input written so far does not contain a complete token.
+//
+// As for NewScannerBytes, the token text reported by Text and Copy is a slice
+// of the input rather than a copy. The scanner retains only the input from
+// the start of the current token onward.
+func NewPushScanner() *Scanner { return &Scanner{direct: true, push: true} }
+
+// ErrNeedMoreInput is reported by the Next method of a scanner constructed by
+// NewPushScanner when the input ends before a complete token, and Finish has
+// not been called.
+var ErrNeedMoreInput = errors.New("more input needed")
+
+// Write adds data to the input of a scanner constructed by NewPushScanner. It
+// reports an error if s is not push-fed, or if Finish has been called. The
+// scanner does not retain data, but copies it.
+func (s *Scanner) Write(data []byte) (int, error) {
+	if !s.push {
+		return 0, errors.New("scanner is not push-fed")
+	} else if s.final {
+		return 0, errors.New("write after Finish")
+	}
+
+	// Discard the input before the current token, once it is a large enough
+	// fraction of the buffer to be worth copying. The old buffer is left
+	// intact, so that slices of it returned by Text or Copy remain valid.
+	if drop := s.pos - s.base; drop > 0 && drop >= len(s.src)/2 {
+		buf := make([]byte, 0, len(s.src)-drop+len(data))
+		s.src = append(buf, s.src[drop:]...)
+		s.base += drop
+	}
+	s.src = append(s.src, data...)
+	return len(data), nil
+}
+
+// Finish reports that all the input of a scanner constructed by
+// NewPushScanner has been written. After Finish, Next reports io.EOF at the
+// end of the input, and an incomplete token is an error.
+func (s *Scanner) Finish() { s.final = true }
+
+// NewScannerAt constructs a new lexical scanner that consumes input from r
+// within the window of size bytes beginning at offset. If size < 0, the window
+// extends to the end of r. The input before offset is not read.
+//
+// The spans reported by the scanner are offsets in r, not in the window.
+// Line and column numbers are counted from the start of the window, unless
+// the caller uses SetLineCol to report the location of offset in r.
+func NewScannerAt(r io.ReaderAt, offset, size int64) *Scanner {
+	if size < 0 {
+		size = math.MaxInt64 - offset
+	}
+	s := NewScanner(io.NewSectionReader(r, offset, size))
+	s.pos, s.end = int(offset), int(offset)
+	return s
+}
+
+// Reset discards the state of s and prepares it to read tokens from r, as if
+// it had been newly constructed by NewScanner, but without allocating a new
+// buffer. Settings such as AllowComments and RetainSpace are kept. Slices
+// returned by Copy before the reset remain valid, and the space remaining in
+// the blocks that hold them is used for later copies.
+//
+// Only a buffer allocated by the scanner is reused. A *bufio.Reader supplied
+// by the caller is never reset or read after the scanner moves on from it.
+func (s *Scanner) Reset(r io.Reader) {
+	if br, ok := r.(*bufio.Reader); ok {
+		s.r, s.ownR = br, false
+	} else if s.r != nil && s.ownR {
+		s.r.Reset(r)
+	} else {
+		s.r, s.ownR = bufio.NewReader(r), true
+	}
+	s.src, s.base, s.direct = nil, 0, false
+	s.push, s.final, s.short = false, false, false
+	s.buf.Reset()
+	s.sbuf.Reset()
+	s.tok, s.ident, s.err = Invalid, false, nil
+	s.begun, s.skip, s.skipNew, s.unread = false, nil, false, false
+	s.pos, s.end, s.last = 0, 0, 0
+	s.pline, s.pcol, s.eline, s.ecol = 0, 0, 0, 0
+	s.spos, s.sline, s.scol = 0, 0, 0
+}
+
+// SetLineCol sets the line and column of the next unread input to lc. This is
+// useful for a scanner that starts partway through its input, so that the
+// locations it reports are consistent with the complete input.
+func (s *Scanner) SetLineCol(lc LineCol) {
+	s.pline, s.pcol = lc.Line-1, lc.Column
+	s.eline, s.ecol = s.pline, s.pcol
 }
 
 // AllowComments configures the scanner to report (true) or reject (false)
@@ -99,13 +241,135 @@ func NewScanner(r io.Reader) *Scanner {
 // are recognized and emitted as tokens.
 func (s *Scanner) AllowComments(ok bool) { s.comments = ok }
 
+// RetainSpace configures the scanner to retain (true) or discard (false) the
+// whitespace preceding each token. If enabled, the whitespace is available
+// via the Space method.
+func (s *Scanner) RetainSpace(ok bool) { s.space = ok }
+
+// ValidateUTF8 configures the scanner to reject (true) or accept (false)
+// strings and comments that contain invalid UTF-8, and strings that contain
+// \u escapes for unpaired UTF-16 surrogates. By default these are accepted,
+// and Unquote replaces them with the Unicode replacement rune.
+func (s *Scanner) ValidateUTF8(ok bool) { s.strict = ok }
+
+// AllowBOM configures the scanner to skip (true) or reject (false) a Unicode
+// byte-order mark (U+FEFF) at the start of the input.
+func (s *Scanner) AllowBOM(ok bool) { s.bom = ok }
+
+// SkipUntilValue configures the scanner to discard (true) or reject (false)
+// input before the first object or array, such as a log prefix. If enabled,
+// everything before the first "{" or "[" of the input is skipped. The
+// skipped input is reported by Skipped.
+func (s *Scanner) SkipUntilValue(ok bool) { s.junk = ok }
+
+// AllowJSON5 configures the scanner to accept (true) or reject (false) the
+// extensions to JSON defined by JSON5 (https://spec.json5.org). If enabled,
+// the scanner also accepts comments, and:
+//
+//   - Strings may be enclosed in single quotes, and may contain the escapes
+//     \', \v, \0, and \xHH, escaped line breaks, and escapes of other
+//     characters, which stand for themselves. The text of a string token is
+//     not modified, so it may not be valid JSON; see Unquote.
+//   - Numbers may have a leading "+", a leading or trailing decimal point, or
+//     hexadecimal digits ("0x1F", reported as Integer).
+//   - Infinity and NaN, with an optional sign, are accepted as described for
+//     AllowNonFiniteNumbers. A leading "+" is included in the token text.
+//   - An identifier other than true, false, null, Infinity, or NaN, such as
+//     an unquoted object key, is reported as a String whose text is the
+//     identifier. A Stream accepts these only as object keys.
+//   - Additional Unicode whitespace is allowed between tokens.
+func (s *Scanner) AllowJSON5(ok bool) { s.json5 = ok }
+
+// AllowNonFiniteNumbers configures the scanner to accept (true) or reject
+// (false) the non-standard numbers NaN, Infinity, and -Infinity, which are
+// reported as the tokens NaN, Infinity, and NegInfinity. These are commonly
+// produced by JavaScript and Python programs.
+func (s *Scanner) AllowNonFiniteNumbers(ok bool) { s.nonfin = ok }
+
+// AllowUnquotedKeys configures the scanner to accept (true) or reject (false)
+// identifiers other than true, false, and null. An identifier is a letter,
+// "$", or "_", followed by any number of these and digits. It is reported as
+// a String whose text is the identifier, for which Bare reports true, and a
+// Stream accepts it only as an object key. JSON5 also allows these.
+func (s *Scanner) AllowUnquotedKeys(ok bool) { s.bareKeys = ok }
+
+// RuneColumns configures the scanner to count the column offsets of the
+// locations it reports in runes (true) or in bytes (false). By default,
+// columns are counted in bytes. Counting in runes makes the columns of
+// non-ASCII input agree with the positions shown by most text editors.
+// Spans are always byte offsets.
+func (s *Scanner) RuneColumns(ok bool) { s.runeCols = ok }
+
+// Bare reports whether the current token is a String written as a bare
+// identifier, as allowed by AllowUnquotedKeys. The text of a bare String,
+// unlike other strings, does not begin with a quotation mark.
+func (s *Scanner) Bare() bool { return s.ident && s.tok == String }
+
+// Skipped returns an anchor for the input discarded before the first token
+// because of AllowBOM or SkipUntilValue, or nil if no input was discarded.
+// The token type of the anchor is Invalid.
+func (s *Scanner) Skipped() Anchor {
+	if s.skip == nil {
+		return nil
+	}
+	return s.skip
+}
+
 // Next advances s to the next token of the input, or reports an error.
 // At the end of the input, Next returns io.EOF.
+//
+// For a scanner constructed by NewPushScanner, if the input written so far
+// ends before the next token is complete, Next returns ErrNeedMoreInput, and
+// the incomplete token is scanned again by the next call to Next.
 func (s *Scanner) Next() error {
+	if s.unread {
+		s.unread = false
+		if s.err != ErrNeedMoreInput {
+			return s.err // report the current token again
+		}
+	}
+	if s.push {
+		return s.nextPush()
+	}
+	return s.next()
+}
+
+// nextPush implements Next for a push-fed scanner. If the scan reaches the
+// end of the input before Finish, the state of s is restored so that the
+// scan can be repeated when more input is available.
+func (s *Scanner) nextPush() error {
+	begun, skip, skipNew := s.begun, s.skip, s.skipNew
+	end, eline, ecol := s.end, s.eline, s.ecol
+	s.short = false
+	err := s.next()
+	if !s.short {
+		return err
+	}
+	s.begun, s.skip, s.skipNew = begun, skip, skipNew
+	s.pos, s.end, s.last = end, end, 0
+	s.pline, s.eline, s.pcol, s.ecol = eline, eline, ecol, ecol
+	s.spos, s.sline, s.scol = end, eline, ecol
+	s.buf.Reset()
+	s.sbuf.Reset()
+	s.tok, s.ident = Invalid, false
+	return s.setErr(ErrNeedMoreInput)
+}
+
+// next implements Next, scanning the next token of the input.
+func (s *Scanner) next() error {
 	s.buf.Reset()
+	s.sbuf.Reset()
 	s.err = nil
 	s.tok = Invalid
+	s.ident = false
+	if !s.begun {
+		s.begun = true
+		if err := s.skipPrefix(); err != nil {
+			return err
+		}
+	}
 	s.pos, s.pline, s.pcol = s.end, s.eline, s.ecol
+	s.spos, s.sline, s.scol = s.pos, s.pline, s.pcol
 
 	for {
 		ch, err := s.rune()
@@ -116,7 +380,10 @@ func (s *Scanner) Next() error {
 		}
 
 		// Discard whitespace.
-		if isSpace(ch) {
+		if isSpace(ch) || s.json5 && isSpace5(ch) {
+			if s.space {
+				s.sbuf.WriteRune(ch)
+			}
 			if ch == '\n' {
 				s.eline++
 				s.ecol = 0
@@ -127,26 +394,38 @@ func (s *Scanner) Next() error {
 
 		// Handle punctuation.
 		if t, ok := selfDelim(ch); ok {
-			s.buf.WriteRune(ch)
+			s.put(ch)
 			s.tok = t
 			return nil
 		}
 
 		// Handle numbers.
-		if isNumStart(ch) {
+		if s.json5 && isNumStart5(ch) {
+			return s.scanNumber5(ch)
+		} else if isNumStart(ch) {
 			return s.scanNumber(ch)
 		}
 
 		// Handle string values.
-		if ch == '"' {
+		if ch == '"' || s.json5 && ch == '\'' {
 			return s.scanString(ch)
 		}
 
 		// Handle comments, if enabled.
-		if ch == '/' && s.comments {
+		if ch == '/' && (s.comments || s.json5) {
 			return s.scanComment(ch)
 		}
 
+		// Handle identifiers, including constants, if enabled.
+		if (s.json5 || s.bareKeys) && isIdentStart(ch) {
+			return s.scanIdent(ch)
+		}
+
+		// Handle non-finite numbers, if enabled.
+		if s.nonfin && (ch == 'N' || ch == 'I') {
+			return s.scanNonFinite(ch)
+		}
+
 		// Handle constants: true, false, null
 		var want mem.RO
 		switch ch {
@@ -167,13 +446,30 @@ func (s *Scanner) Next() error {
 		}
 		if err != nil {
 			return err
-		} else if got := mem.B(s.buf.Bytes()); !got.Equal(want) {
+		} else if got := mem.B(s.Text()); !got.Equal(want) {
 			return s.failf("unknown constant %q", got.StringCopy())
 		}
 		return nil // OK, token is already set
 	}
 }
 
+// UnreadToken pushes back the current token, so that the next call to Next
+// reports it again, with the same error, if any. The current token remains
+// available until then. Only one token can be pushed back: Calling
+// UnreadToken again before Next has no further effect.
+func (s *Scanner) UnreadToken() { s.unread = true }
+
+// Peek reports the type of the next token of the input without consuming it.
+// Peek advances s to the next token, as Next does, and then pushes it back as
+// UnreadToken does, so the text and location of the peeked token are available
+// as those of the current token, and the next call to Next reports it again.
+// At the end of the input, Peek returns Invalid and io.EOF.
+func (s *Scanner) Peek() (Token, error) {
+	err := s.Next()
+	s.UnreadToken()
+	return s.tok, err
+}
+
 // Token returns the type of the current token.
 func (s *Scanner) Token() Token { return s.tok }
 
@@ -183,14 +479,83 @@ func (s *Scanner) Err() error { return s.err }
 // Text returns the undecoded text of the current token.  The return value is
 // only valid until the next call of Next. The caller must copy the contents of
 // the returned slice if it is needed beyond that.
-func (s *Scanner) Text() []byte { return s.buf.Bytes() }
+func (s *Scanner) Text() []byte {
+	if s.direct {
+		return s.src[s.pos-s.base : s.end-s.base : s.end-s.base]
+	}
+	return s.buf.Bytes()
+}
+
+// Space returns the whitespace that preceded the current token. If Next has
+// reported io.EOF, Space returns the whitespace following the last token.
+// Space returns an empty slice unless RetainSpace is enabled. The return value
+// is only valid until the next call of Next.
+func (s *Scanner) Space() []byte { return s.sbuf.Bytes() }
 
 // Copy returns a copy of the undecoded text of the current token.
-func (s *Scanner) Copy() []byte { return s.copyOf(s.buf.Bytes()) }
+// For a scanner constructed by NewScannerBytes, Copy does not copy, but
+// returns a slice of the input that remains valid after Next.
+func (s *Scanner) Copy() []byte {
+	if s.direct {
+		return s.Text()
+	}
+	return s.copyOf(&s.tbuf, s.buf.Bytes())
+}
+
+// Bytes returns the undecoded text of the current token. If ReuseStorage is
+// enabled, the text is copied into storage owned by s, and the result is only
+// valid until the next call of Release. Otherwise, Bytes is equivalent to
+// Copy. For a scanner constructed by NewScannerBytes, Bytes returns a slice
+// of the input in either case.
+func (s *Scanner) Bytes() []byte {
+	if s.direct {
+		return s.Text()
+	} else if s.reuse {
+		return s.copyOf(&s.rbuf, s.buf.Bytes())
+	}
+	return s.Copy()
+}
+
+// ReuseStorage configures the scanner to copy the text returned by Bytes into
+// storage that is reused after each call of Release (true), or into storage
+// that is never reused (false). By default, storage is not reused.
+//
+// Reusing storage reduces allocation when the caller processes a large input
+// in batches, and discards the text of each batch before starting the next.
+func (s *Scanner) ReuseStorage(ok bool) { s.reuse = ok }
+
+// Release discards all the text returned by Bytes while ReuseStorage is
+// enabled, so that its storage can be reused. After Release, the caller must
+// not use any such text returned before the call. Release does not affect the
+// text returned by Copy.
+func (s *Scanner) Release() {
+	for i := range s.rbuf {
+		s.rbuf[i] = s.rbuf[i][:0]
+	}
+}
 
 // Span returns the location span of the current token.
 func (s *Scanner) Span() Span { return Span{Pos: s.pos, End: s.end} }
 
+// SpaceSpan returns the location span of the whitespace that preceded the
+// current token, from the end of the previous token to the start of the
+// current one. If Next has reported io.EOF, the span covers the whitespace
+// following the last token. Unlike Space, SpaceSpan does not require
+// RetainSpace to be enabled.
+func (s *Scanner) SpaceSpan() Span { return Span{Pos: s.spos, End: s.pos} }
+
+// SpaceLocation returns the complete location of the whitespace that preceded
+// the current token, as reported by SpaceSpan. The difference between the
+// first and last lines of the result is the number of line breaks in the
+// whitespace, so a difference of two or more indicates a blank line.
+func (s *Scanner) SpaceLocation() Location {
+	return Location{
+		Span:  s.SpaceSpan(),
+		First: LineCol{Line: s.sline + 1, Column: s.scol},
+		Last:  LineCol{Line: s.pline + 1, Column: s.pcol},
+	}
+}
+
 // Location returns the complete location of the current token.
 func (s *Scanner) Location() Location {
 	return Location{
@@ -200,15 +565,126 @@ func (s *Scanner) Location() Location {
 	}
 }
 
+// Int64 decodes the current token as a 64-bit signed integer. It reports an
+// error if the token is not an Integer, or if its value is out of range.
+func (s *Scanner) Int64() (int64, error) { return decodeInt64(s.tok, s.Text()) }
+
+// Float64 decodes the current token as a 64-bit floating-point value. It
+// reports an error if the token is not an Integer, a Number, or a non-finite
+// number, or if its value is out of range.
+func (s *Scanner) Float64() (float64, error) { return decodeFloat64(s.tok, s.Text()) }
+
+// Bool decodes the current token as a Boolean. It reports an error if the
+// token is not True or False.
+func (s *Scanner) Bool() (bool, error) { return decodeBool(s.tok) }
+
+// Unquote decodes the current token as a string, and returns a new slice
+// containing its unescaped contents. It reports an error if the token is not
+// a String, or is not correctly escaped. If the scanner allows JSON5, the
+// additional quotation and escape sequences of JSON5 are also decoded.
+func (s *Scanner) Unquote() ([]byte, error) { return decodeUnquote(s.tok, s.Text(), s.json5) }
+
+func decodeInt64(tok Token, text []byte) (int64, error) {
+	if tok != Integer {
+		return 0, fmt.Errorf("cannot decode %v as an integer", tok)
+	}
+	return ParseInt(text, 10, 64)
+}
+
+func decodeFloat64(tok Token, text []byte) (float64, error) {
+	switch tok {
+	case NaN:
+		return math.NaN(), nil
+	case Infinity:
+		return math.Inf(1), nil
+	case NegInfinity:
+		return math.Inf(-1), nil
+	}
+	if tok != Integer && tok != Number {
+		return 0, fmt.Errorf("cannot decode %v as a number", tok)
+	}
+	return ParseFloat(text, 64)
+}
+
+func decodeBool(tok Token) (bool, error) {
+	if tok != True && tok != False {
+		return false, fmt.Errorf("cannot decode %v as a bool", tok)
+	}
+	return tok == True, nil
+}
+
+func decodeUnquote(tok Token, text []byte, json5 bool) ([]byte, error) {
+	if tok != String {
+		return nil, fmt.Errorf("cannot decode %v as a string", tok)
+	} else if len(text) != 0 && text[0] != '"' && text[0] != '\'' {
+		return bytes.Clone(text), nil // a JSON5 identifier
+	} else if json5 {
+		return unquoteJSON5(text)
+	}
+	return Unquote(text)
+}
+
+// skipPrefix discards the byte-order mark and leading junk at the start of
+// the input, as enabled by AllowBOM and SkipUntilValue.
+func (s *Scanner) skipPrefix() error {
+	if !s.bom && !s.junk {
+		return nil
+	}
+	start, first := s.end, LineCol{Line: s.eline + 1, Column: s.ecol}
+	if s.bom {
+		ch, err := s.rune()
+		if err != nil && err != io.EOF {
+			return s.fail(err)
+		} else if ch == '\uFEFF' {
+			s.put(ch)
+		} else if err == nil {
+			s.unrune()
+		}
+	}
+	for s.junk {
+		ch, err := s.rune()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return s.fail(err)
+		} else if ch == '{' || ch == '[' {
+			s.unrune()
+			break
+		}
+		s.put(ch)
+		if ch == '\n' {
+			s.eline++
+			s.ecol = 0
+		}
+	}
+	if s.end > start {
+		text := append([]byte(nil), s.buf.Bytes()...)
+		if s.direct {
+			text = s.src[start-s.base : s.end-s.base : s.end-s.base]
+		}
+		s.skip = &textAnchor{text: text, loc: Location{
+			Span:  Span{Pos: start, End: s.end},
+			First: first,
+			Last:  LineCol{Line: s.eline + 1, Column: s.ecol},
+		}}
+		s.skipNew = true
+	}
+	s.buf.Reset()
+	return nil
+}
+
 func (s *Scanner) scanString(open rune) error {
-	s.buf.WriteRune(open)
+	s.put(open)
 	var esc bool
 	for {
+		if !esc {
+			s.consume(plainRun(s.pending(), byte(open)))
+		}
 		ch, err := s.rune()
 		if err != nil {
 			return s.fail(err)
 		} else if ch == open && !esc {
-			s.buf.WriteRune(ch)
+			s.put(ch)
 			s.tok = String
 			return nil
 		}
@@ -216,38 +692,57 @@ func (s *Scanner) scanString(open rune) error {
 			// We are awaiting the completion of a \-escape.
 			switch ch {
 			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
-				s.buf.WriteByte(byte(ch))
+				s.put(ch)
 			case 'u':
-				s.buf.WriteByte(byte(ch))
-				if err := s.readHex4(); err != nil {
+				s.put(ch)
+				r, err := s.readHex4()
+				if err != nil {
 					return s.failf("invalid Unicode escape: %w", err)
+				} else if s.strict && utf16.IsSurrogate(r) {
+					if err := s.readLowSurrogate(r); err != nil {
+						return s.failf("invalid surrogate pair: %w", err)
+					}
 				}
 			default:
-				return s.failf("invalid %q after escape", ch)
+				if !s.json5 {
+					return s.failf("invalid %q after escape", ch)
+				} else if ok, err := s.escape5(ch); err != nil {
+					return s.failf("invalid escape: %w", err)
+				} else if !ok {
+					return s.failf("invalid %q after escape", ch)
+				}
 			}
 			esc = false
 		} else if ch < ' ' {
 			return s.failf("unescaped control %q", ch)
 		} else if ch > unicode.MaxRune {
 			return s.failf("invalid Unicode rune %q", ch)
+		} else if s.badRune(ch) {
+			return s.failf("invalid UTF-8 in string")
 		} else {
-			s.buf.WriteRune(ch)
+			s.put(ch)
 			esc = ch == '\\'
 		}
 	}
 }
 
 func (s *Scanner) scanNumber(start rune) error {
-	s.buf.WriteRune(start)
+	s.put(start)
 
 	if start == '-' {
+		if s.nonfin {
+			if next, err := s.peek(); err == nil && next == 'I' {
+				return s.scanNonFinite(-1) // already recorded
+			}
+		}
+
 		// If there is a leading sign, we need at least one digit.
 		// Otherwise, we already have one in start.
 		ch, err := s.require(isDigit, "digit")
 		if err != nil {
 			return err
 		}
-		s.buf.WriteRune(ch)
+		s.put(ch)
 	}
 
 	// Consume the remainder of an integer.
@@ -262,14 +757,18 @@ func (s *Scanner) scanNumber(start rune) error {
 
 	// Check for extra leading zeroes, which are disallowed by the JSON spec.
 	// That is: 0.12 is OK, 01.2 is not.
-	if hasExtraLeadingZeroes(s.buf.Bytes()) {
+	text := s.Text()
+	if s.direct {
+		text = text[:len(text)-s.last] // exclude the rune just read
+	}
+	if hasExtraLeadingZeroes(text) {
 		return s.failf("extra leading zeroes")
 	}
 
 	// If a decimal point follows, consume a fractional part.
 	var isFloat bool
 	if ch == '.' {
-		s.buf.WriteRune(ch)
+		s.put(ch)
 		var nr int
 		nr, ch, err = s.readWhile(isDigit)
 		if err != nil && err != io.EOF {
@@ -292,12 +791,12 @@ func (s *Scanner) scanNumber(start rune) error {
 		return nil
 	}
 
-	s.buf.WriteRune(ch)
+	s.put(ch)
 	ch, err = s.require(isExpStart, "sign or digit")
 	if err != nil {
 		return err
 	}
-	s.buf.WriteRune(ch)
+	s.put(ch)
 	nr, _, err := s.readWhile(isDigit)
 	if nr == 0 && (ch == '-' || ch == '+') {
 		// It's OK to have no digits if the previous rune was not a sign,
@@ -315,17 +814,17 @@ func (s *Scanner) scanNumber(start rune) error {
 }
 
 func (s *Scanner) scanComment(first rune) error {
-	s.buf.WriteRune(first)
+	s.put(first)
 	ch, err := s.rune()
 	if err != nil {
 		return err
 	}
 	switch ch {
 	case '/': // line comment to LF
-		s.buf.WriteRune(ch)
+		s.put(ch)
 		_, end, err := s.readWhile(isNotLF)
 		if err == nil {
-			s.buf.WriteRune(end)
+			s.put(end)
 			s.eline++
 			s.ecol = 0
 		} else if err != io.EOF {
@@ -335,20 +834,20 @@ func (s *Scanner) scanComment(first rune) error {
 		return nil
 
 	case '*': // block comment
-		s.buf.WriteRune(ch)
+		s.put(ch)
 		for {
 			_, end, err := s.readWhile(isNotStar)
 			if err != nil {
 				return err
 			}
-			s.buf.WriteRune(end) // end == '*'
+			s.put(end) // end == '*'
 
 			// Check whether we have "*/", which would end the comment.
 			next, err := s.rune()
 			if err != nil {
 				return err
 			}
-			s.buf.WriteRune(next)
+			s.put(next)
 			if next == '/' {
 				s.tok = BlockComment
 				return nil
@@ -367,7 +866,7 @@ func (s *Scanner) scanComment(first rune) error {
 }
 
 func (s *Scanner) scanName(first rune) error {
-	s.buf.WriteRune(first)
+	s.put(first)
 	_, _, err := s.readWhile(isNameRune)
 	if err == io.EOF {
 		return nil
@@ -379,18 +878,36 @@ func (s *Scanner) scanName(first rune) error {
 }
 
 func (s *Scanner) rune() (rune, error) {
+	if s.direct {
+		rest := s.src[s.end-s.base:]
+		if len(rest) == 0 || s.push && !s.final && !utf8.FullRune(rest) {
+			s.short = s.push && !s.final
+			s.last = 0
+			return 0, io.EOF
+		}
+		ch, nb := rune(rest[0]), 1
+		if ch >= utf8.RuneSelf {
+			ch, nb = utf8.DecodeRune(rest)
+		}
+		s.last = nb
+		s.end += nb
+		s.ecol += s.width(nb)
+		return ch, nil
+	}
 	ch, nb, err := s.r.ReadRune()
 	s.last = nb
 	s.end += nb
-	s.ecol += nb
+	s.ecol += s.width(nb)
 	return ch, err
 }
 
 func (s *Scanner) unrune() {
 	s.end -= s.last
-	s.ecol -= s.last
+	s.ecol -= s.width(s.last)
 	s.last = 0
-	s.r.UnreadRune()
+	if !s.direct {
+		s.r.UnreadRune()
+	}
 }
 
 // require reads a single rune matching f from the input, or returns an error
@@ -413,35 +930,74 @@ func (s *Scanner) require(f func(rune) bool, label string) (rune, error) {
 func (s *Scanner) readWhile(f func(rune) bool) (int, rune, error) {
 	var nr int
 	for {
+		run := asciiRun(s.pending(), f)
+		s.consume(run)
+		nr += len(run)
+
 		ch, err := s.rune()
 		if err != nil {
 			return nr, 0, err
 		} else if !f(ch) {
 			return nr, ch, nil
+		} else if s.badRune(ch) {
+			return nr, 0, s.failf("invalid UTF-8")
 		}
 		if ch == '\n' {
 			s.eline++
 			s.ecol = 0
 		}
-		s.buf.WriteRune(ch)
+		s.put(ch)
 		nr++
 	}
 }
 
-// readHex4 reads exactly 4 hexadecimal digits from the input.
-func (s *Scanner) readHex4() error {
+// readHex4 reads exactly 4 hexadecimal digits from the input, and returns
+// their value.
+func (s *Scanner) readHex4() (rune, error) {
+	var v rune
 	for i := 0; i < 4; i++ {
 		ch, err := s.rune()
 		if err != nil {
-			return err
+			return 0, err
 		} else if !isHexDigit(ch) {
-			return fmt.Errorf("not a hex digit: %q", ch)
+			return 0, fmt.Errorf("not a hex digit: %q", ch)
 		}
-		s.buf.WriteRune(ch)
+		s.put(ch)
+		v = v<<4 | hexValue(ch)
+	}
+	return v, nil
+}
+
+// readLowSurrogate reads the \u escape for the second half of a UTF-16
+// surrogate pair whose first half is hi.
+func (s *Scanner) readLowSurrogate(hi rune) error {
+	if hi >= 0xdc00 {
+		return fmt.Errorf("unpaired surrogate %U", hi)
+	}
+	for _, want := range `\u` {
+		ch, err := s.rune()
+		if err != nil {
+			return err
+		} else if ch != want {
+			return fmt.Errorf("unpaired surrogate %U", hi)
+		}
+		s.put(ch)
+	}
+	lo, err := s.readHex4()
+	if err != nil {
+		return err
+	} else if utf16.DecodeRune(hi, lo) == utf8.RuneError {
+		return fmt.Errorf("unpaired surrogate %U", hi)
 	}
 	return nil
 }
 
+// badRune reports whether ch, the last rune read, was decoded from invalid
+// UTF-8 that the scanner should reject.
+func (s *Scanner) badRune(ch rune) bool {
+	return s.strict && ch == utf8.RuneError && s.last == 1
+}
+
 type posError struct {
 	pos int
 	err error
@@ -477,6 +1033,17 @@ func isExpStart(ch rune) bool { return ch == '-' || ch == '+' || isDigit(ch) }
 func isDigit(ch rune) bool    { return '0' <= ch && ch <= '9' }
 func isNameRune(ch rune) bool { return ch >= 'a' && ch <= 'z' }
 
+func hexValue(ch rune) rune {
+	switch {
+	case ch <= '9':
+		return ch - '0'
+	case ch <= 'F':
+		return ch - 'A' + 10
+	default:
+		return ch - 'a' + 10
+	}
+}
+
 func isHexDigit(ch rune) bool {
 	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
 }
@@ -507,7 +1074,84 @@ func selfDelim(ch rune) (Token, bool) {
 	return Invalid, false
 }
 
-func (s *Scanner) copyOf(text []byte) []byte {
+// pending returns the input that is available without blocking and has not
+// yet been read. The slice is valid only until the next read.
+func (s *Scanner) pending() []byte {
+	if s.direct {
+		return s.src[s.end-s.base:]
+	}
+	buf, _ := s.r.Peek(s.r.Buffered())
+	return buf
+}
+
+// consume reads buf, a prefix of the pending input containing no newlines,
+// as part of the current token. Reading in bulk this way is much faster than
+// reading one rune at a time.
+func (s *Scanner) consume(buf []byte) {
+	if len(buf) == 0 {
+		return
+	}
+	if !s.direct {
+		s.buf.Write(buf)
+		s.r.Discard(len(buf))
+	}
+	s.end += len(buf)
+	if s.runeCols {
+		s.ecol += utf8.RuneCount(buf)
+	} else {
+		s.ecol += len(buf)
+	}
+	s.last = 0
+}
+
+// width reports the number of columns occupied by a rune of nb bytes.
+func (s *Scanner) width(nb int) int {
+	if s.runeCols && nb > 0 {
+		return 1
+	}
+	return nb
+}
+
+// plainRun returns the longest prefix of buf that can be copied verbatim into
+// a string token delimited by quote, namely valid UTF-8 with no quotes,
+// backslashes, or control characters.
+func plainRun(buf []byte, quote byte) []byte {
+	i := 0
+	for i < len(buf) {
+		if b := buf[i]; b < utf8.RuneSelf {
+			if b < ' ' || b == quote || b == '\\' {
+				break
+			}
+			i++
+		} else if r, n := utf8.DecodeRune(buf[i:]); r == utf8.RuneError && n <= 1 {
+			break // invalid or incomplete
+		} else {
+			i += n
+		}
+	}
+	return buf[:i]
+}
+
+// asciiRun returns the longest prefix of buf consisting of ASCII characters
+// other than newline that match f.
+func asciiRun(buf []byte, f func(rune) bool) []byte {
+	i := 0
+	for i < len(buf) && buf[i] < utf8.RuneSelf && buf[i] != '\n' && f(rune(buf[i])) {
+		i++
+	}
+	return buf[:i]
+}
+
+// put adds ch to the text of the current token. The text of a direct scanner
+// is a slice of its input, so there is nothing to do.
+func (s *Scanner) put(ch rune) {
+	if !s.direct {
+		s.buf.WriteRune(ch)
+	}
+}
+
+// copyOf returns a copy of text allocated from the blocks of *pool.
+func (s *Scanner) copyOf(pool *[][]byte, text []byte) []byte {
 	const minBlockSlop = 4
 	const smallSizeFraction = 16
 	const bufBlockBytes = 16384
@@ -519,25 +1163,27 @@ func (s *Scanner) copyOf(text []byte) []byte {
 	}
 
 	// Look for a block with space enough to hold a copy of text.
+	tbuf := *pool
 	i := 0
-	for i < len(s.tbuf) {
-		if n := len(s.tbuf[i]) + len(text); n < cap(s.tbuf[i]) {
+	for i < len(tbuf) {
+		if n := len(tbuf[i]) + len(text); n < cap(tbuf[i]) {
 			// There is room in this block.
 			break
-		} else if cap(s.tbuf[i])-len(text) < minBlockSlop {
+		} else if cap(tbuf[i])-len(text) < minBlockSlop {
 			// There is no room in this block, but it is nearly-enough full.
 			// Allocate a fresh block at this location and release the old one.
 			// The old block will be retained until all its tokens are released.
-			s.tbuf[i] = make([]byte, 0, bufBlockBytes)
+			tbuf[i] = make([]byte, 0, bufBlockBytes)
 			break
 		}
 		i++
 	}
-	if i == len(s.tbuf) {
+	if i == len(tbuf) {
 		// No block had room; add a new empty one to the arena.
-		s.tbuf = append(s.tbuf, make([]byte, 0, bufBlockBytes))
+		tbuf = append(tbuf, make([]byte, 0, bufBlockBytes))
 	}
-	p := len(s.tbuf[i])
-	s.tbuf[i] = append(s.tbuf[i], text...)
-	return s.tbuf[i][p : p+len(text)]
+	*pool = tbuf
+	p := len(tbuf[i])
+	tbuf[i] = append(tbuf[i], text...)
+	return tbuf[i][p : p+len(text)]
 }
@@ -3,9 +3,17 @@
 package jtree_test
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 
 	"github.com/creachadair/jtree"
 	"github.com/google/go-cmp/cmp"
@@ -133,15 +141,44 @@ func TestScanner_decodeAs(t *testing.T) {
 	}
 
 	t.Run("Integer", func(t *testing.T) {
-		mustScan(t, `-15`, jtree.Integer)
+		s := mustScan(t, `-15`, jtree.Integer)
+		if z, err := s.Int64(); err != nil || z != -15 {
+			t.Errorf("Int64: got %v, %v; want -15, nil", z, err)
+		}
+		if f, err := s.Float64(); err != nil || f != -15 {
+			t.Errorf("Float64: got %v, %v; want -15, nil", f, err)
+		}
+		if _, err := s.Bool(); err == nil {
+			t.Error("Bool: got nil, want error")
+		}
+		if _, err := s.Unquote(); err == nil {
+			t.Error("Unquote: got nil, want error")
+		}
+
+		s = mustScan(t, `99999999999999999999`, jtree.Integer)
+		if z, err := s.Int64(); err == nil {
+			t.Errorf("Int64: got %v, want error", z)
+		}
 	})
 	t.Run("Number", func(t *testing.T) {
-		mustScan(t, `3.25e-5`, jtree.Number)
+		s := mustScan(t, `3.25e-5`, jtree.Number)
+		if f, err := s.Float64(); err != nil || f != 3.25e-5 {
+			t.Errorf("Float64: got %v, %v; want 3.25e-5, nil", f, err)
+		}
+		if z, err := s.Int64(); err == nil {
+			t.Errorf("Int64: got %v, want error", z)
+		}
 	})
 	t.Run("Constants", func(t *testing.T) {
-		mustScan(t, `true`, jtree.True)
-		mustScan(t, `false`, jtree.False)
-		mustScan(t, `null`, jtree.Null)
+		if b, err := mustScan(t, `true`, jtree.True).Bool(); err != nil || !b {
+			t.Errorf("Bool: got %v, %v; want true, nil", b, err)
+		}
+		if b, err := mustScan(t, `false`, jtree.False).Bool(); err != nil || b {
+			t.Errorf("Bool: got %v, %v; want false, nil", b, err)
+		}
+		if b, err := mustScan(t, `null`, jtree.Null).Bool(); err == nil {
+			t.Errorf("Bool: got %v, want error", b)
+		}
 	})
 	t.Run("String", func(t *testing.T) {
 		const wantText = `"a\tb\u0020c\n"` // as written, without quotes
@@ -156,6 +193,14 @@ func TestScanner_decodeAs(t *testing.T) {
 		} else if got := string(u); got != wantDec {
 			t.Errorf("Unquote: got %#q, want %#q", got, wantDec)
 		}
+		if u, err := s.Unquote(); err != nil {
+			t.Errorf("Scanner.Unquote failed: %v", err)
+		} else if got := string(u); got != wantDec {
+			t.Errorf("Scanner.Unquote: got %#q, want %#q", got, wantDec)
+		}
+		if f, err := s.Float64(); err == nil {
+			t.Errorf("Float64: got %v, want error", f)
+		}
 	})
 }
 
@@ -180,6 +225,227 @@ func TestQuote(t *testing.T) {
 			t.Errorf("Input: %#q\nGot:  %#q\nWant: %#q", test.input, got, test.want)
 		}
 	}
+
+	t.Run("Options", func(t *testing.T) {
+		const input = "<a href=\"x&y\">caf\u00e9 \U0001f600</a>\u2028"
+		tests := []struct {
+			opts *jtree.QuoteOptions
+			want string
+		}{
+			{nil, "\"<a href=\\\"x&y\\\">caf\u00e9 \U0001f600</a>\\u2028\""},
+			{&jtree.QuoteOptions{EscapeHTML: true},
+				"\"\\u003ca href=\\\"x\\u0026y\\\"\\u003ecaf\u00e9 \U0001f600\\u003c/a\\u003e\\u2028\""},
+			{&jtree.QuoteOptions{ASCIIOnly: true},
+				`"<a href=\"x&y\">caf\u00e9 \ud83d\ude00</a>\u2028"`},
+			{&jtree.QuoteOptions{EscapeHTML: true, ASCIIOnly: true},
+				`"\u003ca href=\"x\u0026y\"\u003ecaf\u00e9 \ud83d\ude00\u003c/a\u003e\u2028"`},
+		}
+		for _, test := range tests {
+			got := jtree.QuoteWith(input, test.opts)
+			if got != test.want {
+				t.Errorf("QuoteWith(%+v):\nGot:  %#q\nWant: %#q", test.opts, got, test.want)
+			}
+			if dec, err := jtree.UnquoteString(got); err != nil {
+				t.Errorf("Unquote %#q: unexpected error: %v", got, err)
+			} else if string(dec) != input {
+				t.Errorf("Unquote %#q: got %#q, want %#q", got, dec, input)
+			}
+		}
+
+		// With EscapeHTML, the output should match encoding/json.
+		want, err := json.Marshal(input)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if got := jtree.QuoteWith(input, &jtree.QuoteOptions{EscapeHTML: true}); got != string(want) {
+			t.Errorf("QuoteWith EscapeHTML: got %#q, want %#q", got, want)
+		}
+	})
+}
+
+func TestScannerPeek(t *testing.T) {
+	s := jtree.NewScanner(strings.NewReader(`[1, "two"] bad`))
+	var got []string
+	add := func(tag string) { got = append(got, fmt.Sprintf("%s %v %s %s", tag, s.Token(), s.Text(), s.Span())) }
+
+	if tok, err := s.Peek(); err != nil || tok != jtree.LSquare {
+		t.Fatalf("Peek: got %v, %v; want %v, nil", tok, err, jtree.LSquare)
+	}
+	add("peek")
+	for s.Next() == nil {
+		add("next")
+		if s.Token() == jtree.Integer {
+			s.Peek() // repeated peeks do not advance
+			s.Peek()
+			add("peek")
+		}
+		if s.Token() == jtree.String {
+			s.UnreadToken()
+			s.UnreadToken() // no further effect
+			if err := s.Next(); err != nil {
+				t.Fatalf("Next after UnreadToken: unexpected error: %v", err)
+			}
+			add("again")
+		}
+	}
+	if diff := cmp.Diff([]string{
+		`peek "[" [ 0..1`,
+		`next "[" [ 0..1`,
+		`next integer 1 1..2`,
+		`peek "," , 2..3`,
+		`next "," , 2..3`,
+		`next string "two" 4..9`,
+		`again string "two" 4..9`,
+		`next "]" ] 9..10`,
+	}, got); diff != "" {
+		t.Errorf("Tokens (-want, +got):\n%s", diff)
+	}
+
+	// An error is reported again after the token is pushed back.
+	err := s.Err()
+	if err == nil || err == io.EOF {
+		t.Fatalf("Next: got %v, want a scanning error", err)
+	}
+	s.UnreadToken()
+	if got := s.Next(); got != err {
+		t.Errorf("Next after UnreadToken: got %v, want %v", got, err)
+	}
+
+	s = jtree.NewScanner(strings.NewReader(` `))
+	if tok, err := s.Peek(); tok != jtree.Invalid || err != io.EOF {
+		t.Errorf("Peek at end: got %v, %v; want Invalid, EOF", tok, err)
+	}
+	if err := s.Next(); err != io.EOF {
+		t.Errorf("Next at end: got %v, want EOF", err)
+	}
+}
+
+func TestPushScanner(t *testing.T) {
+	const input = "\ufeff// lead\n{\"caf\u00e9\": [1, -2.5e3, true, null], /* \U0001f600 */ \"k\": \"v\\\"w\"}\n[false]  "
+
+	type tokLoc struct {
+		Tok  jtree.Token
+		Text string
+		Loc  string
+	}
+	scanAll := func(s *jtree.Scanner) []tokLoc {
+		var out []tokLoc
+		for s.Next() == nil {
+			out = append(out, tokLoc{s.Token(), string(s.Copy()), s.Location().String()})
+		}
+		if s.Err() != io.EOF {
+			t.Errorf("Next: got %v, want EOF", s.Err())
+		}
+		return out
+	}
+	base := jtree.NewScannerBytes([]byte(input))
+	base.AllowBOM(true)
+	base.AllowComments(true)
+	want := scanAll(base)
+
+	for _, size := range []int{1, 2, 3, 7, len(input)} {
+		s := jtree.NewPushScanner()
+		s.AllowBOM(true)
+		s.AllowComments(true)
+		var got []tokLoc
+		rest := []byte(input)
+		for {
+			err := s.Next()
+			if err == jtree.ErrNeedMoreInput {
+				if len(rest) == 0 {
+					s.Finish()
+					continue
+				}
+				n := min(size, len(rest))
+				if _, err := s.Write(rest[:n]); err != nil {
+					t.Fatalf("Write: unexpected error: %v", err)
+				}
+				rest = rest[n:]
+				continue
+			} else if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Chunk size %d: Next: unexpected error: %v", size, err)
+			}
+			got = append(got, tokLoc{s.Token(), string(s.Copy()), s.Location().String()})
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Chunk size %d: tokens (-want, +got):\n%s", size, diff)
+		}
+		if _, err := s.Write([]byte("x")); err == nil {
+			t.Error("Write after Finish: got nil, want error")
+		}
+	}
+
+	t.Run("Incomplete", func(t *testing.T) {
+		s := jtree.NewPushScanner()
+		s.Write([]byte(`["abc`))
+		if err := s.Next(); err != nil || s.Token() != jtree.LSquare {
+			t.Fatalf("Next: got %v, %v; want %v, nil", s.Token(), err, jtree.LSquare)
+		}
+		if tok, err := s.Peek(); err != jtree.ErrNeedMoreInput {
+			t.Errorf("Peek: got %v, %v; want %v", tok, err, jtree.ErrNeedMoreInput)
+		}
+		s.Write([]byte(`" 12`))
+		if err := s.Next(); err != nil || string(s.Text()) != `"abc"` {
+			t.Errorf("Next: got %#q, %v; want %#q, nil", s.Text(), err, `"abc"`)
+		}
+		if err := s.Next(); err != jtree.ErrNeedMoreInput {
+			t.Errorf("Next: got %v, want %v", err, jtree.ErrNeedMoreInput)
+		}
+		s.Write([]byte(`3 nul`))
+		if err := s.Next(); err != nil || string(s.Text()) != "123" {
+			t.Errorf("Next: got %#q, %v; want 123, nil", s.Text(), err)
+		}
+		s.Finish()
+		if err := s.Next(); err == nil || err == io.EOF || err == jtree.ErrNeedMoreInput {
+			t.Errorf("Next after Finish: got %v, want a scanning error", err)
+		}
+	})
+
+	if _, err := jtree.NewScannerBytes(nil).Write([]byte("x")); err == nil {
+		t.Error("Write to a scanner that is not push-fed: got nil, want error")
+	}
+}
+
+func TestSpaceLocation(t *testing.T) {
+	const input = "\ufeff{\"a\": 1,\n\n  // note\n  \"b\":2}  \n"
+	for _, retain := range []bool{false, true} {
+		s := jtree.NewScanner(strings.NewReader(input))
+		s.AllowBOM(true)
+		s.AllowComments(true)
+		s.RetainSpace(retain)
+		var got []string
+		for {
+			err := s.Next()
+			if err != nil && err != io.EOF {
+				t.Fatalf("Next: unexpected error: %v", err)
+			}
+			sp := s.SpaceSpan()
+			if retain && sp.End-sp.Pos != len(s.Space()) {
+				t.Errorf("Space %q does not match span %v", s.Space(), sp)
+			}
+			got = append(got, fmt.Sprintf("%v %s", s.Token(), s.SpaceLocation()))
+			if err == io.EOF {
+				break
+			}
+		}
+		if diff := cmp.Diff([]string{
+			`"{" 1:3-3`,
+			`string 1:4-4`,
+			`":" 1:7-7`,
+			`integer 1:8-9`,
+			`"," 1:10-10`,
+			`line comment 1:11-3:2`,
+			`string 4:0-2`, // the line comment includes its newline
+			`":" 4:5-5`,
+			`integer 4:6-6`,
+			`"}" 4:7-7`,
+			`invalid token 4:8-5:0`,
+		}, got); diff != "" {
+			t.Errorf("RetainSpace %v: spaces (-want, +got):\n%s", retain, diff)
+		}
+	}
 }
 
 func TestScannerLoc(t *testing.T) {
@@ -219,27 +485,90 @@ func TestScannerLoc(t *testing.T) {
 	}
 }
 
+func TestScannerAt(t *testing.T) {
+	const input = "[1, 2]\n{\"a\": true}\n\"xyz\""
+	type tokSpan struct {
+		Tok  jtree.Token
+		Span string
+		Loc  string
+	}
+	tests := []struct {
+		offset, size int64
+		start        jtree.LineCol
+		want         []tokSpan
+	}{
+		{0, 3, jtree.LineCol{}, []tokSpan{
+			{jtree.LSquare, "0..1", "1:0-1"}, {jtree.Integer, "1..2", "1:1-2"}, {jtree.Comma, "2..3", "1:2-3"},
+		}},
+		{7, 11, jtree.LineCol{Line: 2}, []tokSpan{
+			{jtree.LBrace, "7..8", "2:0-1"}, {jtree.String, "8..11", "2:1-4"}, {jtree.Colon, "11..12", "2:4-5"},
+			{jtree.True, "13..17", "2:6-10"}, {jtree.RBrace, "17..18", "2:10-11"},
+		}},
+		{18, -1, jtree.LineCol{}, []tokSpan{
+			{jtree.String, "19..24", "2:0-5"},
+		}},
+	}
+	for _, tc := range tests {
+		s := jtree.NewScannerAt(strings.NewReader(input), tc.offset, tc.size)
+		if tc.start.Line != 0 {
+			s.SetLineCol(tc.start)
+		}
+		var got []tokSpan
+		for s.Next() == nil {
+			got = append(got, tokSpan{s.Token(), s.Span().String(), s.Location().String()})
+		}
+		if s.Err() != io.EOF {
+			t.Errorf("Next failed: %v", s.Err())
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("Window %d+%d: (-want, +got)\n%s", tc.offset, tc.size, diff)
+		}
+	}
+
+	// A stream over a window parses only the values in that window.
+	var h testHandler
+	if err := jtree.NewStreamAt(strings.NewReader(input), 7, 11).Parse(&h); err != nil {
+		t.Errorf("Parse: unexpected error: %v", err)
+	}
+	if diff := diffStrings(`
+BeginObject
+BeginMember <"a">
+Value true <true>
+EndMember "}"
+EndObject
+.`, h.output()); diff != "" {
+		t.Errorf("Parse (-want, +got):\n%s", diff)
+	}
+}
+
 func TestUnquote(t *testing.T) {
 	tests := []struct {
 		input string
 		want  string
 		fail  bool
 	}{
-		{``, ``, true},                        // missing quotes
-		{`"missing quote`, ``, true},          // missing quotes
-		{`missing quote"`, ``, true},          // missing quotes
-		{`""`, ``, false},                     // ok
-		{`"ok go"`, "ok go", false},           // ok
-		{`"abc\ndef"`, "abc\ndef", false},     // C escapes
-		{`"\tabc\n"`, "\tabc\n", false},       // C escapes
-		{`"\b\f\n\r\t"`, "\b\f\n\r\t", false}, // C escapes
-		{`"a \u0026 b"`, "a & b", false},      // short Unicode escape
-		{`"\u"`, ``, true},                    // incomplete Unicode escape
-		{`"\u00"`, ``, true},                  // incomplete Unicode escape
-		{`"\u00x9"`, "\ufffd", false},         // invalid Unicode escape
-		{`"\u019 "`, "\ufffd", false},         // invalid Unicode escape
-		{`"a\"b"`, `a"b`, false},              // ok
-		{`"a\\b\\cd"`, `a\b\cd`, false},       // ok
+		{``, ``, true},                                // missing quotes
+		{`"missing quote`, ``, true},                  // missing quotes
+		{`missing quote"`, ``, true},                  // missing quotes
+		{`""`, ``, false},                             // ok
+		{`"ok go"`, "ok go", false},                   // ok
+		{`"abc\ndef"`, "abc\ndef", false},             // C escapes
+		{`"\tabc\n"`, "\tabc\n", false},               // C escapes
+		{`"\b\f\n\r\t"`, "\b\f\n\r\t", false},         // C escapes
+		{`"a \u0026 b"`, "a & b", false},              // short Unicode escape
+		{`"\u"`, ``, true},                            // incomplete Unicode escape
+		{`"\u00"`, ``, true},                          // incomplete Unicode escape
+		{`"\u00x9"`, "\ufffd", false},                 // invalid Unicode escape
+		{`"\u019 "`, "\ufffd", false},                 // invalid Unicode escape
+		{`"a\"b"`, `a"b`, false},                      // ok
+		{`"a\\b\\cd"`, `a\b\cd`, false},               // ok
+		{`"\ud83d\ude00!"`, "\U0001F600!", false},     // surrogate pair
+		{`"\ud83d!"`, "\ufffd!", false},               // unpaired high surrogate
+		{`"\ude00\ud83d"`, "\ufffd\ufffd", false},     // reversed pair
+		{`"\ud83d\u0041"`, "\ufffdA", false},          // high surrogate, not a pair
+		{`'single'`, ``, true},                        // JSON5 single quotes
+		{`"\x41\v\0"`, "\ufffd41\ufffd\ufffd", false}, // JSON5 escapes
+		{`"\q\1"`, "\ufffd\ufffd", false},             // invalid escapes
 	}
 
 	for _, test := range tests {
@@ -257,4 +586,606 @@ func TestUnquote(t *testing.T) {
 			t.Errorf("Unquote(%#q): got %#q, want %#q", test.input, cmp, test.want)
 		}
 	}
+
+	t.Run("JSON5", func(t *testing.T) {
+		// A scanner that allows JSON5 also decodes its quotes and escapes.
+		tests := []struct {
+			input, want string
+		}{
+			{`'a\'b"c'`, `a'b"c`},      // single quotes
+			{`"\x41\v\0"`, "A\v\x00"},  // escapes
+			{"'a\\\nb\\\r\nc'", "abc"}, // line continuations
+			{`"\q"`, "q"},              // identity escape
+			{`ident`, `ident`},         // identifier
+		}
+		for _, test := range tests {
+			s := jtree.NewScanner(strings.NewReader(test.input))
+			s.AllowJSON5(true)
+			if err := s.Next(); err != nil {
+				t.Fatalf("Next %#q: unexpected error: %v", test.input, err)
+			}
+			if got, err := s.Unquote(); err != nil {
+				t.Errorf("Unquote(%#q): unexpected error: %v", test.input, err)
+			} else if string(got) != test.want {
+				t.Errorf("Unquote(%#q): got %#q, want %#q", test.input, got, test.want)
+			}
+		}
+	})
+}
+
+func TestUnquoteSurrogates(t *testing.T) {
+	// Cases from the string tests of JSONTestSuite (y_ and i_ files).
+	tests := []struct {
+		input  string
+		want   string
+		strict bool // whether UnquoteStrict accepts the input
+	}{
+		{`"\uD801\udc37"`, "\U00010437", true},                       // y_string_accepted_surrogate_pair
+		{`"\ud83d\ude39\ud83d\udc8d"`, "\U0001F639\U0001F48D", true}, // y_string_accepted_surrogate_pairs
+		{`"\uDBFF\uDFFF"`, "\U0010FFFF", true},                       // y_string_last_surrogates_1_and_2
+		{`"\uD834\uDd1e"`, "\U0001D11E", true},                       // y_string_surrogates_U+1D11E_MUSICAL_SYMBOL_G_CLEF
+		{`"\uDADA"`, "\ufffd", false},                                // i_string_1st_surrogate_but_2nd_missing
+		{`"\uD888\u1234"`, "\ufffd\u1234", false},                    // i_string_1st_valid_surrogate_2nd_invalid
+		{`"\uD800\n"`, "\ufffd\n", false},                            // i_string_incomplete_surrogate_and_escape_valid
+		{`"\uDd1ea"`, "\ufffda", false},                              // i_string_incomplete_surrogate_pair
+		{`"\uD800\uD800\n"`, "\ufffd\ufffd\n", false},                // i_string_incomplete_surrogates_escape_valid
+		{`"\ud800"`, "\ufffd", false},                                // i_string_invalid_lonely_surrogate
+		{`"\ud800abc"`, "\ufffdabc", false},                          // i_string_invalid_surrogate
+		{`"\uDd1e\uD834"`, "\ufffd\ufffd", false},                    // i_string_inverted_surrogates_U+1D11E
+	}
+	for _, test := range tests {
+		got, err := jtree.Unquote([]byte(test.input))
+		if err != nil {
+			t.Errorf("Unquote(%#q): unexpected error: %v", test.input, err)
+		} else if string(got) != test.want {
+			t.Errorf("Unquote(%#q): got %#q, want %#q", test.input, got, test.want)
+		}
+
+		got, err = jtree.UnquoteStrict([]byte(test.input))
+		if test.strict && err != nil {
+			t.Errorf("UnquoteStrict(%#q): unexpected error: %v", test.input, err)
+		} else if test.strict && string(got) != test.want {
+			t.Errorf("UnquoteStrict(%#q): got %#q, want %#q", test.input, got, test.want)
+		} else if !test.strict && err == nil {
+			t.Errorf("UnquoteStrict(%#q): got %#q, want error", test.input, got)
+		}
+	}
+
+	// Cases from the n_ files, which are errors in both modes.
+	for _, input := range []string{
+		`"\uD800\u1x"`, // n_string_1_surrogate_then_escape_u1x
+		`"\uD800\u"`,   // n_string_1_surrogate_then_escape_u
+	} {
+		if got, err := jtree.Unquote([]byte(input)); err == nil {
+			t.Errorf("Unquote(%#q): got %#q, want error", input, got)
+		}
+		if got, err := jtree.UnquoteStrict([]byte(input)); err == nil {
+			t.Errorf("UnquoteStrict(%#q): got %#q, want error", input, got)
+		}
+	}
+
+	// Strict mode also rejects other invalid escapes, including those JSON5
+	// allows, and strings that are not enclosed in double quotes.
+	for _, input := range []string{
+		`"\u00x9"`, `"\x4g"`, `"\1"`, `"\q"`, `"\x41"`, `"\v"`, `"\0"`,
+		"\"a\\\nb\"", "\"a\\\r\nb\"", `'abc'`, `'a"`,
+		`"\uD800\uD800\x"`, // n_string_incomplete_surrogate_escape_invalid
+	} {
+		if got, err := jtree.UnquoteStrict([]byte(input)); err == nil {
+			t.Errorf("UnquoteStrict(%#q): got %#q, want error", input, got)
+		}
+	}
+}
+
+func TestAppendQuote(t *testing.T) {
+	inputs := []string{"", "plain", "a\tb \"c\" \\d", "\x01<\u2028>", "caf\u00e9 \U0001f600"}
+	buf := make([]byte, 0, 256)
+	for _, input := range inputs {
+		quoted := jtree.AppendQuote(append(buf[:0], "prefix:"...), input)
+		if got, want := string(quoted), "prefix:"+jtree.Quote(input); got != want {
+			t.Errorf("AppendQuote(%#q): got %#q, want %#q", input, got, want)
+		}
+		html := &jtree.QuoteOptions{EscapeHTML: true}
+		if got, want := string(jtree.AppendQuoteWith(nil, input, html)), jtree.QuoteWith(input, html); got != want {
+			t.Errorf("AppendQuoteWith(%#q): got %#q, want %#q", input, got, want)
+		}
+
+		text := quoted[len("prefix:"):]
+		dec, err := jtree.AppendUnquote([]byte("prefix:"), text)
+		if err != nil {
+			t.Errorf("AppendUnquote(%#q): unexpected error: %v", text, err)
+		} else if got, want := string(dec), "prefix:"+input; got != want {
+			t.Errorf("AppendUnquote(%#q): got %#q, want %#q", text, got, want)
+		}
+	}
+
+	for _, bad := range []string{`"abc`, `"\u12"`, `'x"`} {
+		got, err := jtree.AppendUnquote([]byte("ok"), []byte(bad))
+		if err == nil {
+			t.Errorf("AppendUnquote(%#q): got %#q, want error", bad, got)
+		} else if string(got) != "ok" {
+			t.Errorf("AppendUnquote(%#q): got %#q, want the original buffer", bad, got)
+		}
+	}
+
+	// With enough capacity, neither function should allocate.
+	in := []byte(`"a \"quoted\" string\n with \u00e9scapes"`)
+	if n := testing.AllocsPerRun(100, func() {
+		buf, _ = jtree.AppendUnquote(buf[:0], in)
+		buf = jtree.AppendQuote(buf[:0], "a \"quoted\" string\n")
+	}); n != 0 {
+		t.Errorf("AppendQuote/AppendUnquote: got %v allocations, want 0", n)
+	}
+}
+
+func TestVerbatim(t *testing.T) {
+	jwcc, err := os.ReadFile("testdata/input.jwcc")
+	if err != nil {
+		t.Fatalf("Read input: %v", err)
+	}
+	tests := []string{
+		"",
+		"  \n\t ",
+		"true",
+		" { } ",
+		"[1,2 , 3]\n",
+		"// comment at EOF",
+		"/* a */ \"b\" // c\n\n  ",
+		"{\r\n  \"a\": [\"\\u0001\\n\", -0.5e+3]\r\n}",
+		string(jwcc),
+	}
+	for _, input := range tests {
+		var buf bytes.Buffer
+		if err := jtree.Verbatim(&buf, strings.NewReader(input)); err != nil {
+			t.Errorf("Verbatim(%#q): unexpected error: %v", input, err)
+		} else if diff := cmp.Diff(input, buf.String()); diff != "" {
+			t.Errorf("Verbatim(%#q): (-want, +got)\n%s", input, diff)
+		}
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := jtree.Verbatim(&buf, strings.NewReader(`[1, 2 @ 3]`)); err == nil {
+			t.Error("Verbatim: got nil, want error")
+		}
+		if got, want := buf.String(), "[1, 2 "; got != want {
+			t.Errorf("Verbatim output: got %#q, want %#q", got, want)
+		}
+	})
+}
+
+func TestScannerBytes(t *testing.T) {
+	tests := []string{
+		"",
+		"  \n\t ",
+		`{"a": [1, -2.5e+3, true, false, null], "bé": "x\n\"y\""}`,
+		"// line\n/* block\n */ [0, {}]\n\"☃\" 1e5",
+		`[1, 2`,
+		`01`,
+		`-`,
+		`"abc`,
+		`tru`,
+		`"\x"`,
+		"1.5x",
+	}
+	type tok struct {
+		Tok  jtree.Token
+		Text string
+		Loc  jtree.Location
+	}
+	scan := func(s *jtree.Scanner) (out []tok, err error) {
+		s.AllowComments(true)
+		for s.Next() == nil {
+			out = append(out, tok{s.Token(), string(s.Copy()), s.Location()})
+		}
+		return out, s.Err()
+	}
+	for _, input := range tests {
+		want, werr := scan(jtree.NewScanner(strings.NewReader(input)))
+		got, gerr := scan(jtree.NewScannerBytes([]byte(input)))
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Input: %#q\nTokens (-reader, +bytes):\n%s", input, diff)
+		}
+		if fmt.Sprint(werr) != fmt.Sprint(gerr) {
+			t.Errorf("Input: %#q\nError: got %v, want %v", input, gerr, werr)
+		}
+	}
+
+	// The text of a byte scanner is a slice of its input.
+	input := []byte(`["abc"]`)
+	s := jtree.NewScannerBytes(input)
+	s.Next()
+	s.Next()
+	text := s.Copy()
+	input[2] = 'X'
+	if got := string(text); got != `"Xbc"` {
+		t.Errorf("Copy: got %#q, want it to share the input", got)
+	}
+}
+
+func TestScannerReuseStorage(t *testing.T) {
+	next := func(s *jtree.Scanner) {
+		t.Helper()
+		if err := s.Next(); err != nil {
+			t.Fatalf("Next: unexpected error: %v", err)
+		}
+	}
+	const input = `"abc" "def" "ghi"`
+
+	// With ReuseStorage, Release recycles the storage returned by Bytes, but
+	// does not affect the results of Copy.
+	s := jtree.NewScanner(strings.NewReader(input))
+	s.ReuseStorage(true)
+	next(s)
+	b1, c1 := s.Bytes(), s.Copy()
+	next(s)
+	b2 := s.Bytes()
+	if got := string(b1) + string(b2); got != `"abc""def"` {
+		t.Errorf("Bytes: got %#q, want both tokens", got)
+	}
+	s.Release()
+	next(s)
+	b3 := s.Bytes()
+	if string(b3) != `"ghi"` {
+		t.Errorf("Bytes: got %#q, want %#q", b3, `"ghi"`)
+	}
+	if &b1[0] != &b3[0] {
+		t.Error("Bytes: storage was not reused after Release")
+	}
+	if string(c1) != `"abc"` {
+		t.Errorf("Copy: got %#q, want %#q", c1, `"abc"`)
+	}
+
+	// Without ReuseStorage, Bytes is the same as Copy.
+	s = jtree.NewScanner(strings.NewReader(input))
+	next(s)
+	b1 = s.Bytes()
+	s.Release()
+	next(s)
+	if b2 := s.Bytes(); &b1[0] == &b2[0] || string(b1) != `"abc"` {
+		t.Errorf("Bytes: got %#q, want an independent copy", b1)
+	}
+
+	// The text of a byte scanner is a slice of its input.
+	in := []byte(input)
+	s = jtree.NewScannerBytes(in)
+	s.ReuseStorage(true)
+	next(s)
+	if b := s.Bytes(); &b[0] != &in[0] {
+		t.Error("Bytes: want a slice of the input")
+	}
+}
+
+func TestScannerChunks(t *testing.T) {
+	// Use a small buffer so that tokens span buffer boundaries, including in
+	// the middle of multi-byte runes and escape sequences.
+	input := `{"name": "a somewhat longer string with ☃ and é \"quoted\" é text",
+  "n": [1234567890123, -0.000123456789e+123, 0],
+  "s": "` + strings.Repeat("ü", 40) + `"}`
+	for _, pad := range []int{0, 1, 2, 3, 5, 8} {
+		in := strings.Repeat(" ", pad) + input
+		var want, got []string
+		s := jtree.NewScannerBytes([]byte(in))
+		for s.Next() == nil {
+			want = append(want, fmt.Sprintf("%v %s %v", s.Token(), s.Text(), s.Location()))
+		}
+		r := jtree.NewScanner(bufio.NewReaderSize(strings.NewReader(in), 16))
+		for r.Next() == nil {
+			got = append(got, fmt.Sprintf("%v %s %v", r.Token(), r.Text(), r.Location()))
+		}
+		if r.Err() != io.EOF {
+			t.Errorf("Pad %d: Next failed: %v", pad, r.Err())
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Pad %d: tokens (-want, +got):\n%s", pad, diff)
+		}
+	}
+}
+
+func TestScannerValidateUTF8(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`"abc ☃"`, true},
+		{`"😀"`, true},
+		{`"\ud83d\ude00"`, true},
+		{`"\ufffd"`, true},
+		{"\"\xef\xbf\xbd\"", true}, // an encoded replacement rune is valid
+		{"\"a\xffb\"", false},
+		{"\"\xc3\"", false},
+		{"\"\xed\xa0\x80\"", false}, // an encoded surrogate
+		{`"\ud83d"`, false},
+		{`"\ude00"`, false},
+		{`"\ud83dA"`, false},
+		{`"\ud83dx"`, false},
+		{`"\ud83d\ud83d"`, false},
+		{"// ok ☃\n1", true},
+		{"// bad \xff\n1", false},
+		{"/* bad \xff */ 1", false},
+	}
+	for _, test := range tests {
+		for _, strict := range []bool{false, true} {
+			s := jtree.NewScanner(strings.NewReader(test.input))
+			s.AllowComments(true)
+			s.ValidateUTF8(strict)
+			for s.Next() == nil {
+			}
+			if want, ok := test.ok || !strict, s.Err() == io.EOF; ok != want {
+				t.Errorf("Input %#q (strict=%v): got error %v, want ok=%v", test.input, strict, s.Err(), want)
+			}
+		}
+	}
+}
+
+func TestScannerJSON5(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // tokens and text, or "" for an error
+	}{
+		{`'single' "double"`, `string 'single' | string "double"`},
+		{`'a "b" \'c\''`, `string 'a "b" \'c\''`},
+		{`"\x41\v\0\q\'"`, `string "\x41\v\0\q\'"`},
+		{"'line\\\ncontinued' 1", "string 'line\\\ncontinued' | integer 1"},
+		{"'crlf\\\r\ncontinued'", "string 'crlf\\\r\ncontinued'"},
+		{`0x1F -0XAB +5 .5 5. -.5e3 5.e-1 0`, `integer 0x1F | integer -0XAB | integer +5 | number .5 | number 5. | number -.5e3 | number 5.e-1 | integer 0`},
+		{`Infinity -Infinity +NaN NaN`, `Infinity Infinity | -Infinity -Infinity | NaN +NaN | NaN NaN`},
+		{`true false null`, `true true | false false | null null`},
+		{`{key: 1, $_x9: 2, café: 3}`, `"{" { | string key | ":" : | integer 1 | "," , | string $_x9 | ":" : | integer 2 | "," , | string café | ":" : | integer 3 | "}" }`},
+		{"// c\n1 /* d */", "line comment // c\n | integer 1 | block commment /* d */"},
+		{"\v\f\u00a0\ufeff\u2028 1", `integer 1`},
+
+		{`0x`, ``},
+		{`01`, ``},
+		{`.`, ``},
+		{`+`, ``},
+		{`-Infinit`, ``},
+		{`'unterminated`, ``},
+		{`"\1"`, ``},
+		{`"\01"`, ``},
+		{`"\xG0"`, ``},
+		{`5e`, ``},
+	}
+	for _, test := range tests {
+		s := jtree.NewScanner(strings.NewReader(test.input))
+		s.AllowJSON5(true)
+		var got []string
+		for s.Next() == nil {
+			got = append(got, fmt.Sprintf("%v %s", s.Token(), s.Text()))
+		}
+		if test.want == "" {
+			if s.Err() == io.EOF {
+				t.Errorf("Input %#q: got %q, want error", test.input, got)
+			}
+			continue
+		} else if s.Err() != io.EOF {
+			t.Errorf("Input %#q: unexpected error: %v", test.input, s.Err())
+		}
+		if diff := cmp.Diff(test.want, strings.Join(got, " | ")); diff != "" {
+			t.Errorf("Input %#q: tokens (-want, +got):\n%s", test.input, diff)
+		}
+
+		// Without JSON5, the input is either rejected or scanned the same.
+		s = jtree.NewScanner(strings.NewReader(test.input))
+		s.AllowComments(true)
+		var std []string
+		for s.Next() == nil {
+			std = append(std, fmt.Sprintf("%v %s", s.Token(), s.Text()))
+		}
+		if s.Err() == io.EOF && !cmp.Equal(std, got) {
+			t.Errorf("Input %#q: accepted without JSON5 as %q", test.input, std)
+		}
+	}
+}
+
+func TestScannerNonFinite(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []jtree.Token // nil for an error
+	}{
+		{`NaN Infinity -Infinity`, []jtree.Token{jtree.NaN, jtree.Infinity, jtree.NegInfinity}},
+		{`[-1, -Infinity]`, []jtree.Token{
+			jtree.LSquare, jtree.Integer, jtree.Comma, jtree.NegInfinity, jtree.RSquare,
+		}},
+		{`+Infinity`, nil},
+		{`-NaN`, nil},
+		{`Infinite`, nil},
+		{`nan`, nil},
+		{`-Inf`, nil},
+	}
+	for _, test := range tests {
+		s := jtree.NewScanner(strings.NewReader(test.input))
+		s.AllowNonFiniteNumbers(true)
+		var got []jtree.Token
+		var fs []float64
+		for s.Next() == nil {
+			got = append(got, s.Token())
+			if f, err := s.Float64(); err == nil {
+				fs = append(fs, f)
+			}
+		}
+		if test.want == nil {
+			if s.Err() == io.EOF {
+				t.Errorf("Input %#q: got %v, want error", test.input, got)
+			}
+			continue
+		} else if s.Err() != io.EOF {
+			t.Errorf("Input %#q: unexpected error: %v", test.input, s.Err())
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("Input %#q: tokens (-want, +got):\n%s", test.input, diff)
+		}
+		t.Logf("Input %#q: values %v", test.input, fs)
+
+		// Without the option, the input is rejected.
+		s = jtree.NewScanner(strings.NewReader(test.input))
+		for s.Next() == nil {
+		}
+		if s.Err() == io.EOF {
+			t.Errorf("Input %#q: accepted without AllowNonFiniteNumbers", test.input)
+		}
+	}
+}
+
+func TestRuneColumns(t *testing.T) {
+	const input = "{\"ключ\": \"☃☃\", \"é\": [1, x]}\n  \"ü\" 2"
+	want := []string{
+		`"{" 1:0-1`, `string 1:1-7`, `":" 1:7-8`, `string 1:9-13`, `"," 1:13-14`,
+		`string 1:15-18`, `":" 1:18-19`, `"[" 1:20-21`, `integer 1:21-22`, `"," 1:22-23`,
+	}
+	for _, name := range []string{"Reader", "Bytes", "Chunks"} {
+		var s *jtree.Scanner
+		switch name {
+		case "Reader":
+			s = jtree.NewScanner(strings.NewReader(input))
+		case "Bytes":
+			s = jtree.NewScannerBytes([]byte(input))
+		case "Chunks":
+			s = jtree.NewScanner(bufio.NewReaderSize(strings.NewReader(input), 16))
+		}
+		s.RuneColumns(true)
+		var got []string
+		for s.Next() == nil {
+			got = append(got, fmt.Sprintf("%v %v", s.Token(), s.Location()))
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s: locations (-want, +got):\n%s", name, diff)
+		}
+	}
+
+	// Check that the error position is reported in runes.
+	err := jtree.NewStream(strings.NewReader(input)).Parse(new(testHandler))
+	st := jtree.NewStream(strings.NewReader(input))
+	st.RuneColumns(true)
+	rerr := st.Parse(new(testHandler))
+	if got, want := rerr.(*jtree.SyntaxError).Column(), 24; got != want {
+		t.Errorf("Rune column: got %d, want %d (%v)", got, want, rerr)
+	}
+	if got, want := err.(*jtree.SyntaxError).Column(), 33; got != want {
+		t.Errorf("Byte column: got %d, want %d (%v)", got, want, err)
+	}
+}
+
+func TestInternPool(t *testing.T) {
+	p := jtree.NewInternPool(2)
+	a := p.Intern([]byte("a"))
+	if got := p.Intern([]byte("a")); unsafe.StringData(got) != unsafe.StringData(a) {
+		t.Error("Intern a: got a new string, want the existing one")
+	}
+	p.Intern([]byte("b"))
+	p.Intern([]byte("a")) // now b is least recently used
+	p.Intern([]byte("c")) // evicts b
+	if got := p.Intern([]byte("a")); unsafe.StringData(got) != unsafe.StringData(a) {
+		t.Error("Intern a: was evicted, want it retained")
+	}
+	want := jtree.InternStats{Len: 2, Hits: 3, Misses: 3, Evictions: 1}
+	if diff := cmp.Diff(want, p.Stats()); diff != "" {
+		t.Errorf("Stats (-want, +got):\n%s", diff)
+	}
+
+	p.Reset()
+	if got := p.Stats(); got != (jtree.InternStats{}) {
+		t.Errorf("Stats after Reset: got %+v, want zero", got)
+	}
+
+	// An unbounded pool is safe for concurrent use.
+	u := jtree.NewInternPool(0)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				u.Intern([]byte(strconv.Itoa((i + j) % 50)))
+			}
+		}()
+	}
+	wg.Wait()
+	if s := u.Stats(); s.Len != 50 || s.Hits+s.Misses != 800 || s.Evictions != 0 {
+		t.Errorf("Stats: got %+v, want 50 strings, 800 calls, no evictions", s)
+	}
+}
+
+func TestMinify(t *testing.T) {
+	const input = `// A leading comment.
+{
+  "a": [1, 2.50, "x\u0041y",],  // trailing comma
+  /* block */ "b": {"c": null, "d": true,},
+}
+[ ]  "s" // last`
+
+	tests := []struct {
+		name string
+		opts *jtree.MinifyOptions
+		want string
+	}{
+		{"Default", nil, "{\"a\":[1,2.50,\"x\\u0041y\"],\"b\":{\"c\":null,\"d\":true}}\n[]\n\"s\""},
+		{"KeepCommas", &jtree.MinifyOptions{KeepTrailingCommas: true},
+			"{\"a\":[1,2.50,\"x\\u0041y\",],\"b\":{\"c\":null,\"d\":true,},}\n[]\n\"s\""},
+		{"KeepComments", &jtree.MinifyOptions{KeepComments: true},
+			"// A leading comment.\n{\"a\":[1,2.50,\"x\\u0041y\"]// trailing comma\n/* block */,\"b\":{\"c\":null,\"d\":true}}\n[]\n\"s\"// last\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := jtree.MinifyWith(&buf, strings.NewReader(input), tc.opts); err != nil {
+				t.Fatalf("MinifyWith: unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("MinifyWith: got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := jtree.Minify(&buf, strings.NewReader(`{"a": tru}`)); err == nil {
+			t.Errorf("Minify: got %q, want error", buf.String())
+		}
+	})
+}
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{"Empty", ``, ``},
+		{"Scalar", ` true `, `true`},
+		{"EmptyContainers", `{"a": {}, "b": [ ]}`, "{\n>.\"a\": {},\n>.\"b\": []\n>}"},
+		{"Nested", `[1, {"x": [2, 3]}]`, "[\n>.1,\n>.{\n>..\"x\": [\n>...2,\n>...3\n>..]\n>.}\n>]"},
+		{"Multiple", `1 [2] "3"`, "1\n>[\n>.2\n>]\n>\"3\""},
+		{"TrailingComma", `[1, 2,]`, "[\n>.1,\n>.2,\n>]"},
+		{"Comments", `// head
+{
+  "a": 1, // one
+  /* before b */
+  "b": /* inline */ 2,
+}`, "// head\n>{\n>.\"a\": 1, // one\n>./* before b */\n>.\"b\": /* inline */ 2,\n>}"},
+		{"CommentInEmpty", `[ // nothing
+]`, "[ // nothing\n>]"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := jtree.Indent(&buf, strings.NewReader(tc.input), ">", "."); err != nil {
+				t.Fatalf("Indent: unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("Indent: got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+
+	// Without comments or trailing commas, the output matches json.Indent.
+	const input = `{"a": [1, 2.5e3, {"b": null, "c": []}], "d": {}, "e": "A"}`
+	var want, got bytes.Buffer
+	if err := json.Indent(&want, []byte(input), "", "  "); err != nil {
+		t.Fatalf("json.Indent: %v", err)
+	}
+	if err := jtree.Indent(&got, strings.NewReader(input), "", "  "); err != nil {
+		t.Fatalf("Indent: %v", err)
+	}
+	if diff := cmp.Diff(want.String(), got.String()); diff != "" {
+		t.Errorf("Indent (-want, +got):\n%s", diff)
+	}
 }
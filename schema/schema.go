@@ -0,0 +1,486 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package schema implements validation of JSON values against a subset of
+// JSON Schema (https://json-schema.org).
+//
+// A schema is compiled from a JSON value, and can check an ast.Value or the
+// values of a jtree.Stream:
+//
+//	s, err := schema.Parse(strings.NewReader(`{
+//	  "type": "object",
+//	  "required": ["name"],
+//	  "properties": {
+//	    "name": {"type": "string", "minLength": 1},
+//	    "port": {"type": "integer", "minimum": 1, "maximum": 65535},
+//	  },
+//	}`))
+//	...
+//	for _, v := range s.Validate(config) {
+//	   log.Print(v)
+//	}
+//
+// # Keywords
+//
+// The following keywords are supported:
+//
+//   - type: a type name, or an array of type names. The names are "null",
+//     "boolean", "object", "array", "number", "integer", and "string".
+//     A number with no fractional part is an integer.
+//   - enum: an array of the permitted values.
+//   - properties: an object whose values are the schemas of the object members
+//     with the corresponding keys.
+//   - additionalProperties: false to forbid members not listed in properties,
+//     or a schema for the values of those members.
+//   - required: an array of the keys an object must have.
+//   - items: a schema for each element of an array.
+//   - minItems, maxItems: bounds on the length of an array.
+//   - minLength, maxLength: bounds on the length of a string, in runes.
+//   - pattern: a regular expression that must match some part of a string.
+//     The syntax is that of the regexp package, not ECMA 262.
+//   - minimum, maximum: inclusive bounds on the value of a number.
+//
+// The annotation keywords $schema, $id, $comment, title, description,
+// default, and examples are accepted and ignored. Any other keyword is
+// reported as an error, rather than being ignored as JSON Schema specifies,
+// so that a schema cannot silently accept values it was meant to reject.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/escape"
+	"github.com/creachadair/jtree/jwcc"
+)
+
+// A Schema is a compiled schema. A Schema is safe for concurrent use.
+type Schema struct {
+	root *node
+}
+
+// A Violation describes a way in which a value does not satisfy a schema.
+type Violation struct {
+	Path    string         // a JSON Pointer (RFC 6901) to the value concerned
+	Key     string         // the key of the nearest enclosing object member, if any
+	Loc     jtree.Location // the location of the value in the input, if known
+	Message string
+}
+
+func (v Violation) String() string {
+	where := fmt.Sprintf("at %q", v.Path)
+	if v.Key != "" && !strings.HasSuffix(v.Path, "/"+escape.EscapeToken(v.Key)) {
+		where += fmt.Sprintf(" (in member %q)", v.Key)
+	}
+	if v.Loc.First.Line == 0 {
+		return where + ": " + v.Message
+	}
+	return fmt.Sprintf("%s: %s: %s", v.Loc.First, where, v.Message)
+}
+
+// Compile compiles a schema from v, which must be an object.
+func Compile(v ast.Value) (*Schema, error) {
+	root, err := compile("", v)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// Parse parses and compiles a schema from a JWCC document.
+func Parse(r io.Reader) (*Schema, error) {
+	doc, err := jwcc.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(doc.Undecorate())
+}
+
+// Validate checks v against s, and returns the violations found, in preorder.
+// It returns nil if v is valid.
+//
+// If v is a jwcc.Value, such as a *jwcc.Document, each violation is located
+// at the value concerned, as reported by jwcc.ValueLocation. Otherwise, since
+// an ast.Value does not record where it was parsed, the locations of the
+// violations are zero.
+func (s *Schema) Validate(v ast.Value) []Violation {
+	var r reporter
+	if jv, ok := v.(jwcc.Value); ok {
+		s.root.walkJWCC(&r, "", "", jv)
+	} else {
+		s.root.walk(&r, "", "", jtree.Location{}, v)
+	}
+	return r.out
+}
+
+// ValidateStream checks each value parsed from st against s, and returns the
+// violations found, in order of location. Unlike Validate, it does not
+// construct the values, except for an object or array whose schema has an
+// enum, and so does not need to hold the input in memory. ValidateStream
+// reports an error if st does not contain valid JSON.
+func (s *Schema) ValidateStream(st *jtree.Stream) ([]Violation, error) {
+	v := &validator{root: s.root}
+	if err := st.Parse(v); err != nil {
+		return nil, err
+	}
+	return v.out, nil
+}
+
+// A node is a compiled schema for a single value. A nil *node accepts any
+// value.
+type node struct {
+	types    []string // if non-empty, the permitted types
+	enum     ast.Array
+	props    map[string]*node
+	closed   bool  // members not in props are forbidden
+	extra    *node // the schema for members not in props
+	required []string
+	items    *node
+
+	minItems, maxItems   int // -1 if unbounded
+	minLength, maxLength int // -1 if unbounded
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+}
+
+var typeNames = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// compile compiles the schema v, whose path within the schema is path.
+func compile(path string, v ast.Value) (*node, error) {
+	obj, ok := v.(ast.Object)
+	if !ok {
+		return nil, fmt.Errorf("at %q: schema is %T, not an object", path, v)
+	}
+	n := &node{minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+	for _, m := range obj {
+		key := m.Key.String()
+		mp := path + "/" + escape.EscapeToken(key)
+		var err error
+		switch key {
+		case "type":
+			n.types, err = compileTypes(m.Value)
+		case "enum":
+			arr, ok := m.Value.(ast.Array)
+			if !ok {
+				err = errors.New("enum must be an array")
+			}
+			n.enum = arr
+		case "properties":
+			props, ok := m.Value.(ast.Object)
+			if !ok {
+				err = errors.New("properties must be an object")
+				break
+			}
+			n.props = make(map[string]*node, len(props))
+			for _, p := range props {
+				pkey := p.Key.String()
+				sub, err := compile(mp+"/"+escape.EscapeToken(pkey), p.Value)
+				if err != nil {
+					return nil, err
+				}
+				n.props[pkey] = sub
+			}
+		case "additionalProperties":
+			if b, ok := m.Value.(ast.Bool); ok {
+				n.closed = !bool(b)
+			} else if n.extra, err = compile(mp, m.Value); err != nil {
+				return nil, err
+			}
+		case "required":
+			n.required, err = compileStrings(m.Value)
+		case "items":
+			if n.items, err = compile(mp, m.Value); err != nil {
+				return nil, err
+			}
+		case "minItems":
+			n.minItems, err = compileCount(m.Value)
+		case "maxItems":
+			n.maxItems, err = compileCount(m.Value)
+		case "minLength":
+			n.minLength, err = compileCount(m.Value)
+		case "maxLength":
+			n.maxLength, err = compileCount(m.Value)
+		case "pattern":
+			s, ok := m.Value.(ast.Text)
+			if !ok {
+				err = errors.New("pattern must be a string")
+				break
+			}
+			n.pattern, err = regexp.Compile(s.String())
+		case "minimum":
+			n.minimum, err = compileBound(m.Value)
+		case "maximum":
+			n.maximum, err = compileBound(m.Value)
+		case "$schema", "$id", "$comment", "title", "description", "default", "examples":
+			// annotations, ignored
+		default:
+			err = fmt.Errorf("unsupported keyword %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("at %q: %w", mp, err)
+		}
+	}
+	return n, nil
+}
+
+func compileTypes(v ast.Value) ([]string, error) {
+	names, err := compileStrings(v)
+	if err != nil {
+		s, ok := v.(ast.Text)
+		if !ok {
+			return nil, errors.New("type must be a string or an array of strings")
+		}
+		names = []string{s.String()}
+	}
+	for _, name := range names {
+		if !slices.Contains(typeNames, name) {
+			return nil, fmt.Errorf("unknown type %q", name)
+		}
+	}
+	return names, nil
+}
+
+func compileStrings(v ast.Value) ([]string, error) {
+	arr, ok := v.(ast.Array)
+	if !ok {
+		return nil, errors.New("value must be an array of strings")
+	}
+	out := make([]string, len(arr))
+	for i, elt := range arr {
+		s, ok := elt.(ast.Text)
+		if !ok {
+			return nil, fmt.Errorf("element %d is %T, not a string", i, elt)
+		}
+		out[i] = s.String()
+	}
+	return out, nil
+}
+
+func compileCount(v ast.Value) (int, error) {
+	z, ok := v.(ast.Number)
+	if !ok || !isInteger(z) || z.Float() < 0 {
+		return 0, fmt.Errorf("value must be a non-negative integer, not %s", v.JSON())
+	}
+	return int(z.Int()), nil
+}
+
+func compileBound(v ast.Value) (*float64, error) {
+	z, ok := v.(ast.Number)
+	if !ok {
+		return nil, fmt.Errorf("value must be a number, not %s", v.JSON())
+	}
+	f := float64(z.Float())
+	return &f, nil
+}
+
+// typeOf returns the name of the type of v.
+func typeOf(v ast.Value) string {
+	if v == ast.Null {
+		return "null"
+	}
+	switch t := v.(type) {
+	case ast.Object:
+		return "object"
+	case ast.Array:
+		return "array"
+	case ast.Bool:
+		return "boolean"
+	case ast.Number:
+		if isInteger(t) {
+			return "integer"
+		}
+		return "number"
+	case ast.Text:
+		return "string"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func isInteger(z ast.Number) bool {
+	f := float64(z.Float())
+	return z.IsInt() || (f == math.Trunc(f) && !math.IsInf(f, 0))
+}
+
+// A failFunc reports a violation for a single value.
+type failFunc func(msg string, args ...any)
+
+// checkType reports whether a value of the named type is permitted by n.
+func (n *node) checkType(name string, fail failFunc) bool {
+	if len(n.types) == 0 || slices.Contains(n.types, name) ||
+		(name == "integer" && slices.Contains(n.types, "number")) {
+		return true
+	}
+	if len(n.types) == 1 {
+		fail("got %s, want %s", name, n.types[0])
+	} else {
+		fail("got %s, want one of %s", name, strings.Join(n.types, ", "))
+	}
+	return false
+}
+
+func (n *node) checkEnum(v ast.Value, fail failFunc) {
+	if n.enum != nil && !n.enum.Contains(v) {
+		fail("value %s is not one of %s", v.JSON(), n.enum.JSON())
+	}
+}
+
+func (n *node) checkString(s string, fail failFunc) {
+	if n.minLength >= 0 || n.maxLength >= 0 {
+		nr := utf8.RuneCountInString(s)
+		if n.minLength >= 0 && nr < n.minLength {
+			fail("string length %d is less than minimum %d", nr, n.minLength)
+		}
+		if n.maxLength >= 0 && nr > n.maxLength {
+			fail("string length %d is greater than maximum %d", nr, n.maxLength)
+		}
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		fail("string %q does not match pattern %q", s, n.pattern)
+	}
+}
+
+func (n *node) checkNumber(z ast.Number, fail failFunc) {
+	f := float64(z.Float())
+	if n.minimum != nil && f < *n.minimum {
+		fail("value %s is less than minimum %v", z.JSON(), *n.minimum)
+	}
+	if n.maximum != nil && f > *n.maximum {
+		fail("value %s is greater than maximum %v", z.JSON(), *n.maximum)
+	}
+}
+
+func (n *node) checkItems(count int, fail failFunc) {
+	if n.minItems >= 0 && count < n.minItems {
+		fail("array length %d is less than minimum %d", count, n.minItems)
+	}
+	if n.maxItems >= 0 && count > n.maxItems {
+		fail("array length %d is greater than maximum %d", count, n.maxItems)
+	}
+}
+
+// checkRequired reports the keys required by n for which has reports false.
+func (n *node) checkRequired(has func(string) bool, fail failFunc) {
+	for _, key := range n.required {
+		if !has(key) {
+			fail("missing required key %q", key)
+		}
+	}
+}
+
+// member returns the schema for the value of an object member with the given
+// key, and reports whether the member is permitted.
+func (n *node) member(key string) (*node, bool) {
+	if n == nil {
+		return nil, true
+	} else if sub, ok := n.props[key]; ok {
+		return sub, true
+	}
+	return n.extra, !n.closed
+}
+
+// elem returns the schema for the elements of an array.
+func (n *node) elem() *node {
+	if n == nil {
+		return nil
+	}
+	return n.items
+}
+
+// walk checks v, whose path is path, against n. The key of the nearest
+// enclosing member is key, and all the violations are reported at loc.
+func (n *node) walk(r *reporter, path, key string, loc jtree.Location, v ast.Value) {
+	if n == nil {
+		return
+	}
+	fail := r.at(path, key, loc)
+	if !n.checkType(typeOf(v), fail) {
+		return
+	}
+	n.checkEnum(v, fail)
+	switch t := v.(type) {
+	case ast.Object:
+		n.checkRequired(func(key string) bool { return t.FindKey(ast.TextEqual(key)) != nil }, fail)
+		for _, m := range t {
+			mkey := m.Key.String()
+			mp := path + "/" + escape.EscapeToken(mkey)
+			sub, ok := n.member(mkey)
+			if !ok {
+				r.at(mp, mkey, loc)("unexpected key %q", mkey)
+			}
+			sub.walk(r, mp, mkey, loc, m.Value)
+		}
+	case ast.Array:
+		n.checkItems(len(t), fail)
+		for i, elt := range t {
+			n.items.walk(r, path+"/"+strconv.Itoa(i), key, loc, elt)
+		}
+	case ast.Number:
+		n.checkNumber(t, fail)
+	case ast.Text:
+		n.checkString(t.String(), fail)
+	}
+}
+
+// walkJWCC checks v, whose path is path, against n, as walk does. Each
+// violation is reported at the location of the value concerned.
+func (n *node) walkJWCC(r *reporter, path, key string, v jwcc.Value) {
+	if n == nil {
+		return
+	}
+	loc := jwcc.ValueLocation(v)
+	fail := r.at(path, key, loc)
+	switch t := v.(type) {
+	case *jwcc.Document:
+		n.walkJWCC(r, path, key, t.Value)
+	case *jwcc.Datum:
+		n.walk(r, path, key, loc, t.Value)
+	case *jwcc.Object:
+		if !n.checkType("object", fail) {
+			return
+		}
+		if n.enum != nil {
+			n.checkEnum(t.Undecorate(), fail)
+		}
+		n.checkRequired(func(key string) bool { return t.FindKey(ast.TextEqual(key)) != nil }, fail)
+		for _, m := range t.Members {
+			mkey := m.Key.String()
+			mp := path + "/" + escape.EscapeToken(mkey)
+			sub, ok := n.member(mkey)
+			if !ok {
+				r.at(mp, mkey, jwcc.ValueLocation(m))("unexpected key %q", mkey)
+			}
+			sub.walkJWCC(r, mp, mkey, m.Value)
+		}
+	case *jwcc.Array:
+		if !n.checkType("array", fail) {
+			return
+		}
+		if n.enum != nil {
+			n.checkEnum(t.Undecorate(), fail)
+		}
+		n.checkItems(len(t.Values), fail)
+		for i, elt := range t.Values {
+			n.items.walkJWCC(r, path+"/"+strconv.Itoa(i), key, elt)
+		}
+	}
+}
+
+// A reporter collects violations.
+type reporter struct {
+	out []Violation
+}
+
+// at returns a failFunc that reports violations for path and key at loc.
+func (r *reporter) at(path, key string, loc jtree.Location) failFunc {
+	return func(msg string, args ...any) {
+		r.out = append(r.out, Violation{Path: path, Key: key, Loc: loc, Message: fmt.Sprintf(msg, args...)})
+	}
+}
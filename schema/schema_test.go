@@ -0,0 +1,202 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/internal/testutil"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/schema"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const testSchema = `// A schema for tests.
+{
+  "title": "config",
+  "type": "object",
+  "required": ["name", "port"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535},
+    "mode": {"enum": ["fast", "slow", {"custom": true}]},
+    "tags": {
+      "type": "array",
+      "maxItems": 2,
+      "items": {"type": ["string", "null"]},
+    },
+    "ratio": {"type": "number"},
+  },
+}`
+
+func TestSchema(t *testing.T) {
+	s, err := schema.Parse(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatalf("Parse schema: %v", err)
+	}
+
+	tests := []struct {
+		input string
+		want  []string // path: message
+	}{
+		{`{"name": "alpha", "port": 80}`, nil},
+		{`{"name": "alpha", "port": 80.0, "mode": {"custom": true}, "tags": ["a", null], "ratio": 2}`, nil},
+		{`[]`, []string{`: got array, want object`}},
+		{`{"port": 0}`, []string{
+			`: missing required key "name"`,
+			`/port: value 0 is less than minimum 1`,
+		}},
+		{`{"name": "", "port": 1.5}`, []string{
+			`/name: string length 0 is less than minimum 1`,
+			`/name: string "" does not match pattern "^[a-z]+$"`,
+			`/port: got number, want integer`,
+		}},
+		{`{"name": "x", "port": 2, "mode": "medium", "tags": ["a", 1, "c"], "a/b": 0}`, []string{
+			`/mode: value "medium" is not one of ["fast","slow",{"custom":true}]`,
+			`/tags: array length 3 is greater than maximum 2`,
+			`/tags/1: got integer, want one of string, null`,
+			`/a~1b: unexpected key "a/b"`,
+		}},
+		{`{"name": "x", "port": 2, "mode": {"custom": false}}`, []string{
+			`/mode: value {"custom":false} is not one of ["fast","slow",{"custom":true}]`,
+		}},
+	}
+	for _, tc := range tests {
+		format := func(vs []schema.Violation) []string {
+			var out []string
+			for _, v := range vs {
+				out = append(out, v.Path+": "+v.Message)
+			}
+			return out
+		}
+		t.Run("Validate", func(t *testing.T) {
+			got := format(s.Validate(testutil.MustParse(t, tc.input)))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Validate %#q (-want, +got):\n%s", tc.input, diff)
+			}
+		})
+		t.Run("Stream", func(t *testing.T) {
+			vs, err := s.ValidateStream(jtree.NewStream(strings.NewReader(tc.input)))
+			if err != nil {
+				t.Fatalf("ValidateStream %#q: unexpected error: %v", tc.input, err)
+			}
+			// The stream validator reports the required keys of an object at
+			// its end, so sort for comparison.
+			got, want := format(vs), tc.want
+			if diff := cmp.Diff(want, got, cmpSorted); diff != "" {
+				t.Errorf("ValidateStream %#q (-want, +got):\n%s", tc.input, diff)
+			}
+			for _, v := range vs {
+				if v.Loc.First.Line == 0 {
+					t.Errorf("Violation %v has no location", v)
+				}
+			}
+		})
+	}
+}
+
+var cmpSorted = cmpopts.SortSlices(func(a, b string) bool { return a < b })
+
+func TestStreamLocation(t *testing.T) {
+	s, err := schema.Parse(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatalf("Parse schema: %v", err)
+	}
+	const input = `{"name": "x",
+  "port": 99999,
+  "bogus": true
+}
+{"name": "y", "port": 2}
+[1]`
+	vs, err := s.ValidateStream(jtree.NewStream(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("ValidateStream: unexpected error: %v", err)
+	}
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.String())
+	}
+	want := []string{
+		`2:10: at "/port": value 99999 is greater than maximum 65535`,
+		`3:2: at "/bogus": unexpected key "bogus"`,
+		`6:0: at "": got array, want object`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Violations (-want, +got):\n%s", diff)
+	}
+
+	if _, err := s.ValidateStream(jtree.NewStream(strings.NewReader(`{"name": `))); err == nil {
+		t.Error("ValidateStream: got nil error for invalid input")
+	}
+}
+
+func TestJWCC(t *testing.T) {
+	s, err := schema.Parse(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatalf("Parse schema: %v", err)
+	}
+	doc, err := jwcc.Parse(strings.NewReader(`// A config with problems.
+{
+  // The name of the service.
+  "name": "Server",
+
+  "port": 99999, // out of range
+  "mode": {"custom": false},
+  "tags": [
+    "a",
+    12,
+  ],
+}`))
+	if err != nil {
+		t.Fatalf("Parse input: %v", err)
+	}
+	var got []string
+	for _, v := range s.Validate(doc) {
+		got = append(got, v.String())
+	}
+	want := []string{
+		`4:10: at "/name": string "Server" does not match pattern "^[a-z]+$"`,
+		`6:10: at "/port": value 99999 is greater than maximum 65535`,
+		`7:10: at "/mode": value {"custom":false} is not one of ["fast","slow",{"custom":true}]`,
+		`10:4: at "/tags/1" (in member "tags"): got integer, want one of string, null`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Violations (-want, +got):\n%s", diff)
+	}
+
+	// The same document, undecorated, reports the same violations without
+	// locations.
+	plain := s.Validate(doc.Undecorate())
+	if len(plain) != len(want) {
+		t.Errorf("Validate undecorated: got %d violations, want %d", len(plain), len(want))
+	}
+	for _, v := range plain {
+		if v.Loc.First.Line != 0 {
+			t.Errorf("Violation %v has a location", v)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{`[]`, `schema is ast.Array, not an object`},
+		{`{"type": "widget"}`, `unknown type "widget"`},
+		{`{"type": 1}`, `type must be a string or an array of strings`},
+		{`{"oneOf": []}`, `unsupported keyword "oneOf"`},
+		{`{"properties": {"a": {"minItems": -1}}}`, `at "/properties/a/minItems": value must be a non-negative integer`},
+		{`{"pattern": "("}`, `missing closing )`},
+		{`{"items": true}`, `schema is ast.Bool, not an object`},
+	}
+	for _, tc := range tests {
+		_, err := schema.Compile(testutil.MustParse(t, tc.input))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Compile %#q: got error %v, want %q", tc.input, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package schema
+
+import (
+	"strconv"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/internal/escape"
+)
+
+// A validator is a jtree.Handler that checks values against a schema as they
+// are parsed.
+type validator struct {
+	reporter
+	root *node
+	stk  []frame // enclosing objects and arrays, innermost last
+
+	// The schema, path, and key of the value of the current object member.
+	next     *node
+	nextPath string
+	nextKey  string
+
+	// An object or array whose schema has an enum is constructed, so that it
+	// can be compared with the permitted values when it is complete.
+	capture *ast.Builder
+	cdepth  int   // nesting depth within the captured value
+	cframe  frame // the captured value
+}
+
+// A frame records the state of an object or array being validated.
+type frame struct {
+	n     *node // nil if the value is not being checked
+	path  string
+	key   string // the key of the nearest enclosing member
+	start jtree.Location
+	array bool
+	count int             // the number of elements or members seen
+	keys  map[string]bool // keys seen, if n has required keys
+}
+
+// enter returns the schema, path, and nearest member key of a new value.
+func (v *validator) enter() (*node, string, string) {
+	if len(v.stk) == 0 {
+		return v.root, "", ""
+	}
+	top := &v.stk[len(v.stk)-1]
+	if top.array {
+		p := top.path + "/" + strconv.Itoa(top.count)
+		top.count++
+		return top.n.elem(), p, top.key
+	}
+	return v.next, v.nextPath, v.nextKey
+}
+
+func (v *validator) begin(array bool, loc jtree.Anchor) error {
+	if v.capture != nil {
+		v.cdepth++
+		return v.forward(array, true, loc)
+	}
+	n, path, key := v.enter()
+	f := frame{path: path, key: key, start: loc.Location(), array: array}
+	if n != nil {
+		name := "object"
+		if array {
+			name = "array"
+		}
+		if n.checkType(name, v.at(path, key, f.start)) {
+			f.n = n
+		}
+	}
+	if f.n != nil && f.n.enum != nil {
+		v.capture, v.cdepth, v.cframe = ast.NewBuilder(), 1, f
+		return v.forward(array, true, loc)
+	}
+	if f.n != nil && !array && len(f.n.required) != 0 {
+		f.keys = make(map[string]bool)
+	}
+	v.stk = append(v.stk, f)
+	return nil
+}
+
+func (v *validator) end(array bool, loc jtree.Anchor) error {
+	if v.capture != nil {
+		if err := v.forward(array, false, loc); err != nil {
+			return err
+		}
+		if v.cdepth--; v.cdepth > 0 {
+			return nil
+		}
+		vs, err := v.capture.Result()
+		if err != nil {
+			return err
+		}
+		f := v.cframe
+		v.capture, v.cframe = nil, frame{}
+		f.n.walk(&v.reporter, f.path, f.key, span(f.start, loc.Location()), vs[0])
+		return nil
+	}
+	f := v.stk[len(v.stk)-1]
+	v.stk = v.stk[:len(v.stk)-1]
+	if f.n == nil {
+		return nil
+	}
+	fail := v.at(f.path, f.key, span(f.start, loc.Location()))
+	if array {
+		f.n.checkItems(f.count, fail)
+	} else {
+		f.n.checkRequired(func(key string) bool { return f.keys[key] }, fail)
+	}
+	return nil
+}
+
+// forward passes the start or end of an object or array to the capture.
+func (v *validator) forward(array, begin bool, loc jtree.Anchor) error {
+	switch {
+	case array && begin:
+		return v.capture.BeginArray(loc)
+	case array:
+		return v.capture.EndArray(loc)
+	case begin:
+		return v.capture.BeginObject(loc)
+	default:
+		return v.capture.EndObject(loc)
+	}
+}
+
+func (v *validator) BeginObject(loc jtree.Anchor) error { return v.begin(false, loc) }
+func (v *validator) EndObject(loc jtree.Anchor) error   { return v.end(false, loc) }
+func (v *validator) BeginArray(loc jtree.Anchor) error  { return v.begin(true, loc) }
+func (v *validator) EndArray(loc jtree.Anchor) error    { return v.end(true, loc) }
+
+func (v *validator) BeginMember(loc jtree.Anchor) error {
+	if v.capture != nil {
+		return v.capture.BeginMember(loc)
+	}
+	key, err := jtree.AnchorUnquote(loc)
+	if err != nil {
+		return err
+	}
+	top := &v.stk[len(v.stk)-1]
+	top.count++
+	if top.keys != nil {
+		top.keys[string(key)] = true
+	}
+	v.nextPath = top.path + "/" + escape.EscapeToken(string(key))
+	v.nextKey = string(key)
+	sub, ok := top.n.member(v.nextKey)
+	if !ok {
+		v.at(v.nextPath, v.nextKey, loc.Location())("unexpected key %q", key)
+	}
+	v.next = sub
+	return nil
+}
+
+func (v *validator) EndMember(loc jtree.Anchor) error {
+	if v.capture != nil {
+		return v.capture.EndMember(loc)
+	}
+	return nil
+}
+
+func (v *validator) Value(loc jtree.Anchor) error {
+	if v.capture != nil {
+		return v.capture.Value(loc)
+	}
+	n, path, key := v.enter()
+	if n == nil {
+		return nil
+	}
+	val, err := ast.AnchorValue(loc)
+	if err != nil {
+		return err
+	}
+	n.walk(&v.reporter, path, key, loc.Location(), val)
+	return nil
+}
+
+func (v *validator) EndOfInput(jtree.Anchor) {}
+
+// span returns the location spanning from start to end.
+func span(start, end jtree.Location) jtree.Location {
+	return jtree.Location{
+		Span:  jtree.Span{Pos: start.Span.Pos, End: end.Span.End},
+		First: start.First,
+		Last:  end.Last,
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package stdjson connects jtree to the token API of the standard library
+// encoding/json package, so that code written around one can use the other.
+//
+// Drive delivers the tokens of a *json.Decoder to a jtree.Handler, and Tokens
+// presents the values parsed by a *jtree.Stream as a sequence of json.Token
+// values, in the same form reported by the Token method of a decoder.
+package stdjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree"
+)
+
+// Drive reads tokens from dec until the end of its input, and delivers the
+// corresponding events to h, as a jtree.Stream would. If h implements
+// jtree.DocumentHandler, it is notified of each top-level value. If reading
+// a token fails or a method of h reports an error, Drive stops and returns
+// that error.
+//
+// A decoder reports numbers as float64 values unless its UseNumber method
+// has been called, so to preserve the text of numbers exactly, call
+// dec.UseNumber before Drive. A decoder does not report the text of strings,
+// so the text of a string anchor is a quotation of its decoded value.
+//
+// The decoder does not report line and column positions, so the location of
+// each anchor has only a span, which covers the token together with any
+// whitespace and punctuation preceding it.
+func Drive(dec *json.Decoder, h jtree.Handler) error {
+	dh, _ := h.(jtree.DocumentHandler)
+
+	var stk []frame // enclosing objects and arrays, innermost last
+	for {
+		pos := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if len(stk) != 0 {
+				return io.ErrUnexpectedEOF
+			}
+			h.EndOfInput(newAnchor(jtree.Invalid, nil, pos, pos))
+			return nil
+		} else if err != nil {
+			return err
+		}
+		loc, err := tokenAnchor(tok, pos, int(dec.InputOffset()))
+		if err != nil {
+			return err
+		}
+
+		if n := len(stk); n > 0 && stk[n-1].obj {
+			top := &stk[n-1]
+			if top.state == afterValue {
+				// The decoder does not report commas, so the member ends at the
+				// token that follows it.
+				if err := h.EndMember(loc); err != nil {
+					return err
+				}
+				top.state = wantKey
+			}
+			if top.state == wantKey && loc.tok == jtree.String {
+				if err := h.BeginMember(loc); err != nil {
+					return err
+				}
+				top.state = wantValue
+				continue
+			}
+		} else if n == 0 && dh != nil {
+			if err := dh.BeginDocument(loc); err != nil {
+				return err
+			}
+		}
+
+		switch loc.tok {
+		case jtree.LBrace:
+			err = h.BeginObject(loc)
+			stk = append(stk, frame{obj: true})
+		case jtree.LSquare:
+			err = h.BeginArray(loc)
+			stk = append(stk, frame{})
+		case jtree.RBrace:
+			err = h.EndObject(loc)
+			stk = stk[:len(stk)-1]
+		case jtree.RSquare:
+			err = h.EndArray(loc)
+			stk = stk[:len(stk)-1]
+		default:
+			err = h.Value(loc)
+		}
+		if err != nil {
+			return err
+		}
+		if loc.tok == jtree.LBrace || loc.tok == jtree.LSquare {
+			continue // the value is not yet complete
+		}
+		if n := len(stk); n > 0 && stk[n-1].obj {
+			stk[n-1].state = afterValue
+		} else if n == 0 && dh != nil {
+			if err := dh.EndDocument(loc); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// A frame records the state of an object or array during Drive.
+type frame struct {
+	obj   bool
+	state int // for an object: wantKey, wantValue, or afterValue
+}
+
+const (
+	wantKey = iota
+	wantValue
+	afterValue
+)
+
+// tokenAnchor returns an anchor for a token reported by a decoder, spanning
+// the input from pos to end.
+func tokenAnchor(tok json.Token, pos, end int) (*anchor, error) {
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			return newAnchor(jtree.LBrace, []byte("{"), pos, end), nil
+		case '}':
+			return newAnchor(jtree.RBrace, []byte("}"), pos, end), nil
+		case '[':
+			return newAnchor(jtree.LSquare, []byte("["), pos, end), nil
+		case ']':
+			return newAnchor(jtree.RSquare, []byte("]"), pos, end), nil
+		}
+	case string:
+		return newAnchor(jtree.String, []byte(jtree.Quote(t)), pos, end), nil
+	case json.Number:
+		return newAnchor(numberToken(string(t)), []byte(t), pos, end), nil
+	case float64:
+		text := strconv.FormatFloat(t, 'g', -1, 64)
+		return newAnchor(numberToken(text), []byte(text), pos, end), nil
+	case bool:
+		if t {
+			return newAnchor(jtree.True, []byte("true"), pos, end), nil
+		}
+		return newAnchor(jtree.False, []byte("false"), pos, end), nil
+	case nil:
+		return newAnchor(jtree.Null, []byte("null"), pos, end), nil
+	}
+	return nil, fmt.Errorf("unexpected token %v (%T)", tok, tok)
+}
+
+func numberToken(text string) jtree.Token {
+	if strings.ContainsAny(text, ".eE") {
+		return jtree.Number
+	}
+	return jtree.Integer
+}
+
+// An anchor is a jtree.Anchor for a token reported by a json.Decoder.
+type anchor struct {
+	tok  jtree.Token
+	text []byte
+	loc  jtree.Location
+}
+
+func newAnchor(tok jtree.Token, text []byte, pos, end int) *anchor {
+	return &anchor{tok: tok, text: text, loc: jtree.Location{Span: jtree.Span{Pos: pos, End: end}}}
+}
+
+func (a *anchor) Token() jtree.Token       { return a.tok }
+func (a *anchor) Text() []byte             { return a.text }
+func (a *anchor) Copy() []byte             { return append([]byte(nil), a.text...) }
+func (a *anchor) Location() jtree.Location { return a.loc }
+
+// Tokens returns an iterator over the tokens of the values parsed from st, in
+// the form reported by the Token method of a json.Decoder: A json.Delim for
+// each bracket, a string for each object key or string value, a bool, nil, or
+// a json.Number for each number. The non-finite numbers of JSON5 are reported
+// as float64 values, since a json.Number cannot represent them.
+//
+// If parsing fails, the iterator yields the error, with a nil token, and
+// stops. Stopping the iteration early stops parsing.
+func Tokens(st *jtree.Stream) iter.Seq2[json.Token, error] {
+	return func(yield func(json.Token, error) bool) {
+		err := st.Parse(&tokenHandler{yield: yield})
+		if err != nil && !errors.Is(err, errStop) {
+			yield(nil, err)
+		}
+	}
+}
+
+// errStop is reported by a tokenHandler when the consumer stops iteration.
+var errStop = errors.New("iteration stopped")
+
+// A tokenHandler is a jtree.Handler that yields json.Token values.
+type tokenHandler struct {
+	yield func(json.Token, error) bool
+}
+
+func (t *tokenHandler) emit(tok json.Token) error {
+	if !t.yield(tok, nil) {
+		return errStop
+	}
+	return nil
+}
+
+func (t *tokenHandler) BeginObject(jtree.Anchor) error { return t.emit(json.Delim('{')) }
+func (t *tokenHandler) EndObject(jtree.Anchor) error   { return t.emit(json.Delim('}')) }
+func (t *tokenHandler) BeginArray(jtree.Anchor) error  { return t.emit(json.Delim('[')) }
+func (t *tokenHandler) EndArray(jtree.Anchor) error    { return t.emit(json.Delim(']')) }
+func (t *tokenHandler) EndMember(jtree.Anchor) error   { return nil }
+func (t *tokenHandler) EndOfInput(jtree.Anchor)        {}
+
+func (t *tokenHandler) BeginMember(loc jtree.Anchor) error {
+	key, err := jtree.AnchorUnquote(loc)
+	if err != nil {
+		return err
+	}
+	return t.emit(string(key))
+}
+
+func (t *tokenHandler) Value(loc jtree.Anchor) error {
+	switch loc.Token() {
+	case jtree.String:
+		s, err := jtree.AnchorUnquote(loc)
+		if err != nil {
+			return err
+		}
+		return t.emit(string(s))
+	case jtree.Integer, jtree.Number:
+		return t.emit(json.Number(loc.Copy()))
+	case jtree.NaN, jtree.Infinity, jtree.NegInfinity:
+		f, err := jtree.AnchorFloat64(loc)
+		if err != nil {
+			return err
+		}
+		return t.emit(f)
+	case jtree.True, jtree.False:
+		return t.emit(loc.Token() == jtree.True)
+	case jtree.Null:
+		return t.emit(nil)
+	}
+	return fmt.Errorf("unexpected token %v", loc.Token())
+}
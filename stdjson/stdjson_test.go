@@ -0,0 +1,188 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package stdjson_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/stdjson"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testInput = `{"a": [1, 2.5, "three", true, null], "b": {}, "c": {"d": [], "e": -3e2}}
+  "tailA" [false]`
+
+// eventLog is a jtree.DocumentHandler that records the events it receives.
+type eventLog struct{ events []string }
+
+func (e *eventLog) add(name string, loc jtree.Anchor) error {
+	e.events = append(e.events, fmt.Sprintf("%s %v %s", name, loc.Token(), loc.Text()))
+	return nil
+}
+
+func (e *eventLog) BeginDocument(loc jtree.Anchor) error { return e.add("doc", loc) }
+func (e *eventLog) EndDocument(loc jtree.Anchor) error   { return e.add("/doc", loc) }
+func (e *eventLog) BeginObject(loc jtree.Anchor) error   { return e.add("obj", loc) }
+func (e *eventLog) EndObject(loc jtree.Anchor) error     { return e.add("/obj", loc) }
+func (e *eventLog) BeginArray(loc jtree.Anchor) error    { return e.add("arr", loc) }
+func (e *eventLog) EndArray(loc jtree.Anchor) error      { return e.add("/arr", loc) }
+func (e *eventLog) BeginMember(loc jtree.Anchor) error   { return e.add("mem", loc) }
+func (e *eventLog) EndMember(jtree.Anchor) error {
+	// The anchor differs, since the decoder does not report commas.
+	e.events = append(e.events, "/mem")
+	return nil
+}
+func (e *eventLog) Value(loc jtree.Anchor) error { return e.add("val", loc) }
+func (e *eventLog) EndOfInput(jtree.Anchor)      { e.events = append(e.events, "eof") }
+
+func TestDrive(t *testing.T) {
+	var want eventLog
+	if err := jtree.NewStream(strings.NewReader(testInput)).Parse(&want); err != nil {
+		t.Fatalf("Stream Parse: unexpected error: %v", err)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(testInput))
+	dec.UseNumber()
+	var got eventLog
+	if err := stdjson.Drive(dec, &got); err != nil {
+		t.Fatalf("Drive: unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want.events, got.events); diff != "" {
+		t.Errorf("Drive events (-want, +got):\n%s", diff)
+	}
+
+	t.Run("Build", func(t *testing.T) {
+		b := ast.NewBuilder()
+		if err := stdjson.Drive(json.NewDecoder(strings.NewReader(testInput)), b); err != nil {
+			t.Fatalf("Drive: unexpected error: %v", err)
+		}
+		vs, err := b.Result()
+		if err != nil {
+			t.Fatalf("Result: unexpected error: %v", err)
+		}
+		var got []string
+		for _, v := range vs {
+			got = append(got, v.JSON())
+		}
+		want := []string{
+			`{"a":[1,2.5,"three",true,null],"b":{},"c":{"d":[],"e":-300}}`,
+			`"tailA"`,
+			`[false]`,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Values (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, input := range []string{`[1, 2`, `{"a" 1}`, `[1,]`} {
+			err := stdjson.Drive(json.NewDecoder(strings.NewReader(input)), jtree.NopHandler{})
+			if err == nil {
+				t.Errorf("Drive %#q: got nil, want error", input)
+			}
+		}
+
+		fail := errors.New("stop here")
+		dec := json.NewDecoder(strings.NewReader(`{"x": [1, 2]}`))
+		if err := stdjson.Drive(dec, errHandler{fail}); !errors.Is(err, fail) {
+			t.Errorf("Drive: got %v, want %v", err, fail)
+		}
+	})
+}
+
+// errHandler is a jtree.Handler that reports an error for any value.
+type errHandler struct{ err error }
+
+func (errHandler) BeginObject(jtree.Anchor) error { return nil }
+func (errHandler) EndObject(jtree.Anchor) error   { return nil }
+func (errHandler) BeginArray(jtree.Anchor) error  { return nil }
+func (errHandler) EndArray(jtree.Anchor) error    { return nil }
+func (errHandler) BeginMember(jtree.Anchor) error { return nil }
+func (errHandler) EndMember(jtree.Anchor) error   { return nil }
+func (e errHandler) Value(jtree.Anchor) error     { return e.err }
+func (errHandler) EndOfInput(jtree.Anchor)        {}
+
+func TestTokens(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(testInput))
+	dec.UseNumber()
+	var want []json.Token
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decoder Token: unexpected error: %v", err)
+		}
+		want = append(want, tok)
+	}
+
+	var got []json.Token
+	for tok, err := range stdjson.Tokens(jtree.NewStream(strings.NewReader(testInput))) {
+		if err != nil {
+			t.Fatalf("Tokens: unexpected error: %v", err)
+		}
+		got = append(got, tok)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Tokens (-want, +got):\n%s", diff)
+	}
+
+	t.Run("NonFinite", func(t *testing.T) {
+		st := jtree.NewStream(strings.NewReader(`[NaN, -Infinity]`))
+		st.AllowNonFiniteNumbers(true)
+		var got []json.Token
+		for tok, err := range stdjson.Tokens(st) {
+			if err != nil {
+				t.Fatalf("Tokens: unexpected error: %v", err)
+			}
+			got = append(got, tok)
+		}
+		if len(got) != 4 {
+			t.Fatalf("Tokens: got %v, want 4 tokens", got)
+		}
+		if f, ok := got[1].(float64); !ok || !math.IsNaN(f) {
+			t.Errorf("Token 1: got %v, want NaN", got[1])
+		}
+		if f, ok := got[2].(float64); !ok || !math.IsInf(f, -1) {
+			t.Errorf("Token 2: got %v, want -Inf", got[2])
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		var got []json.Token
+		var gotErr error
+		for tok, err := range stdjson.Tokens(jtree.NewStream(strings.NewReader(`[1, }`))) {
+			if err != nil {
+				gotErr = err
+				break
+			}
+			got = append(got, tok)
+		}
+		if gotErr == nil {
+			t.Error("Tokens: got no error, want error")
+		}
+		if diff := cmp.Diff([]json.Token{json.Delim('['), json.Number("1")}, got); diff != "" {
+			t.Errorf("Tokens before error (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		var n int
+		for range stdjson.Tokens(jtree.NewStream(strings.NewReader(`[1, 2, 3] [4]`))) {
+			if n++; n == 2 {
+				break
+			}
+		}
+		if n != 2 {
+			t.Errorf("Tokens: got %d tokens, want 2", n)
+		}
+	})
+}
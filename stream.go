@@ -5,11 +5,18 @@ package jtree
 import (
 	"fmt"
 	"io"
+	"iter"
+	"slices"
 	"strings"
 )
 
 // An Anchor represents a location in source text. The methods of an Anchor
 // will report the location, token type, and contents of the anchor.
+//
+// The slice returned by Text is valid only until the anchor changes. For a
+// Stream constructed by NewStreamBytes, the slice returned by Copy is a view
+// of the input rather than a copy, and remains valid as long as the input.
+// See AnchorBytes for text that shares storage with the scanner.
 type Anchor interface {
 	Token() Token       // Returns the token type of the anchor
 	Text() []byte       // Returns a view of the raw (undecoded) text of the anchor
@@ -17,6 +24,41 @@ type Anchor interface {
 	Location() Location // Returns the full location of the anchor
 }
 
+// AnchorBytes returns the raw (undecoded) text of a. If a has a Bytes method,
+// as a Scanner does, AnchorBytes returns its result; otherwise it returns
+// a.Copy(). For the anchors reported by a Stream with ReuseStorage enabled,
+// the result is only valid until the next call of Release.
+func AnchorBytes(a Anchor) []byte {
+	if b, ok := a.(interface{ Bytes() []byte }); ok {
+		return b.Bytes()
+	}
+	return a.Copy()
+}
+
+// AnchorInt64 decodes the value of a as a 64-bit signed integer. It reports an
+// error if a is not an Integer, or if its value is out of range.
+func AnchorInt64(a Anchor) (int64, error) { return decodeInt64(a.Token(), a.Text()) }
+
+// AnchorFloat64 decodes the value of a as a 64-bit floating-point value. It
+// reports an error if a is not an Integer, a Number, or a non-finite number,
+// or if its value is out of range.
+func AnchorFloat64(a Anchor) (float64, error) { return decodeFloat64(a.Token(), a.Text()) }
+
+// AnchorBool decodes the value of a as a Boolean. It reports an error if a is
+// not True or False.
+func AnchorBool(a Anchor) (bool, error) { return decodeBool(a.Token()) }
+
+// AnchorUnquote decodes the value of a as a string, and returns a new slice
+// containing its unescaped contents. It reports an error if a is not a
+// String, or is not correctly escaped. If a is a Scanner, the result is the
+// same as for its Unquote method.
+func AnchorUnquote(a Anchor) ([]byte, error) {
+	if s, ok := a.(*Scanner); ok {
+		return s.Unquote()
+	}
+	return decodeUnquote(a.Token(), a.Text(), false)
+}
+
 // A Handler handles events from parsing an input stream.  If a method reports
 // an error, parsing stops and that error is returned to the caller.
 // The parser ensures objects and arrays are correctly balanced.
@@ -66,25 +108,229 @@ type CommentHandler interface {
 	Comment(loc Anchor)
 }
 
+// ExtHandler is an optional interface that a Handler may implement to observe
+// the punctuation tokens of the input, which are not otherwise reported. This
+// is useful for tools that must preserve the exact layout of their input.
+type ExtHandler interface {
+	// Report a comma or colon token at loc. Punct is called when the token is
+	// read, before any other event whose anchor is the same token.
+	Punct(loc Anchor) error
+
+	// Report a comma at loc that is followed by the closing brace or bracket
+	// of its object or array. TrailingComma is called after Punct reports the
+	// comma, and before the end of the object or array is reported. It is
+	// called only if trailing commas are allowed.
+	TrailingComma(loc Anchor) error
+}
+
+// DocumentHandler is an optional interface that a Handler may implement to
+// learn where each top-level value of the input begins and ends. This is
+// useful for a handler that processes a stream of concatenated values, and
+// must reset its state for each one.
+type DocumentHandler interface {
+	// Begin a new top-level value, whose first token is at loc. BeginDocument
+	// is called before the event for the first token.
+	BeginDocument(loc Anchor) error
+
+	// End the current top-level value, whose last token is at loc.
+	// EndDocument is called after the event for the last token.
+	EndDocument(loc Anchor) error
+}
+
+// RawHandler is an optional interface that a Handler may implement to obtain
+// the source text of selected values, for example to retain the text of an
+// object member whose parsing is deferred, or to copy it to the output
+// unchanged. The events for a captured value are delivered as usual.
+type RawHandler interface {
+	// Report whether to capture the source text of the value whose first
+	// token is at loc. CaptureRaw is called before the event for that token.
+	CaptureRaw(loc Anchor) bool
+
+	// Report the source text of a captured value. The token of loc is the
+	// first token of the value, and its text and location span the complete
+	// value, from its first token to its last, including any whitespace and
+	// comments between them. RawValue is called after the event for the last
+	// token of the value. The text of loc is not modified by the stream, and
+	// remains valid after RawValue returns, so it may be retained without
+	// copying.
+	RawValue(loc Anchor) error
+}
+
+// A tokenAnchor is an Anchor for a punctuation token that is no longer the
+// current token of the scanner.
+type tokenAnchor struct {
+	tok Token
+	loc Location
+}
+
+func (t tokenAnchor) Token() Token       { return t.tok }
+func (t tokenAnchor) Text() []byte       { return []byte(strings.Trim(t.tok.String(), `"`)) }
+func (t tokenAnchor) Copy() []byte       { return t.Text() }
+func (t tokenAnchor) Location() Location { return t.loc }
+
+// A textAnchor is an Anchor for input text that is not the current token of
+// the scanner.
+type textAnchor struct {
+	tok  Token
+	text []byte
+	loc  Location
+}
+
+func (t *textAnchor) Token() Token       { return t.tok }
+func (t *textAnchor) Text() []byte       { return t.text }
+func (t *textAnchor) Copy() []byte       { return append([]byte(nil), t.text...) }
+func (t *textAnchor) Location() Location { return t.loc }
+
+// SkipHandler is an optional interface that a Handler may implement to learn
+// about input discarded before the first value because of the AllowBOM and
+// SkipUntilValue settings. If any input is discarded, Skipped is called once,
+// before any other event.
+type SkipHandler interface {
+	// Report the discarded input at loc. The token type of loc is Invalid.
+	Skipped(loc Anchor)
+}
+
+// CommentInfoHandler is an optional interface that a Handler may implement to
+// handle comment tokens along with a description of each. If a handler
+// implements this method and comments are enabled in the scanner,
+// CommentInfo is called for each comment token instead of Comment.
+type CommentInfoHandler interface {
+	// Process the comment at the specified location, described by info.
+	CommentInfo(loc Anchor, info CommentInfo)
+}
+
+// CommentInfo describes a comment token.
+type CommentInfo struct {
+	// Kind is the token type of the comment, LineComment or BlockComment.
+	Kind Token
+
+	// Text is the text of the comment, without its comment markers and with
+	// leading and trailing whitespace removed. Interior lines of a block
+	// comment are not modified.
+	Text string
+
+	// AfterBlank reports whether the comment is separated from the token
+	// before it by at least one blank line. It is false for a comment at the
+	// start of the input.
+	AfterBlank bool
+
+	// Trailing reports whether the comment begins on the same line as the end
+	// of the token before it, as for a comment at the end of a line of data.
+	Trailing bool
+}
+
+// cleanComment returns the text of a comment token without its markers and
+// surrounding whitespace.
+func cleanComment(tok Token, text []byte) string {
+	s := string(text)
+	if tok == LineComment {
+		s = strings.TrimPrefix(s, "//")
+	} else {
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "/*"), "*/")
+	}
+	return strings.TrimSpace(s)
+}
+
 // Stream is a stream parser that consumes input and delivers events to a
 // Handler corresponding with the structure of the input.
 type Stream struct {
 	s      *Scanner
 	tcomma bool // allow trailing commas in objects and arrays
+
+	// The state of values being captured for a RawHandler. Unless the input
+	// is direct, the source text of the outermost captured value is copied
+	// into raw as it is read. Each outermost capture has its own buffer, since
+	// the handler may retain the text of a value.
+	caps  []rawCapture // values being captured, innermost last
+	raw   []byte       // the source text read since the outermost capture began
+	space bool         // the RetainSpace setting of the scanner before capture
+}
+
+// A rawCapture records the start of a value being captured.
+type rawCapture struct {
+	tok   Token    // the first token of the value
+	first Location // the location of the first token
+	off   int      // the offset of the value in raw
 }
 
 // NewStream constructs a new Stream that consumes input from r.
 func NewStream(r io.Reader) *Stream { return &Stream{s: NewScanner(r)} }
 
+// NewStreamBytes constructs a new Stream that consumes input directly from
+// data, as described by NewScannerBytes. The caller must not modify the
+// contents of data while the stream, or any text it reported, is in use.
+func NewStreamBytes(data []byte) *Stream { return &Stream{s: NewScannerBytes(data)} }
+
+// NewStreamAt constructs a new Stream that consumes input from r within the
+// window of size bytes beginning at offset, as described by NewScannerAt.
+// The window must begin at the start of a value, or between values.
+func NewStreamAt(r io.ReaderAt, offset, size int64) *Stream {
+	return &Stream{s: NewScannerAt(r, offset, size)}
+}
+
+// Reset discards the state of s and prepares it to parse input from r, as if
+// it had been newly constructed by NewStream. Settings such as AllowComments
+// and AllowTrailingCommas are kept.
+// Reusing a Stream in this way avoids allocating new buffers for each input,
+// which is useful when parsing many small inputs.
+func (s *Stream) Reset(r io.Reader) { s.s.Reset(r) }
+
 // AllowComments configures the scanner associated with s to report (true) or
 // reject (false) comment tokens.
 func (s *Stream) AllowComments(ok bool) { s.s.AllowComments(ok) }
 
+// ValidateUTF8 configures the scanner associated with s to reject (true) or
+// accept (false) invalid UTF-8 and unpaired surrogate escapes in strings and
+// comments.
+func (s *Stream) ValidateUTF8(ok bool) { s.s.ValidateUTF8(ok) }
+
+// AllowBOM configures the scanner associated with s to skip (true) or reject
+// (false) a byte-order mark at the start of the input.
+func (s *Stream) AllowBOM(ok bool) { s.s.AllowBOM(ok) }
+
+// SkipUntilValue configures the scanner associated with s to discard (true)
+// or reject (false) input before the first object or array. If the handler
+// implements SkipHandler, the discarded input is reported to it.
+func (s *Stream) SkipUntilValue(ok bool) { s.s.SkipUntilValue(ok) }
+
+// AllowJSON5 configures the scanner associated with s to accept (true) or
+// reject (false) JSON5 syntax, as described by Scanner.AllowJSON5. If
+// enabled, trailing commas are also allowed.
+func (s *Stream) AllowJSON5(ok bool) { s.s.AllowJSON5(ok) }
+
+// AllowNonFiniteNumbers configures the scanner associated with s to accept
+// (true) or reject (false) the numbers NaN, Infinity, and -Infinity, as
+// described by Scanner.AllowNonFiniteNumbers.
+func (s *Stream) AllowNonFiniteNumbers(ok bool) { s.s.AllowNonFiniteNumbers(ok) }
+
+// AllowUnquotedKeys configures the scanner associated with s to accept (true)
+// or reject (false) identifiers as object keys, as described by
+// Scanner.AllowUnquotedKeys. Such a key is reported to BeginMember as a
+// String whose text is not quoted.
+func (s *Stream) AllowUnquotedKeys(ok bool) { s.s.AllowUnquotedKeys(ok) }
+
+// RuneColumns configures the scanner associated with s to count the column
+// offsets of locations in runes (true) or bytes (false).
+func (s *Stream) RuneColumns(ok bool) { s.s.RuneColumns(ok) }
+
+// ReuseStorage configures the scanner associated with s to reuse (true) or
+// not reuse (false) the storage for the text returned by AnchorBytes, as
+// described by Scanner.ReuseStorage.
+func (s *Stream) ReuseStorage(ok bool) { s.s.ReuseStorage(ok) }
+
+// Release discards the text returned by AnchorBytes for the anchors of s
+// while ReuseStorage is enabled, as described by Scanner.Release.
+func (s *Stream) Release() { s.s.Release() }
+
 // AllowTrailingCommas configures the parser to allow (true) or reject (false)
 // trailing comments in objects and arrays.
 func (s *Stream) AllowTrailingCommas(ok bool) { s.tcomma = ok }
 
 func (s *Stream) recoverParseError(errp *error) {
+	if len(s.caps) != 0 {
+		s.caps = s.caps[:0]
+		s.s.RetainSpace(s.space)
+	}
 	if serr := recover(); serr != nil {
 		switch err := serr.(type) {
 		case *SyntaxError:
@@ -112,7 +358,7 @@ func (s *Stream) Parse(h Handler) (err error) {
 			s.syntaxError(err, err.Error())
 		}
 
-		s.parseElement(h)
+		s.parseDocument(h)
 	}
 }
 
@@ -129,13 +375,44 @@ func (s *Stream) ParseOne(h Handler) (err error) {
 	} else if err != nil {
 		s.syntaxError(err, err.Error())
 	}
-	s.parseElement(h)
+	s.parseDocument(h)
 	return nil
 }
 
+// Documents returns an iterator that parses the values of the input stream
+// one at a time, delivering events for each to h. After each complete value,
+// the iterator yields nil, so the caller can process the value and reset any
+// state in h before the next one. In case of error, the iterator yields the
+// error, as reported by ParseOne, and stops. At the end of the input, it stops
+// without yielding.
+func (s *Stream) Documents(h Handler) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		for {
+			err := s.ParseOne(h)
+			if err == io.EOF || !yield(err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseDocument consumes a single top-level value.
+// Precondition: token != Invalid.
+func (s *Stream) parseDocument(h Handler) {
+	dh, ok := h.(DocumentHandler)
+	if ok {
+		s.checkError(dh.BeginDocument(s.s))
+	}
+	s.parseElement(h)
+	if ok {
+		s.checkError(dh.EndDocument(s.s))
+	}
+}
+
 // parseElement consumes a single value of any type.
 // Precondition: token != Invalid.
 func (s *Stream) parseElement(h Handler) {
+	raw := s.beginRaw(h)
 	switch tok := s.s.Token(); tok {
 	case LBrace:
 		s.checkError(h.BeginObject(s.s))
@@ -147,13 +424,63 @@ func (s *Stream) parseElement(h Handler) {
 		s.parseElements(h)
 		s.require(h, RSquare)
 		s.checkError(h.EndArray(s.s))
-	case Integer, Number, String, True, False, Null:
+	case String:
+		if s.s.Bare() {
+			s.syntaxError(nil, "unquoted string %q", s.s.Text())
+		}
+		s.checkError(h.Value(s.s))
+	case Integer, Number, True, False, Null, NaN, Infinity, NegInfinity:
 		s.checkError(h.Value(s.s))
 	case RBrace, RSquare, Comma, Colon:
 		s.syntaxError(nil, "unexpected %v", tok)
 	default:
 		s.syntaxError(nil, "unknown token %v", tok)
 	}
+	if raw {
+		s.endRaw(h)
+	}
+}
+
+// beginRaw begins capturing the value at the current token, if h is a
+// RawHandler that wants it, and reports whether it did so.
+func (s *Stream) beginRaw(h Handler) bool {
+	rh, ok := h.(RawHandler)
+	if !ok || !rh.CaptureRaw(s.s) {
+		return false
+	}
+	c := rawCapture{tok: s.s.Token(), first: s.s.Location()}
+	if !s.s.direct {
+		// The text of the current token was already recorded if an enclosing
+		// value is being captured.
+		if len(s.caps) == 0 {
+			s.space = s.s.space
+			s.s.RetainSpace(true)
+			s.raw = append([]byte(nil), s.s.Text()...)
+		}
+		c.off = len(s.raw) - len(s.s.Text())
+	}
+	s.caps = append(s.caps, c)
+	return true
+}
+
+// endRaw ends the innermost capture, whose last token is the current token,
+// and reports its text to h.
+func (s *Stream) endRaw(h Handler) {
+	c := s.caps[len(s.caps)-1]
+	s.caps = s.caps[:len(s.caps)-1]
+	last := s.s.Location()
+	loc := Location{Span: Span{Pos: c.first.Pos, End: last.End}, First: c.first.First, Last: last.Last}
+
+	var text []byte
+	if s.s.direct {
+		text = s.s.src[loc.Pos-s.s.base : loc.End-s.s.base : loc.End-s.s.base]
+	} else {
+		text = s.raw[c.off:len(s.raw):len(s.raw)]
+		if len(s.caps) == 0 {
+			s.s.RetainSpace(s.space)
+		}
+	}
+	s.checkError(h.(RawHandler).RawValue(&textAnchor{tok: c.tok, text: text, loc: loc}))
 }
 
 // parseMembers consumes zero of more key:value object members.
@@ -176,12 +503,14 @@ func (s *Stream) parseMembers(h Handler) {
 		s.checkError(h.EndMember(s.s))
 		if tok == RBrace {
 			return // end of object
-		} else if s.tcomma {
+		} else if s.tcomma || s.s.json5 {
 			// If trailing commas are allowed and the next token is a close
 			// bracket, consider this a valid end of the object. Otherwise, it
 			// must be a key for a subsequent element.
+			comma := s.s.Location()
 			next := s.advance(h, String, RBrace)
 			if next == RBrace {
+				s.trailingComma(h, comma)
 				return // end of object with trailing comma
 			}
 		} else {
@@ -207,7 +536,9 @@ func (s *Stream) parseElements(h Handler) {
 		// If trailing commas are allowed and the next token is a close bracket,
 		// consider this a valid end of the array; otherwise it will fail on the
 		// next element
-		if next := s.advance(h); s.tcomma && next == RSquare {
+		comma := s.s.Location()
+		if next := s.advance(h); (s.tcomma || s.s.json5) && next == RSquare {
+			s.trailingComma(h, comma)
 			return // end of array with trailing comma
 		}
 		s.parseElement(h)
@@ -216,15 +547,39 @@ func (s *Stream) parseElements(h Handler) {
 
 func (s *Stream) nextToken(h Handler) error {
 	for {
-		if err := s.s.Next(); err != nil {
+		prevTok, prevLine := s.s.tok, s.s.eline // the end of the previous token
+		err := s.s.Next()
+		if s.s.skipNew {
+			s.s.skipNew = false
+			if sh, ok := h.(SkipHandler); ok {
+				sh.Skipped(s.s.skip)
+			}
+		}
+		if err != nil {
 			return err
 		}
+		if len(s.caps) != 0 && !s.s.direct {
+			s.raw = append(s.raw, s.s.Space()...)
+			s.raw = append(s.raw, s.s.Text()...)
+		}
 
 		// If we see a comment token, pass it to the handler if it implements
-		// CommentHandler. Either way, discard the comment and fetch the next
-		// available comment for the rest of the parser.
+		// CommentInfoHandler or CommentHandler. Either way, discard the comment
+		// and fetch the next available comment for the rest of the parser.
 		if tok := s.s.Token(); tok == LineComment || tok == BlockComment {
-			if ch, ok := h.(CommentHandler); ok {
+			switch ch := h.(type) {
+			case CommentInfoHandler:
+				info := CommentInfo{Kind: tok, Text: cleanComment(tok, s.s.Text())}
+				if prevTok == LineComment {
+					// The line comment includes its newline, so its end is
+					// already on the following line.
+					info.AfterBlank = s.s.pline > prevLine
+				} else if prevTok != Invalid {
+					info.AfterBlank = s.s.pline > prevLine+1
+					info.Trailing = s.s.pline == prevLine
+				}
+				ch.CommentInfo(s.s, info)
+			case CommentHandler:
 				ch.Comment(s.s)
 			}
 			continue // skip to the next token for the parser
@@ -235,27 +590,45 @@ func (s *Stream) nextToken(h Handler) error {
 
 func (s *Stream) advance(h Handler, tokens ...Token) Token {
 	if err := s.nextToken(h); err != nil {
-		s.syntaxError(err, tokLabel(tokens, err))
+		s.unexpected(err, tokens, err)
 	}
 	tok := s.s.Token()
+	if s.s.ident && tok != String && tokOneOf(String, tokens) {
+		// A JSON5 identifier in the position of an object key is a key, even
+		// if it is also the name of a constant.
+		s.s.tok, tok = String, String
+	}
 	if len(tokens) != 0 && !tokOneOf(tok, tokens) {
-		s.syntaxError(nil, tokLabel(tokens, tok))
+		s.unexpected(nil, tokens, tok)
+	}
+	if tok == Comma || tok == Colon {
+		if eh, ok := h.(ExtHandler); ok {
+			s.checkError(eh.Punct(s.s))
+		}
 	}
 	return tok
 }
 
+// trailingComma reports a trailing comma at loc to h, if h is an ExtHandler.
+func (s *Stream) trailingComma(h Handler, loc Location) {
+	if eh, ok := h.(ExtHandler); ok {
+		s.checkError(eh.TrailingComma(tokenAnchor{tok: Comma, loc: loc}))
+	}
+}
+
 func (s *Stream) require(h Handler, token Token) {
 	if tok := s.s.Token(); tok != token {
-		s.syntaxError(nil, "expected %v, got %v", token, tok)
+		s.unexpected(nil, []Token{token}, tok)
 	}
 }
 
 func (s *Stream) syntaxError(err error, msg string, args ...any) {
-	panic(&SyntaxError{
-		Location: s.s.Location().First,
-		Message:  fmt.Sprintf(msg, args...),
-		err:      err,
-	})
+	panic(newSyntaxError(s.s, nil, err, fmt.Sprintf(msg, args...)))
+}
+
+// unexpected reports a syntax error for got, where one of want was expected.
+func (s *Stream) unexpected(err error, want []Token, got any) {
+	panic(newSyntaxError(s.s, want, err, tokLabel(want, got)))
 }
 
 func (s *Stream) checkError(err error) {
@@ -298,13 +671,39 @@ func tokOneOf(cur Token, tokens []Token) bool {
 }
 
 // SyntaxError is the concrete type of errors reported by the stream parser.
+//
+// The location of the error is that of the token where it was detected. For
+// a lexical error, or a premature end of input, Token is Invalid and the
+// underlying error from the scanner can be recovered with errors.Unwrap or
+// errors.As.
 type SyntaxError struct {
-	Location LineCol
+	Location LineCol // the line and column of the error
+	Offset   int     // the byte offset of the error
+	Token    Token   // the token at which the error was detected
+	Expected []Token // the tokens the parser expected instead, if known
 	Message  string
 
+	end int // the end offset of the text at which the error was detected
 	err error
 }
 
+// newSyntaxError returns a *SyntaxError for the current token of sc.
+func newSyntaxError(sc *Scanner, want []Token, err error, msg string) *SyntaxError {
+	loc, tok := sc.Location(), sc.Token()
+	if err != nil {
+		tok = Invalid // the token was not scanned
+	}
+	return &SyntaxError{
+		Location: loc.First,
+		Offset:   loc.Span.Pos,
+		Token:    tok,
+		Expected: slices.Clone(want),
+		Message:  msg,
+		end:      loc.Span.End,
+		err:      err,
+	}
+}
+
 // Error satisfies the error interface.
 func (s *SyntaxError) Error() string {
 	return fmt.Sprintf("at %s: %s", s.Location, s.Message)
@@ -312,3 +711,24 @@ func (s *SyntaxError) Error() string {
 
 // Unwrap supports error wrapping.
 func (s *SyntaxError) Unwrap() error { return s.err }
+
+// ErrorLocation reports the location of the syntax error.
+func (s *SyntaxError) ErrorLocation() LineCol { return s.Location }
+
+// Line reports the 1-based line number of the syntax error.
+func (s *SyntaxError) Line() int { return s.Location.Line }
+
+// Column reports the 0-based column offset of the syntax error.
+func (s *SyntaxError) Column() int { return s.Location.Column }
+
+// FormatError renders a diagnostic for s, given the source text src in which
+// it was reported. The first line of the result gives the location and the
+// message, followed by a snippet of src showing where the error occurred, as
+// described by Snippet.
+func (s *SyntaxError) FormatError(src []byte) string {
+	head := fmt.Sprintf("line %d, column %d: %s", s.Location.Line, s.Location.Column+1, s.Message)
+	if text, marks, ok := snippetLines(src, 0, Span{Pos: s.Offset, End: s.end}); ok {
+		return head + "\n" + text + "\n" + marks
+	}
+	return head
+}
@@ -3,14 +3,19 @@
 package jtree_test
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/creachadair/jtree"
+	"github.com/creachadair/jtree/ast"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestStream(t *testing.T) {
@@ -217,6 +222,39 @@ Value string <"ok">
 	}
 }
 
+func TestValid(t *testing.T) {
+	jwcc := &jtree.ValidOptions{AllowComments: true, AllowTrailingCommas: true}
+	tests := []struct {
+		input string
+		opts  *jtree.ValidOptions
+		want  bool
+	}{
+		{"", nil, false},
+		{"  ", nil, false},
+		{"true", nil, true},
+		{` {"a": [1, 2.5, null], "b": {}} `, nil, true},
+		{`[1, 2`, nil, false},
+		{`[1] [2]`, nil, false},
+		{`"ok" // comment`, nil, false},
+		{`"ok" // comment`, jwcc, true},
+		{`/* x */ [1, 2,]`, jwcc, true},
+		{`{"a": 1,}`, nil, false},
+		{`{"a": 1,}`, jwcc, true},
+		{`[[[]]]`, &jtree.ValidOptions{MaxDepth: 3}, true},
+		{`[[[{}]]]`, &jtree.ValidOptions{MaxDepth: 3}, false},
+		{`[[], [], {}]`, &jtree.ValidOptions{MaxDepth: 2}, true},
+	}
+	for _, test := range tests {
+		ok, err := jtree.ValidBytes([]byte(test.input), test.opts)
+		if ok != test.want {
+			t.Errorf("ValidBytes(%#q): got %v, want %v (err=%v)", test.input, ok, test.want, err)
+		}
+		if ok != (err == nil) {
+			t.Errorf("ValidBytes(%#q): got (%v, %v), error is inconsistent", test.input, ok, err)
+		}
+	}
+}
+
 func diffStrings(want, got string) string {
 	return cmp.Diff(strings.Split(strings.TrimSpace(want), "\n"),
 		strings.Split(strings.TrimSpace(got), "\n"))
@@ -260,3 +298,881 @@ func (t *testHandler) SyntaxError(loc jtree.Anchor, err error) error {
 	t.pr("SyntaxError %v", err)
 	return nil
 }
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  *jtree.ValidOptions
+		want  []string
+	}{
+		{`{"a": [1, 2, {"b": null}], "c": true}`, nil, nil},
+		{``, nil, []string{`at 1:0: empty input`}},
+		{`[1, 2] 3`, nil, []string{`at 1:7: extra input after value`}},
+		{`[1, 2`, nil, []string{`at 1:5: expected "]" or ",", got EOF`}},
+		{`{"a":`, nil, []string{`at 1:5: unexpected EOF`}},
+		{`[1 2, 3,, 4 @, 5]`, nil, []string{
+			`at 1:3: expected "]" or ",", got integer`,
+			`at 1:8: unexpected ","`,
+			`at 1:12: unexpected '@' (offset 13)`,
+		}},
+		{`{"a": 1, "b" 2, "a": 3, "c": [}, "b": 4}`, nil, []string{
+			`at 1:13: expected ":", got integer`,
+			`at 1:16: duplicate key "a" (first at 1:1)`,
+			`at 1:30: unexpected "}"`,
+			`at 1:33: duplicate key "b" (first at 1:9)`,
+		}},
+		{`[1, 2,]`, nil, []string{`at 1:6: unexpected "]" after ","`}},
+		{`[1, 2,] // ok`, &jtree.ValidOptions{AllowComments: true, AllowTrailingCommas: true}, nil},
+		{`[[[]], {"a": [[1]]}]`, &jtree.ValidOptions{MaxDepth: 3}, []string{
+			`at 1:14: nesting depth exceeds 3`,
+		}},
+	}
+	for _, test := range tests {
+		errs := jtree.Check(strings.NewReader(test.input), test.opts)
+		var got []string
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("Check(%#q): errors (-want, +got):\n%s", test.input, diff)
+		}
+		if (errs.Err() == nil) != (len(test.want) == 0) {
+			t.Errorf("Check(%#q): Err() = %v, want error %v", test.input, errs.Err(), len(test.want) != 0)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	const input = `{"a": [1, 2, 3], "b": {"c": "hello", "d": null}}  [true, false]`
+	tests := []struct {
+		n     int
+		want  string
+		trunc bool
+	}{
+		{0, `"__truncated__"`, true},
+		{6, `{"a":["__truncated__"]}`, true},
+		{10, `{"a":[1,2,"__truncated__"]}`, true},
+		{12, `{"a":[1,2,3],"__truncated__":true}`, true},
+		{18, `{"a":[1,2,3],"b":{"__truncated__":true}}`, true},
+		{30, `{"a":[1,2,3],"b":{"c":"hello","__truncated__":true}}`, true},
+		{41, "{\"a\":[1,2,3],\"b\":{\"c\":\"hello\",\"d\":null}}\n\"__truncated__\"", true},
+		{47, "{\"a\":[1,2,3],\"b\":{\"c\":\"hello\",\"d\":null}}\n[true,\"__truncated__\"]", true},
+		{100, "{\"a\":[1,2,3],\"b\":{\"c\":\"hello\",\"d\":null}}\n[true,false]", false},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		trunc, err := jtree.Truncate(&buf, strings.NewReader(input), test.n)
+		if err != nil {
+			t.Errorf("Truncate(%d): unexpected error: %v", test.n, err)
+		}
+		if trunc != test.trunc {
+			t.Errorf("Truncate(%d): got truncated=%v, want %v", test.n, trunc, test.trunc)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("Truncate(%d): got %#q, want %#q", test.n, got, test.want)
+		}
+	}
+
+	t.Run("Scalar", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := jtree.Truncate(&buf, strings.NewReader(`"a long string"`), 5); err != nil {
+			t.Fatalf("Truncate: unexpected error: %v", err)
+		}
+		if got, want := buf.String(), `"__truncated__"`; got != want {
+			t.Errorf("Truncate: got %#q, want %#q", got, want)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := jtree.Truncate(&buf, strings.NewReader(`[1, 2`), 100); err == nil {
+			t.Error("Truncate: got nil, want error")
+		}
+	})
+}
+
+type commentInfoHandler struct {
+	testHandler
+	infos []jtree.CommentInfo
+}
+
+func (c *commentInfoHandler) CommentInfo(loc jtree.Anchor, info jtree.CommentInfo) {
+	c.infos = append(c.infos, info)
+}
+
+func TestCommentInfo(t *testing.T) {
+	const input = `// header
+/* block */
+
+{
+  "a": 1, // trailing
+
+  //   spaced out   
+  "b": /* inline */ 2,
+  /*
+     multi
+     line
+  */
+}
+`
+	st := jtree.NewStream(strings.NewReader(input))
+	st.AllowComments(true)
+	st.AllowTrailingCommas(true)
+	var h commentInfoHandler
+	if err := st.Parse(&h); err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	want := []jtree.CommentInfo{
+		{Kind: jtree.LineComment, Text: "header"},
+		{Kind: jtree.BlockComment, Text: "block"},
+		{Kind: jtree.LineComment, Text: "trailing", Trailing: true},
+		{Kind: jtree.LineComment, Text: "spaced out", AfterBlank: true},
+		{Kind: jtree.BlockComment, Text: "inline", Trailing: true},
+		{Kind: jtree.BlockComment, Text: "multi\n     line"},
+	}
+	if diff := cmp.Diff(want, h.infos); diff != "" {
+		t.Errorf("Comments (-want, +got):\n%s", diff)
+	}
+}
+
+type extHandler struct{ testHandler }
+
+func (e *extHandler) Punct(loc jtree.Anchor) error {
+	e.pr("Punct %s %v", loc.Text(), loc.Location())
+	return nil
+}
+
+func (e *extHandler) TrailingComma(loc jtree.Anchor) error {
+	e.pr("TrailingComma %s %v", loc.Text(), loc.Location())
+	return nil
+}
+
+func TestExtHandler(t *testing.T) {
+	st := jtree.NewStream(strings.NewReader(`{"a": [1, 2,], "b": 3,}`))
+	st.AllowTrailingCommas(true)
+	var h extHandler
+	if err := st.Parse(&h); err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	const want = `
+BeginObject
+BeginMember <"a">
+Punct : 1:4-5
+BeginArray
+Value integer <1>
+Punct , 1:8-9
+Value integer <2>
+Punct , 1:11-12
+TrailingComma , 1:11-12
+EndArray
+Punct , 1:13-14
+EndMember ","
+BeginMember <"b">
+Punct : 1:18-19
+Value integer <3>
+Punct , 1:21-22
+EndMember ","
+TrailingComma , 1:21-22
+EndObject
+.`
+	if diff := diffStrings(want, h.output()); diff != "" {
+		t.Errorf("Parse (-want, +got):\n%s", diff)
+	}
+}
+
+func TestStreamReset(t *testing.T) {
+	st := jtree.NewStream(strings.NewReader(`[1, /* x */ 2,]`))
+	st.AllowComments(true)
+	st.AllowTrailingCommas(true)
+
+	inputs := []string{`{"a": [true]}`, `// c
+  [3, 4,]`, `"x" null`}
+	wants := []string{`
+BeginObject
+BeginMember <"a">
+BeginArray
+Value true <true>
+EndArray
+EndMember "}"
+EndObject
+.`, `
+BeginArray
+Value integer <3>
+Value integer <4>
+EndArray
+.`, `
+Value string <"x">
+Value null <null>
+.`}
+
+	// Parse part of the first input, then discard it.
+	var h testHandler
+	if err := st.ParseOne(&h); err != nil {
+		t.Fatalf("ParseOne: unexpected error: %v", err)
+	}
+	for i, input := range inputs {
+		st.Reset(strings.NewReader(input))
+		var h testHandler
+		if err := st.Parse(&h); err != nil {
+			t.Fatalf("Parse %q: unexpected error: %v", input, err)
+		}
+		if diff := diffStrings(wants[i], h.output()); diff != "" {
+			t.Errorf("Parse %q (-want, +got):\n%s", input, diff)
+		}
+	}
+
+	// Locations restart after a reset.
+	s := jtree.NewScanner(strings.NewReader("\n\n  true"))
+	s.Next()
+	s.Reset(strings.NewReader(" false"))
+	if err := s.Next(); err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	if got, want := s.Location().String(), "1:1-6"; got != want {
+		t.Errorf("Location: got %q, want %q", got, want)
+	}
+
+	// The state of the previous token does not survive a reset.
+	s = jtree.NewScanner(strings.NewReader("key"))
+	s.AllowUnquotedKeys(true)
+	if err := s.Next(); err != nil || !s.Bare() {
+		t.Fatalf("Next: got bare=%v, %v; want bare identifier", s.Bare(), err)
+	}
+	s.Reset(strings.NewReader(`"key"`))
+	if s.Bare() {
+		t.Error("Bare after Reset: got true, want false")
+	}
+
+	// A buffered reader supplied by the caller is not reset.
+	mine := bufio.NewReader(strings.NewReader("1 2 3"))
+	s = jtree.NewScanner(mine)
+	s.Next()
+	s.Reset(strings.NewReader("4"))
+	s.Reset(strings.NewReader("5"))
+	if err := s.Next(); err != nil || string(s.Text()) != "5" {
+		t.Errorf("Next: got %q, %v; want 5", s.Text(), err)
+	}
+	if rest, err := io.ReadAll(mine); err != nil || string(rest) != " 2 3" {
+		t.Errorf("Caller's reader: got %q, %v; want %q", rest, err, " 2 3")
+	}
+}
+
+type skipHandler struct{ testHandler }
+
+func (s *skipHandler) Skipped(loc jtree.Anchor) {
+	s.pr("Skipped %q %v", loc.Text(), loc.Location())
+}
+
+func TestSkipPrefix(t *testing.T) {
+	tests := []struct {
+		input     string
+		bom, junk bool
+		want      string // handler output, or "" for a syntax error
+	}{
+		{"\ufeff[1]", false, false, ""},
+		{"\ufeff[1]", true, false, `Skipped "\ufeff" 1:0-3 BeginArray Value integer <1> EndArray .`},
+		{"[1]", true, false, `BeginArray Value integer <1> EndArray .`},
+		{"\ufeff", true, false, `Skipped "\ufeff" 1:0-3 .`},
+		{"2024-01-01 INFO {}", true, false, ""},
+		{"2024-01-01 INFO {}", false, true, `Skipped "2024-01-01 INFO " 1:0-16 BeginObject EndObject .`},
+		{"\ufeffstart:\n  [] {}", true, true, `Skipped "\ufeffstart:\n  " 1:0-2:2 BeginArray EndArray BeginObject EndObject .`},
+		{"[true] junk", false, true, ""}, // only leading input is skipped
+		{"nothing here", false, true, `Skipped "nothing here" 1:0-12 .`},
+	}
+	for _, test := range tests {
+		st := jtree.NewStreamBytes([]byte(test.input))
+		st.AllowBOM(test.bom)
+		st.SkipUntilValue(test.junk)
+		var h skipHandler
+		err := st.Parse(&h)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("Parse %q: got %q, want error", test.input, h.output())
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("Parse %q: unexpected error: %v", test.input, err)
+			continue
+		}
+		got := strings.Join(strings.Fields(h.output()), " ")
+		want := strings.Join(strings.Fields(test.want), " ")
+		if got != want {
+			t.Errorf("Parse %q:\ngot:  %s\nwant: %s", test.input, got, want)
+		}
+	}
+
+	// The scanner reports the skipped input, and the following token is
+	// located after it.
+	s := jtree.NewScanner(strings.NewReader("log: 1\nlog: {}"))
+	s.SkipUntilValue(true)
+	if s.Skipped() != nil {
+		t.Error("Skipped before Next: got an anchor, want nil")
+	}
+	if err := s.Next(); err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	if got := string(s.Skipped().Text()); got != "log: 1\nlog: " {
+		t.Errorf("Skipped: got %q, want %q", got, "log: 1\nlog: ")
+	}
+	if got, want := s.Location().String(), "2:5-6"; got != want {
+		t.Errorf("Location: got %s, want %s", got, want)
+	}
+}
+
+func TestUnquotedKeys(t *testing.T) {
+	const input = `{acls: [], $x_1: {null: true, Infinity: 2}, "q": 3}`
+	st := jtree.NewStream(strings.NewReader(input))
+	st.AllowUnquotedKeys(true)
+	var h testHandler
+	if err := st.Parse(&h); err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	const want = `
+BeginObject
+BeginMember <acls>
+BeginArray
+EndArray
+EndMember ","
+BeginMember <$x_1>
+BeginObject
+BeginMember <null>
+Value true <true>
+EndMember ","
+BeginMember <Infinity>
+Value integer <2>
+EndMember "}"
+EndObject
+EndMember ","
+BeginMember <"q">
+Value integer <3>
+EndMember "}"
+EndObject
+.`
+	if diff := diffStrings(want, h.output()); diff != "" {
+		t.Errorf("Parse (-want, +got):\n%s", diff)
+	}
+
+	for _, bad := range []string{`{a: b}`, `[a]`, `{"a": Infinity}`, `{a b: 1}`, `{a: 1}x`} {
+		st := jtree.NewStream(strings.NewReader(bad))
+		st.AllowUnquotedKeys(true)
+		if err := st.Parse(&testHandler{}); err == nil {
+			t.Errorf("Parse %#q: got nil, want error", bad)
+		}
+	}
+	if err := jtree.NewStream(strings.NewReader(`{a: 1}`)).Parse(&testHandler{}); err == nil {
+		t.Error("Parse without AllowUnquotedKeys: got nil, want error")
+	}
+
+	// The scanner flags bare strings.
+	s := jtree.NewScanner(strings.NewReader(`key "key"`))
+	s.AllowUnquotedKeys(true)
+	for _, want := range []bool{true, false} {
+		if err := s.Next(); err != nil {
+			t.Fatalf("Next: unexpected error: %v", err)
+		}
+		if got := s.Bare(); got != want {
+			t.Errorf("Bare %s: got %v, want %v", s.Text(), got, want)
+		}
+		if u, err := s.Unquote(); err != nil || string(u) != "key" {
+			t.Errorf("Unquote %s: got %q, %v; want key", s.Text(), u, err)
+		}
+	}
+}
+
+type docHandler struct{ testHandler }
+
+func (d *docHandler) BeginDocument(loc jtree.Anchor) error {
+	d.pr("BeginDocument %s", loc.Location().First)
+	return nil
+}
+
+func (d *docHandler) EndDocument(loc jtree.Anchor) error {
+	d.pr("EndDocument %s", loc.Location().Last)
+	return nil
+}
+
+func TestDocuments(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		const input = "{\"a\": 1}\n[true]\n\"ok\""
+		const want = `
+BeginDocument 1:0
+BeginObject
+BeginMember <"a">
+Value integer <1>
+EndMember "}"
+EndObject
+EndDocument 1:8
+---
+BeginDocument 2:0
+BeginArray
+Value true <true>
+EndArray
+EndDocument 2:6
+---
+BeginDocument 3:0
+Value string <"ok">
+EndDocument 3:4
+---
+.`
+		h := new(docHandler)
+		st := jtree.NewStream(strings.NewReader(input))
+		for err := range st.Documents(h) {
+			if err != nil {
+				t.Fatalf("Documents failed: %v", err)
+			}
+			h.pr("---")
+		}
+		if diff := diffStrings(want, h.output()); diff != "" {
+			t.Errorf("Input: %#q\nOutput: (-want, +got)\n%s", input, diff)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		st := jtree.NewStream(strings.NewReader(`[1] [2 {} 3`))
+		var errs []error
+		for err := range st.Documents(new(testHandler)) {
+			errs = append(errs, err)
+		}
+		if len(errs) != 2 || errs[0] != nil || errs[1] == nil {
+			t.Errorf("Documents: got %v, want [nil, error]", errs)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		st := jtree.NewStream(strings.NewReader(`1 2 3`))
+		h := new(testHandler)
+		for err := range st.Documents(h) {
+			if err != nil {
+				t.Fatalf("Documents failed: %v", err)
+			}
+			break
+		}
+		if err := st.ParseOne(h); err != nil {
+			t.Fatalf("ParseOne failed: %v", err)
+		}
+		if diff := diffStrings("Value integer <1>\nValue integer <2>", h.output()); diff != "" {
+			t.Errorf("Output: (-want, +got)\n%s", diff)
+		}
+	})
+}
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   jtree.SyntaxError
+		lexErr bool
+	}{
+		{`{"a" 1}`, jtree.SyntaxError{
+			Location: jtree.LineCol{Line: 1, Column: 5}, Offset: 5,
+			Token: jtree.Integer, Expected: []jtree.Token{jtree.Colon},
+		}, false},
+		{"[1,\n  2 3]", jtree.SyntaxError{
+			Location: jtree.LineCol{Line: 2, Column: 4}, Offset: 8,
+			Token: jtree.Integer, Expected: []jtree.Token{jtree.RSquare, jtree.Comma},
+		}, false},
+		{`{`, jtree.SyntaxError{
+			Location: jtree.LineCol{Line: 1, Column: 1}, Offset: 1,
+			Token: jtree.Invalid, Expected: []jtree.Token{jtree.RBrace, jtree.String},
+		}, true},
+		{`]`, jtree.SyntaxError{
+			Location: jtree.LineCol{Line: 1, Column: 0}, Offset: 0,
+			Token: jtree.RSquare,
+		}, false},
+		{`[nope]`, jtree.SyntaxError{
+			Location: jtree.LineCol{Line: 1, Column: 1}, Offset: 1,
+			Token: jtree.Invalid,
+		}, true},
+	}
+	opt := cmp.Comparer(func(a, b jtree.SyntaxError) bool {
+		return a.Location == b.Location && a.Offset == b.Offset && a.Token == b.Token &&
+			cmp.Equal(a.Expected, b.Expected)
+	})
+	for _, test := range tests {
+		err := jtree.NewStream(strings.NewReader(test.input)).Parse(new(testHandler))
+		var serr *jtree.SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("Parse %#q: got error %v, want *SyntaxError", test.input, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, *serr, opt); diff != "" {
+			t.Errorf("Parse %#q: error (-want, +got):\n%s", test.input, diff)
+		}
+		if serr.Line() != serr.Location.Line || serr.Column() != serr.Location.Column {
+			t.Errorf("Parse %#q: Line, Column = %d, %d; want %v", test.input, serr.Line(), serr.Column(), serr.Location)
+		}
+		if got := errors.Unwrap(err) != nil; got != test.lexErr {
+			t.Errorf("Parse %#q: has cause = %v, want %v", test.input, got, test.lexErr)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	src := []byte("{\n  \"key\": tru,\r\n\t\"x\": 1}")
+	tests := []struct {
+		offset int
+		loc    jtree.Location
+		want   string
+	}{
+		{0, jtree.Location{Span: jtree.Span{Pos: 11, End: 14}, First: jtree.LineCol{Line: 2, Column: 9}},
+			"line 2, column 10:\n  \"key\": tru,\n         ^^^"},
+		{0, jtree.Location{Span: jtree.Span{Pos: 23, End: 24}, First: jtree.LineCol{Line: 3, Column: 6}},
+			"line 3, column 7:\n\t\"x\": 1}\n\t     ^"},
+		{0, jtree.Location{Span: jtree.Span{Pos: 0, End: 25}, First: jtree.LineCol{Line: 1, Column: 0}},
+			"line 1, column 1:\n{\n^"},
+		{10, jtree.Location{Span: jtree.Span{Pos: 11, End: 14}, First: jtree.LineCol{Line: 2, Column: 9}},
+			"line 2, column 10:\n tru,\n ^^^"},
+		{0, jtree.Location{Span: jtree.Span{Pos: 100}, First: jtree.LineCol{Line: 9, Column: 0}},
+			"line 9, column 1"},
+	}
+	for _, test := range tests {
+		if got := jtree.Snippet(src[test.offset:], test.offset, test.loc); got != test.want {
+			t.Errorf("Snippet(%d, %v):\ngot:\n%s\nwant:\n%s", test.offset, test.loc, got, test.want)
+		}
+	}
+
+	err := jtree.NewStream(bytes.NewReader(src)).Parse(new(testHandler))
+	const want = "line 2, column 10: unknown constant \"tru\" (offset 14)\n  \"key\": tru,\n         ^^^"
+	if got := err.(*jtree.SyntaxError).FormatError(src); got != want {
+		t.Errorf("FormatError:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	const input = `{
+  "config": {"version": "1.2", "a/b": [10, {"x~y": null}, 30]},
+  "list": [ {"n": 1}, {"n": 2} ],
+  "config": {"version": "dup"}
+}`
+	tests := []struct {
+		paths []string
+		want  []string // "" for nil
+	}{
+		{nil, nil},
+		{[]string{"/config/version"}, []string{`"1.2"`}},
+		{[]string{"/list/1", "/list/1/n", "/nope", "/list/0/n"}, []string{`{"n": 2}`, `2`, ``, `1`}},
+		{[]string{"/config/a~1b/1/x~0y", "/config/a~1b"}, []string{`null`, `[10, {"x~y": null}, 30]`}},
+		{[]string{"/list/2", "/config/version/0"}, []string{``, ``}},
+		{[]string{"/list", "/list"}, []string{`[ {"n": 1}, {"n": 2} ]`, `[ {"n": 1}, {"n": 2} ]`}},
+		{[]string{""}, []string{input}},
+	}
+	for _, test := range tests {
+		got, err := jtree.Extract(strings.NewReader(input), test.paths...)
+		if err != nil {
+			t.Errorf("Extract %q: unexpected error: %v", test.paths, err)
+			continue
+		}
+		gs := make([]string, len(got))
+		for i, g := range got {
+			gs[i] = string(g)
+		}
+		if diff := cmp.Diff(test.want, gs, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("Extract %q: (-want, +got):\n%s", test.paths, diff)
+		}
+	}
+
+	// Extract stops reading once all the paths are found.
+	r := io.MultiReader(strings.NewReader(`{"version": 3, "rest": `), iotest.ErrReader(errors.New("read too far")))
+	if got, err := jtree.Extract(r, "/version"); err != nil || string(got[0]) != "3" {
+		t.Errorf("Extract: got %q, %v; want 3, nil", got, err)
+	}
+
+	if _, err := jtree.Extract(strings.NewReader(`{"a": [1, 2}`), "/b"); err == nil {
+		t.Error("Extract: got nil, want syntax error")
+	}
+	if _, err := jtree.Extract(strings.NewReader(`{}`), "a"); err == nil {
+		t.Error("Extract: got nil, want invalid pointer error")
+	}
+}
+
+func TestTeeHandler(t *testing.T) {
+	const input = `[1, /* two */ {"a": 2},]`
+	var h1 testHandler
+	var h2 commentInfoHandler
+	var h3 extHandler
+	st := jtree.NewStream(strings.NewReader(input))
+	st.AllowComments(true)
+	st.AllowTrailingCommas(true)
+	if err := st.Parse(jtree.TeeHandler(&h1, &h2, &h3)); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	const want = `
+BeginArray
+Value integer <1>
+BeginObject
+BeginMember <"a">
+Value integer <2>
+EndMember "}"
+EndObject
+EndArray
+.`
+	if diff := diffStrings(want, h1.output()); diff != "" {
+		t.Errorf("Handler 1 (-want, +got):\n%s", diff)
+	}
+	if len(h2.infos) != 1 || h2.infos[0].Text != "two" {
+		t.Errorf("Handler 2 comments: got %+v, want one comment", h2.infos)
+	}
+	if got := h3.output(); !strings.Contains(got, "Punct") || !strings.Contains(got, "TrailingComma") {
+		t.Errorf("Handler 3: got %q, want Punct and TrailingComma events", got)
+	}
+
+	// An error from one handler stops delivery to the rest.
+	var h4 testHandler
+	fail := errors.New("stop")
+	err := jtree.NewStream(strings.NewReader(input)).Parse(jtree.TeeHandler(failHandler{fail}, &h4))
+	if !errors.Is(err, fail) || h4.output() != "" {
+		t.Errorf("Parse: got %v, output %q; want %v, no output", err, h4.output(), fail)
+	}
+}
+
+type failHandler struct{ err error }
+
+func (f failHandler) BeginObject(jtree.Anchor) error { return f.err }
+func (f failHandler) EndObject(jtree.Anchor) error   { return f.err }
+func (f failHandler) BeginArray(jtree.Anchor) error  { return f.err }
+func (f failHandler) EndArray(jtree.Anchor) error    { return f.err }
+func (f failHandler) BeginMember(jtree.Anchor) error { return f.err }
+func (f failHandler) EndMember(jtree.Anchor) error   { return f.err }
+func (f failHandler) Value(jtree.Anchor) error       { return f.err }
+func (f failHandler) EndOfInput(jtree.Anchor)        {}
+
+func TestFilterHandler(t *testing.T) {
+	const input = `{"items": [{"n": 1}, 2, [3]], "other": {"items": [4]}} {"items": [5]}`
+	var paths []string
+	var b ast.Builder
+	h := jtree.FilterHandler(func(path []any) bool {
+		paths = append(paths, fmt.Sprint(path))
+		return len(path) == 2 && path[0] == "items"
+	}, &b)
+	if err := jtree.NewStream(strings.NewReader(input)).Parse(h); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	vs, err := b.Result()
+	if err != nil {
+		t.Fatalf("Result failed: %v", err)
+	}
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.JSON())
+	}
+	if diff := cmp.Diff([]string{`{"n":1}`, `2`, `[3]`, `5`}, got); diff != "" {
+		t.Errorf("Selected values (-want, +got):\n%s", diff)
+	}
+	wantPaths := []string{
+		"[]", "[items]", "[items 0]", "[items 1]", "[items 2]",
+		"[other]", "[other items]", "[other items 0]",
+		"[]", "[items]", "[items 0]",
+	}
+	if diff := cmp.Diff(wantPaths, paths); diff != "" {
+		t.Errorf("Paths (-want, +got):\n%s", diff)
+	}
+}
+
+func TestDepthLimitHandler(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		ok    bool
+	}{
+		{`1`, true},
+		{`[[], {"a": [1]}]`, true},
+		{`[{"a": [[1]]}]`, false},
+		{`[[[ ]]] [[[[]]]]`, false},
+	} {
+		var th testHandler
+		err := jtree.NewStream(strings.NewReader(test.input)).Parse(jtree.DepthLimitHandler(3, &th))
+		if test.ok && err != nil {
+			t.Errorf("Parse %#q: unexpected error: %v", test.input, err)
+		} else if !test.ok && !errors.Is(err, jtree.ErrDepthLimit) {
+			t.Errorf("Parse %#q: got %v, want %v", test.input, err, jtree.ErrDepthLimit)
+		}
+	}
+}
+
+type valueCounter struct {
+	jtree.NopHandler
+	n int
+}
+
+func (c *valueCounter) Value(jtree.Anchor) error { c.n++; return nil }
+
+func TestNopHandler(t *testing.T) {
+	var c valueCounter
+	if err := jtree.NewStream(strings.NewReader(`{"a": [1, true, null], "b": {}} "x"`)).Parse(&c); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if c.n != 4 {
+		t.Errorf("Count: got %d values, want 4", c.n)
+	}
+}
+
+func TestValueHandler(t *testing.T) {
+	const input = `{"a": [1, {"b!": true}, []], "c": {}, "d": null, "e": "x"}`
+
+	var got []string
+	h := jtree.ValueHandler(func(path []any, loc jtree.Anchor) error {
+		got = append(got, fmt.Sprintf("%v %s", path, loc.Text()))
+		return nil
+	})
+	if err := jtree.NewStream(strings.NewReader(input)).Parse(h); err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	want := []string{`[a 0] 1`, `[a 1 b!] true`, `[d] null`, `[e] "x"`}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Values (-want, +got):\n%s", diff)
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		errStop := errors.New("stop")
+		var n int
+		h := jtree.ValueHandler(func([]any, jtree.Anchor) error {
+			if n++; n == 2 {
+				return errStop
+			}
+			return nil
+		})
+		err := jtree.NewStream(strings.NewReader(input)).Parse(h)
+		if !errors.Is(err, errStop) {
+			t.Errorf("Parse: got error %v, want %v", err, errStop)
+		}
+		if n != 2 {
+			t.Errorf("Got %d calls, want 2", n)
+		}
+	})
+}
+
+// rawHandler is a jtree.RawHandler that captures the text of each object and
+// array.
+type rawHandler struct {
+	jtree.NopHandler
+	got []string
+}
+
+func (r *rawHandler) CaptureRaw(loc jtree.Anchor) bool {
+	return loc.Token() == jtree.LBrace || loc.Token() == jtree.LSquare
+}
+
+func (r *rawHandler) RawValue(loc jtree.Anchor) error {
+	r.got = append(r.got, fmt.Sprintf("%v %v %s", loc.Token(), loc.Location(), loc.Text()))
+	return nil
+}
+
+func TestRawHandler(t *testing.T) {
+	const input = `{"a": [1, /* two */ 2],
+  "b": {"c" : {}}} 3 [ "x" ]`
+	want := []string{
+		`"[" 1:6-22 [1, /* two */ 2]`,
+		`"{" 2:14-16 {}`,
+		`"{" 2:7-17 {"c" : {}}`,
+		"\"{\" 1:0-2:18 {\"a\": [1, /* two */ 2],\n  \"b\": {\"c\" : {}}}",
+		`"[" 2:21-28 [ "x" ]`,
+	}
+	for _, tc := range []struct {
+		name string
+		st   *jtree.Stream
+	}{
+		{"Reader", jtree.NewStream(iotest.OneByteReader(strings.NewReader(input)))},
+		{"Bytes", jtree.NewStreamBytes([]byte(input))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var h rawHandler
+			tc.st.AllowComments(true)
+			if err := tc.st.Parse(&h); err != nil {
+				t.Fatalf("Parse: unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(want, h.got); diff != "" {
+				t.Errorf("Raw values (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Retain", func(t *testing.T) {
+		// The text of each value remains valid after later values are read.
+		var h retainHandler
+		st := jtree.NewStream(strings.NewReader(`[{"aaaaaaaaa": 1}, {"b": 2}] {"c": [3]}`))
+		if err := st.Parse(&h); err != nil {
+			t.Fatalf("Parse: unexpected error: %v", err)
+		}
+		var got []string
+		for _, text := range h.texts {
+			got = append(got, string(text))
+		}
+		want := []string{`{"aaaaaaaaa": 1}`, `{"b": 2}`, `{"c": [3]}`}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Retained values (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+// retainHandler is a jtree.RawHandler that retains the text of each object
+// without copying it.
+type retainHandler struct {
+	jtree.NopHandler
+	texts [][]byte
+}
+
+func (r *retainHandler) CaptureRaw(loc jtree.Anchor) bool { return loc.Token() == jtree.LBrace }
+
+func (r *retainHandler) RawValue(loc jtree.Anchor) error {
+	r.texts = append(r.texts, loc.Text())
+	return nil
+}
+
+// wrapAnchor is an Anchor that provides only the methods of the interface, as
+// a wrapper defined outside the package would.
+type wrapAnchor struct{ jtree.Anchor }
+
+type decodeHandler struct {
+	jtree.NopHandler
+	got []any
+}
+
+func (d *decodeHandler) Value(loc jtree.Anchor) error {
+	a := wrapAnchor{loc}
+	var v any
+	var err error
+	switch loc.Token() {
+	case jtree.Integer:
+		v, err = jtree.AnchorInt64(a)
+	case jtree.Number:
+		v, err = jtree.AnchorFloat64(a)
+	case jtree.True, jtree.False:
+		v, err = jtree.AnchorBool(a)
+	case jtree.String:
+		var s []byte
+		s, err = jtree.AnchorUnquote(a)
+		v = string(s)
+	}
+	if err != nil {
+		return err
+	}
+	d.got = append(d.got, v)
+	return nil
+}
+
+func TestAnchorDecode(t *testing.T) {
+	var h decodeHandler
+	if err := jtree.NewStream(strings.NewReader(`[-15, 2.5, true, false, "a\tb"]`)).Parse(&h); err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	want := []any{int64(-15), 2.5, true, false, "a\tb"}
+	if diff := cmp.Diff(want, h.got); diff != "" {
+		t.Errorf("Decoded values (-want, +got):\n%s", diff)
+	}
+
+	a := wrapAnchor{jtree.NewScanner(strings.NewReader(`"x"`))}
+	if _, err := jtree.AnchorInt64(a); err == nil {
+		t.Error("AnchorInt64: got nil, want error")
+	}
+	if _, err := jtree.AnchorFloat64(a); err == nil {
+		t.Error("AnchorFloat64: got nil, want error")
+	}
+	if _, err := jtree.AnchorBool(a); err == nil {
+		t.Error("AnchorBool: got nil, want error")
+	}
+
+	// An anchor without a Bytes method falls back to Copy.
+	s := jtree.NewScanner(strings.NewReader(`"x"`))
+	s.Next()
+	if got := string(jtree.AnchorBytes(wrapAnchor{s})); got != `"x"` {
+		t.Errorf("AnchorBytes: got %#q, want %#q", got, `"x"`)
+	}
+}
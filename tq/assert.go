@@ -0,0 +1,165 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package tq
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/creachadair/jtree/ast"
+)
+
+// Require returns its input if it has the structure described by shape;
+// otherwise it fails with an error describing the first mismatch found. If
+// the mismatch is nested within the input, the error is an *EvalError whose
+// path locates it. A shape is one of:
+//
+//   - A string naming a type, as reported by TypeOf, or "any" for a value of
+//     any type. Alternatives are separated by "|", for example "string|null".
+//   - A map[string]any, for an object having each of the keys of the map,
+//     whose values have the corresponding shapes. A key ending in "?" is
+//     optional; to require a key that ends in "?", double the "?". Members
+//     not named by the map are permitted.
+//   - A []any with one element, for an array whose elements all have that
+//     shape, or an empty []any for an array of any elements.
+//   - A Query, for a value on which the query succeeds.
+//
+// For example:
+//
+//	tq.Require(map[string]any{
+//	   "name":  "string",
+//	   "port?": tq.Check(tq.Match(func(z ast.Number) bool { return z.Float() > 0 }), "port must be positive"),
+//	   "tags":  []any{"string"},
+//	})
+//
+// Require panics if shape is not valid.
+func Require(shape any) Query { return compileShape(shape) }
+
+// Check returns its input if pred succeeds on the input and yields a value
+// other than false or null; otherwise it fails with an error reporting msg.
+func Check(pred Query, msg string) Query { return checkQuery{pred, msg} }
+
+type checkQuery struct {
+	pred Query
+	msg  string
+}
+
+func (c checkQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	_, w, err := qs.eval(c.pred, v)
+	if err != nil {
+		return qs, nil, fmt.Errorf("%s: %w", c.msg, err)
+	} else if p := plain(w); p == ast.Null || p == ast.Bool(false) {
+		return qs, nil, errors.New(c.msg)
+	}
+	return qs, v, nil
+}
+
+// typeShape requires its input to have one of the named types. An empty
+// typeShape permits any type.
+type typeShape []string
+
+func (t typeShape) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if len(t) == 0 {
+		return qs, v, nil
+	}
+	_, name, err := typeQuery{}.eval(qs, v)
+	if err != nil {
+		return qs, nil, err
+	} else if !slices.Contains(t, name.String()) {
+		return qs, nil, fmt.Errorf("got %s, want %s", name, strings.Join(t, " or "))
+	}
+	return qs, v, nil
+}
+
+// objectShape requires its input to be an object with the specified members.
+type objectShape []shapeKey
+
+type shapeKey struct {
+	name     string
+	optional bool
+	shape    Query
+}
+
+func (o objectShape) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if _, _, err := (typeShape{"object"}).eval(qs, v); err != nil {
+		return qs, nil, err
+	}
+	for _, k := range o {
+		_, w, err := exactKey(k.name).eval(qs, v)
+		if err != nil {
+			if k.optional {
+				continue
+			}
+			return qs, nil, fmt.Errorf("missing required key %q", k.name)
+		}
+		if _, _, err := qs.eval(k.shape, w); err != nil {
+			return qs, nil, wrapPath(err, k.name)
+		}
+	}
+	return qs, v, nil
+}
+
+// arrayShape requires its input to be an array whose elements have the
+// specified shape, or any shape if elem == nil.
+type arrayShape struct{ elem Query }
+
+func (a arrayShape) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	elems, ok := arrayElems(v)
+	if !ok {
+		_, _, err := (typeShape{"array"}).eval(qs, v)
+		return qs, nil, err
+	}
+	if a.elem != nil {
+		for i, elt := range elems {
+			if _, _, err := qs.eval(a.elem, elt); err != nil {
+				return qs, nil, wrapPath(err, i)
+			}
+		}
+	}
+	return qs, v, nil
+}
+
+var shapeTypes = []string{"object", "array", "string", "number", "boolean", "null"}
+
+// compileShape returns a query for the specified shape, as described by
+// Require. It panics if shape is not valid.
+func compileShape(shape any) Query {
+	switch t := shape.(type) {
+	case string:
+		var out typeShape
+		for _, name := range strings.Split(t, "|") {
+			if name == "any" {
+				return typeShape(nil)
+			} else if !slices.Contains(shapeTypes, name) {
+				panic(fmt.Sprintf("invalid shape type %q", name))
+			}
+			out = append(out, name)
+		}
+		return out
+	case map[string]any:
+		out := make(objectShape, 0, len(t))
+		for key, sub := range t {
+			k := shapeKey{name: key, shape: compileShape(sub)}
+			if base, ok := strings.CutSuffix(key, "??"); ok {
+				k.name = base + "?"
+			} else if base, ok := strings.CutSuffix(key, "?"); ok {
+				k.name, k.optional = base, true
+			}
+			out = append(out, k)
+		}
+		slices.SortFunc(out, func(a, b shapeKey) int { return strings.Compare(a.name, b.name) })
+		return out
+	case []any:
+		switch len(t) {
+		case 0:
+			return arrayShape{}
+		case 1:
+			return arrayShape{compileShape(t[0])}
+		}
+	case Query:
+		return t
+	}
+	panic(fmt.Sprintf("invalid shape %T", shape))
+}
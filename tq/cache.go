@@ -0,0 +1,173 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package tq
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+
+	"github.com/creachadair/jtree/ast"
+)
+
+// A Cache memoizes the results of evaluating compiled queries, so that
+// repeatedly evaluating the same query on the same value does not repeat the
+// work. A zero Cache is ready for use. A Cache is safe for concurrent use by
+// multiple goroutines; if several goroutines request the same result at once,
+// the query is evaluated only once and the others wait for its result.
+//
+// Results are shared among all callers that request them, and must not be
+// modified. Likewise, a value must not be modified while it has results in the
+// cache, unless the cache is told about the change by calling Invalidate or
+// Reset, or by using a Key that reflects the change.
+//
+// The cache holds at most MaxEntries results, discarding the least recently
+// used result to make room for a new one. A memoized result keeps its root
+// value reachable, so that the memory of a root is not reused for another
+// value while the cache holds its results.
+type Cache struct {
+	// Key, if non-nil, returns the key used to identify a root value in the
+	// cache, for example a content hash or a version number, which must be
+	// comparable. By default, values are identified by their location in
+	// memory, which does not detect modifications.
+	Key func(ast.Value) any
+
+	// MaxEntries is the maximum number of results the cache holds. If it is
+	// zero, DefaultCacheSize is used. If it is negative, the number of results
+	// is not limited.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element // values are *cacheEntry
+	lru     list.List                  // entries, most recently used first
+}
+
+// DefaultCacheSize is the maximum number of results held by a Cache whose
+// MaxEntries field is zero.
+const DefaultCacheSize = 1024
+
+type cacheKey struct {
+	root any
+	q    *Compiled
+}
+
+type cacheEntry struct {
+	key   cacheKey
+	ready chan struct{} // closed when the result is available
+	value ast.Value
+	err   error
+}
+
+// Eval evaluates q beginning from root, as Eval does, or returns a result
+// previously memoized in c for the same root and query.  Errors are memoized
+// along with successful results.
+func (c *Cache) Eval(root ast.Value, q *Compiled) (ast.Value, error) {
+	key := cacheKey{root: c.rootKey(root), q: q}
+
+	c.mu.Lock()
+	if elt, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elt)
+		c.mu.Unlock()
+		e := elt.Value.(*cacheEntry)
+		<-e.ready
+		return e.value, e.err
+	}
+	if c.entries == nil {
+		c.entries = make(map[cacheKey]*list.Element)
+	}
+	e := &cacheEntry{key: key, ready: make(chan struct{})}
+	c.entries[key] = c.lru.PushFront(e)
+	if n := c.maxEntries(); n > 0 {
+		for c.lru.Len() > n {
+			c.remove(c.lru.Back())
+		}
+	}
+	c.mu.Unlock()
+
+	defer close(e.ready)
+	e.value, e.err = Eval[ast.Value](root, q)
+	return e.value, e.err
+}
+
+// Invalidate discards all the results memoized in c for root.
+func (c *Cache) Invalidate(root ast.Value) {
+	id := c.rootKey(root)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elt := range c.entries {
+		if key.root == id {
+			c.remove(elt)
+		}
+	}
+}
+
+// Reset discards all the results memoized in c.
+func (c *Cache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+	c.lru.Init()
+}
+
+// Len reports the number of results memoized in c.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// maxEntries returns the maximum number of results c may hold, or 0 if the
+// number is not limited.
+func (c *Cache) maxEntries() int {
+	switch {
+	case c.MaxEntries == 0:
+		return DefaultCacheSize
+	case c.MaxEntries < 0:
+		return 0
+	}
+	return c.MaxEntries
+}
+
+// remove discards the result at elt. The caller must hold c.mu.
+func (c *Cache) remove(elt *list.Element) {
+	delete(c.entries, elt.Value.(*cacheEntry).key)
+	c.lru.Remove(elt)
+}
+
+// rootKey returns the key identifying v in c.
+func (c *Cache) rootKey(v ast.Value) any {
+	if c.Key != nil {
+		return c.Key(v)
+	}
+	return valueID(v)
+}
+
+// A sliceID identifies a slice by the location and length of its contents.
+// It holds a pointer to the contents, so that their memory is not reused for
+// another slice while the sliceID is in use.
+type sliceID[T any] struct {
+	first *T // the first element, or nil if the slice is empty
+	len   int
+}
+
+func newSliceID[T any](s []T) sliceID[T] {
+	if len(s) == 0 {
+		return sliceID[T]{}
+	}
+	return sliceID[T]{first: &s[0], len: len(s)}
+}
+
+// valueID returns a comparable value identifying v by its location in memory,
+// or by its contents if v is a scalar value.
+func valueID(v ast.Value) any {
+	switch t := v.(type) {
+	case ast.Object:
+		return newSliceID(t)
+	case ast.Array:
+		return newSliceID(t)
+	}
+	if v == nil || reflect.TypeOf(v).Comparable() {
+		return v // pointers and scalars
+	}
+	return v.JSON()
+}
@@ -0,0 +1,341 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package tq
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/creachadair/jtree/ast"
+)
+
+// A Compiled is a query that has been checked and simplified by Compile.
+// A Compiled is itself a Query, and may be evaluated any number of times,
+// or used as a subquery of other queries.
+type Compiled struct{ q Query }
+
+func (c *Compiled) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	return qs.eval(c.q, v)
+}
+
+// Compile checks q for errors and returns a simplified query that is
+// equivalent to q when evaluated by Eval, although the text of the errors it
+// reports may differ.
+//
+// Compile reports an error if q uses a parameter (see Get) that is not bound
+// by an earlier As on every path to the use. The names of parameters that the
+// caller will bind in the environment (see Env.Bind) may be given as params.
+// The parameter "$", which Eval binds to the root value, is always defined.
+// Parameters used after a Func or a Call are not checked, since these may
+// modify the environment arbitrarily.
+//
+// The result is simplified by flattening nested paths and pre-computing
+// subqueries whose values do not depend on their input, such as Array and
+// Object queries composed of Value queries.
+func Compile(q Query, params ...string) (*Compiled, error) {
+	sc := scope{names: map[string]bool{"$": true}}
+	for _, p := range params {
+		base, _ := splitMark(p)
+		sc.names[base] = true
+	}
+	var c checker
+	c.check(q, sc)
+	if err := errors.Join(c.errs...); err != nil {
+		return nil, err
+	}
+	return &Compiled{q: fold(q)}, nil
+}
+
+// A scope records the parameter names that are bound at a point in a query.
+type scope struct {
+	names map[string]bool
+	open  bool // any name may be bound
+}
+
+func (s scope) has(name string) bool { return s.open || s.names[name] }
+
+func (s scope) with(names ...string) scope {
+	out := scope{names: make(map[string]bool, len(s.names)+len(names)), open: s.open}
+	for name := range s.names {
+		out.names[name] = true
+	}
+	for _, name := range names {
+		out.names[name] = true
+	}
+	return out
+}
+
+// union returns a scope in which the names bound in either s or t are bound.
+func (s scope) union(t scope) scope {
+	out := s.with()
+	for name := range t.names {
+		out.names[name] = true
+	}
+	out.open = s.open || t.open
+	return out
+}
+
+// intersect returns a scope in which only the names bound in both s and t are
+// bound.
+func (s scope) intersect(t scope) scope {
+	out := scope{names: make(map[string]bool), open: s.open && t.open}
+	for name := range s.names {
+		if t.has(name) {
+			out.names[name] = true
+		}
+	}
+	for name := range t.names {
+		if s.has(name) {
+			out.names[name] = true
+		}
+	}
+	return out
+}
+
+// checker accumulates errors found while checking a query.
+type checker struct {
+	errs []error
+	seen map[string]bool // parameters already reported
+}
+
+// check checks q in the scope sc, and returns the scope following q.
+func (c *checker) check(q Query, sc scope) scope {
+	switch t := q.(type) {
+	case getQuery:
+		if !sc.has(t.name) && !c.seen[t.name] {
+			if c.seen == nil {
+				c.seen = make(map[string]bool)
+			}
+			c.seen[t.name] = true
+			c.errs = append(c.errs, fmt.Errorf("parameter %q is not bound", t.name))
+		}
+	case asQuery:
+		c.check(t.q, sc)
+		return sc.with(t.name)
+	case seqQuery:
+		for _, sq := range t {
+			sc = c.check(sq, sc)
+		}
+	case Alt:
+		// A name is bound after an Alt only if every alternative binds it.
+		if len(t) == 0 {
+			return sc
+		}
+		out := c.check(t[0], sc)
+		for _, alt := range t[1:] {
+			out = out.intersect(c.check(alt, sc))
+		}
+		return out
+	case Func, callQuery:
+		return scope{names: sc.names, open: true}
+	case *Compiled:
+		return c.check(t.q, sc)
+	case scopeQuery:
+		c.check(t.Query, sc)
+	case unbindQuery:
+		out := sc.with()
+		delete(out.names, t.name)
+		return out
+	case Object:
+		for _, sq := range t {
+			c.check(sq, sc)
+		}
+	case Array:
+		for _, sq := range t {
+			c.check(sq, sc)
+		}
+	case mergeQuery:
+		for _, sq := range t.qs {
+			c.check(sq, sc)
+		}
+	case recQuery:
+		// A binding made while visiting one value is visible when visiting its
+		// descendants.
+		var inner checker
+		c.check(t.Query, sc.union(inner.check(t.Query, scope{open: sc.open})))
+	case eachQuery:
+		c.check(t.Query, sc)
+	case selectQuery:
+		c.check(t.Query, sc)
+	case refQuery:
+		c.check(t.Query, sc)
+	case setQuery:
+		c.check(t.q, sc)
+	case defaultQuery:
+		c.check(t.q, sc)
+	case checkQuery:
+		c.check(t.pred, sc)
+	case objectShape:
+		for _, k := range t {
+			c.check(k.shape, sc)
+		}
+	case arrayShape:
+		if t.elem != nil {
+			c.check(t.elem, sc)
+		}
+	}
+	return sc
+}
+
+// fold returns a simplified query equivalent to q.
+func fold(q Query) Query {
+	switch t := q.(type) {
+	case seqQuery:
+		var out seqQuery
+		for _, sq := range t {
+			fq := fold(sq)
+			if ss, ok := fq.(seqQuery); ok {
+				out = append(out, ss...)
+				continue
+			}
+			// Evaluate pure steps following a constant in advance.
+			if n := len(out); n > 0 {
+				if cq, ok := out[n-1].(constQuery); ok && isPure(fq) {
+					if _, w, err := fq.eval(nil, cq.Value); err == nil {
+						out[n-1] = constQuery{w}
+						continue
+					}
+				}
+			}
+			out = append(out, fq)
+		}
+		if len(out) == 1 {
+			return out[0]
+		}
+		return out
+	case Alt:
+		var out Alt
+		for _, alt := range t {
+			fa := fold(alt)
+			out = append(out, fa)
+			if _, ok := fa.(constQuery); ok {
+				break // a constant never fails, so later alternatives are unused
+			}
+		}
+		if len(out) == 1 {
+			return out[0]
+		}
+		return out
+	case Object:
+		out := make(Object, len(t))
+		for key, sq := range t {
+			out[key] = fold(sq)
+		}
+		return foldConst(out, values(out)...)
+	case Array:
+		out := make(Array, len(t))
+		for i, sq := range t {
+			out[i] = fold(sq)
+		}
+		return foldConst(out, out...)
+	case mergeQuery:
+		out := mergeQuery{qs: make([]Query, len(t.qs)), deep: t.deep}
+		for i, sq := range t.qs {
+			out.qs[i] = fold(sq)
+		}
+		return foldConst(out, out.qs...)
+	case defaultQuery:
+		fq := fold(t.q)
+		if _, ok := fq.(constQuery); ok {
+			return fq
+		}
+		return defaultQuery{fq, t.v}
+	case *Compiled:
+		return t.q // already folded
+	case asQuery:
+		return asQuery{t.name, fold(t.q)}
+	case recQuery:
+		return recQuery{fold(t.Query)}
+	case eachQuery:
+		return eachQuery{fold(t.Query)}
+	case selectQuery:
+		return selectQuery{fold(t.Query)}
+	case refQuery:
+		return refQuery{fold(t.Query)}
+	case scopeQuery:
+		return scopeQuery{fold(t.Query)}
+	case setQuery:
+		return setQuery{t.name, fold(t.q)}
+	}
+	return q
+}
+
+// foldConst returns the constant value of q if all its subqueries qs are
+// constants and q can be evaluated successfully; otherwise it returns q.
+func foldConst(q Query, qs ...Query) Query {
+	for _, sq := range qs {
+		if _, ok := sq.(constQuery); !ok {
+			return q
+		}
+	}
+	if _, w, err := q.eval(nil, ast.Null); err == nil {
+		return constQuery{w}
+	}
+	return q
+}
+
+func values(o Object) []Query {
+	out := make([]Query, 0, len(o))
+	for _, q := range o {
+		out = append(out, q)
+	}
+	return out
+}
+
+// isPure reports whether the result of q depends only on its input, so that
+// it can be evaluated in advance for a constant input.
+func isPure(q Query) bool {
+	switch t := q.(type) {
+	case objKey, NKey, exactKey, nthQuery, sliceQuery, pickQuery, lenQuery,
+		globQuery, keysQuery, valuesQuery, entriesQuery, fromEntriesQuery,
+		hasQuery, typeQuery, delQuery, constQuery, typeShape:
+		return true
+	case seqQuery:
+		return allPure(t...)
+	case Alt:
+		return allPure(t...)
+	case Array:
+		return allPure(t...)
+	case Object:
+		return allPure(values(t)...)
+	case mergeQuery:
+		return allPure(t.qs...)
+	case eachQuery:
+		return isPure(t.Query)
+	case recQuery:
+		return isPure(t.Query)
+	case selectQuery:
+		return isPure(t.Query)
+	case refQuery:
+		return isPure(t.Query)
+	case scopeQuery:
+		return isPure(t.Query)
+	case setQuery:
+		return isPure(t.q)
+	case defaultQuery:
+		return isPure(t.q)
+	case checkQuery:
+		return isPure(t.pred)
+	case objectShape:
+		for _, k := range t {
+			if !isPure(k.shape) {
+				return false
+			}
+		}
+		return true
+	case arrayShape:
+		return t.elem == nil || isPure(t.elem)
+	case *Compiled:
+		return isPure(t.q)
+	}
+	return false
+}
+
+func allPure(qs ...Query) bool {
+	for _, q := range qs {
+		if !isPure(q) {
+			return false
+		}
+	}
+	return true
+}
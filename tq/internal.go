@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/persist"
 )
 
 func pathElem(key any) Query {
@@ -37,6 +40,9 @@ func pathElem(key any) Query {
 type NKey string
 
 func (n NKey) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		return findMember(qs, o, ast.TextEqualFold(string(n)), string(n))
+	}
 	return with(qs, v, func(obj ast.Object) (*qstate, ast.Value, error) {
 		mem := obj.FindKey(ast.TextEqualFold(string(n)))
 		if mem == nil {
@@ -49,6 +55,12 @@ func (n NKey) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 type objKey string
 
 func (o objKey) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if po, ok := v.(persist.Object); ok {
+		return persistKey(qs, po, string(o), true)
+	}
+	if jo, ok := decorated[*jwcc.Object](v); ok {
+		return findMember(qs, jo, ast.TextEqualFold(string(o)), string(o))
+	}
 	return with(qs, v, func(obj ast.Object) (*qstate, ast.Value, error) {
 		mem := obj.Find(string(o))
 		if mem == nil {
@@ -58,10 +70,52 @@ func (o objKey) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	})
 }
 
+// exactKey is a query for an object key using case-sensitive comparison.
+type exactKey string
+
+func (k exactKey) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if po, ok := v.(persist.Object); ok {
+		return persistKey(qs, po, string(k), false)
+	}
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		return findMember(qs, o, ast.TextEqual(string(k)), string(k))
+	}
+	return with(qs, v, func(obj ast.Object) (*qstate, ast.Value, error) {
+		mem := obj.FindKey(ast.TextEqual(string(k)))
+		if mem == nil {
+			return qs, nil, fmt.Errorf("key %q not found", k)
+		}
+		return qs, mem.Value, nil
+	})
+}
+
+// keyFuncQuery is a query for the first object member whose key satisfies
+// the function.
+type keyFuncQuery func(ast.Text) bool
+
+func (f keyFuncQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		if m := o.FindKey(f); m != nil {
+			return qs, m.Value, nil
+		}
+		return qs, nil, errors.New("no matching key")
+	}
+	return with(qs, v, func(obj ast.Object) (*qstate, ast.Value, error) {
+		mem := obj.FindKey(f)
+		if mem == nil {
+			return qs, nil, errors.New("no matching key")
+		}
+		return qs, mem.Value, nil
+	})
+}
+
 type nthQuery int
 
 func (nq nthQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
-	return with(qs, v, func(a ast.Array) (*qstate, ast.Value, error) {
+	if pa, ok := v.(persist.Array); ok {
+		return persistIndex(qs, pa, nq)
+	}
+	return withArray(qs, v, func(a ast.Array) (*qstate, ast.Value, error) {
 		idx := int(nq)
 		if idx < 0 {
 			idx += len(a)
@@ -76,7 +130,7 @@ func (nq nthQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 type sliceQuery struct{ lo, hi int }
 
 func (q sliceQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
-	return with(qs, v, func(arr ast.Array) (*qstate, ast.Value, error) {
+	return withArray(qs, v, func(arr ast.Array) (*qstate, ast.Value, error) {
 		lox := q.lo
 		if lox < 0 {
 			lox += len(arr)
@@ -99,7 +153,7 @@ func (q sliceQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 type pickQuery []int
 
 func (q pickQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
-	return with(qs, v, func(arr ast.Array) (*qstate, ast.Value, error) {
+	return withArray(qs, v, func(arr ast.Array) (*qstate, ast.Value, error) {
 		var out ast.Array
 		for _, off := range q {
 			if off < 0 {
@@ -117,12 +171,12 @@ func (q pickQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 type eachQuery struct{ Query }
 
 func (q eachQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
-	return with(qs, v, func(a ast.Array) (*qstate, ast.Value, error) {
+	return withArray(qs, v, func(a ast.Array) (*qstate, ast.Value, error) {
 		var out ast.Array
 		for i, elt := range a {
-			_, v, err := q.Query.eval(qs, elt)
+			_, v, err := qs.eval(q.Query, elt)
 			if err != nil {
-				return qs, nil, fmt.Errorf("index %d: %w", i, err)
+				return qs, nil, wrapPath(err, i)
 			}
 			out = append(out, v)
 		}
@@ -133,6 +187,10 @@ func (q eachQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 type lenQuery struct{}
 
 func (lenQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	v = plain(v)
+	if d, ok := v.(*jwcc.Document); ok {
+		v = d.Value
+	}
 	if t, ok := v.(interface {
 		Len() int
 	}); ok {
@@ -155,31 +213,31 @@ func (q recQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 		next := stk[len(stk)-1]
 		stk = stk[:len(stk)-1]
 
-		ns, r, err := q.Query.eval(next.s, next.v)
+		ns, r, err := next.s.eval(q.Query, next.v)
 		if err == nil {
-			if a, ok := r.(ast.Array); ok {
-				out = append(out, a...)
-			} else {
+			switch t := r.(type) {
+			case ast.Array:
+				out = append(out, t...)
+			case *jwcc.Array:
+				out = append(out, children(t)...)
+			default:
 				out = append(out, r)
 			}
 		}
 
 		// N.B. Push in reverse order, so we visit in lexical order.
-		switch t := next.v.(type) {
-		case ast.Object:
-			for i := len(t) - 1; i >= 0; i-- {
-				stk = append(stk, entry{ns, t[i].Value})
-			}
-		case ast.Array:
-			for i := len(t) - 1; i >= 0; i-- {
-				stk = append(stk, entry{ns, t[i]})
-			}
+		kids := children(next.v)
+		for i := len(kids) - 1; i >= 0; i-- {
+			stk = append(stk, entry{ns, kids[i]})
 		}
 	}
 
 	if len(out) == 0 {
 		return qs, nil, errors.New("no matches")
 	}
+	if _, ok := v.(jwcc.Value); ok {
+		return qs, decoratedArray(nil, out), nil
+	}
 	return qs, out, nil
 }
 
@@ -187,9 +245,17 @@ type delQuery struct{ name string }
 
 func (d delQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	// As a special case, treat null as equivalent to an empty object.
-	if v == ast.Null {
+	if isNull(v) {
 		return qs, v, nil
 	}
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		return qs, deleteMember(o, d.name), nil
+	} else if po, ok := v.(persist.Object); ok {
+		if key, _, ok := po.Find(d.name); ok {
+			po = po.Delete(key)
+		}
+		return qs, po, nil
+	}
 	return with(qs, v, func(o ast.Object) (*qstate, ast.Value, error) {
 		found := o.Find(d.name)
 		if found == nil {
@@ -212,13 +278,24 @@ type setQuery struct {
 }
 
 func (s setQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
-	_, t, err := s.q.eval(qs, v)
+	_, t, err := qs.eval(s.q, v)
 	if err != nil {
 		return qs, nil, err
 	}
-	if v == ast.Null {
+	if _, ok := v.(jwcc.Value); ok && isNull(v) {
+		v = new(jwcc.Object)
+	} else if v == ast.Null {
 		v = ast.Object{}
 	}
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		return qs, setMember(o, s.name, t), nil
+	} else if po, ok := v.(persist.Object); ok {
+		key, _, ok := po.Find(s.name)
+		if !ok {
+			key = s.name
+		}
+		return qs, po.Replace(key, s.name, t), nil
+	}
 	return with(qs, v, func(o ast.Object) (*qstate, ast.Value, error) {
 		found := o.Find(s.name)
 		if found == nil {
@@ -245,10 +322,84 @@ func (c constQuery) eval(qs *qstate, _ ast.Value) (*qstate, ast.Value, error) {
 	return qs, c.Value, nil
 }
 
+type mergeQuery struct {
+	qs   []Query
+	deep bool
+}
+
+func (q mergeQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	out := ast.Object{}
+	for i, mq := range q.qs {
+		_, w, err := qs.eval(mq, v)
+		if err != nil {
+			return qs, nil, fmt.Errorf("merge %d: %w", i, err)
+		}
+		if jv, ok := w.(jwcc.Value); ok {
+			w = jv.Undecorate()
+		}
+		w = flatten(w)
+		if w == ast.Null {
+			continue
+		}
+		obj, ok := w.(ast.Object)
+		if !ok {
+			return qs, nil, fmt.Errorf("merge %d: got %T, want ast.Object", i, w)
+		}
+		out = mergeObjects(out, obj, q.deep)
+	}
+	return qs, out, nil
+}
+
+// mergeObjects returns a new object containing the members of dst updated
+// with the members of src. If deep is true, object values with the same key
+// are merged recursively. Neither input is modified.
+func mergeObjects(dst, src ast.Object, deep bool) ast.Object {
+	out := make(ast.Object, len(dst), len(dst)+len(src))
+	copy(out, dst)
+	for _, m := range src {
+		i := out.IndexKey(ast.TextEqualFold(m.Key.String()))
+		if i < 0 {
+			out = append(out, m)
+			continue
+		}
+		if deep {
+			a, aok := out[i].Value.(ast.Object)
+			b, bok := m.Value.(ast.Object)
+			if aok && bok {
+				out[i] = &ast.Member{Key: out[i].Key, Value: mergeObjects(a, b, true)}
+				continue
+			}
+		}
+		out[i] = &ast.Member{Key: m.Key, Value: m.Value}
+	}
+	return out
+}
+
+type defaultQuery struct {
+	q Query
+	v ast.Value
+}
+
+func (d defaultQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	// N.B. If q fails, any bindings it made are discarded along with the error.
+	rs, w, err := qs.eval(d.q, v)
+	if err != nil {
+		return qs, d.v, nil
+	}
+	return rs, w, nil
+}
+
 type globQuery struct{}
 
 func (globQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if d, ok := v.(*jwcc.Document); ok {
+		v = d.Value
+	}
 	switch t := v.(type) {
+	case *jwcc.Object:
+		return qs, decoratedArray(nil, children(t)), nil
+	case *jwcc.Array:
+		return qs, t, nil
 	case ast.Object:
 		out := make(ast.Array, len(t))
 		for i, m := range t {
@@ -266,11 +417,18 @@ type keysQuery struct{}
 
 func (keysQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	var out ast.Array
-	if o, ok := v.(ast.Object); ok {
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		for _, m := range o.Members {
+			out = append(out, m.Key)
+		}
+		return qs, decoratedArray(nil, out), nil
+	} else if o, ok := v.(ast.Object); ok {
 		for _, m := range o {
 			out = append(out, m.Key)
 		}
 		return qs, out, nil
+	} else if _, ok := v.(jwcc.Value); ok && isNull(v) {
+		return qs, decoratedArray(nil, out), nil
 	} else if v == ast.Null {
 		return qs, out, nil
 	}
@@ -278,6 +436,222 @@ func (keysQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	return qs, nil, fmt.Errorf("cannot list keys of %T", v)
 }
 
+// pathStep reports the object key or array index selected by q from the input
+// value v, if q is a simple key or index query.
+func pathStep(q Query, v ast.Value) (any, bool) {
+	switch t := q.(type) {
+	case objKey:
+		return string(t), true
+	case NKey:
+		return string(t), true
+	case exactKey:
+		return string(t), true
+	case nthQuery:
+		idx := int(t)
+		if idx < 0 {
+			idx += len(children(v))
+		}
+		return idx, true
+	}
+	return nil, false
+}
+
+// wrapPath returns an *EvalError for err with the given path prefix. If err
+// is already an *EvalError, the prefix is added to a copy of its path.
+func wrapPath(err error, prefix ...any) error {
+	if len(prefix) == 0 {
+		return err
+	}
+	if e, ok := err.(*EvalError); ok {
+		path := append(append([]any(nil), prefix...), e.Path...)
+		return &EvalError{Path: path, Err: e.Err}
+	}
+	return &EvalError{Path: append([]any(nil), prefix...), Err: err}
+}
+
+// formatPath renders a path of object keys and array indices as a string in
+// the style of a JavaScript expression, e.g., a.b[3]["c d"].
+func formatPath(path []any) string {
+	var sb strings.Builder
+	for _, elt := range path {
+		switch t := elt.(type) {
+		case int:
+			fmt.Fprintf(&sb, "[%d]", t)
+		case string:
+			if isIdent(t) {
+				if sb.Len() != 0 {
+					sb.WriteByte('.')
+				}
+				sb.WriteString(t)
+			} else {
+				fmt.Fprintf(&sb, "[%q]", t)
+			}
+		default:
+			fmt.Fprintf(&sb, "[%v]", t)
+		}
+	}
+	return sb.String()
+}
+
+// isIdent reports whether s is a non-empty string of letters, digits, and
+// underscores that does not begin with a digit.
+func isIdent(s string) bool {
+	for i, ch := range s {
+		switch {
+		case ch == '_', unicode.IsLetter(ch):
+		case unicode.IsDigit(ch) && i > 0:
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+type hasQuery struct{ name string }
+
+func (h hasQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		return qs, ast.Bool(o.Find(h.name) != nil), nil
+	} else if o, ok := v.(ast.Object); ok {
+		return qs, ast.Bool(o.Find(h.name) != nil), nil
+	}
+	return qs, ast.Bool(false), nil
+}
+
+type typeQuery struct{}
+
+func (typeQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if d, ok := v.(*jwcc.Document); ok {
+		v = d.Value
+	}
+	switch t := plain(v).(type) {
+	case ast.Object, *jwcc.Object:
+		return qs, ast.String("object"), nil
+	case ast.Array, *jwcc.Array:
+		return qs, ast.String("array"), nil
+	case ast.Text:
+		return qs, ast.String("string"), nil
+	case ast.Number:
+		return qs, ast.String("number"), nil
+	case ast.Bool:
+		return qs, ast.String("boolean"), nil
+	default:
+		if t == ast.Null {
+			return qs, ast.String("null"), nil
+		}
+		return qs, nil, fmt.Errorf("unknown value type %T", v)
+	}
+}
+
+type valuesQuery struct{}
+
+func (valuesQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		return qs, decoratedArray(nil, children(o)), nil
+	} else if o, ok := v.(ast.Object); ok {
+		return qs, ast.Array(children(o)), nil
+	} else if _, ok := v.(jwcc.Value); ok && isNull(v) {
+		return qs, decoratedArray(nil, nil), nil
+	} else if v == ast.Null {
+		return qs, ast.Array(nil), nil
+	}
+	return qs, nil, fmt.Errorf("cannot list values of %T", v)
+}
+
+type entriesQuery struct{}
+
+func (entriesQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if o, ok := decorated[*jwcc.Object](v); ok {
+		out := &jwcc.Array{Values: make([]jwcc.Value, len(o.Members))}
+		for i, m := range o.Members {
+			// The comments of the member are transferred to its entry.
+			e := &jwcc.Object{Members: []*jwcc.Member{
+				jwcc.Field("key", m.Key),
+				{Key: ast.String("value"), Value: m.Value},
+			}}
+			*e.Comments() = *m.Comments()
+			out.Values[i] = e
+		}
+		return qs, out, nil
+	} else if o, ok := v.(ast.Object); ok {
+		out := make(ast.Array, len(o))
+		for i, m := range o {
+			out[i] = ast.Object{ast.Field("key", m.Key), ast.Field("value", m.Value)}
+		}
+		return qs, out, nil
+	} else if _, ok := v.(jwcc.Value); ok && isNull(v) {
+		return qs, decoratedArray(nil, nil), nil
+	} else if v == ast.Null {
+		return qs, ast.Array(nil), nil
+	}
+	return qs, nil, fmt.Errorf("cannot list entries of %T", v)
+}
+
+type fromEntriesQuery struct{}
+
+func (fromEntriesQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if a, ok := decorated[*jwcc.Array](v); ok {
+		out := new(jwcc.Object)
+		for i, elt := range a.Values {
+			e, ok := elt.(*jwcc.Object)
+			if !ok {
+				return qs, nil, wrapPath(fmt.Errorf("got %T, want object", elt), i)
+			}
+			km := e.FindKey(ast.TextEqual("key"))
+			if km == nil {
+				return qs, nil, wrapPath(errors.New(`entry has no "key"`), i)
+			}
+			key, err := entryKey(km.Value)
+			if err != nil {
+				return qs, nil, wrapPath(err, i)
+			}
+			m := &jwcc.Member{Key: key, Value: jwcc.ToValue(nil)}
+			if vm := e.FindKey(ast.TextEqual("value")); vm != nil {
+				m.Value = vm.Value
+			}
+			// The comments of the entry are transferred to its member.
+			*m.Comments() = *e.Comments()
+			out.Members = append(out.Members, m)
+		}
+		return qs, out, nil
+	} else if a, ok := v.(ast.Array); ok {
+		out := make(ast.Object, len(a))
+		for i, elt := range a {
+			e, ok := elt.(ast.Object)
+			if !ok {
+				return qs, nil, wrapPath(fmt.Errorf("got %T, want object", elt), i)
+			}
+			km := e.FindKey(ast.TextEqual("key"))
+			if km == nil {
+				return qs, nil, wrapPath(errors.New(`entry has no "key"`), i)
+			}
+			key, err := entryKey(km.Value)
+			if err != nil {
+				return qs, nil, wrapPath(err, i)
+			}
+			out[i] = &ast.Member{Key: key, Value: ast.Null}
+			if vm := e.FindKey(ast.TextEqual("value")); vm != nil {
+				out[i].Value = vm.Value
+			}
+		}
+		return qs, out, nil
+	} else if _, ok := v.(jwcc.Value); ok && isNull(v) {
+		return qs, new(jwcc.Object), nil
+	} else if v == ast.Null {
+		return qs, ast.Object(nil), nil
+	}
+	return qs, nil, fmt.Errorf("cannot convert %T from entries", v)
+}
+
+// entryKey returns the key of an entry object given the value of its "key"
+// member, which must be a string.
+func entryKey(v ast.Value) (ast.Text, error) {
+	if key, ok := plain(v).(ast.Text); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("entry key is %T, not a string", plain(v))
+}
+
 func splitMark(s string) (key, mark string) {
 	if s == "" {
 		return "", ""
@@ -311,21 +685,37 @@ type asQuery struct {
 }
 
 func (q asQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
-	_, w, err := q.q.eval(qs, v)
+	_, w, err := qs.eval(q.q, v)
 	if err != nil {
 		return qs, nil, err
 	}
 	return qs.bind(q.name, w), v, nil
 }
 
+type scopeQuery struct{ Query }
+
+func (q scopeQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	_, w, err := qs.eval(q.Query, v)
+	return qs, w, err
+}
+
+type unbindQuery struct{ name string }
+
+func (q unbindQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	if _, ok := qs.lookup(q.name); !ok {
+		return qs, v, nil
+	}
+	return qs.bind(q.name, nil), v, nil
+}
+
 type refQuery struct{ Query }
 
 func (r refQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
-	_, w, err := r.Query.eval(qs, v)
+	_, w, err := qs.eval(r.Query, v)
 	if err != nil {
 		return qs, nil, err
 	}
-	switch t := w.(type) {
+	switch t := plain(w).(type) {
 	case ast.Number:
 		return nthQuery(int(t.Int())).eval(qs, v)
 	case ast.Text:
@@ -337,10 +727,10 @@ func (r refQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 type selectQuery struct{ Query }
 
 func (q selectQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
-	return with(qs, v, func(a ast.Array) (*qstate, ast.Value, error) {
+	return withArray(qs, v, func(a ast.Array) (*qstate, ast.Value, error) {
 		var out ast.Array
 		for _, elt := range a {
-			if _, _, err := q.Query.eval(qs, elt); err == nil {
+			if _, _, err := qs.eval(q.Query, elt); err == nil {
 				out = append(out, elt)
 			}
 		}
@@ -358,18 +748,64 @@ func with[T ast.Value](qs *qstate, v ast.Value, f func(T) (*qstate, ast.Value, e
 
 type qstate struct {
 	name  string
-	value ast.Value
+	value ast.Value // if nil, name is unbound
 	up    *qstate
+	trace *Trace // if non-nil, record evaluation steps here
 }
 
 func (s *qstate) bind(name string, value ast.Value) *qstate {
-	return &qstate{name: name, value: value, up: s}
+	return &qstate{name: name, value: value, up: s, trace: s.tracer()}
+}
+
+func (s *qstate) tracer() *Trace {
+	if s == nil {
+		return nil
+	}
+	return s.trace
+}
+
+// eval evaluates q on v in the environment s. All evaluation of subqueries
+// should go through this method, so that tracing can observe it.
+func (s *qstate) eval(q Query, v ast.Value) (*qstate, ast.Value, error) {
+	v = prepare(q, v)
+	tr := s.tracer()
+	if tr == nil {
+		return q.eval(s, v)
+	}
+	pos := len(tr.Steps)
+	tr.Steps = append(tr.Steps, TraceStep{Depth: tr.depth, Query: queryLabel(q), Input: v})
+	tr.depth++
+	rs, w, err := q.eval(s, v)
+	tr.depth--
+
+	step := &tr.Steps[pos]
+	step.Output, step.Err = w, err
+	if err == nil {
+		step.Bindings = rs.snapshot()
+	}
+	return rs, w, err
+}
+
+// snapshot returns a map of the names visible in s to their values.
+func (s *qstate) snapshot() map[string]ast.Value {
+	m := make(map[string]ast.Value)
+	for cur := s; cur != nil; cur = cur.up {
+		if _, ok := m[cur.name]; !ok {
+			m[cur.name] = cur.value
+		}
+	}
+	for name, v := range m {
+		if v == nil {
+			delete(m, name)
+		}
+	}
+	return m
 }
 
 func (s *qstate) lookup(name string) (ast.Value, bool) {
 	for cur := s; cur != nil; cur = cur.up {
 		if cur.name == name {
-			return cur.value, true
+			return cur.value, cur.value != nil
 		}
 	}
 	return nil, false
@@ -0,0 +1,148 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package tq
+
+import (
+	"fmt"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/jwcc"
+)
+
+// decorated reports whether v is a JWCC value of type T. A *jwcc.Document is
+// unwrapped to its underlying value before checking.
+func decorated[T jwcc.Value](v ast.Value) (T, bool) {
+	if d, ok := v.(*jwcc.Document); ok {
+		v = d.Value
+	}
+	t, ok := v.(T)
+	return t, ok
+}
+
+// plain returns the undecorated form of v if v is a JWCC datum or document
+// wrapping a datum, otherwise it returns v unchanged.
+func plain(v ast.Value) ast.Value {
+	if d, ok := decorated[*jwcc.Datum](v); ok {
+		return d.Value
+	}
+	return v
+}
+
+// isNull reports whether v is a JSON null, either plain or decorated.
+func isNull(v ast.Value) bool { return plain(v) == ast.Null }
+
+// toJWCC converts v to a jwcc.Value, decorating it if necessary.
+func toJWCC(v ast.Value) jwcc.Value {
+	if t, ok := v.(jwcc.Value); ok {
+		return t
+	}
+	return jwcc.Decorate(v)
+}
+
+// withArray calls f with the elements of v, if v is an array.
+//
+// If v is a *jwcc.Array, f receives a view of its elements, and if f returns
+// an ast.Array it is converted to a *jwcc.Array carrying the comments of v.
+func withArray(qs *qstate, v ast.Value, f func(ast.Array) (*qstate, ast.Value, error)) (*qstate, ast.Value, error) {
+	ja, ok := decorated[*jwcc.Array](v)
+	if !ok {
+		return with(qs, v, f)
+	}
+	view := make(ast.Array, len(ja.Values))
+	for i, elt := range ja.Values {
+		view[i] = elt
+	}
+	rs, w, err := f(view)
+	if out, ok := w.(ast.Array); ok && err == nil {
+		return rs, decoratedArray(ja, out), nil
+	}
+	return rs, w, err
+}
+
+// decoratedArray returns a *jwcc.Array with the elements of vs and a copy of
+// the comments of src.
+func decoratedArray(src jwcc.Value, vs ast.Array) *jwcc.Array {
+	out := &jwcc.Array{Values: make([]jwcc.Value, len(vs))}
+	for i, elt := range vs {
+		out.Values[i] = toJWCC(elt)
+	}
+	if src != nil {
+		*out.Comments() = *src.Comments()
+	}
+	return out
+}
+
+// findMember returns the value of the first member of o whose key satisfies f.
+func findMember(qs *qstate, o *jwcc.Object, f func(ast.Text) bool, key string) (*qstate, ast.Value, error) {
+	if m := o.FindKey(f); m != nil {
+		return qs, m.Value, nil
+	}
+	return qs, nil, fmt.Errorf("key %q not found", key)
+}
+
+// setMember returns a copy of o with the member named key set to v.  If o has
+// such a member already, its comments are preserved; and if v is not already
+// decorated, it inherits the comments of the value it replaces.
+func setMember(o *jwcc.Object, key string, v ast.Value) *jwcc.Object {
+	nv := toJWCC(v)
+	out := &jwcc.Object{Members: make([]*jwcc.Member, len(o.Members))}
+	*out.Comments() = *o.Comments()
+	copy(out.Members, o.Members)
+
+	i := o.IndexKey(ast.TextEqualFold(key))
+	if i < 0 {
+		out.Members = append(out.Members, &jwcc.Member{Key: ast.String(key), Value: nv})
+		return out
+	}
+	old := o.Members[i]
+	if _, ok := v.(jwcc.Value); !ok {
+		*nv.Comments() = *old.Value.Comments()
+	}
+	m := &jwcc.Member{Key: ast.String(key), Value: nv}
+	*m.Comments() = *old.Comments()
+	out.Members[i] = m
+	return out
+}
+
+// deleteMember returns a copy of o without the member named key.
+func deleteMember(o *jwcc.Object, key string) *jwcc.Object {
+	i := o.IndexKey(ast.TextEqualFold(key))
+	if i < 0 {
+		return o
+	}
+	out := &jwcc.Object{Members: make([]*jwcc.Member, 0, len(o.Members)-1)}
+	*out.Comments() = *o.Comments()
+	out.Members = append(out.Members, o.Members[:i]...)
+	out.Members = append(out.Members, o.Members[i+1:]...)
+	return out
+}
+
+// children returns the member values or elements of v, if v is an object or
+// array, or nil.
+func children(v ast.Value) []ast.Value {
+	switch t := v.(type) {
+	case ast.Object:
+		out := make([]ast.Value, len(t))
+		for i, m := range t {
+			out[i] = m.Value
+		}
+		return out
+	case ast.Array:
+		return t
+	case *jwcc.Document:
+		return children(t.Value)
+	case *jwcc.Object:
+		out := make([]ast.Value, len(t.Members))
+		for i, m := range t.Members {
+			out[i] = m.Value
+		}
+		return out
+	case *jwcc.Array:
+		out := make([]ast.Value, len(t.Values))
+		for i, v := range t.Values {
+			out[i] = v
+		}
+		return out
+	}
+	return nil
+}
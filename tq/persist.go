@@ -0,0 +1,78 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package tq
+
+import (
+	"fmt"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/persist"
+)
+
+// Queries that index or update objects and arrays accept the persistent
+// values defined by the persist package, and operate on them without copying.
+// Queries that only pass their input along to subqueries also accept them.
+// Other queries receive their input converted to an ordinary value.
+// Keys in a persistent object are compared as they are for an ordinary
+// object, so a query gives the same result for either representation.
+
+// prepare returns the input for evaluating q on v.
+func prepare(q Query, v ast.Value) ast.Value {
+	switch v.(type) {
+	case persist.Object, persist.Array:
+		if !acceptsPersist(q) {
+			return flatten(v)
+		}
+	}
+	return v
+}
+
+// acceptsPersist reports whether q accepts persistent values as input.
+func acceptsPersist(q Query) bool {
+	switch q.(type) {
+	case objKey, exactKey, nthQuery, setQuery, delQuery, constQuery, getQuery,
+		seqQuery, Alt, Object, Array, asQuery, scopeQuery, unbindQuery,
+		defaultQuery, selectQuery, callQuery, *Compiled:
+		return true
+	}
+	return false
+}
+
+// flatten converts a persistent object or array into an ordinary one, and
+// returns other values unchanged.
+func flatten(v ast.Value) ast.Value {
+	switch t := v.(type) {
+	case persist.Object:
+		return t.Object()
+	case persist.Array:
+		return t.Array()
+	}
+	return v
+}
+
+// persistKey returns the value of the member of o with the given key,
+// compared case-insensitively if fold is true.
+func persistKey(qs *qstate, o persist.Object, key string, fold bool) (*qstate, ast.Value, error) {
+	var v ast.Value
+	var ok bool
+	if fold {
+		_, v, ok = o.Find(key)
+	} else {
+		v, ok = o.Get(key)
+	}
+	if !ok {
+		return qs, nil, fmt.Errorf("key %q not found", key)
+	}
+	return qs, v, nil
+}
+
+func persistIndex(qs *qstate, a persist.Array, nq nthQuery) (*qstate, ast.Value, error) {
+	idx := int(nq)
+	if idx < 0 {
+		idx += a.Len()
+	}
+	if idx < 0 || idx >= a.Len() {
+		return qs, nil, fmt.Errorf("index %d out of range (0..%d)", nq, a.Len())
+	}
+	return qs, a.At(idx), nil
+}
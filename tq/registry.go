@@ -0,0 +1,78 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package tq
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/creachadair/jtree/ast"
+)
+
+// A Registry is a collection of named queries, allowing a library of queries
+// to be defined once and shared by name. A zero Registry is empty and ready
+// for use. A Registry is safe for concurrent use by multiple goroutines.
+type Registry struct {
+	mu sync.RWMutex
+	qs map[string]Query
+}
+
+// Define adds q to r under the given name, replacing any previous definition.
+func (r *Registry) Define(name string, q Query) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.qs == nil {
+		r.qs = make(map[string]Query)
+	}
+	r.qs[name] = q
+}
+
+// Lookup reports the query defined in r for name, if any.
+func (r *Registry) Lookup(name string) (Query, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	q, ok := r.qs[name]
+	return q, ok
+}
+
+// Names returns the names defined in r, in lexicographic order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.qs))
+	for name := range r.qs {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Call returns a query that evaluates the query defined in r for name on its
+// input. The name is resolved each time the query is evaluated, so a query
+// may refer to names that are defined later, including recursively to its
+// own name. Evaluation fails if name is not defined.
+func (r *Registry) Call(name string) Query { return callQuery{r, name} }
+
+// DefaultRegistry is the Registry used by the Define and Call functions.
+var DefaultRegistry = new(Registry)
+
+// Define adds q to DefaultRegistry under the given name.
+func Define(name string, q Query) { DefaultRegistry.Define(name, q) }
+
+// Call returns a query that evaluates the query defined for name in
+// DefaultRegistry. See Registry.Call.
+func Call(name string) Query { return DefaultRegistry.Call(name) }
+
+type callQuery struct {
+	r    *Registry
+	name string
+}
+
+func (c callQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
+	q, ok := c.r.Lookup(c.name)
+	if !ok {
+		return qs, nil, fmt.Errorf("query %q is not defined", c.name)
+	}
+	return qs.eval(q, v)
+}
@@ -0,0 +1,165 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package tq
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/persist"
+)
+
+// EvalSeq evaluates q beginning from root, like Eval, but returns an iterator
+// over its results.
+//
+// If q is an Each, Recur, or Select query, or a Path ending in one of these,
+// the iterator yields the elements of the array that Eval would return, one
+// at a time as they are found. The caller may stop the iteration early, in
+// which case the remaining elements are not evaluated. For any other query,
+// the iterator yields the single result of q.
+//
+// If evaluation fails, or a result does not have type T, the iterator yields
+// a zero T and the error, then stops.
+func EvalSeq[T ast.Value](root ast.Value, q Query) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		var empty *qstate
+		var stopped bool
+		err := evalStream(empty.bind("$", root), q, root, func(v ast.Value) error {
+			t, ok := v.(T)
+			if !ok {
+				return fmt.Errorf("got %T, want %T", v, zero)
+			} else if !yield(t, nil) {
+				stopped = true
+				return errStop
+			}
+			return nil
+		})
+		if err != nil && !stopped {
+			yield(zero, err)
+		}
+	}
+}
+
+// errStop is a sentinel error reported by an emit function to end streaming
+// evaluation early.
+var errStop = errors.New("stop iteration")
+
+// evalStream evaluates q on v in the environment qs, and calls emit with each
+// of its results. If q does not produce a stream of matches, emit is called
+// once with the result of q. If emit reports an error, evaluation stops and
+// evalStream reports an error that wraps it.
+func evalStream(qs *qstate, q Query, v ast.Value, emit func(ast.Value) error) error {
+	switch t := q.(type) {
+	case *Compiled:
+		return evalStream(qs, t.q, v, emit)
+
+	case seqQuery:
+		if len(t) == 0 {
+			return emit(v)
+		}
+		cs, cur := qs, v
+		var path []any
+		for _, sq := range t[:len(t)-1] {
+			ns, next, err := cs.eval(sq, cur)
+			if err != nil {
+				return wrapPath(err, path...)
+			}
+			if step, ok := pathStep(sq, cur); ok {
+				path = append(path, step)
+			}
+			cs, cur = ns, next
+		}
+		if err := evalStream(cs, t[len(t)-1], cur, emit); err != nil {
+			return wrapPath(err, path...)
+		}
+		return nil
+
+	case eachQuery:
+		elts, ok := arrayElems(v)
+		if !ok {
+			break
+		}
+		for i, elt := range elts {
+			_, w, err := qs.eval(t.Query, elt)
+			if err != nil {
+				return wrapPath(err, i)
+			} else if err := emit(w); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case selectQuery:
+		elts, ok := arrayElems(v)
+		if !ok {
+			break
+		}
+		for _, elt := range elts {
+			if _, _, err := qs.eval(t.Query, elt); err == nil {
+				if err := emit(elt); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+
+	case recQuery:
+		type entry struct {
+			s *qstate
+			v ast.Value
+		}
+		var found bool
+		stk := []entry{{qs, v}}
+		for len(stk) != 0 {
+			next := stk[len(stk)-1]
+			stk = stk[:len(stk)-1]
+
+			ns, r, err := next.s.eval(t.Query, next.v)
+			if err == nil {
+				rs, ok := arrayElems(r)
+				if !ok {
+					rs = []ast.Value{r}
+				}
+				for _, elt := range rs {
+					found = true
+					if err := emit(elt); err != nil {
+						return err
+					}
+				}
+			}
+
+			// N.B. Push in reverse order, so we visit in lexical order.
+			kids := children(next.v)
+			for i := len(kids) - 1; i >= 0; i-- {
+				stk = append(stk, entry{ns, kids[i]})
+			}
+		}
+		if !found {
+			return errors.New("no matches")
+		}
+		return nil
+	}
+
+	_, w, err := qs.eval(q, v)
+	if err != nil {
+		return err
+	}
+	return emit(w)
+}
+
+// arrayElems returns the elements of v if it is an array, either plain or
+// decorated.
+func arrayElems(v ast.Value) ([]ast.Value, bool) {
+	if a, ok := v.(ast.Array); ok {
+		return a, true
+	} else if _, ok := decorated[*jwcc.Array](v); ok {
+		return children(v), true
+	} else if pa, ok := v.(persist.Array); ok {
+		return pa.Array(), true
+	}
+	return nil, false
+}
@@ -50,11 +50,30 @@
 // to case, use tq.NKey. Path constructors support the shorthand "%x" for a
 // query like tq.NKey("x"). You can escape this if you want the literal string
 // "%x" by writing "%%x".
+//
+// # JWCC Values
+//
+// Queries may also be evaluated over JWCC values (see package jwcc). When the
+// input is a jwcc.Value, queries that traverse or edit the input, such as
+// Path, Each, Select, Slice, Set, and Delete, return jwcc.Value results that
+// retain the comments of the input. Queries that construct new values, such as
+// Object, Array, and Value, produce plain ast.Value results.
+//
+// # Assertions
+//
+// The Require and Check queries return their input unchanged if it has an
+// expected structure, and otherwise fail with an error describing the
+// mismatch. They can be placed in a path to validate the input at that
+// point, so that a query doubles as a lightweight validator:
+//
+//	tq.Path("server", tq.Require(map[string]any{"host": "string", "port?": "number"}), "host")
 package tq
 
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/creachadair/jtree/ast"
 )
@@ -63,7 +82,11 @@ import (
 // value or an error.
 func Eval[T ast.Value](root ast.Value, q Query) (T, error) {
 	var empty *qstate
-	_, w, err := q.eval(empty.bind("$", root), root)
+	return evalIn[T](empty.bind("$", root), root, q)
+}
+
+func evalIn[T ast.Value](qs *qstate, root ast.Value, q Query) (T, error) {
+	_, w, err := qs.eval(q, root)
 	if t, ok := w.(T); ok {
 		return t, nil
 	}
@@ -71,6 +94,25 @@ func Eval[T ast.Value](root ast.Value, q Query) (T, error) {
 	return zero, err
 }
 
+// EvalError is the concrete type of errors reported by query evaluation when
+// the failure occurs beneath a path of object keys and array indices.
+type EvalError struct {
+	// Path gives the object keys (string) and array indices (int) traversed
+	// from the input of the query to the point where the failure occurred.
+	Path []any
+
+	Err error // the underlying error
+}
+
+func (e *EvalError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("at %s: %v", formatPath(e.Path), e.Err)
+}
+
+func (e *EvalError) Unwrap() error { return e.Err }
+
 // A Query describes a traversal of a JSON value. The behavior of a query is
 // defined in terms of how it maps its input to an output. Both the input and
 // the output are JSON structures.
@@ -131,10 +173,14 @@ type seqQuery []Query
 
 func (q seqQuery) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	cs, cur := qs, v
+	var path []any
 	for _, sq := range q {
-		ns, next, err := sq.eval(cs, cur)
+		ns, next, err := cs.eval(sq, cur)
 		if err != nil {
-			return cs, nil, err
+			return cs, nil, wrapPath(err, path...)
+		}
+		if step, ok := pathStep(sq, cur); ok {
+			path = append(path, step)
 		}
 		cs, cur = ns, next
 	}
@@ -150,7 +196,7 @@ func (q Alt) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	for _, alt := range q {
 		// N.B. Evaluate each alternative in qs, so previous attempts do not
 		// affect the environment of the next.
-		rs, w, err := alt.eval(qs, v)
+		rs, w, err := qs.eval(alt, v)
 		if err == nil {
 			return rs, w, nil
 		}
@@ -158,6 +204,16 @@ func (q Alt) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	return qs, nil, errors.New("no matching alternatives")
 }
 
+// Default returns a query that evaluates q on its input and returns the
+// result if q succeeds. If q fails, the query returns the given fallback value
+// instead of an error. The value must be a string, int, float, bool, nil, or
+// ast.Value.
+func Default(q Query, value any) Query { return defaultQuery{q, ast.ToValue(value)} }
+
+// OrNull returns a query that evaluates q on its input and returns the result
+// if q succeeds, or null if q fails. It is shorthand for Default(q, nil).
+func OrNull(q Query) Query { return Default(q, nil) }
+
 // Recur applies a query to each recursive descendant of its input and returns
 // an array of the resulting values. The arguments have the same constraints as
 // Path.
@@ -175,7 +231,7 @@ type Object map[string]Query
 func (o Object) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	var out ast.Object
 	for key, q := range o {
-		_, val, err := q.eval(qs, v)
+		_, val, err := qs.eval(q, v)
 		if err != nil {
 			return qs, nil, fmt.Errorf("match %q: %w", key, err)
 		}
@@ -191,7 +247,7 @@ type Array []Query
 func (a Array) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	out := make(ast.Array, len(a))
 	for i, q := range a {
-		_, val, err := q.eval(qs, v)
+		_, val, err := qs.eval(q, v)
 		if err != nil {
 			return qs, nil, fmt.Errorf("index %d: %w", i, err)
 		}
@@ -200,10 +256,29 @@ func (a Array) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 	return qs, out, nil
 }
 
+// Merge returns a query that evaluates each of the given queries on its input
+// and merges the resulting objects from left to right into a single object.
+// If a key occurs in more than one object, the value from the rightmost object
+// is used, at the position where the key first occurred.  Keys are matched in
+// the same way as Set. A JSON null value is treated as an empty object.  It is
+// an error if any of the queries yields a value that is not an object or null.
+//
+// The result of Merge is a new ast.Object; if any of the objects merged are
+// JWCC values, their comments are discarded.
+func Merge(queries ...Query) Query { return mergeQuery{queries, false} }
+
+// DeepMerge is like Merge, except that when a key occurs in more than one
+// object and the values are objects, they are merged recursively rather than
+// replaced.
+func DeepMerge(queries ...Query) Query { return mergeQuery{queries, true} }
+
 // Delete returns a query that removes the specified key from its input object
 // and returns the resulting object. It is an error if the input is not an
 // object, but no error is reported if the input lacks that key. A JSON null
 // value is treated as an empty object for purposes of this query.
+//
+// If the input is a persist.Object, the result is a persist.Object that shares
+// the unmodified structure of the input.
 func Delete(name string) Query { return delQuery{name} }
 
 // Set returns a query that adds name to a copy of its input object, with the
@@ -213,6 +288,9 @@ func Delete(name string) Query { return delQuery{name} }
 // If name already exists in the input, its value is replaced in the output. It
 // is an error if the input is not an object.  A JSON null value is treated as
 // an empty object for purposes of this query.
+//
+// If the input is a persist.Object, the result is a persist.Object that shares
+// the unmodified structure of the input.
 func Set(name string, keys ...any) Query { return setQuery{name, Path(keys...)} }
 
 // Value returns a query that ignores its input and returns the given value.
@@ -228,6 +306,33 @@ func Glob() Query { return globQuery{} }
 // is an error if the input is not an object or null.
 func Keys() Query { return keysQuery{} }
 
+// Has returns a query that yields true if its input is an object with a
+// member whose key matches name, and otherwise false. Keys are matched in the
+// same way as a Path query. Unlike a Path query, Has does not fail if the
+// input is not an object.
+func Has(name string) Query { return hasQuery{name} }
+
+// TypeOf returns a query that yields the name of the JSON type of its input
+// as a string: "object", "array", "string", "number", "boolean", or "null".
+func TypeOf() Query { return typeQuery{} }
+
+// Values returns a query that yields an array of the values of an object, in
+// the order of their members. It is an error if the input is not an object or
+// null.
+func Values() Query { return valuesQuery{} }
+
+// Entries returns a query that converts an object into an array of objects,
+// one for each member, having the form {"key": k, "value": v}. It is an error
+// if the input is not an object or null.
+func Entries() Query { return entriesQuery{} }
+
+// FromEntries returns a query that converts an array of objects having the
+// form {"key": k, "value": v} into an object with a member for each, in the
+// order of the array. This reverses the effect of Entries.  Each key must be a
+// string; if the "value" member is missing, the value is null.  It is an error
+// if the input is not an array or null.
+func FromEntries() Query { return fromEntriesQuery{} }
+
 // Get returns a query that ignores its input and instead returns the value
 // associated with the specified parameter name. The query fails if the name is
 // not defined.
@@ -241,6 +346,18 @@ func As(name string, keys ...any) Query {
 	return asQuery{base, Path(keys...)}
 }
 
+// Scope evaluates the given subquery on its input and returns its result, in
+// the environment of the input. Bindings made by As within the subquery are
+// visible only within it. The arguments have the same constraints as Path.
+func Scope(keys ...any) Query { return scopeQuery{Path(keys...)} }
+
+// Unbind returns its input in an environment where name is not bound.
+// Unbinding a name that is not bound has no effect.
+func Unbind(name string) Query {
+	base, _ := splitMark(name)
+	return unbindQuery{base}
+}
+
 // Env is the namespace environment for a query.
 type Env struct{ *qstate }
 
@@ -253,15 +370,37 @@ func (e Env) Get(name string) ast.Value {
 	return nil
 }
 
+// Names returns the names bound in e, in lexicographic order.
+func (e Env) Names() []string {
+	m := e.qstate.snapshot()
+	out := make([]string, 0, len(m))
+	for name := range m {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Snapshot returns a map from each name bound in e to its value. Where a name
+// is bound more than once, the map records the innermost binding, which is
+// the one reported by Get. The map is a copy and may be modified freely.
+func (e Env) Snapshot() map[string]ast.Value { return e.qstate.snapshot() }
+
 // Bind extends e with a binding for the given name and value.  If the name
 // already exists in e, the new definition shadows the previous one.
+// Binding a name to nil is equivalent to calling Unbind.
 func (e Env) Bind(name string, value ast.Value) Env {
 	return Env{e.qstate.bind(name, value)}
 }
 
+// Unbind returns a copy of e in which name is not bound.
+func (e Env) Unbind(name string) Env {
+	return Env{e.qstate.bind(name, nil)}
+}
+
 // Eval evaluates the specified query starting from v.
 func (e Env) Eval(v ast.Value, q Query) (Env, ast.Value, error) {
-	rs, w, err := q.eval(e.qstate, v)
+	rs, w, err := e.qstate.eval(q, v)
 	return Env{qstate: rs}, w, err
 }
 
@@ -279,3 +418,54 @@ func (f Func) eval(qs *qstate, v ast.Value) (*qstate, ast.Value, error) {
 // from q is not a string or a number. The parameter q has the same constraints
 // as the arguments to Path.
 func Ref(q ...any) Query { return refQuery{Path(q...)} }
+
+// FromCursorPath returns a query that traverses path from its input, where
+// the path elements have the same meaning as for the Down method of a
+// cursor.Cursor, so that a path used for interactive navigation can be reused
+// as a query:
+//
+//   - A string selects an object member by its key, case-sensitively. A
+//     string beginning with "%" selects a member case-insensitively, and a
+//     doubled "%%" escapes this meaning.
+//   - An int selects an array element by offset, counting backward from the
+//     end if negative.
+//   - A func(ast.Text) bool selects the first object member whose key it
+//     reports true for.
+//   - A func(ast.Value) (ast.Value, error) is called to compute the next value.
+//   - A nil element is ignored.
+//
+// Unlike a cursor, the query yields the value of a selected object member
+// rather than the member itself, and an int cannot select an object member by
+// position. FromCursorPath panics if a path element has any other type.
+func FromCursorPath(path ...any) Query {
+	sq := make(seqQuery, 0, len(path))
+	for _, elt := range path {
+		switch t := elt.(type) {
+		case string:
+			if strings.HasPrefix(t, "%%") {
+				sq = append(sq, exactKey(t[1:]))
+			} else if strings.HasPrefix(t, "%") {
+				sq = append(sq, NKey(t[1:]))
+			} else {
+				sq = append(sq, exactKey(t))
+			}
+		case int:
+			sq = append(sq, nthQuery(t))
+		case func(ast.Text) bool:
+			sq = append(sq, keyFuncQuery(t))
+		case func(ast.Value) (ast.Value, error):
+			sq = append(sq, Func(func(e Env, v ast.Value) (Env, ast.Value, error) {
+				w, err := t(v)
+				return e, w, err
+			}))
+		case nil:
+			// skip
+		default:
+			panic(fmt.Sprintf("invalid cursor path element %T", elt))
+		}
+	}
+	if len(sq) == 1 {
+		return sq[0]
+	}
+	return sq
+}
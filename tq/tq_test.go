@@ -5,11 +5,18 @@ package tq_test
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/persist"
 	"github.com/creachadair/jtree/tq"
+	"github.com/google/go-cmp/cmp"
 )
 
 func mustParseFile(t *testing.T, path string) ast.Value {
@@ -487,6 +494,42 @@ func TestQuery(t *testing.T) {
 		}
 	})
 
+	t.Run("EvalError", func(t *testing.T) {
+		val := mustParse(t, []byte(`{"episodes": [
+         {"links": [{"url": "a"}]},
+         {"links": [{"title": "b"}, {"url": "c"}]}
+      ]}`))
+		v, err := tq.Eval[ast.Value](val, tq.Path("episodes", tq.Each("links", 0, "url")))
+		if err == nil {
+			t.Fatalf("Eval: got %v, wanted error", v)
+		}
+		var ee *tq.EvalError
+		if !errors.As(err, &ee) {
+			t.Fatalf("Eval: got error %[1]T (%[1]v), want *EvalError", err)
+		}
+		if diff := cmp.Diff([]any{"episodes", 1, "links", 0}, ee.Path); diff != "" {
+			t.Errorf("Error path (-want, +got):\n%s", diff)
+		}
+		const wantErr = `at episodes[1].links[0]: key "url" not found`
+		if got := err.Error(); got != wantErr {
+			t.Errorf("Error: got %q, want %q", got, wantErr)
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		v := mustEval(t, tq.Path("episodes", 0, tq.Object{
+			"date":  tq.Default(tq.Path("airDate"), "unknown"),
+			"guest": tq.Default(tq.Path("guestNames", 0), "unknown"),
+			"extra": tq.OrNull(tq.Path("nonesuch")),
+		}))
+		o := v.(ast.Object)
+		o.Sort()
+		const wantJSON = `{"date":"2021-11-30","extra":null,"guest":"unknown"}`
+		if got := v.JSON(); got != wantJSON {
+			t.Errorf("Result: got %#q, want %#q", got, wantJSON)
+		}
+	})
+
 	t.Run("KeysObj", func(t *testing.T) {
 		v := mustEval(t, tq.Path("episodes", 0, tq.Keys(), -1))
 		const want = "hasDetail"
@@ -495,6 +538,223 @@ func TestQuery(t *testing.T) {
 		}
 	})
 
+	t.Run("Values", func(t *testing.T) {
+		v := mustEval(t, tq.Path("episodes", 0, tq.Values(), 1))
+		if got := v.String(); got != wantString {
+			t.Errorf("Result: got %q, want %q", got, wantString)
+		}
+	})
+
+	t.Run("Has", func(t *testing.T) {
+		v := mustEval(t, tq.Array{
+			tq.Path("episodes", 0, tq.Has("airDate")),
+			tq.Path("episodes", 0, tq.Has("nonesuch")),
+			tq.Path("episodes", tq.Has("airDate")),
+		})
+		const wantJSON = `[true,false,false]`
+		if got := v.JSON(); got != wantJSON {
+			t.Errorf("Result: got %#q, want %#q", got, wantJSON)
+		}
+	})
+
+	t.Run("TypeOf", func(t *testing.T) {
+		val := mustParse(t, []byte(`[{}, [], "a", 1, 2.5, true, null]`))
+		v, err := tq.Eval[ast.Value](val, tq.Each(tq.TypeOf()))
+		if err != nil {
+			t.Fatalf("Eval failed: %v", err)
+		}
+		const wantJSON = `["object","array","string","number","number","boolean","null"]`
+		if got := v.JSON(); got != wantJSON {
+			t.Errorf("Result: got %#q, want %#q", got, wantJSON)
+		}
+	})
+
+	t.Run("Scope", func(t *testing.T) {
+		val := mustParse(t, []byte(`{"a": 1, "b": 2}`))
+
+		// A binding made inside a scope is not visible outside it.
+		if v, err := tq.Eval[ast.Value](val, tq.Path(tq.Scope(tq.As("x", "a")), "$x")); err == nil {
+			t.Errorf("Eval: got %v, wanted error", v)
+		}
+		v, err := tq.Eval[ast.Value](val, tq.Path(
+			tq.As("x", "a"),
+			tq.Scope(tq.As("x", "b"), "$x"),
+			tq.As("y"),
+			tq.Array{tq.Get("x"), tq.Get("y")},
+		))
+		if err != nil {
+			t.Fatalf("Eval failed: %v", err)
+		}
+		if got, want := v.JSON(), `[1,2]`; got != want {
+			t.Errorf("Result: got %#q, want %#q", got, want)
+		}
+
+		// Unbinding a name removes it, and reveals no outer binding.
+		q := tq.Path(tq.As("x", "a"), tq.As("x", "b"), tq.Unbind("x"), "$x")
+		if v, err := tq.Eval[ast.Value](val, q); err == nil {
+			t.Errorf("Eval: got %v, wanted error", v)
+		}
+		if _, err := tq.Compile(q); err == nil {
+			t.Error("Compile: got nil, wanted error")
+		}
+		if v, err := tq.Eval[ast.Value](val, tq.Path(tq.Unbind("$"), "$")); err == nil {
+			t.Errorf("Eval: got %v, wanted error", v)
+		}
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		val := mustParse(t, []byte(`{"a": 1, "b": 2}`))
+		var names []string
+		var snap map[string]ast.Value
+		_, err := tq.Eval[ast.Value](val, tq.Path(
+			tq.As("x", "a"), tq.As("y", "b"), tq.As("x", "b"), tq.Unbind("y"),
+			tq.Func(func(e tq.Env, v ast.Value) (tq.Env, ast.Value, error) {
+				names, snap = e.Names(), e.Snapshot()
+				return e, v, nil
+			}),
+		))
+		if err != nil {
+			t.Fatalf("Eval failed: %v", err)
+		}
+		if diff := cmp.Diff([]string{"$", "x"}, names); diff != "" {
+			t.Errorf("Names (-want, +got):\n%s", diff)
+		}
+		if len(snap) != 2 || snap["x"].JSON() != "2" || snap["$"].JSON() != val.JSON() {
+			t.Errorf("Snapshot: got %v, want x=2 and $=root", snap)
+		}
+	})
+
+	t.Run("Persist", func(t *testing.T) {
+		obj := persist.NewObject(ast.Object{
+			ast.Field("a", 1),
+			ast.Field("b", ast.Array{ast.Int(2), ast.Int(3)}),
+		})
+		tests := []struct {
+			query tq.Query
+			want  string
+		}{
+			{tq.Path(tq.Set("c", tq.Value("x")), tq.Delete("a")), `{"b":[2,3],"c":"x"}`},
+			{tq.Path("b", -1), `3`},
+			{tq.Keys(), `["a","b"]`},
+			{tq.Path(tq.Set("b", tq.Value(persist.NewArray(ast.Array{ast.Int(5)}))), "b", 0), `5`},
+		}
+		for _, test := range tests {
+			v, err := tq.Eval[ast.Value](obj, test.query)
+			if err != nil {
+				t.Errorf("Eval failed: %v", err)
+			} else if got := v.JSON(); got != test.want {
+				t.Errorf("Result: got %#q, want %#q", got, test.want)
+			}
+		}
+
+		// Set and Delete preserve the persistent representation.
+		v, err := tq.Eval[persist.Object](obj, tq.Set("a", tq.Value(2)))
+		if err != nil {
+			t.Fatalf("Eval failed: %v", err)
+		}
+		if got, want := v.JSON(), `{"a":2,"b":[2,3]}`; got != want {
+			t.Errorf("Result: got %#q, want %#q", got, want)
+		}
+		if got, want := obj.JSON(), `{"a":1,"b":[2,3]}`; got != want {
+			t.Errorf("Input: got %#q, want %#q", got, want)
+		}
+	})
+
+	t.Run("PersistParity", func(t *testing.T) {
+		// A query gives the same result for an ordinary object and for the
+		// persistent form of the same object.
+		val := mustParse(t, []byte(`{"Name": "top", "list": [{"ID": 1}, {"id": 2}], "name": "second"}`)).(ast.Object)
+		obj := persist.NewObject(val)
+		for _, q := range []tq.Query{
+			tq.Path("name"),
+			tq.Path("NAME"),
+			tq.Path("LIST", 0, "id"),
+			tq.Path(tq.Set("NAME", tq.Value(5)), "name"),
+			tq.Path(tq.Set("NAME", tq.Value(5)), tq.Keys()),
+			tq.Path(tq.Delete("name"), "name"),
+			tq.Path(tq.Delete("name"), tq.Keys()),
+			tq.Path("list", tq.Each(tq.Path("id"))),
+			tq.FromCursorPath("name"), // exact match
+			tq.Path("nonesuch"),
+		} {
+			want, wantErr := tq.Eval[ast.Value](val, q)
+			got, gotErr := tq.Eval[ast.Value](obj, q)
+			if (wantErr == nil) != (gotErr == nil) {
+				t.Errorf("Eval %v: ast error %v, persist error %v", q, wantErr, gotErr)
+			} else if wantErr == nil && got.JSON() != want.JSON() {
+				t.Errorf("Eval %v: ast result %s, persist result %s", q, want.JSON(), got.JSON())
+			}
+		}
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		val := mustParse(t, []byte(`{
+         "base": {"name": "x", "opts": {"a": 1, "b": 2}, "tags": ["p"]},
+         "over": {"opts": {"b": 3, "c": 4}, "tags": ["q"], "extra": true}
+      }`))
+		tests := []struct {
+			query tq.Query
+			want  string
+		}{
+			{tq.Merge(tq.Path("base"), tq.Path("over")),
+				`{"name":"x","opts":{"b":3,"c":4},"tags":["q"],"extra":true}`},
+			{tq.DeepMerge(tq.Path("base"), tq.Path("over")),
+				`{"name":"x","opts":{"a":1,"b":3,"c":4},"tags":["q"],"extra":true}`},
+			{tq.Merge(tq.Value(nil), tq.Path("base", "opts"), tq.Value(nil)),
+				`{"a":1,"b":2}`},
+			{tq.Merge(), `{}`},
+		}
+		for _, test := range tests {
+			v, err := tq.Eval[ast.Value](val, test.query)
+			if err != nil {
+				t.Errorf("Eval failed: %v", err)
+			} else if got := v.JSON(); got != test.want {
+				t.Errorf("Result: got %#q, want %#q", got, test.want)
+			}
+		}
+
+		// The inputs should not have been modified.
+		if got, want := val.JSON(), `{"base":{"name":"x","opts":{"a":1,"b":2},"tags":["p"]},"over":{"opts":{"b":3,"c":4},"tags":["q"],"extra":true}}`; got != want {
+			t.Errorf("Input: got %#q, want %#q", got, want)
+		}
+
+		if v, err := tq.Eval[ast.Value](val, tq.Merge(tq.Path("base", "tags"))); err == nil {
+			t.Errorf("Merge array: got %v, wanted error", v)
+		}
+	})
+
+	t.Run("Entries", func(t *testing.T) {
+		val := mustParse(t, []byte(`{"a": 1, "b": [true]}`))
+		v, err := tq.Eval[ast.Value](val, tq.Entries())
+		if err != nil {
+			t.Fatalf("Eval failed: %v", err)
+		}
+		const wantJSON = `[{"key":"a","value":1},{"key":"b","value":[true]}]`
+		if got := v.JSON(); got != wantJSON {
+			t.Errorf("Entries: got %#q, want %#q", got, wantJSON)
+		}
+
+		w, err := tq.Eval[ast.Value](v, tq.Path(tq.Each(tq.Set("value", tq.Value(1))), tq.FromEntries()))
+		if err != nil {
+			t.Fatalf("Eval failed: %v", err)
+		}
+		if got, want := w.JSON(), `{"a":1,"b":1}`; got != want {
+			t.Errorf("FromEntries: got %#q, want %#q", got, want)
+		}
+	})
+
+	t.Run("FromEntriesError", func(t *testing.T) {
+		val := mustParse(t, []byte(`[{"key": "a"}, {"value": 2}]`))
+		v, err := tq.Eval[ast.Value](val, tq.FromEntries())
+		if err == nil {
+			t.Fatalf("Eval: got %v, wanted error", v)
+		}
+		const wantErr = `at [1]: entry has no "key"`
+		if got := err.Error(); got != wantErr {
+			t.Errorf("Error: got %q, want %q", got, wantErr)
+		}
+	})
+
 	t.Run("KeysNull", func(t *testing.T) {
 		v := mustEval(t, tq.Path(ast.Null, tq.Keys()))
 		const wantJSON = `[]` // empty array
@@ -516,3 +776,478 @@ func TestQuery(t *testing.T) {
 func failq(e tq.Env, _ ast.Value) (tq.Env, ast.Value, error) {
 	return e, nil, errors.New("gratuitous failure")
 }
+
+func TestCompile(t *testing.T) {
+	val := mustParse(t, []byte(`{"a": [1, 2, 3], "b": {"c": "d"}}`))
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			query tq.Query
+			bad   []string
+		}{
+			{tq.Path("a", 0), nil},
+			{tq.Path("$", "a"), nil},
+			{tq.Path(tq.As("x", "a"), "$x"), nil},
+			{tq.Path("$x"), []string{"x"}},
+			{tq.Path(tq.Array{tq.As("x", "a")}, "$x"), []string{"x"}},
+			{tq.Path(tq.Alt{tq.As("x", "a"), tq.As("x", "b")}, "$x"), nil},
+			{tq.Path(tq.Alt{tq.As("x", "a"), tq.As("y", "b")}, "$x"), []string{"x"}},
+			{tq.Path(tq.Alt{tq.As("x", "a"), tq.Value(1)}, tq.Get("x")), []string{"x"}},
+			{tq.Path(tq.Alt{tq.As("x", "a"), tq.As("y", "b")}, tq.Alt{tq.Get("x"), tq.Get("y")}), []string{"x", "y"}},
+			{tq.Path(tq.Object{"p": tq.Get("p"), "q": tq.Get("q")}), []string{"p", "q"}},
+			{tq.Path(tq.Func(func(e tq.Env, v ast.Value) (tq.Env, ast.Value, error) {
+				return e.Bind("z", v), v, nil
+			}), "$z"), nil},
+		}
+		for _, test := range tests {
+			_, err := tq.Compile(test.query)
+			if test.bad == nil {
+				if err != nil {
+					t.Errorf("Compile: unexpected error: %v", err)
+				}
+				continue
+			}
+			if err == nil {
+				t.Errorf("Compile: got nil, want errors for %q", test.bad)
+				continue
+			}
+			for _, name := range test.bad {
+				if !strings.Contains(err.Error(), fmt.Sprintf("%q", name)) {
+					t.Errorf("Compile: error %q does not mention %q", err, name)
+				}
+			}
+		}
+
+		// Parameters may be supplied by the caller.
+		if _, err := tq.Compile(tq.Get("p"), "p"); err != nil {
+			t.Errorf("Compile with params: unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Eval", func(t *testing.T) {
+		tests := []struct {
+			query tq.Query
+			want  string
+		}{
+			{tq.Path("a", -1), `3`},
+			{tq.Path(tq.Path("a", tq.Path(tq.Len())), tq.Path()), `3`},
+			{tq.Array{tq.Value(1), tq.Path(tq.Value(ast.Array{ast.String("x"), ast.String("y")}), 1)}, `[1,"y"]`},
+			{tq.Merge(tq.Value(ast.Object{ast.Field("p", 1)}), tq.Path("b")), `{"p":1,"c":"d"}`},
+			{tq.Alt{tq.Path("nonesuch"), tq.Value("ok"), tq.Path("a")}, `"ok"`},
+			{tq.Path(tq.As("x", "b"), "a", 0, tq.Array{tq.Get("x"), tq.Path()}), `[{"c":"d"},1]`},
+		}
+		for _, test := range tests {
+			c, err := tq.Compile(test.query)
+			if err != nil {
+				t.Errorf("Compile: unexpected error: %v", err)
+				continue
+			}
+			// Evaluate repeatedly, to check that the result is reusable.
+			for i := 0; i < 2; i++ {
+				v, err := tq.Eval[ast.Value](val, c)
+				if err != nil {
+					t.Errorf("Eval: unexpected error: %v", err)
+				} else if got := v.JSON(); got != test.want {
+					t.Errorf("Eval: got %#q, want %#q", got, test.want)
+				}
+			}
+		}
+
+		// Constant subqueries are evaluated in advance.
+		c, err := tq.Compile(tq.Path(tq.Array{tq.Value(1), tq.Value(2)}, tq.Len()))
+		if err != nil {
+			t.Fatalf("Compile: unexpected error: %v", err)
+		}
+		_, tr, err := tq.EvalTraced[ast.Number](val, c)
+		if err != nil {
+			t.Fatalf("Eval: unexpected error: %v", err)
+		}
+		if len(tr.Steps) != 2 || tr.Steps[1].Query != "value 2" {
+			t.Errorf("Trace: got %v, want a single constant step", tr)
+		}
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	val := mustParseFile(t, "../testdata/input.json")
+
+	var r tq.Registry
+	r.Define("dates", tq.Path("episodes", tq.Each("airDate")))
+	r.Define("first", tq.Path(r.Call("dates"), 0))
+
+	if got, want := r.Names(), []string{"dates", "first"}; !cmp.Equal(got, want) {
+		t.Errorf("Names: got %q, want %q", got, want)
+	}
+
+	v, err := tq.Eval[ast.String](val, r.Call("first"))
+	if err != nil {
+		t.Fatalf("Eval: unexpected error: %v", err)
+	}
+	want, err := tq.Eval[ast.String](val, tq.Path("episodes", 0, "airDate"))
+	if err != nil {
+		t.Fatalf("Eval: unexpected error: %v", err)
+	} else if v != want {
+		t.Errorf("Eval: got %q, want %q", v, want)
+	}
+
+	// Redefining a name affects queries that call it.
+	r.Define("dates", tq.Value(ast.Array{ast.String("x")}))
+	if v, err := tq.Eval[ast.String](val, r.Call("first")); err != nil || v != "x" {
+		t.Errorf("Eval: got (%q, %v), want (x, nil)", v, err)
+	}
+
+	if v, err := tq.Eval[ast.Value](val, r.Call("nonesuch")); err == nil {
+		t.Errorf("Eval: got %v, want error", v)
+	}
+}
+
+func TestEvalSeq(t *testing.T) {
+	val := mustParseFile(t, "../testdata/input.json")
+
+	tests := []struct {
+		name  string
+		query tq.Query
+	}{
+		{"Each", tq.Path("episodes", tq.Each("episode"))},
+		{"Recur", tq.Recur("airDate")},
+		{"Select", tq.Path("episodes", tq.Select("guestNames"))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			all, err := tq.Eval[ast.Array](val, test.query)
+			if err != nil {
+				t.Fatalf("Eval: unexpected error: %v", err)
+			}
+
+			// Stop early, and check that we got a prefix of the full result.
+			var got ast.Array
+			for v, err := range tq.EvalSeq[ast.Value](val, test.query) {
+				if err != nil {
+					t.Fatalf("EvalSeq: unexpected error: %v", err)
+				}
+				got = append(got, v)
+				if len(got) == 5 {
+					break
+				}
+			}
+			if len(got) != 5 {
+				t.Fatalf("EvalSeq: got %d results, want 5", len(got))
+			}
+			if got, want := got.JSON(), all[:5].JSON(); got != want {
+				t.Errorf("EvalSeq: got %#q, want %#q", got, want)
+			}
+		})
+	}
+
+	t.Run("Single", func(t *testing.T) {
+		var got []ast.Value
+		for v, err := range tq.EvalSeq[ast.Number](val, tq.Path("episodes", 0, "episode")) {
+			if err != nil {
+				t.Fatalf("EvalSeq: unexpected error: %v", err)
+			}
+			got = append(got, v)
+		}
+		if len(got) != 1 || got[0].JSON() != "557" {
+			t.Errorf("EvalSeq: got %v, want [557]", got)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, q := range []tq.Query{
+			tq.Path("episodes", tq.Each("nonesuch")),
+			tq.Path("episodes", tq.Each("episode")), // wrong type
+			tq.Recur("nonesuch"),
+		} {
+			var nerr int
+			for _, err := range tq.EvalSeq[ast.String](val, q) {
+				if err != nil {
+					t.Logf("Got expected error: %v", err)
+					nerr++
+				}
+			}
+			if nerr != 1 {
+				t.Errorf("EvalSeq: got %d errors, want 1", nerr)
+			}
+		}
+
+		var err error
+		for _, err = range tq.EvalSeq[ast.Value](val, tq.Path("episodes", tq.Each("nonesuch"))) {
+		}
+		var ee *tq.EvalError
+		if !errors.As(err, &ee) {
+			t.Fatalf("EvalSeq: got error %[1]T (%[1]v), want *EvalError", err)
+		}
+		if diff := cmp.Diff([]any{"episodes", 0}, ee.Path); diff != "" {
+			t.Errorf("Error path (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	val := mustParseFile(t, "../testdata/input.json")
+	other := mustParse(t, []byte(`{"episodes": [{"episode": 1}]}`))
+
+	var calls int
+	count := tq.Func(func(e tq.Env, v ast.Value) (tq.Env, ast.Value, error) {
+		calls++
+		return e, v, nil
+	})
+	q, err := tq.Compile(tq.Path(count, "episodes", 0, "episode"))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	var c tq.Cache
+	check := func(root ast.Value, want string, wantCalls int) {
+		t.Helper()
+		v, err := c.Eval(root, q)
+		if err != nil {
+			t.Fatalf("Eval: unexpected error: %v", err)
+		}
+		if got := v.JSON(); got != want {
+			t.Errorf("Eval: got %s, want %s", got, want)
+		}
+		if calls != wantCalls {
+			t.Errorf("Eval: query evaluated %d times, want %d", calls, wantCalls)
+		}
+	}
+
+	check(val, "557", 1)
+	check(val, "557", 1) // memoized
+	check(other, "1", 2) // a different root
+	check(val, "557", 2) // still memoized
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len: got %d, want 2", got)
+	}
+
+	c.Invalidate(val)
+	check(val, "557", 3) // re-evaluated
+	check(other, "1", 3) // not affected
+
+	c.Reset()
+	check(other, "1", 4)
+
+	// Concurrent requests for the same result evaluate the query once.
+	c.Reset()
+	calls = 0
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Eval(val, q)
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("Concurrent Eval: query evaluated %d times, want 1", calls)
+	}
+
+	// When the cache is full, the least recently used result is discarded.
+	c = tq.Cache{MaxEntries: 2}
+	calls = 0
+	third := mustParse(t, []byte(`{"episodes": [{"episode": 3}]}`))
+	check(val, "557", 1)
+	check(other, "1", 2)
+	check(val, "557", 2) // memoized, and now most recently used
+	check(third, "3", 3) // discards other
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len: got %d, want 2", got)
+	}
+	check(val, "557", 3)
+	check(other, "1", 4) // re-evaluated
+
+	// Distinct values with the same contents have distinct results.
+	arr := mustParse(t, []byte(`[1, 2, 3]`)).(ast.Array)
+	first, err := tq.Compile(tq.Path(count, 0))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	c, calls = tq.Cache{}, 0
+	for _, root := range []ast.Value{arr, arr[:2], arr[1:], slices.Clone(arr), arr} {
+		c.Eval(root, first)
+	}
+	if calls != 4 {
+		t.Errorf("Eval of slices: query evaluated %d times, want 4", calls)
+	}
+}
+
+func TestEvalTraced(t *testing.T) {
+	val := mustParse(t, []byte(`{"a": {"b": [1, 2]}}`))
+	v, tr, err := tq.EvalTraced[ast.Value](val, tq.Path(
+		"a", tq.As("x"), "b", tq.Alt{tq.Path(5), tq.Path(-1)},
+	))
+	if err != nil {
+		t.Fatalf("EvalTraced failed: %v", err)
+	}
+	if got := v.JSON(); got != "2" {
+		t.Errorf("Result: got %#q, want 2", got)
+	}
+	const want = `
+path (4 steps): {"a":{"b":[1,2]}} → 2 [x={"b":[1,2]}]
+  key "a": {"a":{"b":[1,2]}} → {"b":[1,2]}
+  as "x": {"b":[1,2]} → {"b":[1,2]} [x={"b":[1,2]}]
+    path (0 steps): {"b":[1,2]} → {"b":[1,2]}
+  key "b": {"b":[1,2]} → [1,2] [x={"b":[1,2]}]
+  alt (2 choices): [1,2] → 2 [x={"b":[1,2]}]
+    index 5: [1,2] ✗ index 5 out of range (0..2)
+    index -1: [1,2] → 2 [x={"b":[1,2]}]
+`
+	if diff := cmp.Diff(strings.TrimPrefix(want, "\n"), tr.String()); diff != "" {
+		t.Errorf("Trace (-want, +got):\n%s", diff)
+	}
+	if len(tr.Steps) != 8 {
+		t.Fatalf("Trace has %d steps, want 8", len(tr.Steps))
+	}
+	if step := tr.Steps[6]; step.Err == nil || step.Bindings != nil {
+		t.Errorf("Step 6: got err=%v, bindings=%v; want error and no bindings", step.Err, step.Bindings)
+	}
+}
+
+func TestJWCC(t *testing.T) {
+	data, err := os.ReadFile("../testdata/input.jwcc")
+	if err != nil {
+		t.Fatalf("Read input: %v", err)
+	}
+	doc, err := jwcc.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse input: %v", err)
+	}
+	mustEval := evalFunc[jwcc.Value](doc)
+
+	t.Run("Path", func(t *testing.T) {
+		v := mustEval(t, tq.Path("array", 1))
+		if got, want := v.Comments().Line, "// comment on value 2\n"; got != want {
+			t.Errorf("Line comment: got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Each", func(t *testing.T) {
+		v := mustEval(t, tq.Path("array", tq.Each()))
+		a, ok := v.(*jwcc.Array)
+		if !ok {
+			t.Fatalf("Result: got %T, want *jwcc.Array", v)
+		}
+		if got, want := a.Values[0].Comments().Line, "// comment on value 1\n"; got != want {
+			t.Errorf("Line comment: got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		v := mustEval(t, tq.Path("array", tq.Select(tq.Len())))
+		const wantJSON = `["value 1",null,"ok"]` // true has no length
+		if got := v.JSON(); got != wantJSON {
+			t.Errorf("Result: got %#q, want %#q", got, wantJSON)
+		}
+		a := v.(*jwcc.Array)
+		if got, want := a.Values[2].Comments().Line, "// note trailing comma\n"; got != want {
+			t.Errorf("Line comment: got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("SetDelete", func(t *testing.T) {
+		v := mustEval(t, tq.Path(
+			tq.Set("null", "$", "bool"),
+			tq.Delete("object"),
+			tq.Set("extra", tq.Value(5)),
+		))
+		o, ok := v.(*jwcc.Object)
+		if !ok {
+			t.Fatalf("Result: got %T, want *jwcc.Object", v)
+		}
+		const wantJSON = `{"array":["value 1",true,null,"ok"],"bool":true,"null":true,"extra":5}`
+		if got := o.JSON(); got != wantJSON {
+			t.Errorf("Result: got %#q, want %#q", got, wantJSON)
+		}
+		if got, want := o.Comments().Before, doc.Value.Comments().Before; !cmp.Equal(got, want) {
+			t.Errorf("Object comments: got %q, want %q", got, want)
+		}
+		if got, want := o.Members[0].Value.(*jwcc.Array).Values[0].Comments().Line, "// comment on value 1\n"; got != want {
+			t.Errorf("Line comment: got %q, want %q", got, want)
+		}
+
+		// The original document should not be modified.
+		if got := doc.Value.(*jwcc.Object).Members[2].Value.JSON(); got != "null" {
+			t.Errorf("Original value: got %#q, want null", got)
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		v := mustEval(t, tq.Path("object", tq.Keys()))
+		const wantJSON = `["x","y","z"]`
+		if got := v.JSON(); got != wantJSON {
+			t.Errorf("Result: got %#q, want %#q", got, wantJSON)
+		}
+	})
+}
+
+func TestRequire(t *testing.T) {
+	val := mustParse(t, []byte(`{
+  "name": "server",
+  "port": 8080,
+  "tags": ["a", "b"],
+  "users": [{"id": "alice", "admin": true}, {"id": "bob", "admin": "no"}],
+  "ok?": null
+}`))
+	positive := tq.Check(tq.Match(func(z ast.Number) bool { return z.Float() > 0 }), "must be positive")
+
+	tests := []struct {
+		name  string
+		shape any
+		want  string // error text, "" for success
+	}{
+		{"Any", "any", ""},
+		{"Type", "object", ""},
+		{"TypeMismatch", "array|string", "got object, want array or string"},
+		{"Keys", map[string]any{"name": "string", "port": positive, "tags": []any{"string"}}, ""},
+		{"Optional", map[string]any{"name?": "string", "missing?": "number", "ok??": "null"}, ""},
+		{"Missing", map[string]any{"name": "string", "host": "string"}, `missing required key "host"`},
+		{"Nested", map[string]any{
+			"users": []any{map[string]any{"id": "string", "admin": "boolean"}},
+		}, `at users[1].admin: got string, want boolean`},
+		{"Check", map[string]any{"tags": tq.Check(tq.Path(tq.Len(), tq.Match(func(z ast.Number) bool {
+			return z.Int() > 2
+		})), "too few tags")}, "at tags: too few tags: value does not match"},
+		{"CheckFalse", tq.Check(tq.Has("nonesuch"), "no nonesuch"), "no nonesuch"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tq.Eval[ast.Value](val, tq.Require(tc.shape))
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("Eval: unexpected error: %v", err)
+				} else if got.JSON() != val.JSON() {
+					t.Errorf("Eval: got %v, want input", got)
+				}
+			} else if err == nil || err.Error() != tc.want {
+				t.Errorf("Eval: got error %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	t.Run("JWCC", func(t *testing.T) {
+		doc, err := jwcc.Parse(strings.NewReader(`{"a": [1, "x"] // comment
+}`))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		_, err = tq.Eval[ast.Value](doc, tq.Require(map[string]any{"a": []any{"number"}}))
+		var ee *tq.EvalError
+		if !errors.As(err, &ee) || fmt.Sprint(ee.Path) != "[a 1]" {
+			t.Errorf("Eval: got error %v, want path [a 1]", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, shape := range []any{"widget", []any{"string", "number"}, 25} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("Require(%v): did not panic", shape)
+					}
+				}()
+				tq.Require(shape)
+			}()
+		}
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package tq
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/creachadair/jtree/ast"
+)
+
+// EvalTraced evaluates the given query beginning from root, as Eval does, and
+// also returns a trace of the steps of the evaluation. A trace is returned
+// even if evaluation fails.
+func EvalTraced[T ast.Value](root ast.Value, q Query) (T, *Trace, error) {
+	tr := new(Trace)
+	qs := &qstate{name: "$", value: root, trace: tr}
+	v, err := evalIn[T](qs, root, q)
+	return v, tr, err
+}
+
+// A Trace is a record of the steps of a query evaluation.
+type Trace struct {
+	// The steps of the evaluation, in the order they began.
+	Steps []TraceStep
+
+	depth int // current nesting depth
+}
+
+// A TraceStep records the evaluation of a single query during a trace.
+type TraceStep struct {
+	Depth  int       // nesting depth of the query; the outermost is 0
+	Query  string    // a brief description of the query
+	Input  ast.Value // the input to the query
+	Output ast.Value // the output of the query, if it succeeded
+	Err    error     // the error reported by the query, if it failed
+
+	// The parameter bindings visible after the query succeeded.
+	// This is nil if the query failed.
+	Bindings map[string]ast.Value
+}
+
+// String renders a human-readable summary of the step on one line.
+func (t TraceStep) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s%s: %s", strings.Repeat("  ", t.Depth), t.Query, jsonOf(t.Input))
+	if t.Err != nil {
+		fmt.Fprintf(&sb, " ✗ %v", t.Err)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, " → %s", jsonOf(t.Output))
+	if len(t.Bindings) > 1 {
+		names := make([]string, 0, len(t.Bindings))
+		for name := range t.Bindings {
+			if name != "$" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		sb.WriteString(" [")
+		for i, name := range names {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "%s=%s", name, jsonOf(t.Bindings[name]))
+		}
+		sb.WriteString("]")
+	}
+	return sb.String()
+}
+
+// String renders a human-readable summary of the trace, one step per line,
+// with nested steps indented beneath the queries that invoked them.
+func (t *Trace) String() string {
+	var sb strings.Builder
+	for _, step := range t.Steps {
+		sb.WriteString(step.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// jsonOf renders v as JSON text, abbreviated if it is long.
+func jsonOf(v ast.Value) string {
+	const maxLen = 60
+	if v == nil {
+		return "<nil>"
+	}
+	s := v.JSON()
+	if len(s) > maxLen {
+		return s[:maxLen-3] + "..."
+	}
+	return s
+}
+
+// queryLabel returns a brief human-readable description of q.
+func queryLabel(q Query) string {
+	switch t := q.(type) {
+	case objKey:
+		return fmt.Sprintf("key %q", string(t))
+	case NKey:
+		return fmt.Sprintf("nkey %q", string(t))
+	case exactKey:
+		return fmt.Sprintf("exact key %q", string(t))
+	case keyFuncQuery:
+		return "key func"
+	case nthQuery:
+		return fmt.Sprintf("index %d", int(t))
+	case seqQuery:
+		return fmt.Sprintf("path (%d steps)", len(t))
+	case Alt:
+		return fmt.Sprintf("alt (%d choices)", len(t))
+	case Object:
+		return "object"
+	case Array:
+		return "array"
+	case sliceQuery:
+		return fmt.Sprintf("slice %d:%d", t.lo, t.hi)
+	case pickQuery:
+		return fmt.Sprintf("pick %v", []int(t))
+	case eachQuery:
+		return "each"
+	case recQuery:
+		return "recur"
+	case selectQuery:
+		return "select"
+	case lenQuery:
+		return "len"
+	case globQuery:
+		return "glob"
+	case keysQuery:
+		return "keys"
+	case valuesQuery:
+		return "values"
+	case entriesQuery:
+		return "entries"
+	case fromEntriesQuery:
+		return "from-entries"
+	case hasQuery:
+		return fmt.Sprintf("has %q", t.name)
+	case typeQuery:
+		return "typeof"
+	case checkQuery:
+		return fmt.Sprintf("check %q", t.msg)
+	case typeShape:
+		if len(t) == 0 {
+			return "require any"
+		}
+		return "require " + strings.Join(t, "|")
+	case objectShape:
+		return fmt.Sprintf("require object (%d keys)", len(t))
+	case arrayShape:
+		return "require array"
+	case mergeQuery:
+		if t.deep {
+			return fmt.Sprintf("deep-merge (%d objects)", len(t.qs))
+		}
+		return fmt.Sprintf("merge (%d objects)", len(t.qs))
+	case delQuery:
+		return fmt.Sprintf("delete %q", t.name)
+	case setQuery:
+		return fmt.Sprintf("set %q", t.name)
+	case constQuery:
+		return "value " + jsonOf(t.Value)
+	case defaultQuery:
+		return "default " + jsonOf(t.v)
+	case getQuery:
+		return fmt.Sprintf("get %q", t.name)
+	case asQuery:
+		return fmt.Sprintf("as %q", t.name)
+	case refQuery:
+		return "ref"
+	case scopeQuery:
+		return "scope"
+	case unbindQuery:
+		return fmt.Sprintf("unbind %q", t.name)
+	case Func:
+		return "func"
+	case *Compiled:
+		return "compiled"
+	case callQuery:
+		return fmt.Sprintf("call %q", t.name)
+	default:
+		return fmt.Sprintf("%T", q)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ValidOptions are settings for the Valid and ValidBytes functions.
+// A nil *ValidOptions is ready for use, and accepts only standard JSON.
+type ValidOptions struct {
+	// Allow comments in the input.
+	AllowComments bool
+
+	// Allow trailing commas in objects and arrays.
+	AllowTrailingCommas bool
+
+	// If positive, the maximum nesting depth of objects and arrays permitted.
+	// If zero or negative, nesting depth is not limited.
+	MaxDepth int
+}
+
+func (o *ValidOptions) allowComments() bool { return o != nil && o.AllowComments }
+
+func (o *ValidOptions) allowTrailingCommas() bool { return o != nil && o.AllowTrailingCommas }
+
+func (o *ValidOptions) maxDepth() int {
+	if o == nil {
+		return 0
+	}
+	return o.MaxDepth
+}
+
+// Valid reports whether r contains exactly one syntactically valid JSON value,
+// subject to the given options. If the input is not valid, Valid reports false
+// along with an error describing the first problem found. Valid does not
+// construct any representation of the input.
+func Valid(r io.Reader, opts *ValidOptions) (bool, error) {
+	st := NewStream(r)
+	st.AllowComments(opts.allowComments())
+	st.AllowTrailingCommas(opts.allowTrailingCommas())
+
+	h := &validHandler{max: opts.maxDepth()}
+	if err := st.ParseOne(h); err == io.EOF {
+		return false, errors.New("empty input")
+	} else if err != nil {
+		return false, err
+	}
+	if err := st.nextToken(h); err == nil {
+		return false, fmt.Errorf("at %s: extra input after value", st.s.Location().First)
+	} else if err != io.EOF {
+		return false, err
+	}
+	return true, nil
+}
+
+// ValidBytes reports whether data contains exactly one syntactically valid
+// JSON value, subject to the given options. It is shorthand for calling Valid
+// with a reader for data.
+func ValidBytes(data []byte, opts *ValidOptions) (bool, error) {
+	return Valid(bytes.NewReader(data), opts)
+}
+
+// validHandler is a Handler that checks nesting depth but otherwise discards
+// all events.
+type validHandler struct {
+	depth, max int
+}
+
+func (v *validHandler) push(loc Anchor) error {
+	v.depth++
+	if v.max > 0 && v.depth > v.max {
+		return fmt.Errorf("at %s: nesting depth exceeds %d", loc.Location().First, v.max)
+	}
+	return nil
+}
+
+func (v *validHandler) pop() error { v.depth--; return nil }
+
+func (v *validHandler) BeginObject(loc Anchor) error { return v.push(loc) }
+func (v *validHandler) EndObject(Anchor) error       { return v.pop() }
+func (v *validHandler) BeginArray(loc Anchor) error  { return v.push(loc) }
+func (v *validHandler) EndArray(Anchor) error        { return v.pop() }
+func (*validHandler) BeginMember(Anchor) error       { return nil }
+func (*validHandler) EndMember(Anchor) error         { return nil }
+func (*validHandler) Value(Anchor) error             { return nil }
+func (*validHandler) EndOfInput(Anchor)              {}
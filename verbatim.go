@@ -0,0 +1,33 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package jtree
+
+import "io"
+
+// Verbatim copies the JSON text from r to w token by token, preserving all
+// whitespace and comments, so that the output is identical to the input.
+// Verbatim reports an error if the input cannot be scanned; in that case the
+// output contains the text of the input up to the invalid token.
+//
+// Verbatim does not check the syntax of the input beyond the lexical level,
+// and invalid UTF-8 sequences in the input are replaced by the Unicode
+// replacement rune in the output.
+func Verbatim(w io.Writer, r io.Reader) error {
+	s := NewScanner(r)
+	s.AllowComments(true)
+	s.RetainSpace(true)
+	for {
+		err := s.Next()
+		if _, werr := w.Write(s.Space()); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := w.Write(s.Text()); err != nil {
+			return err
+		}
+	}
+}
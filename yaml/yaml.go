@@ -0,0 +1,311 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+// Package yaml converts YAML documents into JSON syntax trees, so that the
+// query, diff, and formatting tools of jtree can be used on YAML input.
+//
+// Parse reads the documents of a YAML stream, and Convert converts a single
+// node parsed by gopkg.in/yaml.v3. The result is a jwcc.Value in which the
+// comments of the YAML input are attached, as line comments, to the values
+// and object members they annotate. Use the Undecorate method of the result
+// to obtain a plain ast.Value.
+//
+// Scalars are resolved by their YAML tags: Null, Boolean, integer, and float
+// scalars become the corresponding JSON values, and all others, including
+// timestamps and binary data, become strings with the text of the scalar.
+// Aliases are replaced by copies of the values they refer to, and merge keys
+// ("<<") are expanded. The keys of a mapping must be scalars, and are
+// converted to strings.
+//
+// To guard against documents that use aliases to expand into very large
+// values, conversion fails if the values copied by aliases are too large a
+// fraction of the result, using the same limits as gopkg.in/yaml.v3 applies
+// when it decodes into Go values.
+package yaml
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/jtree/ast"
+	"github.com/creachadair/jtree/jwcc"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Parse parses and converts each of the YAML documents in r. Comments at the
+// start and end of each document are attached to the resulting document.
+func Parse(r io.Reader) ([]*jwcc.Document, error) {
+	dec := yamlv3.NewDecoder(r)
+	var out []*jwcc.Document
+	for {
+		var doc yamlv3.Node
+		if err := dec.Decode(&doc); err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return nil, err
+		}
+		v, err := Convert(&doc)
+		if err != nil {
+			return nil, err
+		}
+		d, ok := v.(*jwcc.Document)
+		if !ok {
+			d = &jwcc.Document{Value: v}
+		}
+		out = append(out, d)
+	}
+}
+
+// Convert converts the YAML node n and its descendants into a JWCC value. If
+// n is a document node, the result is a *jwcc.Document.
+func Convert(n *yamlv3.Node) (jwcc.Value, error) {
+	c := &converter{active: make(map[*yamlv3.Node]bool)}
+	return c.convert(n)
+}
+
+// A converter converts YAML nodes into JWCC values.
+type converter struct {
+	active  map[*yamlv3.Node]bool // aliased nodes being converted
+	nodes   int                   // the number of nodes converted
+	aliased int                   // the number of nodes converted via aliases
+}
+
+// checkAliases reports an error if the nodes copied by aliases are too large
+// a fraction of the nodes converted so far. The limits are the same as those
+// used by gopkg.in/yaml.v3 when it decodes into Go values.
+func (c *converter) checkAliases(n *yamlv3.Node) error {
+	c.nodes++
+	if len(c.active) != 0 {
+		c.aliased++
+	}
+	if c.aliased > 100 && c.nodes > 1000 && float64(c.aliased)/float64(c.nodes) > allowedAliasRatio(c.nodes) {
+		return c.fail(n, "document contains excessive aliasing")
+	}
+	return nil
+}
+
+// allowedAliasRatio returns the largest permitted fraction of nodes copied by
+// aliases when count nodes have been converted. Small documents may use
+// aliases freely, but large ones may not consist mostly of copies.
+func allowedAliasRatio(count int) float64 {
+	switch {
+	case count <= 400_000:
+		return 0.99
+	case count >= 4_000_000:
+		return 0.10
+	}
+	return 0.99 - 0.89*float64(count-400_000)/3_600_000
+}
+
+func (c *converter) fail(n *yamlv3.Node, msg string, args ...any) error {
+	return fmt.Errorf("line %d, column %d: %s", n.Line, n.Column, fmt.Sprintf(msg, args...))
+}
+
+func (c *converter) convert(n *yamlv3.Node) (jwcc.Value, error) {
+	if err := c.checkAliases(n); err != nil {
+		return nil, err
+	}
+	var out jwcc.Value
+	var err error
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, c.fail(n, "empty document")
+		}
+		v, err := c.convert(n.Content[0])
+		if err != nil {
+			return nil, err
+		}
+		doc := &jwcc.Document{Value: v}
+		com := v.Comments()
+		com.Before = append(comments(n.HeadComment), com.Before...)
+		doc.Comments().End = comments(n.FootComment)
+		return doc, nil
+
+	case yamlv3.MappingNode:
+		out, err = c.mapping(n)
+	case yamlv3.SequenceNode:
+		out, err = c.sequence(n)
+	case yamlv3.ScalarNode:
+		var v ast.Value
+		v, err = c.scalar(n)
+		out = &jwcc.Datum{Value: v}
+
+	case yamlv3.AliasNode:
+		if c.active[n.Alias] {
+			return nil, c.fail(n, "recursive alias %q", n.Value)
+		}
+		c.active[n.Alias] = true
+		defer delete(c.active, n.Alias)
+		return c.convert(n.Alias)
+
+	default:
+		return nil, c.fail(n, "unknown node kind %v", n.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	com := out.Comments()
+	com.Before = comments(n.HeadComment)
+	com.Line = lineComment(n.LineComment)
+	return out, nil
+}
+
+func (c *converter) mapping(n *yamlv3.Node) (*jwcc.Object, error) {
+	obj := new(jwcc.Object)
+	var merged []*jwcc.Member // members from merge keys, which do not override
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		kn, vn := n.Content[i], n.Content[i+1]
+		if kn.Kind != yamlv3.ScalarNode {
+			return nil, c.fail(kn, "mapping key is not a scalar")
+		}
+		val, err := c.convert(vn)
+		if err != nil {
+			return nil, err
+		}
+		if kn.ShortTag() == "!!merge" {
+			ms, err := c.merge(vn, val)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, ms...)
+			continue
+		}
+
+		m := &jwcc.Member{Key: ast.String(kn.Value), Value: val}
+		com := m.Comments()
+		com.Before = comments(kn.HeadComment)
+		com.Line = lineComment(kn.LineComment)
+		com.End = comments(kn.FootComment)
+
+		// The comments of a value on the same line as its key belong to the
+		// member, since the formatter does not render them on the value.
+		vc := val.Comments()
+		if com.Line == "" {
+			com.Line = vc.Line
+		}
+		vc.Line = ""
+		obj.Members = append(obj.Members, m)
+	}
+	for _, m := range merged {
+		if obj.FindKey(ast.TextEqual(m.Key.String())) == nil {
+			obj.Members = append(obj.Members, m)
+		}
+	}
+	obj.Comments().End = comments(n.FootComment)
+	return obj, nil
+}
+
+// merge returns the members to be merged into a mapping from the value v of
+// a merge key, whose node is n. The value must be a mapping, or a sequence of
+// mappings in which earlier mappings take precedence.
+func (c *converter) merge(n *yamlv3.Node, v jwcc.Value) ([]*jwcc.Member, error) {
+	switch t := v.(type) {
+	case *jwcc.Object:
+		return t.Members, nil
+	case *jwcc.Array:
+		var out []*jwcc.Member
+		for _, elt := range t.Values {
+			obj, ok := elt.(*jwcc.Object)
+			if !ok {
+				return nil, c.fail(n, "merge value is not a mapping")
+			}
+			out = append(out, obj.Members...)
+		}
+		return out, nil
+	}
+	return nil, c.fail(n, "merge value is not a mapping")
+}
+
+func (c *converter) sequence(n *yamlv3.Node) (*jwcc.Array, error) {
+	arr := &jwcc.Array{Values: make([]jwcc.Value, len(n.Content))}
+	var foot []string // the foot comment of the previous element
+	for i, en := range n.Content {
+		v, err := c.convert(en)
+		if err != nil {
+			return nil, err
+		}
+		com := v.Comments()
+		com.Before = append(foot, com.Before...)
+		foot = comments(en.FootComment)
+		arr.Values[i] = v
+	}
+	arr.Comments().End = append(foot, comments(n.FootComment)...)
+	return arr, nil
+}
+
+func (c *converter) scalar(n *yamlv3.Node) (ast.Value, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return ast.Null, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, c.fail(n, "invalid Boolean: %v", err)
+		}
+		return ast.Bool(b), nil
+	case "!!int":
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return nil, c.fail(n, "invalid integer: %v", err)
+		}
+		switch z := v.(type) {
+		case int:
+			return ast.Int(z), nil
+		case uint64:
+			return ast.ParseSingle(strings.NewReader(strconv.FormatUint(z, 10)))
+		case float64:
+			return ast.Float(z), nil // out of range for integers
+		}
+		return nil, c.fail(n, "invalid integer %q", n.Value)
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, c.fail(n, "invalid float: %v", err)
+		}
+		return ast.Float(f), nil
+	}
+	return ast.String(n.Value), nil
+}
+
+// comments converts the text of a YAML comment into JWCC line comments. Blank
+// lines separate the text into chunks.
+func comments(text string) []string {
+	if text == "" {
+		return nil
+	}
+	var out []string
+	var cur []string
+	flush := func() {
+		if len(cur) != 0 {
+			out = append(out, strings.Join(cur, "\n"))
+			cur = cur[:0]
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			flush()
+			if len(out) != 0 && out[len(out)-1] != "" {
+				out = append(out, "")
+			}
+			continue
+		}
+		cur = append(cur, "//"+strings.TrimPrefix(line, "#"))
+	}
+	flush()
+	if len(out) != 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// lineComment converts the text of a YAML line comment into a JWCC line
+// comment.
+func lineComment(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "//" + strings.TrimPrefix(strings.TrimSpace(text), "#")
+}
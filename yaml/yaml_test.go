@@ -0,0 +1,111 @@
+// Copyright (C) 2026 Michael J. Fromberger. All Rights Reserved.
+
+package yaml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/creachadair/jtree/jwcc"
+	"github.com/creachadair/jtree/yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testInput = `# Service configuration.
+name: demo
+port: 8080   # the listen port
+ratio: 0.5
+enabled: yes
+debug: false
+owner: ~
+when: 2001-12-14
+big: 18446744073709551615
+inf: .inf
+defaults: &base
+  retries: 3
+  timeout: 10s
+primary:
+  <<: *base
+  timeout: 30s
+# Known hosts.
+hosts:
+  - alpha
+  # The backup host.
+  - beta
+  - [1, "two", 3.0]
+---
+second
+`
+
+func TestParse(t *testing.T) {
+	docs, err := yaml.Parse(strings.NewReader(testInput))
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	var got []string
+	for _, d := range docs {
+		got = append(got, d.Undecorate().JSON())
+	}
+	want := []string{
+		`{"name":"demo","port":8080,"ratio":0.5,"enabled":"yes","debug":false,"owner":null,` +
+			`"when":"2001-12-14","big":18446744073709551615,"inf":Infinity,` +
+			`"defaults":{"retries":3,"timeout":"10s"},"primary":{"timeout":"30s","retries":3},` +
+			`"hosts":["alpha","beta",[1,"two",3]]}`,
+		`"second"`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse (-want, +got):\n%s", diff)
+	}
+
+	obj := docs[0].Value.(*jwcc.Object)
+	// The YAML parser attaches a comment to the first key, unless a blank line
+	// separates them.
+	if diff := cmp.Diff([]string{"// Service configuration."}, obj.Find("name").Comments().Before); diff != "" {
+		t.Errorf("Leading comments (-want, +got):\n%s", diff)
+	}
+	if got, want := obj.Find("port").Comments().Line, "// the listen port"; got != want {
+		t.Errorf("Port line comment: got %q, want %q", got, want)
+	}
+	hosts := obj.Find("hosts")
+	if diff := cmp.Diff([]string{"// Known hosts."}, hosts.Comments().Before); diff != "" {
+		t.Errorf("Hosts comments (-want, +got):\n%s", diff)
+	}
+	beta := hosts.Value.(*jwcc.Array).Values[1]
+	if diff := cmp.Diff([]string{"// The backup host."}, beta.Comments().Before); diff != "" {
+		t.Errorf("Element comments (-want, +got):\n%s", diff)
+	}
+
+	// The result should be formattable as JWCC.
+	var buf strings.Builder
+	if err := jwcc.Format(&buf, docs[0]); err != nil {
+		t.Errorf("Format: unexpected error: %v", err)
+	} else if !strings.Contains(buf.String(), "// The backup host.") {
+		t.Errorf("Format: comment missing from output:\n%s", buf.String())
+	}
+}
+
+// aliasBomb is a small document whose aliases expand to about 10^8 nodes.
+const aliasBomb = `a0: &a0 [x, x, x, x, x, x, x, x, x, x]
+a1: &a1 [*a0, *a0, *a0, *a0, *a0, *a0, *a0, *a0, *a0, *a0]
+a2: &a2 [*a1, *a1, *a1, *a1, *a1, *a1, *a1, *a1, *a1, *a1]
+a3: &a3 [*a2, *a2, *a2, *a2, *a2, *a2, *a2, *a2, *a2, *a2]
+a4: &a4 [*a3, *a3, *a3, *a3, *a3, *a3, *a3, *a3, *a3, *a3]
+a5: &a5 [*a4, *a4, *a4, *a4, *a4, *a4, *a4, *a4, *a4, *a4]
+a6: &a6 [*a5, *a5, *a5, *a5, *a5, *a5, *a5, *a5, *a5, *a5]
+a7: &a7 [*a6, *a6, *a6, *a6, *a6, *a6, *a6, *a6, *a6, *a6]
+a8: &a8 [*a7, *a7, *a7, *a7, *a7, *a7, *a7, *a7, *a7, *a7]
+`
+
+func TestErrors(t *testing.T) {
+	for _, input := range []string{
+		"a: [1, 2",          // syntax error
+		"? [1, 2]\n: value", // non-scalar key
+		"a: &x 1\n<<: *x",   // merge of a scalar
+		"a: !!int notanint", // invalid tagged scalar
+		aliasBomb,           // excessive aliasing
+	} {
+		if docs, err := yaml.Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse %q: got %v, want error", input, docs)
+		}
+	}
+}